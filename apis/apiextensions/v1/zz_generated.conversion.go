@@ -169,6 +169,20 @@ func (c *GeneratedRevisionSpecConverter) v1ConnectionDetailToV1beta1ConnectionDe
 		pString4 = &xstring4
 	}
 	v1beta1ConnectionDetail.Value = pString4
+	var pV1beta1ConnectionDetailEmptyPolicy *v1beta1.ConnectionDetailEmptyPolicy
+	if source.TreatEmptyAs != nil {
+		v1beta1ConnectionDetailEmptyPolicy := v1beta1.ConnectionDetailEmptyPolicy(*source.TreatEmptyAs)
+		pV1beta1ConnectionDetailEmptyPolicy = &v1beta1ConnectionDetailEmptyPolicy
+	}
+	v1beta1ConnectionDetail.TreatEmptyAs = pV1beta1ConnectionDetailEmptyPolicy
+	var v1beta1ConnectionDetailTransformList []v1beta1.ConnectionDetailTransform
+	if source.Transforms != nil {
+		v1beta1ConnectionDetailTransformList = make([]v1beta1.ConnectionDetailTransform, len(source.Transforms))
+		for i := 0; i < len(source.Transforms); i++ {
+			v1beta1ConnectionDetailTransformList[i] = v1beta1.ConnectionDetailTransform(source.Transforms[i])
+		}
+	}
+	v1beta1ConnectionDetail.Transforms = v1beta1ConnectionDetailTransformList
 	return v1beta1ConnectionDetail
 }
 func (c *GeneratedRevisionSpecConverter) v1ContainerFunctionNetworkToV1beta1ContainerFunctionNetwork(source ContainerFunctionNetwork) v1beta1.ContainerFunctionNetwork {
@@ -689,6 +703,20 @@ func (c *GeneratedRevisionSpecConverter) v1beta1ConnectionDetailToV1ConnectionDe
 		pString4 = &xstring4
 	}
 	v1ConnectionDetail.Value = pString4
+	var pV1ConnectionDetailEmptyPolicy *ConnectionDetailEmptyPolicy
+	if source.TreatEmptyAs != nil {
+		v1ConnectionDetailEmptyPolicy := ConnectionDetailEmptyPolicy(*source.TreatEmptyAs)
+		pV1ConnectionDetailEmptyPolicy = &v1ConnectionDetailEmptyPolicy
+	}
+	v1ConnectionDetail.TreatEmptyAs = pV1ConnectionDetailEmptyPolicy
+	var v1ConnectionDetailTransformList []ConnectionDetailTransform
+	if source.Transforms != nil {
+		v1ConnectionDetailTransformList = make([]ConnectionDetailTransform, len(source.Transforms))
+		for i := 0; i < len(source.Transforms); i++ {
+			v1ConnectionDetailTransformList[i] = ConnectionDetailTransform(source.Transforms[i])
+		}
+	}
+	v1ConnectionDetail.Transforms = v1ConnectionDetailTransformList
 	return v1ConnectionDetail
 }
 func (c *GeneratedRevisionSpecConverter) v1beta1ContainerFunctionNetworkToV1ContainerFunctionNetwork(source v1beta1.ContainerFunctionNetwork) ContainerFunctionNetwork {
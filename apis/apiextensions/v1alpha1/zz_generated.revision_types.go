@@ -739,6 +739,49 @@ const (
 	ConnectionDetailTypeFromValue               ConnectionDetailType = "FromValue"
 )
 
+// A ConnectionDetailEmptyPolicy determines how a connection detail that is
+// present but empty is treated.
+type ConnectionDetailEmptyPolicy string
+
+// ConnectionDetailEmptyPolicy policies.
+const (
+	// ConnectionDetailEmptyPolicyMissing treats an empty connection detail as
+	// though it were missing, i.e. not yet ready.
+	ConnectionDetailEmptyPolicyMissing ConnectionDetailEmptyPolicy = "Missing"
+
+	// ConnectionDetailEmptyPolicyValid treats an empty connection detail as a
+	// legitimate empty value.
+	ConnectionDetailEmptyPolicyValid ConnectionDetailEmptyPolicy = "Valid"
+
+	// ConnectionDetailEmptyPolicyError treats an empty connection detail as an
+	// error.
+	ConnectionDetailEmptyPolicyError ConnectionDetailEmptyPolicy = "Error"
+)
+
+// A ConnectionDetailTransform transforms the value of a connection detail.
+// +kubebuilder:validation:Enum=Trim;Lowercase;Uppercase;Base64Encode;Base64Decode
+type ConnectionDetailTransform string
+
+// ConnectionDetailTransform transforms.
+const (
+	// ConnectionDetailTransformTrim removes leading and trailing whitespace.
+	ConnectionDetailTransformTrim ConnectionDetailTransform = "Trim"
+
+	// ConnectionDetailTransformLowercase converts a value to lower case.
+	ConnectionDetailTransformLowercase ConnectionDetailTransform = "Lowercase"
+
+	// ConnectionDetailTransformUppercase converts a value to upper case.
+	ConnectionDetailTransformUppercase ConnectionDetailTransform = "Uppercase"
+
+	// ConnectionDetailTransformBase64Encode encodes a value using standard
+	// base64 encoding.
+	ConnectionDetailTransformBase64Encode ConnectionDetailTransform = "Base64Encode"
+
+	// ConnectionDetailTransformBase64Decode decodes a value that is encoded
+	// using standard base64 encoding.
+	ConnectionDetailTransformBase64Decode ConnectionDetailTransform = "Base64Decode"
+)
+
 // ConnectionDetail includes the information about the propagation of the connection
 // information from one secret to another.
 type ConnectionDetail struct {
@@ -779,6 +822,21 @@ type ConnectionDetail struct {
 	// +optional
 	// +immutable
 	Value *string `json:"value,omitempty"`
+
+	// TreatEmptyAs determines how a connection detail that is present but
+	// empty is treated. Missing treats it as not yet available, Valid emits it
+	// as an empty value, and Error returns an error. Defaults to Valid.
+	// +optional
+	// +immutable
+	// +kubebuilder:validation:Enum=Missing;Valid;Error
+	TreatEmptyAs *ConnectionDetailEmptyPolicy `json:"treatEmptyAs,omitempty"`
+
+	// Transforms are applied to the connection detail's value, in order,
+	// before its TreatEmptyAs policy. A transform that fails, for example
+	// because a value isn't valid base64, is an error.
+	// +optional
+	// +immutable
+	Transforms []ConnectionDetailTransform `json:"transforms,omitempty"`
 }
 
 // A Function represents a Composition Function.
@@ -264,6 +264,16 @@ func (in *ConnectionDetail) DeepCopyInto(out *ConnectionDetail) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.TreatEmptyAs != nil {
+		in, out := &in.TreatEmptyAs, &out.TreatEmptyAs
+		*out = new(ConnectionDetailEmptyPolicy)
+		**out = **in
+	}
+	if in.Transforms != nil {
+		in, out := &in.Transforms, &out.Transforms
+		*out = make([]ConnectionDetailTransform, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionDetail.
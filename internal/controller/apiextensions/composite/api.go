@@ -24,7 +24,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
@@ -51,7 +53,10 @@ const (
 	errListCompositionRevisions        = "cannot list CompositionRevisions"
 	errUpdateComposite                 = "cannot update composite resource"
 	errCompositionNotCompatible        = "referenced composition is not compatible with this composite resource"
+	errFmtCompositeTypeMismatch        = "composition is for apiVersion %q kind %q, but composite resource is apiVersion %q kind %q"
 	errGetXRD                          = "cannot get composite resource definition"
+	errListXRDs                        = "cannot list composite resource definitions"
+	errFmtServedVersions               = "cannot determine served versions of %s"
 	errFetchCompositionRevision        = "cannot fetch composition revision"
 )
 
@@ -60,17 +65,58 @@ const (
 	reasonCompositionSelection event.Reason = "CompositionSelection"
 )
 
+// AnnotationKeyReloaderMatch is the annotation Stakater Reloader uses to
+// determine which secrets should trigger a rolling restart of the workloads
+// that consume them.
+const AnnotationKeyReloaderMatch = "reloader.stakater.com/match"
+
+// ReloaderAnnotations returns annotations that cause Stakater Reloader to
+// restart workloads that consume a connection secret when it changes.
+func ReloaderAnnotations() map[string]string {
+	return map[string]string{AnnotationKeyReloaderMatch: "true"}
+}
+
+// An APIFilteredSecretPublisherOption configures an
+// APIFilteredSecretPublisher.
+type APIFilteredSecretPublisherOption func(*APIFilteredSecretPublisher)
+
+// WithSecretAnnotations configures an APIFilteredSecretPublisher to add the
+// supplied annotations to the connection secrets it publishes, for example
+// the ReloaderAnnotations.
+func WithSecretAnnotations(a map[string]string) APIFilteredSecretPublisherOption {
+	return func(p *APIFilteredSecretPublisher) {
+		p.annotations = a
+	}
+}
+
+// WithSecretChecksumAnnotation configures an APIFilteredSecretPublisher to
+// annotate the connection secrets it publishes with a checksum of their data.
+// This ensures a secret's metadata changes whenever any of its values do, for
+// workload reloaders that watch annotations rather than data.
+func WithSecretChecksumAnnotation(key string) APIFilteredSecretPublisherOption {
+	return func(p *APIFilteredSecretPublisher) {
+		p.checksumAnnotation = key
+	}
+}
+
 // APIFilteredSecretPublisher publishes ConnectionDetails content after filtering
 // it through a set of permitted keys.
 type APIFilteredSecretPublisher struct {
 	client resource.Applicator
 	filter []string
+
+	annotations        map[string]string
+	checksumAnnotation string
 }
 
 // NewAPIFilteredSecretPublisher returns a ConnectionPublisher that only
 // publishes connection secret keys that are included in the supplied filter.
-func NewAPIFilteredSecretPublisher(c client.Client, filter []string) *APIFilteredSecretPublisher {
-	return &APIFilteredSecretPublisher{client: resource.NewAPIPatchingApplicator(c), filter: filter}
+func NewAPIFilteredSecretPublisher(c client.Client, filter []string, o ...APIFilteredSecretPublisherOption) *APIFilteredSecretPublisher {
+	p := &APIFilteredSecretPublisher{client: resource.NewAPIPatchingApplicator(c), filter: filter}
+	for _, fn := range o {
+		fn(p)
+	}
+	return p
 }
 
 // PublishConnection publishes the supplied ConnectionDetails to the Secret
@@ -82,24 +128,20 @@ func (a *APIFilteredSecretPublisher) PublishConnection(ctx context.Context, o re
 	}
 
 	s := resource.ConnectionSecretFor(o, o.GetObjectKind().GroupVersionKind())
-	m := map[string]bool{}
-	for _, key := range a.filter {
-		m[key] = true
-	}
-	for key, val := range c {
-		// If the filter does not have any keys, we allow all given keys to be
-		// published.
-		if len(m) == 0 || m[key] {
-			s.Data[key] = val
-		}
+	for key, val := range filterConnectionDetails(c, a.filter) {
+		s.Data[key] = val
 	}
+	a.annotate(s)
 
 	err := a.client.Apply(ctx, s,
 		resource.ConnectionSecretMustBeControllableBy(o.GetUID()),
 		resource.AllowUpdateIf(func(current, desired runtime.Object) bool {
 			// We consider the update to be a no-op and don't allow it if the
-			// current and existing secret data are identical.
-			return !cmp.Equal(current.(*corev1.Secret).Data, desired.(*corev1.Secret).Data, cmpopts.EquateEmpty())
+			// current and existing secret data are identical, and the current
+			// secret already has any annotations we add. The checksum
+			// annotation only changes when the data does.
+			cs, ds := current.(*corev1.Secret), desired.(*corev1.Secret)
+			return !cmp.Equal(cs.Data, ds.Data, cmpopts.EquateEmpty()) || !hasAnnotations(cs, a.annotations)
 		}),
 	)
 	if resource.IsNotAllowed(err) {
@@ -113,6 +155,48 @@ func (a *APIFilteredSecretPublisher) PublishConnection(ctx context.Context, o re
 	return true, nil
 }
 
+// annotate the supplied connection secret with any configured annotations.
+func (a *APIFilteredSecretPublisher) annotate(s *corev1.Secret) {
+	if len(a.annotations) == 0 && a.checksumAnnotation == "" {
+		return
+	}
+	meta.AddAnnotations(s, a.annotations)
+	if a.checksumAnnotation != "" {
+		meta.AddAnnotations(s, map[string]string{a.checksumAnnotation: connectionDetailsDigest(s.Data)})
+	}
+}
+
+// hasAnnotations returns true if the supplied object has all of the supplied
+// annotations.
+func hasAnnotations(o metav1.Object, a map[string]string) bool {
+	for k, v := range a {
+		if o.GetAnnotations()[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// filterConnectionDetails returns the supplied connection details that are
+// included in the supplied filter. If the filter does not have any keys, all
+// connection details are included.
+func filterConnectionDetails(c managed.ConnectionDetails, filter []string) managed.ConnectionDetails {
+	if len(filter) == 0 {
+		return c
+	}
+	m := map[string]bool{}
+	for _, key := range filter {
+		m[key] = true
+	}
+	out := make(managed.ConnectionDetails, len(c))
+	for key, val := range c {
+		if m[key] {
+			out[key] = val
+		}
+	}
+	return out
+}
+
 // UnpublishConnection is no-op since PublishConnection only creates resources
 // that will be garbage collected by Kubernetes when the managed resource is
 // deleted.
@@ -378,24 +462,132 @@ func (cc *ConfiguratorChain) Configure(ctx context.Context, cp resource.Composit
 	return nil
 }
 
+// A CompatibilityOption configures which compositions a Configurator
+// considers compatible with a composite resource.
+type CompatibilityOption func(*compatibility)
+
+// WithCompatibleAPIVersions configures a Configurator to consider a
+// composition compatible with a composite resource of the same kind if both
+// of their API versions are among the supplied API versions, e.g.
+// example.org/v1alpha1 and example.org/v1. This allows a composition to serve
+// several served versions of a composite resource.
+func WithCompatibleAPIVersions(apiVersions ...string) CompatibilityOption {
+	return func(c *compatibility) {
+		c.apiVersions = make(map[string]bool, len(apiVersions))
+		for _, v := range apiVersions {
+			c.apiVersions[v] = true
+		}
+	}
+}
+
+// A ServedVersionsFn returns the versions of the supplied kind of composite
+// resource that are served.
+type ServedVersionsFn func(ctx context.Context, gk schema.GroupKind) ([]string, error)
+
+// WithServedVersionCompatibility configures a Configurator to consider a
+// composition compatible with a composite resource of the same group and kind
+// if both of their versions are served, for example because the composite
+// resource's definition serves both the version the composition was written
+// for and the version it was promoted to. By default the composition and
+// composite resource must have exactly the same API version.
+func WithServedVersionCompatibility(fn ServedVersionsFn) CompatibilityOption {
+	return func(c *compatibility) {
+		c.served = fn
+	}
+}
+
+// XRDServedVersions returns a ServedVersionsFn that returns the served
+// versions declared by the CompositeResourceDefinition of a kind of composite
+// resource.
+func XRDServedVersions(c client.Reader) ServedVersionsFn {
+	return func(ctx context.Context, gk schema.GroupKind) ([]string, error) {
+		l := &v1.CompositeResourceDefinitionList{}
+		if err := c.List(ctx, l); err != nil {
+			return nil, errors.Wrap(err, errListXRDs)
+		}
+		for _, xrd := range l.Items {
+			if xrd.Spec.Group != gk.Group || xrd.Spec.Names.Kind != gk.Kind {
+				continue
+			}
+			served := make([]string, 0, len(xrd.Spec.Versions))
+			for _, v := range xrd.Spec.Versions {
+				if v.Served {
+					served = append(served, v.Name)
+				}
+			}
+			return served, nil
+		}
+		return nil, nil
+	}
+}
+
+type compatibility struct {
+	apiVersions map[string]bool
+	served      ServedVersionsFn
+}
+
+func newCompatibility(o ...CompatibilityOption) compatibility {
+	c := compatibility{}
+	for _, fn := range o {
+		fn(&c)
+	}
+	return c
+}
+
+// check returns an error describing what mismatched if the supplied
+// composition is not compatible with the supplied composite resource.
+func (c compatibility) check(ctx context.Context, cp resource.Composite, comp *v1.Composition) error {
+	gvk := cp.GetObjectKind().GroupVersionKind()
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	ref := comp.Spec.CompositeTypeRef
+
+	versionOK := ref.APIVersion == apiVersion || (c.apiVersions[ref.APIVersion] && c.apiVersions[apiVersion])
+	if versionOK && ref.Kind == kind {
+		return nil
+	}
+
+	// The composition may be for another served version of the same group
+	// and kind.
+	if rgv, err := schema.ParseGroupVersion(ref.APIVersion); err == nil && c.served != nil && rgv.Group == gvk.Group && ref.Kind == kind {
+		served, err := c.served(ctx, gvk.GroupKind())
+		if err != nil {
+			return errors.Wrapf(err, errFmtServedVersions, gvk.GroupKind())
+		}
+		if contains(served, rgv.Version) && contains(served, gvk.Version) {
+			return nil
+		}
+	}
+
+	return errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, ref.APIVersion, ref.Kind, apiVersion, kind), errCompositionNotCompatible)
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
 // NewAPIConfigurator returns a Configurator that configures a
 // composite resource using its composition.
-func NewAPIConfigurator(c client.Client) *APIConfigurator {
-	return &APIConfigurator{client: c}
+func NewAPIConfigurator(c client.Client, o ...CompatibilityOption) *APIConfigurator {
+	return &APIConfigurator{client: c, compatibility: newCompatibility(o...)}
 }
 
 // An APIConfigurator configures a composite resource using its
 // composition.
 type APIConfigurator struct {
 	client client.Client
+	compatibility
 }
 
 // Configure any required fields that were omitted from the composite resource
 // by copying them from its composition.
 func (c *APIConfigurator) Configure(ctx context.Context, cp resource.Composite, comp *v1.Composition) error {
-	apiVersion, kind := cp.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
-	if comp.Spec.CompositeTypeRef.APIVersion != apiVersion || comp.Spec.CompositeTypeRef.Kind != kind {
-		return errors.New(errCompositionNotCompatible)
+	if err := c.check(ctx, cp, comp); err != nil {
+		return err
 	}
 
 	if cp.GetWriteConnectionSecretToReference() != nil || comp.Spec.WriteConnectionSecretsToNamespace == nil {
@@ -33,6 +33,7 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 
 	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
@@ -54,6 +55,7 @@ func TestPublishConnection(t *testing.T) {
 		applicator resource.Applicator
 		o          resource.ConnectionSecretOwner
 		filter     []string
+		opts       []APIFilteredSecretPublisherOption
 		c          managed.ConnectionDetails
 	}
 	type want struct {
@@ -135,11 +137,77 @@ func TestPublishConnection(t *testing.T) {
 				published: true,
 			},
 		},
+		"SuccessfulPublishWithAnnotations": {
+			reason: "We should add any configured annotations, including a checksum of the secret's data.",
+			args: args{
+				applicator: resource.ApplyFn(func(_ context.Context, o client.Object, _ ...resource.ApplyOption) error {
+					want := resource.ConnectionSecretFor(owner, owner.GetObjectKind().GroupVersionKind())
+					want.Data = managed.ConnectionDetails{"cool": {42}}
+					want.SetAnnotations(map[string]string{
+						AnnotationKeyReloaderMatch: "true",
+						"example.org/checksum":     connectionDetailsDigest(managed.ConnectionDetails{"cool": {42}}),
+					})
+					if diff := cmp.Diff(want, o); diff != "" {
+						t.Errorf("-want, +got:\n%s", diff)
+					}
+					return nil
+				}),
+				o:    owner,
+				c:    managed.ConnectionDetails{"cool": {42}},
+				opts: []APIFilteredSecretPublisherOption{WithSecretAnnotations(ReloaderAnnotations()), WithSecretChecksumAnnotation("example.org/checksum")},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"AnnotationsNoOp": {
+			reason: "We should not publish a secret whose data and annotations are unchanged.",
+			args: args{
+				applicator: resource.ApplyFn(func(ctx context.Context, o client.Object, ao ...resource.ApplyOption) error {
+					for _, fn := range ao {
+						if err := fn(ctx, o.DeepCopyObject(), o); err != nil {
+							return err
+						}
+					}
+					return nil
+				}),
+				o:    owner,
+				c:    managed.ConnectionDetails{"cool": {42}},
+				opts: []APIFilteredSecretPublisherOption{WithSecretAnnotations(ReloaderAnnotations()), WithSecretChecksumAnnotation("example.org/checksum")},
+			},
+			want: want{
+				published: false,
+			},
+		},
+		"AnnotationsMissing": {
+			reason: "We should publish a secret whose data is unchanged if it's missing the configured annotations.",
+			args: args{
+				applicator: resource.ApplyFn(func(ctx context.Context, o client.Object, ao ...resource.ApplyOption) error {
+					current := o.DeepCopyObject().(*corev1.Secret)
+					current.SetAnnotations(nil)
+					for _, fn := range ao {
+						if err := fn(ctx, current, o); err != nil {
+							return err
+						}
+					}
+					return nil
+				}),
+				o:    owner,
+				c:    managed.ConnectionDetails{"cool": {42}},
+				opts: []APIFilteredSecretPublisherOption{WithSecretAnnotations(ReloaderAnnotations())},
+			},
+			want: want{
+				published: true,
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			a := &APIFilteredSecretPublisher{tc.args.applicator, tc.args.filter}
+			a := &APIFilteredSecretPublisher{client: tc.args.applicator, filter: tc.args.filter}
+			for _, fn := range tc.args.opts {
+				fn(a)
+			}
 			got, err := a.PublishConnection(context.Background(), tc.args.o, tc.args.c)
 			if diff := cmp.Diff(tc.want.published, got); diff != "" {
 				t.Errorf("\n%s\nPublish(...): -want, +got:\n%s", tc.reason, diff)
@@ -572,6 +640,75 @@ func TestFetchRevision(t *testing.T) {
 	}
 }
 
+func TestXRDServedVersions(t *testing.T) {
+	errBoom := errors.New("boom")
+	gk := schema.GroupKind{Group: "example.org", Kind: "XDatabase"}
+
+	xrd := func(group, kind string, versions ...v1.CompositeResourceDefinitionVersion) v1.CompositeResourceDefinition {
+		d := v1.CompositeResourceDefinition{}
+		d.Spec.Group = group
+		d.Spec.Names.Kind = kind
+		d.Spec.Versions = versions
+		return d
+	}
+
+	type want struct {
+		served []string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		c      client.Reader
+		want   want
+	}{
+		"ListError": {
+			reason: "We should return any error encountered listing XRDs.",
+			c:      &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			want: want{
+				err: errors.Wrap(errBoom, errListXRDs),
+			},
+		},
+		"NotFound": {
+			reason: "We should return no versions if no XRD defines the kind.",
+			c: &test.MockClient{MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
+				obj.(*v1.CompositeResourceDefinitionList).Items = []v1.CompositeResourceDefinition{
+					xrd("example.net", "XDatabase", v1.CompositeResourceDefinitionVersion{Name: "v1", Served: true}),
+				}
+				return nil
+			})},
+		},
+		"Served": {
+			reason: "We should return the served versions declared by the XRD that defines the kind.",
+			c: &test.MockClient{MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
+				obj.(*v1.CompositeResourceDefinitionList).Items = []v1.CompositeResourceDefinition{
+					xrd("example.org", "XCache", v1.CompositeResourceDefinitionVersion{Name: "v2", Served: true}),
+					xrd("example.org", "XDatabase",
+						v1.CompositeResourceDefinitionVersion{Name: "v1alpha1"},
+						v1.CompositeResourceDefinitionVersion{Name: "v1beta1", Served: true},
+						v1.CompositeResourceDefinitionVersion{Name: "v1", Served: true},
+					),
+				}
+				return nil
+			})},
+			want: want{
+				served: []string{"v1beta1", "v1"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			served, err := XRDServedVersions(tc.c)(context.Background(), gk)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nXRDServedVersions(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.served, served); diff != "" {
+				t.Errorf("\n%s\nXRDServedVersions(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestConfigure(t *testing.T) {
 	errBoom := errors.New("boom")
 
@@ -584,8 +721,25 @@ func TestConfigure(t *testing.T) {
 		ConnectionSecretWriterTo: cs,
 	}
 
+	xr := func(apiVersion string) *composite.Unstructured {
+		return composite.New(composite.WithGroupVersionKind(schema.FromAPIVersionAndKind(apiVersion, "XDatabase")))
+	}
+	served := func(versions ...string) CompatibilityOption {
+		return WithServedVersionCompatibility(func(_ context.Context, _ schema.GroupKind) ([]string, error) {
+			return versions, nil
+		})
+	}
+	composition := func(apiVersion string) *v1.Composition {
+		return &v1.Composition{
+			Spec: v1.CompositionSpec{
+				CompositeTypeRef: v1.TypeReference{APIVersion: apiVersion, Kind: "XDatabase"},
+			},
+		}
+	}
+
 	type args struct {
 		kube client.Client
+		o    []CompatibilityOption
 		cp   resource.Composite
 		comp *v1.Composition
 	}
@@ -610,7 +764,131 @@ func TestConfigure(t *testing.T) {
 			},
 			want: want{
 				cp:  &fake.Composite{},
-				err: errors.New(errCompositionNotCompatible),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "ola/crossplane.io", "olala", "", ""), errCompositionNotCompatible),
+			},
+		},
+		"APIVersionMismatch": {
+			reason: "Should explain that the API version mismatched if the kinds match",
+			args: args{
+				comp: &v1.Composition{
+					Spec: v1.CompositionSpec{
+						CompositeTypeRef: v1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: "XDatabase"},
+					},
+				},
+				cp: xr("example.org/v1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1"),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "example.org/v1alpha1", "XDatabase", "example.org/v1", "XDatabase"), errCompositionNotCompatible),
+			},
+		},
+		"KindMismatch": {
+			reason: "Should return an error if the kinds mismatch, even if the API version is allowed",
+			args: args{
+				o: []CompatibilityOption{WithCompatibleAPIVersions("example.org/v1alpha1", "example.org/v1")},
+				comp: &v1.Composition{
+					Spec: v1.CompositionSpec{
+						CompositeTypeRef: v1.TypeReference{APIVersion: "example.org/v1", Kind: "XCache"},
+					},
+				},
+				cp: xr("example.org/v1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1"),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "example.org/v1", "XCache", "example.org/v1", "XDatabase"), errCompositionNotCompatible),
+			},
+		},
+		"CompatibleAPIVersion": {
+			reason: "Should accept a composition for another compatible API version of the same kind",
+			args: args{
+				o: []CompatibilityOption{WithCompatibleAPIVersions("example.org/v1alpha1", "example.org/v1")},
+				comp: &v1.Composition{
+					Spec: v1.CompositionSpec{
+						CompositeTypeRef: v1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: "XDatabase"},
+					},
+				},
+				cp: xr("example.org/v1"),
+			},
+			want: want{
+				cp: xr("example.org/v1"),
+			},
+		},
+		"IncompatibleAPIVersion": {
+			reason: "Should return an error if only the composition's API version is allowed",
+			args: args{
+				o: []CompatibilityOption{WithCompatibleAPIVersions("example.org/v1alpha1")},
+				comp: &v1.Composition{
+					Spec: v1.CompositionSpec{
+						CompositeTypeRef: v1.TypeReference{APIVersion: "example.org/v1alpha1", Kind: "XDatabase"},
+					},
+				},
+				cp: xr("example.org/v1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1"),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "example.org/v1alpha1", "XDatabase", "example.org/v1", "XDatabase"), errCompositionNotCompatible),
+			},
+		},
+		"SameVersionServedVersionCompatibility": {
+			reason: "Should accept a composition for the same API version without determining which versions are served",
+			args: args{
+				o: []CompatibilityOption{WithServedVersionCompatibility(func(_ context.Context, _ schema.GroupKind) ([]string, error) {
+					return nil, errBoom
+				})},
+				comp: composition("example.org/v1"),
+				cp:   xr("example.org/v1"),
+			},
+			want: want{
+				cp: xr("example.org/v1"),
+			},
+		},
+		"ServedVersionCompatible": {
+			reason: "Should accept a composition for another served version of the same group and kind",
+			args: args{
+				o:    []CompatibilityOption{served("v1", "v1beta1")},
+				comp: composition("example.org/v1"),
+				cp:   xr("example.org/v1beta1"),
+			},
+			want: want{
+				cp: xr("example.org/v1beta1"),
+			},
+		},
+		"ServedVersionNotServed": {
+			reason: "Should return an error if the composition's version is not served",
+			args: args{
+				o:    []CompatibilityOption{served("v1beta1")},
+				comp: composition("example.org/v1"),
+				cp:   xr("example.org/v1beta1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1beta1"),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "example.org/v1", "XDatabase", "example.org/v1beta1", "XDatabase"), errCompositionNotCompatible),
+			},
+		},
+		"ServedVersionIncompatibleGroup": {
+			reason: "Should return an error if the composition is for another group, even if the versions are served",
+			args: args{
+				o:    []CompatibilityOption{served("v1", "v1beta1")},
+				comp: composition("example.net/v1"),
+				cp:   xr("example.org/v1beta1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1beta1"),
+				err: errors.Wrap(errors.Errorf(errFmtCompositeTypeMismatch, "example.net/v1", "XDatabase", "example.org/v1beta1", "XDatabase"), errCompositionNotCompatible),
+			},
+		},
+		"ServedVersionsError": {
+			reason: "Should return an error if we cannot determine which versions are served",
+			args: args{
+				o: []CompatibilityOption{WithServedVersionCompatibility(func(_ context.Context, _ schema.GroupKind) ([]string, error) {
+					return nil, errBoom
+				})},
+				comp: composition("example.org/v1"),
+				cp:   xr("example.org/v1beta1"),
+			},
+			want: want{
+				cp:  xr("example.org/v1beta1"),
+				err: errors.Wrapf(errBoom, errFmtServedVersions, schema.GroupKind{Group: "example.org", Kind: "XDatabase"}),
 			},
 		},
 		"AlreadyFilled": {
@@ -667,7 +945,7 @@ func TestConfigure(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			c := &APIConfigurator{client: tc.args.kube}
+			c := NewAPIConfigurator(tc.args.kube, tc.args.o...)
 			err := c.Configure(context.Background(), tc.args.cp, tc.args.comp)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nConfigure(...): -want, +got:\n%s", tc.reason, diff)
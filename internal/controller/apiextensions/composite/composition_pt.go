@@ -19,6 +19,7 @@ package composite
 import (
 	"context"
 	"strconv"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -116,6 +117,18 @@ func WithComposedConnectionDetailsExtractor(e ConnectionDetailsExtractor) PTComp
 	}
 }
 
+// WithCompositeConnectionDetailsCache configures a PatchAndTransformComposer
+// to serve previously assembled XR connection details from the supplied cache
+// when neither the composition, any composed resource, nor any connection secret
+// has changed, rather than fetching and extracting them again. The cache is
+// opt-in. Pass the same cache to the Reconciler using
+// WithConnectionDetailsCacheEviction so that deleted XRs are evicted from it.
+func WithCompositeConnectionDetailsCache(cc *CompositeConnectionDetailsCache) PTComposerOption {
+	return func(c *PTComposer) {
+		c.cache = cc
+	}
+}
+
 type composedResource struct {
 	Renderer
 	managed.ConnectionDetailsFetcher
@@ -133,6 +146,7 @@ type PTComposer struct {
 	composite   Renderer
 	composition CompositionTemplateAssociator
 	composed    composedResource
+	cache       *CompositeConnectionDetailsCache
 }
 
 // NewPTComposer returns a Composer that composes resources using Patch and
@@ -247,6 +261,35 @@ func (c *PTComposer) Compose(ctx context.Context, xr resource.Composite, req Com
 	}
 
 	conn := managed.ConnectionDetails{}
+	sources := map[string]string{}
+	summary := make([]ConnectionSourceStatus, 0, len(cds))
+
+	// If nothing our connection details are assembled from has changed since
+	// we last assembled them we serve them from cache, and skip fetching and
+	// extracting them below.
+	ckey, cached := "", false
+	if c.cache != nil {
+		srcs := make([]ConnectionDetailsCacheSource, len(cds))
+		for i := range cds {
+			if cds[i].TemplateRenderErr != nil {
+				continue
+			}
+			rv, err := ConnectionSecretResourceVersion(ctx, c.client, cds[i].Resource)
+			if err != nil {
+				return CompositionResult{}, errors.Wrap(err, errFetchDetails)
+			}
+			srcs[i] = ConnectionDetailsCacheSource{Resource: cds[i].Resource, SecretResourceVersion: rv}
+		}
+		ckey = CompositeConnectionDetailsCacheKey(xr, req.Composition, srcs...)
+		if cc, ok := c.cache.Get(xr.GetUID(), ckey); ok {
+			conn, sources, summary, cached = cc.ConnectionDetails, cc.Sources, cc.ConnectionSources, true
+		}
+	}
+
+	// Connection details are fetched in one batch if our fetcher supports it.
+	var batch []managed.ConnectionDetails
+	bf, batched := c.composed.ConnectionDetailsFetcher.(BatchConnectionDetailsFetcher)
+
 	for i := range cds {
 		// If we were unable to render the composed resource we should not try
 		// to observe it.
@@ -258,18 +301,37 @@ func (c *PTComposer) Compose(ctx context.Context, xr resource.Composite, req Com
 			return CompositionResult{}, errors.Wrap(err, errRenderCR)
 		}
 
-		cds[i].ConnectionDetails, err = c.composed.FetchConnection(ctx, cds[i].Resource)
-		if err != nil {
-			return CompositionResult{}, errors.Wrap(err, errFetchDetails)
-		}
+		if !cached {
+			if batched && batch == nil {
+				if batch, err = fetchBatch(ctx, bf, cds); err != nil {
+					return CompositionResult{}, errors.Wrap(err, errFetchDetails)
+				}
+			}
 
-		e, err := c.composed.ExtractConnection(cds[i].Resource, cds[i].ConnectionDetails, ExtractConfigsFromTemplate(cds[i].Template)...)
-		if err != nil {
-			return CompositionResult{}, errors.Wrap(err, errExtractDetails)
-		}
+			cs := ConnectionSourceStatus{Name: cds[i].Resource.GetName(), LastFetchTime: &metav1.Time{Time: time.Now()}}
+			if batched {
+				cds[i].ConnectionDetails = batch[i]
+			} else if cds[i].ConnectionDetails, err = c.composed.FetchConnection(ctx, cds[i].Resource); err != nil {
+				cs.Error = err.Error()
+				return CompositionResult{ConnectionSources: append(summary, cs)}, errors.Wrap(err, errFetchDetails)
+			}
+
+			ecfgs, err := SubstituteConnectionSecretKeys(xr, ExtractConfigsFromTemplate(cds[i].Template))
+			if err != nil {
+				return CompositionResult{}, errors.Wrap(err, errExtractDetails)
+			}
+
+			e, err := c.composed.ExtractConnection(cds[i].Resource, cds[i].ConnectionDetails, ecfgs...)
+			if err != nil {
+				return CompositionResult{}, errors.Wrap(err, errExtractDetails)
+			}
 
-		for key, val := range e {
-			conn[key] = val
+			for key, val := range e {
+				conn[key] = val
+				sources[key] = cds[i].Resource.GetName()
+			}
+			cs.Keys = len(e)
+			summary = append(summary, cs)
 		}
 
 		cds[i].Ready, err = c.composed.IsReady(ctx, cds[i].Resource, ReadinessChecksFromTemplate(cds[i].Template)...)
@@ -296,12 +358,16 @@ func (c *PTComposer) Compose(ctx context.Context, xr resource.Composite, req Com
 		return CompositionResult{}, errors.Wrap(err, errUpdate)
 	}
 
+	if c.cache != nil && !cached {
+		c.cache.Set(xr.GetUID(), ckey, CompositeConnectionDetails{ConnectionDetails: conn, Sources: sources, ConnectionSources: summary})
+	}
+
 	out := make([]ComposedResource, len(cds))
 	for i := range cds {
 		out[i] = cds[i].ComposedResource
 	}
 
-	return CompositionResult{ConnectionDetails: conn, Composed: out, Events: events}, nil
+	return CompositionResult{ConnectionDetails: conn, ConnectionDetailSources: sources, ConnectionSources: summary, Composed: out, Events: events}, nil
 }
 
 // toXRPatchesFromTAs selects patches defined in composed templates,
@@ -293,6 +293,47 @@ func TestPTCompose(t *testing.T) {
 					Composition: &v1.Composition{},
 				},
 			},
+			want: want{
+				res: CompositionResult{
+					ConnectionSources: []ConnectionSourceStatus{{Error: errBoom.Error()}},
+				},
+				err: errors.Wrap(errBoom, errFetchDetails),
+			},
+		},
+		"FetchConnectionDetailsBatchError": {
+			reason: "We should return any error encountered while fetching a batch of composed resources' connection details.",
+			params: params{
+				kube: &test.MockClient{
+					MockUpdate: test.NewMockUpdateFn(nil),
+
+					// Apply calls Get and Patch
+					MockGet:   test.NewMockGetFn(nil),
+					MockPatch: test.NewMockPatchFn(nil),
+				},
+				o: []PTComposerOption{
+					WithTemplateAssociator(CompositionTemplateAssociatorFn(func(ctx context.Context, c resource.Composite, ct []v1.ComposedTemplate) ([]TemplateAssociation, error) {
+						tas := []TemplateAssociation{{
+							Template: v1.ComposedTemplate{
+								Name: pointer.String("cool-resource"),
+							},
+						}}
+						return tas, nil
+					})),
+					WithComposedRenderer(RendererFn(func(ctx context.Context, cp resource.Composite, cd resource.Composed, t v1.ComposedTemplate, env *env.Environment) error {
+						return nil
+					})),
+					WithCompositeRenderer(RendererFn(func(ctx context.Context, cp resource.Composite, cd resource.Composed, t v1.ComposedTemplate, env *env.Environment) error {
+						return nil
+					})),
+					WithComposedConnectionDetailsFetcher(&roundTripFetcher{err: errBoom}),
+				},
+			},
+			args: args{
+				xr: &fake.Composite{},
+				req: CompositionRequest{
+					Composition: &v1.Composition{},
+				},
+			},
 			want: want{
 				err: errors.Wrap(errBoom, errFetchDetails),
 			},
@@ -466,6 +507,11 @@ func TestPTCompose(t *testing.T) {
 						Ready:        true,
 					}},
 					ConnectionDetails: details,
+
+					// The composed resource's name is empty because our
+					// composed renderer doesn't render it.
+					ConnectionDetailSources: map[string]string{"a": ""},
+					ConnectionSources:       []ConnectionSourceStatus{{Keys: 1}},
 				},
 			},
 		},
@@ -481,7 +527,7 @@ func TestPTCompose(t *testing.T) {
 				t.Errorf("\n%s\nCompose(...): -want, +got:\n%s", tc.reason, diff)
 			}
 
-			if diff := cmp.Diff(tc.want.res, res, cmpopts.EquateEmpty()); diff != "" {
+			if diff := cmp.Diff(tc.want.res, res, cmpopts.EquateEmpty(), cmpopts.IgnoreFields(ConnectionSourceStatus{}, "LastFetchTime")); diff != "" {
 				t.Errorf("\n%s\nCompose(...): -want, +got:\n%s", tc.reason, diff)
 			}
 		})
@@ -267,6 +267,10 @@ type PTFCompositionState struct {
 	ConnectionDetails managed.ConnectionDetails
 	ComposedResources ComposedResourceStates
 	Events            []event.Event
+
+	// ConnectionDetailSources maps each connection detail key to the name of
+	// the composed resource it was extracted from, if any.
+	ConnectionDetailSources map[string]string
 }
 
 // Compose resources using both either the Patch & Transform style resources
@@ -377,7 +381,7 @@ func (c *PTFComposer) Compose(ctx context.Context, xr resource.Composite, req Co
 		out = append(out, cd.ComposedResource)
 	}
 
-	return CompositionResult{ConnectionDetails: state.ConnectionDetails, Composed: out, Events: state.Events}, nil
+	return CompositionResult{ConnectionDetails: state.ConnectionDetails, ConnectionDetailSources: state.ConnectionDetailSources, Composed: out, Events: state.Events}, nil
 }
 
 func allPatches(cds ComposedResourceStates) []v1.Patch {
@@ -974,7 +978,10 @@ func NewConnectionDetailsObserver(e ConnectionDetailsExtractor) *ConnectionDetai
 // ObserveComposedResources to extract XR connection details.
 func (o *ConnectionDetailsObserver) ObserveComposedResources(ctx context.Context, s *PTFCompositionState) error {
 	for _, cd := range s.ComposedResources {
-		ecfgs := append(ExtractConfigsFromTemplate(cd.Template), ExtractConfigsFromDesired(cd.Desired)...)
+		ecfgs, err := SubstituteConnectionSecretKeys(s.Composite, append(ExtractConfigsFromTemplate(cd.Template), ExtractConfigsFromDesired(cd.Desired)...))
+		if err != nil {
+			return errors.Wrapf(err, errFmtExtractConnectionDetails, cd.ResourceName, cd.Resource.GetObjectKind().GroupVersionKind().Kind, cd.Resource.GetName())
+		}
 		e, err := o.details.ExtractConnection(cd.Resource, cd.ConnectionDetails, ecfgs...)
 		if err != nil {
 			return errors.Wrapf(err, errFmtExtractConnectionDetails, cd.ResourceName, cd.Resource.GetObjectKind().GroupVersionKind().Kind, cd.Resource.GetName())
@@ -984,8 +991,13 @@ func (o *ConnectionDetailsObserver) ObserveComposedResources(ctx context.Context
 			s.ConnectionDetails = managed.ConnectionDetails{}
 		}
 
+		if s.ConnectionDetailSources == nil {
+			s.ConnectionDetailSources = map[string]string{}
+		}
+
 		for key, val := range e {
 			s.ConnectionDetails[key] = val
+			s.ConnectionDetailSources[key] = cd.Resource.GetName()
 		}
 	}
 
@@ -2346,20 +2346,29 @@ func TestConnectionDetailsObserver(t *testing.T) {
 							ComposedResource: ComposedResource{
 								ResourceName: "cool-resource",
 							},
-							Resource: &fake.Composed{},
+							Resource: &fake.Composed{
+								ObjectMeta: metav1.ObjectMeta{
+									Name: "cool-resource-42",
+								},
+							},
 						},
 					},
 				},
 			},
 			want: want{
 				s: &PTFCompositionState{
-					ConnectionDetails: managed.ConnectionDetails{"a": []byte("b")},
+					ConnectionDetails:       managed.ConnectionDetails{"a": []byte("b")},
+					ConnectionDetailSources: map[string]string{"a": "cool-resource-42"},
 					ComposedResources: ComposedResourceStates{
 						"cool-resource": ComposedResourceState{
 							ComposedResource: ComposedResource{
 								ResourceName: "cool-resource",
 							},
-							Resource: &fake.Composed{},
+							Resource: &fake.Composed{
+								ObjectMeta: metav1.ObjectMeta{
+									Name: "cool-resource-42",
+								},
+							},
 						},
 					},
 				},
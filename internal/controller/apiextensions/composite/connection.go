@@ -17,31 +17,82 @@ limitations under the License.
 package composite
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
 
 	iov1alpha1 "github.com/crossplane/crossplane/apis/apiextensions/fn/io/v1alpha1"
 	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/crossplane/crossplane/internal/xcrd"
 )
 
 // Error strings.
 const (
 	errConnDetailName = "connection detail is missing name"
 
-	errFmtConnDetailKey  = "connection detail of type %q key is not set"
-	errFmtConnDetailVal  = "connection detail of type %q value is not set"
-	errFmtConnDetailPath = "connection detail of type %q fromFieldPath is not set"
+	errFmtConnDetailKey   = "connection detail of type %q key is not set"
+	errFmtConnDetailVal   = "connection detail of type %q value is not set"
+	errFmtConnDetailPath  = "connection detail of type %q fromFieldPath is not set"
+	errFmtConnDetailEmpty = "connection detail %q is empty"
+
+	errFmtConnDetailPathInvalid = "invalid connection detail fromFieldPath %q"
+
+	errListSecrets           = "cannot list secrets"
+	errFmtNoDiscoveredSecret = "no secret with label %s=%s found"
+	errFmtDiscoveredSecrets  = "%d secrets with label %s=%s found, want exactly one"
+
+	errFmtSecretRefPath    = "invalid connection secret reference field path %q"
+	errFmtSecretRefResolve = "cannot resolve connection secret reference at field path %q"
+	errFmtSecretRefNoName  = "connection secret reference at field path %q has no name"
+
+	errRewriteSecretRef       = "cannot rewrite connection secret reference"
+	errFmtInvalidRewrittenRef = "connection secret reference %s/%s was rewritten to invalid reference %s/%s: %s"
+
+	errFmtChainFetcher  = "cannot fetch connection details using fetcher %d of chain"
+	errFmtChainConflict = "connection detail %q fetched by fetchers %d and %d of chain has different values"
+
+	errUnpublishFilteredNoFetcher = "cannot unpublish filtered connection details: publisher cannot fetch published connection details"
+	errFetchPublished             = "cannot fetch published connection details"
+	errUnpublishFiltered          = "cannot unpublish filtered connection details"
+
+	errPublishStoreEntry = "cannot publish connection details to SecretStore"
+	errFmtNotOwner       = "store entry is owned by another resource with UID %q"
+	errFmtRemapCollision = "connection detail keys %q and %q would both be published as %q"
+
+	errFmtInvalidFilterPattern = "invalid connection detail filter pattern %q"
+	errFmtUnknownFilterMode    = "unknown connection detail filter mode %q"
+
+	errFmtConnectionDetailsTooLarge     = "connection details are %d bytes, exceeding the maximum of %d bytes: %s"
+	errFmtConnectionDetailValueTooLarge = "connection detail %q is %d bytes, exceeding the maximum of %d bytes"
+
+	errParseStoreNameTemplate  = "cannot parse connection details store entry name template"
+	errRenderStoreNameTemplate = "cannot render connection details store entry name template"
+	errFmtNamespaceNotAllowed  = "composition may not publish connection details to namespace %q"
+	errFmtSecretTypeNotAllowed = "composition may not publish connection details as a secret of type %q"
+	errParseSecretMetadata     = "cannot parse composition's connection secret metadata annotation"
 )
 
 // A ConnectionDetailsFetcherFn fetches the connection details of the supplied
@@ -53,17 +104,107 @@ func (f ConnectionDetailsFetcherFn) FetchConnection(ctx context.Context, o resou
 	return f(ctx, o)
 }
 
+// A NopConnectionDetailsFetcher fetches no connection details.
+type NopConnectionDetailsFetcher struct{}
+
+// FetchConnection returns no connection details.
+func (NopConnectionDetailsFetcher) FetchConnection(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	return nil, nil
+}
+
+// A NopConnectionPublisher publishes no connection details.
+type NopConnectionPublisher struct{}
+
+// PublishConnection does nothing and reports that nothing was published.
+func (NopConnectionPublisher) PublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+	return false, nil
+}
+
+// UnpublishConnection does nothing.
+func (NopConnectionPublisher) UnpublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	return nil
+}
+
+// A ChainMergeStrategy determines which fetcher in a chain takes precedence
+// when more than one fetches the same connection detail key.
+type ChainMergeStrategy string
+
+// Chain merge strategies.
+const (
+	// ChainMergeLastWins uses the value of the last fetcher in the chain that
+	// fetched a key.
+	ChainMergeLastWins ChainMergeStrategy = "LastWins"
+
+	// ChainMergeFirstWins uses the value of the first fetcher in the chain
+	// that fetched a key, for example so that a primary source takes
+	// precedence over a fallback.
+	ChainMergeFirstWins ChainMergeStrategy = "FirstWins"
+
+	// ChainMergeStrict returns an error if two fetchers in the chain fetch
+	// different values for the same key, for example because two sources of
+	// connection details overlap. Fetchers may fetch identical values.
+	ChainMergeStrict ChainMergeStrategy = "Strict"
+)
+
+// NewConnectionDetailsFetcherChain returns a chain of the supplied fetchers
+// that merges their connection details using the supplied strategy. Chains
+// are last wins if the strategy is unknown.
+func NewConnectionDetailsFetcherChain(s ChainMergeStrategy, f ...managed.ConnectionDetailsFetcher) managed.ConnectionDetailsFetcher {
+	switch s {
+	case ChainMergeFirstWins:
+		return FirstWinsConnectionDetailsFetcherChain(f)
+	case ChainMergeStrict:
+		return StrictConnectionDetailsFetcherChain(f)
+	}
+	return ConnectionDetailsFetcherChain(f)
+}
+
+// A ChainErrorPolicy determines how a chain handles fetchers that fail.
+type ChainErrorPolicy string
+
+// Chain error policies.
+const (
+	// ChainErrorFailFast returns the first error, and no connection details.
+	ChainErrorFailFast ChainErrorPolicy = "FailFast"
+
+	// ChainErrorCollect continues past fetchers that fail, returning the
+	// connection details fetched by the rest along with an aggregate of all
+	// errors.
+	ChainErrorCollect ChainErrorPolicy = "Collect"
+)
+
+// NewConnectionDetailsFetcherChainWithErrorPolicy returns a chain of the
+// supplied fetchers that merges their connection details using the supplied
+// strategy, and handles errors per the supplied policy. Chains fail fast if
+// the policy is unknown.
+func NewConnectionDetailsFetcherChainWithErrorPolicy(s ChainMergeStrategy, p ChainErrorPolicy, f ...managed.ConnectionDetailsFetcher) managed.ConnectionDetailsFetcher {
+	if p == ChainErrorCollect {
+		return &CollectingConnectionDetailsFetcherChain{fetchers: f, strategy: s}
+	}
+	return NewConnectionDetailsFetcherChain(s, f...)
+}
+
 // A ConnectionDetailsFetcherChain chains multiple ConnectionDetailsFetchers.
 type ConnectionDetailsFetcherChain []managed.ConnectionDetailsFetcher
 
 // FetchConnection details of the supplied composed resource, if any.
 func (fc ConnectionDetailsFetcherChain) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	// An empty chain behaves like a NopConnectionDetailsFetcher.
+	if len(fc) == 0 {
+		return nil, nil
+	}
 	all := make(managed.ConnectionDetails)
 	for _, p := range fc {
+		// Don't call fetchers that are doomed to fail.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		conn, err := p.FetchConnection(ctx, o)
 		if err != nil {
 			return nil, err
 		}
+		// Later fetchers take precedence; their values overwrite those of
+		// earlier fetchers.
 		for k, v := range conn {
 			all[k] = v
 		}
@@ -71,33 +212,482 @@ func (fc ConnectionDetailsFetcherChain) FetchConnection(ctx context.Context, o r
 	return all, nil
 }
 
+// A FirstWinsConnectionDetailsFetcherChain chains multiple
+// ConnectionDetailsFetchers, with earlier fetchers taking precedence.
+type FirstWinsConnectionDetailsFetcherChain []managed.ConnectionDetailsFetcher
+
+// FetchConnection details of the supplied composed resource, if any.
+func (fc FirstWinsConnectionDetailsFetcherChain) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	all := make(managed.ConnectionDetails)
+	for _, p := range fc {
+		// Don't call fetchers that are doomed to fail.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		conn, err := p.FetchConnection(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		// Earlier fetchers take precedence; later fetchers only contribute
+		// keys that no earlier fetcher fetched.
+		for k, v := range conn {
+			if _, ok := all[k]; !ok {
+				all[k] = v
+			}
+		}
+	}
+	return all, nil
+}
+
+// A StrictConnectionDetailsFetcherChain chains multiple
+// ConnectionDetailsFetchers, returning an error if any two fetch different
+// values for the same key.
+type StrictConnectionDetailsFetcherChain []managed.ConnectionDetailsFetcher
+
+// FetchConnection details of the supplied composed resource, if any.
+func (fc StrictConnectionDetailsFetcherChain) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	all := make(managed.ConnectionDetails)
+	from := make(map[string]int)
+	for i, p := range fc {
+		// Don't call fetchers that are doomed to fail.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		conn, err := p.FetchConnection(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		if err := merge(all, from, i, conn); err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
+
+// merge the supplied connection details, fetched by the fetcher at index i of
+// a chain, into all. Keys that an earlier fetcher fetched with a different
+// value keep the earlier value, and the first such key is returned as an
+// error. The from map tracks which fetcher fetched each key.
+func merge(all managed.ConnectionDetails, from map[string]int, i int, conn managed.ConnectionDetails) error {
+	var err error
+	for _, k := range OrderedKeys(conn, AlphabeticalKeyOrder()) {
+		j, ok := from[k]
+		if !ok {
+			from[k] = i
+			all[k] = conn[k]
+			continue
+		}
+		if !bytes.Equal(all[k], conn[k]) && err == nil {
+			err = errors.Errorf(errFmtChainConflict, k, j, i)
+		}
+	}
+	return err
+}
+
+// A CollectingConnectionDetailsFetcherChain chains multiple
+// ConnectionDetailsFetchers, continuing past any that fail so that one
+// unavailable backend doesn't block all connection details.
+type CollectingConnectionDetailsFetcherChain struct {
+	fetchers []managed.ConnectionDetailsFetcher
+	strategy ChainMergeStrategy
+}
+
+// FetchConnection details of the supplied composed resource, if any. The
+// connection details fetched by fetchers that didn't fail are returned even
+// if some did, along with an aggregate of their errors. Callers may decide
+// whether to tolerate the partial connection details.
+func (fc *CollectingConnectionDetailsFetcherChain) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	all := make(managed.ConnectionDetails)
+	from := make(map[string]int)
+	var errs []error
+	for i, p := range fc.fetchers {
+		// Don't call fetchers that are doomed to fail.
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		conn, err := p.FetchConnection(ctx, o)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, errFmtChainFetcher, i))
+			continue
+		}
+		if fc.strategy == ChainMergeStrict {
+			// Conflicting keys keep the value of the earlier fetcher.
+			if err := merge(all, from, i, conn); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for k, v := range conn {
+			// Earlier fetchers take precedence if the chain is first wins.
+			if _, ok := all[k]; ok && fc.strategy == ChainMergeFirstWins {
+				continue
+			}
+			all[k] = v
+		}
+	}
+	return all, utilerrors.NewAggregate(errs)
+}
+
+// AnnotationKeyExposableKeys is the annotation a provider may set on a
+// connection secret to declare the comma separated set of keys that may be
+// exposed by Crossplane.
+const AnnotationKeyExposableKeys = "crossplane.io/exposable-keys"
+
+// AnnotationKeySecretReady is the annotation a provider may set to "true" on a
+// connection secret to declare that it has finished writing it.
+const AnnotationKeySecretReady = "crossplane.io/secret-ready"
+
+// A SecretConnectionDetailsFetcherOption configures a
+// SecretConnectionDetailsFetcher.
+type SecretConnectionDetailsFetcherOption func(*SecretConnectionDetailsFetcher)
+
+// WithFetcherLogger configures the logger a SecretConnectionDetailsFetcher
+// uses. Connection detail values are never logged.
+func WithFetcherLogger(l logging.Logger) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.log = l
+	}
+}
+
+// WithExposableKeysAnnotation configures a SecretConnectionDetailsFetcher to
+// honor the supplied annotation on a connection secret. When a connection
+// secret has the annotation only the comma separated keys it lists will be
+// fetched. Connection secrets without the annotation are unaffected.
+func WithExposableKeysAnnotation(a string) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.exposableKeysAnnotation = a
+	}
+}
+
+// WithSecretReadyAnnotation configures a SecretConnectionDetailsFetcher to
+// honor the supplied annotation on a connection secret. Connection secrets are
+// not fetched until the annotation is set to "true"; until then their keys are
+// treated as not yet present. This prevents premature consumption of secrets
+// that providers write before they're complete.
+func WithSecretReadyAnnotation(a string) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.readyAnnotation = a
+	}
+}
+
+// WithSecretReferenceFieldPath configures a SecretConnectionDetailsFetcher to
+// read a composed resource's connection secret reference from the supplied
+// field path if the composed resource doesn't have a standard
+// spec.writeConnectionSecretToRef, for example because a provider or another
+// controller writes it elsewhere. The field path must refer to an object with
+// a name and, optionally, a namespace.
+func WithSecretReferenceFieldPath(path string) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.referencePath = path
+	}
+}
+
+// WithSecretDiscoveryLabel configures a SecretConnectionDetailsFetcher to
+// discover a composed resource's connection secret by listing secrets whose
+// supplied label is set to the composed resource's name, if the composed
+// resource doesn't reference its connection secret. This supports providers
+// that label the secrets they generate rather than reference them. Exactly one
+// secret must match.
+func WithSecretDiscoveryLabel(key string) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.discoveryLabel = key
+	}
+}
+
+// A SecretReferenceRewriteFn rewrites the connection secret reference of the
+// supplied composed resource before its connection secret is read.
+type SecretReferenceRewriteFn func(o resource.ConnectionSecretOwner, ref xpv1.SecretReference) (xpv1.SecretReference, error)
+
+// WithSecretReferenceRewrite configures a SecretConnectionDetailsFetcher to
+// rewrite every connection secret reference it resolves using the supplied
+// function. This supports restoring or cloning composite resources whose
+// composed resources reference connection secrets by their old names.
+func WithSecretReferenceRewrite(fn SecretReferenceRewriteFn) SecretConnectionDetailsFetcherOption {
+	return func(f *SecretConnectionDetailsFetcher) {
+		f.rewrite = fn
+	}
+}
+
 // An SecretConnectionDetailsFetcher may use the API server to read connection
 // details from a Kubernetes Secret.
 type SecretConnectionDetailsFetcher struct {
 	client client.Reader
+	log    logging.Logger
+
+	exposableKeysAnnotation string
+	readyAnnotation         string
+	referencePath           string
+	discoveryLabel          string
+	rewrite                 SecretReferenceRewriteFn
 }
 
 // NewSecretConnectionDetailsFetcher returns a ConnectionDetailsFetcher that may
 // use the API server to read connection details from a Kubernetes Secret.
-func NewSecretConnectionDetailsFetcher(c client.Client) *SecretConnectionDetailsFetcher {
-	return &SecretConnectionDetailsFetcher{client: c}
+func NewSecretConnectionDetailsFetcher(c client.Client, o ...SecretConnectionDetailsFetcherOption) *SecretConnectionDetailsFetcher {
+	f := &SecretConnectionDetailsFetcher{client: c, log: logging.NewNopLogger()}
+	for _, fn := range o {
+		fn(f)
+	}
+	return f
 }
 
 // FetchConnection details of the supplied composed resource from its Kubernetes
 // connection secret, per its WriteConnectionSecretToRef, if any.
 func (cdf *SecretConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	log := cdf.log.WithValues("composite", o.GetLabels()[xcrd.LabelKeyNamePrefixForComposed], "composed", o.GetName())
+	conn, err := cdf.fetch(ctx, log, o)
+	if err != nil {
+		log.Info("Cannot fetch connection details", "error", err)
+		return nil, err
+	}
+	log.Debug("Fetched connection details", "keys", len(conn))
+	return conn, nil
+}
+
+func (cdf *SecretConnectionDetailsFetcher) fetch(ctx context.Context, log logging.Logger, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
 	sref := o.GetWriteConnectionSecretToReference()
+	if sref == nil && cdf.referencePath != "" {
+		ref, err := secretReferenceFromFieldPath(o, cdf.referencePath)
+		if err != nil {
+			return nil, err
+		}
+		sref = ref
+	}
+	if sref == nil && cdf.discoveryLabel != "" {
+		s, err := cdf.discover(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		return cdf.fromSecret(o, s), nil
+	}
 	if sref == nil {
 		// secret but has not yet. We presume this isn't an issue and that we'll
 		// propagate any connection details during a future iteration.
 		return nil, nil
 	}
-	s := &corev1.Secret{}
+	if cdf.rewrite != nil {
+		ref, err := rewriteSecretReference(o, *sref, cdf.rewrite)
+		if err != nil {
+			return nil, err
+		}
+		sref = ref
+	}
 	nn := types.NamespacedName{Namespace: sref.Namespace, Name: sref.Name}
-	if err := cdf.client.Get(ctx, nn, s); client.IgnoreNotFound(err) != nil {
+	k := secretReadKey{Store: string(xpv1.SecretStoreKubernetes), Namespace: nn.Namespace, Name: nn.Name}
+	s, err := readSecret(ctx, k, func() (*corev1.Secret, error) {
+		s := &corev1.Secret{}
+		return s, cdf.client.Get(ctx, nn, s)
+	})
+	if kerrors.IsNotFound(err) {
+		log.Debug("Connection secret not found", "secret", nn.Name, "namespace", nn.Namespace)
+		return nil, nil
+	}
+	if err != nil {
 		return nil, errors.Wrap(err, errGetSecret)
 	}
-	return s.Data, nil
+	return cdf.fromSecret(o, s), nil
+}
+
+// discover the connection secret of the supplied composed resource by label.
+func (cdf *SecretConnectionDetailsFetcher) discover(ctx context.Context, o resource.ConnectionSecretOwner) (*corev1.Secret, error) {
+	l := &corev1.SecretList{}
+	if err := cdf.client.List(ctx, l, client.MatchingLabels{cdf.discoveryLabel: o.GetName()}); err != nil {
+		return nil, errors.Wrap(err, errListSecrets)
+	}
+	switch len(l.Items) {
+	case 0:
+		return nil, errors.Errorf(errFmtNoDiscoveredSecret, cdf.discoveryLabel, o.GetName())
+	case 1:
+		return &l.Items[0], nil
+	default:
+		return nil, errors.Errorf(errFmtDiscoveredSecrets, len(l.Items), cdf.discoveryLabel, o.GetName())
+	}
+}
+
+// fromSecret returns the connection details of the supplied secret, if it's
+// ready.
+func (cdf *SecretConnectionDetailsFetcher) fromSecret(o resource.ConnectionSecretOwner, s *corev1.Secret) managed.ConnectionDetails {
+	if cdf.readyAnnotation != "" && s.GetAnnotations()[cdf.readyAnnotation] != "true" {
+		cdf.log.Debug("Connection secret not yet ready", "secret", s.GetName(), "namespace", s.GetNamespace(), "composed", o.GetName(), "annotation", cdf.readyAnnotation)
+		return nil
+	}
+	return cdf.exposable(o, s)
+}
+
+// rewriteSecretReference rewrites the supplied secret reference using the
+// supplied function, and validates the result.
+func rewriteSecretReference(o resource.ConnectionSecretOwner, ref xpv1.SecretReference, fn SecretReferenceRewriteFn) (*xpv1.SecretReference, error) {
+	out, err := fn(o, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, errRewriteSecretRef)
+	}
+	msgs := validation.IsDNS1123Subdomain(out.Name)
+	if out.Namespace != "" {
+		msgs = append(msgs, validation.IsDNS1123Label(out.Namespace)...)
+	}
+	if len(msgs) > 0 {
+		return nil, errors.Errorf(errFmtInvalidRewrittenRef, ref.Namespace, ref.Name, out.Namespace, out.Name, strings.Join(msgs, "; "))
+	}
+	return &out, nil
+}
+
+// secretReferenceFromFieldPath returns the secret reference at the supplied
+// field path of the supplied object, or nil if the field path does not exist.
+func secretReferenceFromFieldPath(o resource.ConnectionSecretOwner, path string) (*xpv1.SecretReference, error) {
+	if _, err := fieldpath.Parse(path); err != nil {
+		return nil, errors.Wrapf(err, errFmtSecretRefPath, path)
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, errors.Wrap(err, errPaveObject)
+	}
+	ref := &xpv1.SecretReference{}
+	err = fieldpath.Pave(u).GetValueInto(path, ref)
+	if fieldpath.IsNotFound(err) {
+		// The reference may be written in future.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtSecretRefResolve, path)
+	}
+	if ref.Name == "" {
+		return nil, errors.Errorf(errFmtSecretRefNoName, path)
+	}
+	return ref, nil
+}
+
+// exposable returns the subset of the supplied secret's data that its
+// provider has declared may be exposed, if it has declared any.
+func (cdf *SecretConnectionDetailsFetcher) exposable(o resource.ConnectionSecretOwner, s *corev1.Secret) managed.ConnectionDetails {
+	if cdf.exposableKeysAnnotation == "" {
+		return s.Data
+	}
+	a, ok := s.GetAnnotations()[cdf.exposableKeysAnnotation]
+	if !ok {
+		return s.Data
+	}
+
+	allow := map[string]bool{}
+	for _, k := range strings.Split(a, ",") {
+		allow[strings.TrimSpace(k)] = true
+	}
+
+	out := make(managed.ConnectionDetails, len(s.Data))
+	for k, v := range s.Data {
+		if !allow[k] {
+			cdf.log.Info("Dropping connection secret key that its provider has not declared exposable", "key", k, "secret", s.GetName(), "namespace", s.GetNamespace(), "composed", o.GetName())
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// A ConnectionMetadataFetcher fetches the metadata of the SecretStore entry
+// the supplied resource publishes its connection details to. It returns nil
+// metadata if the entry doesn't exist.
+type ConnectionMetadataFetcher interface {
+	FetchConnectionMetadata(ctx context.Context, o resource.ConnectionSecretOwner) (*xpv1.ConnectionSecretMetadata, error)
+}
+
+// A FilterMode determines how a SecretStoreConnectionPublisher or
+// FilteredConnectionDetailsFetcher matches connection detail keys against its
+// filter.
+type FilterMode string
+
+// Filter modes.
+const (
+	// FilterModeExact filters match keys with exactly the same name.
+	FilterModeExact FilterMode = "Exact"
+
+	// FilterModeGlob filters match keys using shell file name patterns, for
+	// example node-*-password.
+	FilterModeGlob FilterMode = "Glob"
+
+	// FilterModeRegex filters match keys using regular expressions. Each
+	// expression must match the entire key.
+	FilterModeRegex FilterMode = "Regex"
+)
+
+// A SecretStoreConnectionPublisherOption configures a
+// SecretStoreConnectionPublisher.
+type SecretStoreConnectionPublisherOption func(*SecretStoreConnectionPublisher)
+
+// WithPublishResultSink configures where a SecretStoreConnectionPublisher
+// records the result of each publish.
+func WithPublishResultSink(s PublishResultSink) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.sink = s
+	}
+}
+
+// WithPublishRecorder configures how a SecretStoreConnectionPublisher records
+// the outcome of each publish, for example as Prometheus metrics.
+func WithPublishRecorder(r PublishRecorder) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.recorder = r
+	}
+}
+
+// WithPublisherLogger configures the logger a SecretStoreConnectionPublisher
+// uses. It logs the keys its filter drops at debug level.
+func WithPublisherLogger(l logging.Logger) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.log = l
+	}
+}
+
+// WithSharedSecretStoreEntries configures a SecretStoreConnectionPublisher to
+// allow several resources to publish to the same SecretStore entry. Ownership
+// of entries is neither recorded nor enforced.
+func WithSharedSecretStoreEntries() SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.shared = true
+	}
+}
+
+// WithMaxConnectionDetailsSize configures a SecretStoreConnectionPublisher to
+// refuse to publish connection details larger than the supplied number of
+// bytes, for example 1MiB for Kubernetes Secrets. The size is the total length
+// of the keys and values that would be published. Sizes are not limited by
+// default, or if the supplied size is not positive.
+func WithMaxConnectionDetailsSize(bytes int) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.maxSize = bytes
+	}
+}
+
+// WithMaxConnectionDetailValueSize configures a SecretStoreConnectionPublisher
+// to refuse to publish connection details with a value larger than the
+// supplied number of bytes. Values are checked after they're filtered and
+// renamed. Value sizes are not limited by default, or if the supplied size is
+// not positive.
+func WithMaxConnectionDetailValueSize(bytes int) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.maxValueSize = bytes
+	}
+}
+
+// WithConnectionDetailValueTruncation configures a
+// SecretStoreConnectionPublisher to truncate connection detail values that
+// are larger than the maximum value size, logging a warning, rather than
+// refusing to publish them.
+func WithConnectionDetailValueTruncation() SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.truncate = true
+	}
+}
+
+// WithEmptyConnectionDetailsPublishing configures a
+// SecretStoreConnectionPublisher to publish connection details even when none
+// remain after filtering, creating or updating an empty SecretStore entry. By
+// default there's nothing to publish in that case, so the SecretStore isn't
+// called.
+func WithEmptyConnectionDetailsPublishing() SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.publishEmpty = true
+	}
 }
 
 // SecretStoreConnectionPublisher is a ConnectionPublisher that stores
@@ -105,79 +695,873 @@ func (cdf *SecretConnectionDetailsFetcher) FetchConnection(ctx context.Context,
 type SecretStoreConnectionPublisher struct {
 	publisher managed.ConnectionPublisher
 	filter    []string
+	sink      PublishResultSink
+	recorder  PublishRecorder
+	log       logging.Logger
+	shared    bool
+	remap     map[string]string
+	maxSize   int
+	dryRun    bool
+	encodings map[string]ConnectionValueEncoding
+
+	maxValueSize int
+	truncate     bool
+
+	exact    map[string]bool
+	patterns []func(key string) bool
+	deny     map[string]bool
+
+	storeConfigs client.Reader
+
+	required        []string
+	requireNonEmpty bool
+
+	publishEmpty bool
+
+	predicate PublishPredicate
 }
 
-// NewSecretStoreConnectionPublisher returns a SecretStoreConnectionPublisher
-func NewSecretStoreConnectionPublisher(p managed.ConnectionPublisher, filter []string) *SecretStoreConnectionPublisher {
-	return &SecretStoreConnectionPublisher{
+// NewSecretStoreConnectionPublisher returns a SecretStoreConnectionPublisher.
+// By default it records the UID of the resource that owns each SecretStore
+// entry as a label of the entry's metadata, and refuses to publish to entries
+// owned by another resource.
+func NewSecretStoreConnectionPublisher(p managed.ConnectionPublisher, filter []string, o ...SecretStoreConnectionPublisherOption) *SecretStoreConnectionPublisher {
+	sp := &SecretStoreConnectionPublisher{
 		publisher: p,
 		filter:    filter,
+		sink:      NopPublishResultSink{},
+		recorder:  NopPublishRecorder{},
+		log:       logging.NewNopLogger(),
+		exact:     make(map[string]bool, len(filter)),
+		predicate: AlwaysPublish(),
+	}
+	for _, key := range filter {
+		sp.exact[key] = true
+	}
+	for _, fn := range o {
+		fn(sp)
+	}
+	return sp
+}
+
+// NewSecretStoreConnectionPublisherWithFilterMode returns a
+// SecretStoreConnectionPublisher that matches connection detail keys against
+// its filter using the supplied mode. Patterns are compiled once, and an
+// error is returned if any are invalid.
+func NewSecretStoreConnectionPublisherWithFilterMode(p managed.ConnectionPublisher, filter []string, mode FilterMode, o ...SecretStoreConnectionPublisherOption) (*SecretStoreConnectionPublisher, error) {
+	sp := NewSecretStoreConnectionPublisher(p, filter, o...)
+	if mode == FilterModeExact {
+		return sp, nil
+	}
+	patterns, err := compileFilterPatterns(filter, mode)
+	if err != nil {
+		return nil, err
+	}
+	sp.exact = nil
+	sp.patterns = patterns
+	return sp, nil
+}
+
+// compileFilterPatterns compiles the supplied glob or regex filter into
+// functions that each return true if a key matches one of its patterns.
+func compileFilterPatterns(filter []string, mode FilterMode) ([]func(key string) bool, error) {
+	patterns := make([]func(key string) bool, 0, len(filter))
+	switch mode {
+	case FilterModeGlob:
+		for _, pattern := range filter {
+			pattern := pattern
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.Wrapf(err, errFmtInvalidFilterPattern, pattern)
+			}
+			patterns = append(patterns, func(key string) bool {
+				ok, _ := path.Match(pattern, key)
+				return ok
+			})
+		}
+	case FilterModeRegex:
+		for _, pattern := range filter {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, errors.Wrapf(err, errFmtInvalidFilterPattern, pattern)
+			}
+			patterns = append(patterns, re.MatchString)
+		}
+	default:
+		return nil, errors.Errorf(errFmtUnknownFilterMode, mode)
+	}
+	return patterns, nil
+}
+
+// NewSecretStoreConnectionPublisherWithRemap returns a
+// SecretStoreConnectionPublisher that renames connection detail keys per the
+// supplied remap before publishing them. Keys are renamed after they're
+// filtered, so the filter refers to keys by their original names. Keys that
+// aren't remapped are published under their original names.
+func NewSecretStoreConnectionPublisherWithRemap(p managed.ConnectionPublisher, filter []string, remap map[string]string, o ...SecretStoreConnectionPublisherOption) *SecretStoreConnectionPublisher {
+	sp := NewSecretStoreConnectionPublisher(p, filter, o...)
+	sp.remap = remap
+	return sp
+}
+
+// NewSecretStoreConnectionPublisherWithDenyList returns a
+// SecretStoreConnectionPublisher that never publishes the connection detail
+// keys in the supplied deny list. The deny list takes precedence over the
+// filter, so a key in both is not published. If the filter is empty every key
+// that isn't denied is published.
+func NewSecretStoreConnectionPublisherWithDenyList(p managed.ConnectionPublisher, filter, deny []string, o ...SecretStoreConnectionPublisherOption) *SecretStoreConnectionPublisher {
+	sp := NewSecretStoreConnectionPublisher(p, filter, o...)
+	sp.deny = make(map[string]bool, len(deny))
+	for _, key := range deny {
+		sp.deny[key] = true
 	}
+	return sp
 }
 
-// PublishConnection details for the supplied resource.
-func (p *SecretStoreConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (published bool, err error) {
+// A PublishReport describes which connection details were published.
+type PublishReport struct {
+	// Published is true if publishing changed the published connection
+	// details.
+	Published bool
+
+	// PublishedKeys are the keys of the connection details that were
+	// published, after any renaming, in alphabetical order.
+	PublishedKeys []string
+
+	// DroppedKeys are the keys of the connection details that were dropped
+	// by the publisher's filter, in alphabetical order.
+	DroppedKeys []string
+
+	// TruncatedKeys are the keys of the connection details whose values were
+	// truncated because they exceeded the maximum value size, after any
+	// renaming, in alphabetical order.
+	TruncatedKeys []string
+}
+
+// PublishConnection details for the supplied resource. Ownership can only be
+// enforced if the underlying publisher is also a ConnectionMetadataFetcher,
+// and no-op publishes detected if it's also a ConnectionDetailsFetcher.
+func (p *SecretStoreConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	r, err := p.PublishConnectionWithReport(ctx, o, c)
+	return r.Published, err
+}
+
+// PublishConnectionWithReport publishes connection details for the supplied
+// resource like PublishConnection, and reports which keys were published and
+// which were dropped by the filter.
+func (p *SecretStoreConnectionPublisher) PublishConnectionWithReport(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (PublishReport, error) {
 	// This resource does not want to expose a connection secret.
 	if o.GetPublishConnectionDetailsTo() == nil {
-		return false, nil
+		return PublishReport{}, nil
 	}
 
-	data := map[string][]byte{}
-	m := map[string]bool{}
-	for _, key := range p.filter {
-		m[key] = true
+	log := p.log.WithValues("composite", o.GetName(), "store", storeName(o))
+	r, err := p.publishWithReport(ctx, log, o, c)
+	if err != nil {
+		log.Info("Cannot publish connection details", "error", err)
+		return r, err
+	}
+	if !p.dryRun {
+		log.Debug("Published connection details", "keys", len(r.PublishedKeys), "changed", r.Published)
+	}
+	return r, nil
+}
+
+func (p *SecretStoreConnectionPublisher) publishWithReport(ctx context.Context, log logging.Logger, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (PublishReport, error) {
+	r := PublishReport{DroppedKeys: p.dropped(c)}
+	if len(r.DroppedKeys) > 0 {
+		log.Debug("Connection details were dropped by filter", "keys", r.DroppedKeys)
+	}
+
+	// Dry runs don't write to the store, so we don't record them either.
+	if p.dryRun {
+		data, truncated, err := p.prepare(c)
+		r.PublishedKeys = OrderedKeys(data, AlphabeticalKeyOrder())
+		r.TruncatedKeys = truncated
+		return r, err
 	}
 
+	ok, err := p.predicate(ctx, o)
+	if err != nil {
+		err = errors.Wrap(err, errPublishPredicate)
+		p.record(ctx, o, time.Now(), 0, false, err)
+		return r, err
+	}
+	if !ok {
+		log.Debug("Skipped publishing because the publish predicate is not satisfied")
+		return r, nil
+	}
+
+	data, truncated, err := p.prepare(c)
+	r.TruncatedKeys = truncated
+	if len(truncated) > 0 {
+		log.Info("Connection detail values were truncated because they exceeded the maximum size", "keys", truncated, "max-bytes", p.maxValueSize)
+	}
+	if filtered := len(c) - len(data); err == nil && filtered > 0 {
+		p.recorder.RecordFilteredKeys(o.GetObjectKind().GroupVersionKind(), filtered)
+	}
+	if err != nil {
+		p.recorder.RecordPublishError(o.GetObjectKind().GroupVersionKind())
+		return r, err
+	}
+	keys := len(data)
+	r.PublishedKeys = OrderedKeys(data, AlphabeticalKeyOrder())
+
+	start := time.Now()
+
+	// There's nothing to publish, so we don't touch the store. This is
+	// recorded as a no-op, just like a publish that changed nothing.
+	if keys == 0 && !p.publishEmpty {
+		log.Debug("Skipped publishing because there are no connection details to publish")
+		p.record(ctx, o, start, keys, false, nil)
+		return r, nil
+	}
+
+	if err := p.checkStoreConfig(ctx, o); err != nil {
+		p.record(ctx, o, start, keys, false, err)
+		return r, err
+	}
+
+	current, fetched := p.current(ctx, o)
+	owner, ownerFetched := p.owner(ctx, o)
+	po := o
+	if !p.shared {
+		if ownerFetched && owner != "" && owner != string(o.GetUID()) {
+			err = errors.Wrap(errors.Errorf(errFmtNotOwner, owner), errPublishStoreEntry)
+			p.record(ctx, o, start, keys, false, err)
+			return r, err
+		}
+		po = withOwnerUID(o)
+	}
+
+	published, err := p.publish(ctx, po, data)
+
+	// Not all stores can tell whether a write changed anything, so we don't
+	// trust them to report a no-op. Recording ownership of an entry that
+	// didn't record it is a change.
+	recorded := p.shared || !ownerFetched || owner == string(o.GetUID())
+	if fetched && recorded && unchanged(current, p.allowsPublished, data) {
+		published = false
+	}
+
+	p.record(ctx, o, start, keys, published, err)
+	r.Published = published
+	return r, err
+}
+
+// dropped returns the keys of the supplied connection details that the filter
+// doesn't allow, in alphabetical order.
+func (p *SecretStoreConnectionPublisher) dropped(c managed.ConnectionDetails) []string {
+	var keys []string
+	for key := range c {
+		if !p.allows(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// prepare the supplied connection details for publishing by filtering,
+// renaming, and validating them, including checking they satisfy any
+// requirements. It returns the keys of any values that were
+// truncated, in alphabetical order.
+func (p *SecretStoreConnectionPublisher) prepare(c managed.ConnectionDetails) (map[string][]byte, []string, error) {
+	data := map[string][]byte{}
 	for key, val := range c {
-		// If the filter does not have any keys, we allow all given keys to be
-		// published.
-		if len(m) == 0 || m[key] {
+		if p.allows(key) {
 			data[key] = val
 		}
 	}
+	data, err := p.rename(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	truncated, err := p.checkValueSize(data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errPublishStoreEntry)
+	}
+	if err := checkSize(data, p.maxSize); err != nil {
+		return nil, nil, errors.Wrap(err, errPublishStoreEntry)
+	}
+	if err := p.checkRequired(data); err != nil {
+		return nil, nil, errors.Wrap(err, errPublishStoreEntry)
+	}
+	return data, truncated, nil
+}
+
+// checkValueSize returns an error naming the first of the supplied connection
+// details, alphabetically, whose value exceeds the maximum value size. If
+// truncation is enabled it instead truncates such values, and returns their
+// keys.
+func (p *SecretStoreConnectionPublisher) checkValueSize(data map[string][]byte) ([]string, error) {
+	if p.maxValueSize <= 0 {
+		return nil, nil
+	}
+	var truncated []string
+	for _, k := range OrderedKeys(data, AlphabeticalKeyOrder()) {
+		if len(data[k]) <= p.maxValueSize {
+			continue
+		}
+		if !p.truncate {
+			return nil, errors.Errorf(errFmtConnectionDetailValueTooLarge, k, len(data[k]), p.maxValueSize)
+		}
+		data[k] = data[k][:p.maxValueSize]
+		truncated = append(truncated, k)
+	}
+	return truncated, nil
+}
+
+func (p *SecretStoreConnectionPublisher) record(ctx context.Context, o resource.ConnectionSecretOwner, start time.Time, keys int, changed bool, err error) {
+	recordPublish(p.recorder, o, changed, err)
+
+	r := PublishResult{
+		Time:     start,
+		Owner:    ownerID(o),
+		Store:    storeName(o),
+		Keys:     keys,
+		Changed:  changed,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	p.sink.RecordPublish(ctx, r)
+}
+
+// checkSize returns an error naming the supplied connection details' keys,
+// largest first, if their total size exceeds the supplied maximum.
+func checkSize(data map[string][]byte, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	size := make(map[string]int, len(data))
+	total := 0
+	for k, v := range data {
+		size[k] = len(k) + len(v)
+		total += size[k]
+	}
+	if total <= max {
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if size[keys[i]] != size[keys[j]] {
+			return size[keys[i]] > size[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	sizes := make([]string, len(keys))
+	for i, k := range keys {
+		sizes[i] = fmt.Sprintf("%q (%d bytes)", k, size[k])
+	}
+	return errors.Errorf(errFmtConnectionDetailsTooLarge, total, max, strings.Join(sizes, ", "))
+}
+
+// allows returns true if the filter allows the supplied key. If the filter
+// does not have any keys, we allow all keys to be published. Denied keys are
+// never allowed.
+func (p *SecretStoreConnectionPublisher) allows(key string) bool {
+	if p.deny[key] {
+		return false
+	}
+	if len(p.filter) == 0 || p.exact[key] {
+		return true
+	}
+	for _, match := range p.patterns {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
 
-	return p.publisher.PublishConnection(ctx, o, data)
+// allowsPublished returns true if the filter allows the supplied key as it's
+// published, i.e. after it's renamed.
+func (p *SecretStoreConnectionPublisher) allowsPublished(key string) bool {
+	for from, to := range p.remap {
+		if to == key && p.allows(from) {
+			return true
+		}
+	}
+	if _, renamed := p.remap[key]; renamed {
+		return false
+	}
+	return p.allows(key)
 }
 
-// UnpublishConnection details for the supplied resource.
+// rename the supplied connection details per the remap. It returns an error
+// if more than one key would be published under the same name.
+func (p *SecretStoreConnectionPublisher) rename(c managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	if len(p.remap) == 0 {
+		return c, nil
+	}
+	out := make(managed.ConnectionDetails, len(c))
+	from := make(map[string]string, len(c))
+	for _, key := range OrderedKeys(c, AlphabeticalKeyOrder()) {
+		to := key
+		if r, ok := p.remap[key]; ok {
+			to = r
+		}
+		if prev, ok := from[to]; ok {
+			return nil, errors.Errorf(errFmtRemapCollision, prev, key, to)
+		}
+		from[to] = key
+		out[to] = c[key]
+	}
+	return out, nil
+}
+
+// current returns the connection details already published for the supplied
+// resource. It returns false if they can't be read.
+func (p *SecretStoreConnectionPublisher) current(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, bool) {
+	f, ok := p.publisher.(managed.ConnectionDetailsFetcher)
+	if !ok {
+		return nil, false
+	}
+	current, err := f.FetchConnection(ctx, o)
+	if err != nil {
+		return nil, false
+	}
+	return current, true
+}
+
+// owner returns the UID recorded as the owner of the SecretStore entry the
+// supplied resource publishes to, or an empty string if the entry doesn't
+// record one. It returns false if the owner can't be read.
+func (p *SecretStoreConnectionPublisher) owner(ctx context.Context, o resource.ConnectionSecretOwner) (string, bool) {
+	f, ok := p.publisher.(ConnectionMetadataFetcher)
+	if !ok {
+		return "", false
+	}
+	md, err := f.FetchConnectionMetadata(ctx, o)
+	if err != nil {
+		return "", false
+	}
+	if md == nil {
+		return "", true
+	}
+	return md.GetOwnerUID(), true
+}
+
+// withOwnerUID returns a copy of the supplied resource whose
+// PublishConnectionDetailsTo records it as the owner of the SecretStore entry
+// it publishes to. We operate on a copy because we don't want to persist the
+// owner label to the resource.
+func withOwnerUID(o resource.ConnectionSecretOwner) resource.ConnectionSecretOwner {
+	so, ok := copyOwner(o)
+	if !ok {
+		return o
+	}
+	to := so.GetPublishConnectionDetailsTo()
+	if to.Metadata == nil {
+		to.Metadata = &xpv1.ConnectionSecretMetadata{}
+	}
+	to.Metadata.SetOwnerUID(o.GetUID())
+	so.SetPublishConnectionDetailsTo(to)
+	return so
+}
+
+// copyOwner returns a deep copy of the supplied resource. It returns false if
+// the resource can't be copied.
+func copyOwner(o resource.ConnectionSecretOwner) (resource.ConnectionSecretOwner, bool) {
+	// An unstructured composite's DeepCopyObject is promoted from the
+	// unstructured.Unstructured it embeds, so it doesn't return a composite.
+	if u, ok := o.(*composite.Unstructured); ok {
+		return &composite.Unstructured{Unstructured: *u.Unstructured.DeepCopy()}, true
+	}
+	so, ok := o.DeepCopyObject().(resource.ConnectionSecretOwner)
+	return so, ok
+}
+
+// unchanged returns true if the supplied connection details are identical to
+// those already published, ignoring keys the filter does not allow.
+func unchanged(current managed.ConnectionDetails, allows func(key string) bool, data managed.ConnectionDetails) bool {
+	allowed := make(managed.ConnectionDetails, len(current))
+	for key, val := range current {
+		if allows(key) {
+			allowed[key] = val
+		}
+	}
+	added, changed, removed := DiffConnectionDetails(allowed, data)
+	return len(added)+len(changed)+len(removed) == 0
+}
+
+// UnpublishConnection details for the supplied resource, deleting them from
+// the configured SecretStore. Unpublishing connection details that were
+// already removed from the SecretStore, for example out-of-band, is not an
+// error.
 func (p *SecretStoreConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
-	return p.publisher.UnpublishConnection(ctx, o, c)
+	// This resource does not expose a connection secret, or we're not
+	// writing to the store.
+	if o.GetPublishConnectionDetailsTo() == nil || p.dryRun {
+		return nil
+	}
+
+	// Never unpublish an entry that another resource owns, just like we
+	// never publish to one.
+	if p.ownedByAnother(ctx, o) {
+		p.log.Debug("Skipped unpublishing because the store entry is owned by another resource", "composite", o.GetName(), "store", storeName(o))
+		return nil
+	}
+	return resource.IgnoreNotFound(p.publisher.UnpublishConnection(ctx, o, c))
+}
+
+// ownedByAnother returns true if the SecretStore entry the supplied resource
+// publishes to records another resource as its owner. Entries whose owner
+// can't be read are trusted to be ours, just like PublishConnection does.
+func (p *SecretStoreConnectionPublisher) ownedByAnother(ctx context.Context, o resource.ConnectionSecretOwner) bool {
+	if p.shared {
+		return false
+	}
+	owner, ok := p.owner(ctx, o)
+	return ok && owner != "" && owner != string(o.GetUID())
+}
+
+// UnpublishFiltered unpublishes any keys of the supplied resource's published
+// connection details that the publisher's filter no longer allows. Publishing
+// is additive, so without this keys that were published before they were
+// removed from the filter remain published. Reading the published connection
+// details requires the underlying publisher to also be a fetcher.
+func (p *SecretStoreConnectionPublisher) UnpublishFiltered(ctx context.Context, o resource.ConnectionSecretOwner) error {
+	// This resource does not expose a connection secret, everything is
+	// allowed, or we're not writing to the store.
+	if o.GetPublishConnectionDetailsTo() == nil || len(p.filter) == 0 || p.dryRun {
+		return nil
+	}
+
+	f, ok := p.publisher.(managed.ConnectionDetailsFetcher)
+	if !ok {
+		return errors.New(errUnpublishFilteredNoFetcher)
+	}
+	if p.ownedByAnother(ctx, o) {
+		return nil
+	}
+	published, err := f.FetchConnection(ctx, o)
+	if err != nil {
+		return errors.Wrap(err, errFetchPublished)
+	}
+
+	stale := managed.ConnectionDetails{}
+	for key, val := range published {
+		if !p.allowsPublished(key) {
+			stale[key] = val
+		}
+	}
+
+	// Unpublishing no keys would unpublish the entire secret.
+	if len(stale) == 0 {
+		return nil
+	}
+	return errors.Wrap(p.publisher.UnpublishConnection(ctx, o, stale), errUnpublishFiltered)
+}
+
+// A SecretStoreConnectionDetailsConfiguratorOption configures a
+// SecretStoreConnectionDetailsConfigurator.
+type SecretStoreConnectionDetailsConfiguratorOption func(*SecretStoreConnectionDetailsConfigurator)
+
+// WithStoreCompatibility configures which compositions the configurator
+// considers compatible with a composite resource.
+func WithStoreCompatibility(o ...CompatibilityOption) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.compatibility = newCompatibility(o...)
+	}
+}
+
+// WithStoreNameTemplate configures the configurator to name the store entry
+// that connection details are published to using the supplied text/template.
+// The template may reference the composite resource's .Name, .Namespace, and
+// .UID. The .Namespace of a claimed composite resource is its claim's
+// namespace. A short UID suffix is appended to rendered names that do not
+// include the composite resource's name, to keep them unique. The composite
+// resource's UID is used if the template renders an empty name.
+func WithStoreNameTemplate(t string) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.name, c.nameErr = template.New("name").Option("missingkey=error").Parse(t)
+	}
+}
+
+// AnnotationKeyConnectionSecretNamespace is the annotation a composition may
+// set to the namespace its composite resources' connection details should be
+// published to. The configurator copies it to the annotations of the
+// composite resource's PublishConnectionDetailsTo metadata, for publishers
+// that support routing connection details to a namespace.
+const AnnotationKeyConnectionSecretNamespace = "crossplane.io/connection-secret-namespace"
+
+// AnnotationKeyConnectionSecretMetadata is the annotation a composition may
+// set to JSON encoded connection secret metadata, i.e. labels, annotations,
+// and a secret type, that should be attached to its composite resources'
+// published connection details. For example:
+//
+//	crossplane.io/connection-secret-metadata: '{"labels":{"team":"payments"}}'
+//
+// Connection details published to Kubernetes are Opaque secrets unless a type
+// is declared, for example '{"type":"kubernetes.io/tls"}'.
+const AnnotationKeyConnectionSecretMetadata = "crossplane.io/connection-secret-metadata"
+
+// WithAllowedNamespaces configures the configurator to reject compositions
+// that request their connection details be published to a namespace other
+// than the supplied namespaces. Any namespace is allowed by default.
+func WithAllowedNamespaces(ns ...string) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.namespaces = make(map[string]bool, len(ns))
+		for _, n := range ns {
+			c.namespaces[n] = true
+		}
+	}
+}
+
+// KnownSecretTypes returns the secret types Kubernetes defines.
+func KnownSecretTypes() []corev1.SecretType {
+	return []corev1.SecretType{
+		corev1.SecretTypeOpaque,
+		corev1.SecretTypeServiceAccountToken,
+		corev1.SecretTypeDockercfg,
+		corev1.SecretTypeDockerConfigJson,
+		corev1.SecretTypeBasicAuth,
+		corev1.SecretTypeSSHAuth,
+		corev1.SecretTypeTLS,
+		corev1.SecretTypeBootstrapToken,
+	}
+}
+
+// WithAllowedSecretTypes configures the configurator to reject compositions
+// whose connection secret metadata declares a secret type other than the
+// supplied types, for example KnownSecretTypes. Like Kubernetes, the
+// configurator allows any type by default. Compositions that don't declare a
+// type are always allowed; their connection details are published as Opaque
+// secrets.
+func WithAllowedSecretTypes(t ...corev1.SecretType) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.secretTypes = make(map[corev1.SecretType]bool, len(t))
+		for _, st := range t {
+			c.secretTypes[st] = true
+		}
+	}
+}
+
+// WithDefaultStoreConfig configures the configurator to publish the
+// connection details of composite resources whose composition doesn't specify
+// a store config to the supplied store config. A store config specified by
+// the composition always takes precedence, and the default never replaces a
+// store config already set on the composite resource. Connection details
+// aren't published to a store by default.
+func WithDefaultStoreConfig(name string) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.defaultStore = name
+	}
+}
+
+// WithPreviousStoreCleanup configures the configurator to unpublish a
+// composite resource's connection details from the SecretStore entry it used
+// to publish to when its composition starts publishing them to a different
+// store config. The previous entry is left in place by default.
+func WithPreviousStoreCleanup(u PreviousStoreUnpublisher) SecretStoreConnectionDetailsConfiguratorOption {
+	return func(c *SecretStoreConnectionDetailsConfigurator) {
+		c.previous = u
+	}
+}
+
+// PublishNamespace returns the namespace the supplied resource's connection
+// details should be published to, if any.
+func PublishNamespace(o resource.ConnectionDetailsPublisherTo) string {
+	to := o.GetPublishConnectionDetailsTo()
+	if to == nil || to.Metadata == nil {
+		return ""
+	}
+	return to.Metadata.Annotations[AnnotationKeyConnectionSecretNamespace]
 }
 
 // NewSecretStoreConnectionDetailsConfigurator returns a Configurator that
 // configures a composite resource using its composition.
-func NewSecretStoreConnectionDetailsConfigurator(c client.Client) *SecretStoreConnectionDetailsConfigurator {
-	return &SecretStoreConnectionDetailsConfigurator{client: c}
+func NewSecretStoreConnectionDetailsConfigurator(c client.Client, o ...SecretStoreConnectionDetailsConfiguratorOption) *SecretStoreConnectionDetailsConfigurator {
+	sc := &SecretStoreConnectionDetailsConfigurator{client: c}
+	for _, fn := range o {
+		fn(sc)
+	}
+	return sc
 }
 
 // A SecretStoreConnectionDetailsConfigurator configures a composite resource
 // using its composition.
 type SecretStoreConnectionDetailsConfigurator struct {
 	client client.Client
+	compatibility
+
+	name    *template.Template
+	nameErr error
+
+	namespaces  map[string]bool
+	secretTypes map[corev1.SecretType]bool
+
+	defaultStore string
+
+	previous PreviousStoreUnpublisher
+}
+
+// storeNameSuffixLength is the number of UID characters appended to rendered
+// store entry names that may not be unique.
+const storeNameSuffixLength = 8
+
+type storeNameData struct {
+	Name      string
+	Namespace string
+	UID       string
+}
+
+// entryName returns the name of the store entry the supplied composite
+// resource's connection details should be published to.
+func (c *SecretStoreConnectionDetailsConfigurator) entryName(cp resource.Composite) (string, error) {
+	uid := string(cp.GetUID())
+	if c.nameErr != nil {
+		return "", errors.Wrap(c.nameErr, errParseStoreNameTemplate)
+	}
+	if c.name == nil {
+		return uid, nil
+	}
+
+	d := storeNameData{Name: cp.GetName(), Namespace: cp.GetNamespace(), UID: uid}
+	if ref := cp.GetClaimReference(); ref != nil && d.Namespace == "" {
+		d.Namespace = ref.Namespace
+	}
+
+	b := &bytes.Buffer{}
+	if err := c.name.Execute(b, d); err != nil {
+		return "", errors.Wrap(err, errRenderStoreNameTemplate)
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" {
+		return uid, nil
+	}
+
+	// Composite resources are cluster scoped, so their names and UIDs are
+	// the only tokens guaranteed to be unique.
+	if (d.Name != "" && strings.Contains(name, d.Name)) || (uid != "" && strings.Contains(name, uid)) {
+		return name, nil
+	}
+	suffix := uid
+	if len(suffix) > storeNameSuffixLength {
+		suffix = suffix[:storeNameSuffixLength]
+	}
+	return name + "-" + suffix, nil
 }
 
 // Configure any required fields that were omitted from the composite resource
 // by copying them from its composition.
 func (c *SecretStoreConnectionDetailsConfigurator) Configure(ctx context.Context, cp resource.Composite, comp *v1.Composition) error {
-	apiVersion, kind := cp.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
-	if comp.Spec.CompositeTypeRef.APIVersion != apiVersion || comp.Spec.CompositeTypeRef.Kind != kind {
-		return errors.New(errCompositionNotCompatible)
+	if err := c.check(ctx, cp, comp); err != nil {
+		return err
 	}
 
-	if cp.GetPublishConnectionDetailsTo() != nil || comp.Spec.PublishConnectionDetailsWithStoreConfigRef == nil {
+	ref := comp.Spec.PublishConnectionDetailsWithStoreConfigRef
+	store := c.defaultStore
+	if ref != nil {
+		store = ref.Name
+	}
+	if store == "" {
 		return nil
 	}
 
-	cp.SetPublishConnectionDetailsTo(&xpv1.PublishConnectionDetailsTo{
-		Name: string(cp.GetUID()),
-		SecretStoreConfigRef: &xpv1.Reference{
-			Name: comp.Spec.PublishConnectionDetailsWithStoreConfigRef.Name,
-		},
-	})
+	ns := comp.GetAnnotations()[AnnotationKeyConnectionSecretNamespace]
+	if ns != "" && c.namespaces != nil && !c.namespaces[ns] {
+		return errors.Errorf(errFmtNamespaceNotAllowed, ns)
+	}
+
+	md, err := connectionSecretMetadata(comp)
+	if err != nil {
+		return err
+	}
+	if md != nil && md.Type != nil && c.secretTypes != nil && !c.secretTypes[*md.Type] {
+		return errors.Errorf(errFmtSecretTypeNotAllowed, *md.Type)
+	}
+
+	// Once the composite resource has a PublishConnectionDetailsTo, whether
+	// we generated it or a user configured it, we leave its name, namespace,
+	// and metadata alone. We only keep its store config in sync with the
+	// composition's, and don't issue an update if it already is. The default
+	// store config only applies to composite resources that don't already
+	// have a store config; it never replaces one a user set.
+	if to := cp.GetPublishConnectionDetailsTo(); to != nil {
+		if ref == nil && to.SecretStoreConfigRef != nil {
+			return nil
+		}
+		if to.SecretStoreConfigRef != nil && to.SecretStoreConfigRef.Name == store {
+			return nil
+		}
+		updated := to.DeepCopy()
+		updated.SecretStoreConfigRef = &xpv1.Reference{Name: store}
+
+		// We unpublish from the previous store before we record the new
+		// one, so that if we fail we'll try again next time. There's no
+		// previous store if the composite resource didn't have a store
+		// config.
+		if c.previous != nil && to.SecretStoreConfigRef != nil {
+			if _, err := c.previous.UnpublishPrevious(ctx, cp, to, updated); err != nil && !errors.Is(err, ErrUnsupported) {
+				return errors.Wrap(err, errUnpublishPrevious)
+			}
+		}
+		cp.SetPublishConnectionDetailsTo(updated)
+		return errors.Wrap(c.client.Update(ctx, cp), errUpdateComposite)
+	}
+
+	name, err := c.entryName(cp)
+	if err != nil {
+		return err
+	}
+
+	to := &xpv1.PublishConnectionDetailsTo{
+		Name:                 name,
+		SecretStoreConfigRef: &xpv1.Reference{Name: store},
+	}
+	withSecretMetadata(to, md)
+	withPublishNamespace(to, ns)
+	cp.SetPublishConnectionDetailsTo(to)
 
 	return errors.Wrap(c.client.Update(ctx, cp), errUpdateComposite)
 }
 
+// connectionSecretMetadata returns the connection secret metadata the supplied
+// composition wants attached to published connection details, if any.
+func connectionSecretMetadata(comp *v1.Composition) (*xpv1.ConnectionSecretMetadata, error) {
+	raw, ok := comp.GetAnnotations()[AnnotationKeyConnectionSecretMetadata]
+	if !ok {
+		return nil, nil
+	}
+	md := &xpv1.ConnectionSecretMetadata{}
+	if err := json.Unmarshal([]byte(raw), md); err != nil {
+		return nil, errors.Wrap(err, errParseSecretMetadata)
+	}
+	return md, nil
+}
+
+// withSecretMetadata attaches a copy of the supplied metadata to the supplied
+// PublishConnectionDetailsTo, which must be one the configurator generated.
+func withSecretMetadata(to *xpv1.PublishConnectionDetailsTo, md *xpv1.ConnectionSecretMetadata) {
+	if md == nil || (len(md.Labels) == 0 && len(md.Annotations) == 0 && md.Type == nil) {
+		return
+	}
+	to.Metadata = md.DeepCopy()
+}
+
+// withPublishNamespace records the namespace connection details should be
+// published to in the supplied PublishConnectionDetailsTo, which must be one
+// the configurator generated. An empty namespace records nothing.
+func withPublishNamespace(to *xpv1.PublishConnectionDetailsTo, ns string) {
+	if ns == "" {
+		return
+	}
+	if to.Metadata == nil {
+		to.Metadata = &xpv1.ConnectionSecretMetadata{}
+	}
+	if to.Metadata.Annotations == nil {
+		to.Metadata.Annotations = map[string]string{}
+	}
+	to.Metadata.Annotations[AnnotationKeyConnectionSecretNamespace] = ns
+}
+
 // ConnectionDetailsExtractor extracts the connection details of a resource.
 type ConnectionDetailsExtractor interface {
 	// ExtractConnection of the supplied resource.
@@ -207,7 +1591,11 @@ func ExtractConnectionDetails(cd resource.Composed, data managed.ConnectionDetai
 			if cfg.Value == nil {
 				return nil, errors.Errorf(errFmtConnDetailVal, tp)
 			}
-			out[cfg.Name] = []byte(*cfg.Value)
+			v, err := transform(cfg, []byte(*cfg.Value))
+			if err != nil {
+				return nil, err
+			}
+			out[cfg.Name] = v
 		case ConnectionDetailTypeFromConnectionSecretKey:
 			if cfg.FromConnectionSecretKey == nil {
 				return nil, errors.Errorf(errFmtConnDetailKey, tp)
@@ -217,16 +1605,43 @@ func ExtractConnectionDetails(cd resource.Composed, data managed.ConnectionDetai
 				// key will still be written at some point in the future.
 				continue
 			}
-			out[cfg.Name] = data[*cfg.FromConnectionSecretKey]
+			v, err := transform(cfg, data[*cfg.FromConnectionSecretKey])
+			if err != nil {
+				return nil, err
+			}
+			v, ok, err := treatEmpty(cfg, v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out[cfg.Name] = v
+			}
 		case ConnectionDetailTypeFromFieldPath:
 			if cfg.FromFieldPath == nil {
 				return nil, errors.Errorf(errFmtConnDetailPath, tp)
 			}
-			// Note we're checking that the error _is_ nil. If we hit an error
-			// we silently avoid including this connection secret. It's possible
-			// the path will start existing with a valid value in future.
-			if b, err := fromFieldPath(cd, *cfg.FromFieldPath); err == nil {
-				out[cfg.Name] = b
+			// A malformed path will never resolve, so unlike a path that
+			// doesn't exist yet we consider it an error.
+			if _, err := fieldpath.Parse(*cfg.FromFieldPath); err != nil {
+				return nil, errors.Wrapf(err, errFmtConnDetailPathInvalid, *cfg.FromFieldPath)
+			}
+			// If we hit an error we silently avoid including this connection
+			// secret. It's possible the path will start existing with a valid
+			// value in future.
+			b, err := fromFieldPath(cd, *cfg.FromFieldPath)
+			if err != nil {
+				continue
+			}
+			v, err := transform(cfg, b)
+			if err != nil {
+				return nil, err
+			}
+			v, ok, err := treatEmpty(cfg, v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out[cfg.Name] = v
 			}
 		}
 	}
@@ -243,6 +1658,22 @@ const (
 	ConnectionDetailTypeFromValue               ConnectionDetailType = "FromValue"
 )
 
+// treatEmpty applies the supplied config's empty value policy to the supplied
+// value. It returns false if the value should be treated as missing.
+func treatEmpty(cfg ConnectionDetailExtractConfig, v []byte) ([]byte, bool, error) {
+	if len(v) > 0 {
+		return v, true, nil
+	}
+	switch cfg.TreatEmptyAs {
+	case v1.ConnectionDetailEmptyPolicyMissing:
+		return nil, false, nil
+	case v1.ConnectionDetailEmptyPolicyError:
+		return nil, false, errors.Errorf(errFmtConnDetailEmpty, cfg.Name)
+	default:
+		return v, true, nil
+	}
+}
+
 // A ConnectionDetailExtractConfig configures how an XR connection detail should
 // be extracted.
 type ConnectionDetailExtractConfig struct {
@@ -269,6 +1700,14 @@ type ConnectionDetailExtractConfig struct {
 	// an explicit value may be set to inject a fixed, non-sensitive connection
 	// secret values, for example a well-known port.
 	Value *string
+
+	// TreatEmptyAs determines how a connection detail that is present but
+	// empty is treated. Empty values are valid by default.
+	TreatEmptyAs v1.ConnectionDetailEmptyPolicy
+
+	// Transforms are applied to the connection detail's value, in order,
+	// before its TreatEmptyAs policy.
+	Transforms []v1.ConnectionDetailTransform
 }
 
 // ExtractConfigsFromTemplate builds extract configs for the supplied P&T style
@@ -286,6 +1725,11 @@ func ExtractConfigsFromTemplate(t *v1.ComposedTemplate) []ConnectionDetailExtrac
 			FromFieldPath:           t.ConnectionDetails[i].FromFieldPath,
 		}
 
+		if t.ConnectionDetails[i].TreatEmptyAs != nil {
+			out[i].TreatEmptyAs = *t.ConnectionDetails[i].TreatEmptyAs
+		}
+		out[i].Transforms = t.ConnectionDetails[i].Transforms
+
 		if t.ConnectionDetails[i].Name != nil {
 			out[i].Name = *t.ConnectionDetails[i].Name
 			continue
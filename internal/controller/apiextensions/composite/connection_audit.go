@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errAppendAuditRecord = "cannot append connection details audit record"
+)
+
+// An AuditAction is the action an AuditRecord records.
+type AuditAction string
+
+// Audit actions.
+const (
+	AuditActionPublish   AuditAction = "Publish"
+	AuditActionUnpublish AuditAction = "Unpublish"
+)
+
+// An AuditRecord is an immutable record of a version of published connection
+// details. It contains hashes of connection detail values, never the values
+// themselves.
+type AuditRecord struct {
+	// Time at which the connection details were published or unpublished.
+	Time time.Time `json:"time"`
+
+	// Action that was taken.
+	Action AuditAction `json:"action"`
+
+	// Owner of the connection details, as namespace/name or UID.
+	Owner string `json:"owner"`
+
+	// Store the connection details were published to.
+	Store string `json:"store,omitempty"`
+
+	// Digest is the SHA-256 hash of all connection details.
+	Digest string `json:"digest"`
+
+	// Keys maps each connection detail key to the SHA-256 hash of its value.
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// An AuditLog is an append-only store of audit records. Implementations should
+// be backed by a write-once store, and must return an error if they cannot
+// durably append the supplied record.
+type AuditLog interface {
+	Append(ctx context.Context, r AuditRecord) error
+}
+
+// An AuditLogFn is a function that satisfies the AuditLog interface.
+type AuditLogFn func(ctx context.Context, r AuditRecord) error
+
+// Append the supplied audit record.
+func (fn AuditLogFn) Append(ctx context.Context, r AuditRecord) error {
+	return fn(ctx, r)
+}
+
+// A WORMAuditPublisher appends an audit record to a write-once, read-many
+// (WORM) audit log each time it changes published connection details. Audit
+// can't be skipped, so it fails closed: if the audit log rejects a record the
+// publish is considered to have failed, and will be retried.
+type WORMAuditPublisher struct {
+	publisher managed.ConnectionPublisher
+	log       AuditLog
+}
+
+// NewWORMAuditPublisher returns a ConnectionPublisher that publishes
+// connection details using the supplied publisher, and records each change to
+// the supplied audit log.
+func NewWORMAuditPublisher(p managed.ConnectionPublisher, l AuditLog) *WORMAuditPublisher {
+	return &WORMAuditPublisher{publisher: p, log: l}
+}
+
+// PublishConnection details for the supplied resource, and record them in the
+// audit log if they changed.
+func (p *WORMAuditPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published, err := p.publisher.PublishConnection(ctx, o, c)
+	if err != nil || !published {
+		return published, err
+	}
+	if err := p.log.Append(ctx, auditRecord(AuditActionPublish, o, c)); err != nil {
+		return false, errors.Wrap(err, errAppendAuditRecord)
+	}
+	return true, nil
+}
+
+// UnpublishConnection details for the supplied resource, and record that they
+// were unpublished in the audit log.
+func (p *WORMAuditPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	if err := p.publisher.UnpublishConnection(ctx, o, c); err != nil {
+		return err
+	}
+	return errors.Wrap(p.log.Append(ctx, auditRecord(AuditActionUnpublish, o, c)), errAppendAuditRecord)
+}
+
+func auditRecord(a AuditAction, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) AuditRecord {
+	r := AuditRecord{
+		Time:   time.Now(),
+		Action: a,
+		Owner:  ownerID(o),
+		Store:  storeName(o),
+		Keys:   make(map[string]string, len(c)),
+	}
+
+	for k, v := range c {
+		h := sha256.Sum256(v)
+		r.Keys[k] = hex.EncodeToString(h[:])
+	}
+	r.Digest = connectionDetailsDigest(c)
+	return r
+}
+
+// connectionDetailsDigest returns a hex encoded SHA-256 digest of the supplied
+// connection details. It changes if any key or value changes.
+func connectionDetailsDigest(c managed.ConnectionDetails) string {
+	d := sha256.New()
+	for _, k := range OrderedKeys(c, AlphabeticalKeyOrder()) {
+		h := sha256.Sum256(c[k])
+
+		// We hash each key and the hash of its value, separated by NUL
+		// bytes so that distinct sets of details can't produce the same
+		// input.
+		_, _ = d.Write([]byte(k))
+		_, _ = d.Write([]byte{0})
+		_, _ = d.Write(h[:])
+		_, _ = d.Write([]byte{0})
+	}
+	return hex.EncodeToString(d.Sum(nil))
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &WORMAuditPublisher{}
+
+func TestWORMAuditPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+	o := &fake.Composite{ObjectMeta: metav1.ObjectMeta{UID: "cool-uid"}}
+	conn := managed.ConnectionDetails{"password": []byte("s3cr3t")}
+
+	publish := func(changed bool, err error) managed.ConnectionPublisher {
+		return managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+				return changed, err
+			},
+		}
+	}
+
+	type args struct {
+		p      managed.ConnectionPublisher
+		appErr error
+	}
+	type want struct {
+		published bool
+		err       error
+		records   int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Changed": {
+			reason: "We should append an audit record when published connection details change.",
+			args: args{
+				p: publish(true, nil),
+			},
+			want: want{published: true, records: 1},
+		},
+		"Unchanged": {
+			reason: "We should not append an audit record when published connection details don't change.",
+			args: args{
+				p: publish(false, nil),
+			},
+			want: want{published: false},
+		},
+		"PublishError": {
+			reason: "We should not append an audit record if publishing fails.",
+			args: args{
+				p: publish(false, errBoom),
+			},
+			want: want{err: errBoom},
+		},
+		"AppendError": {
+			reason: "We should fail closed if the audit log rejects the record.",
+			args: args{
+				p:      publish(true, nil),
+				appErr: errBoom,
+			},
+			want: want{err: errors.Wrap(errBoom, errAppendAuditRecord), records: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var records []AuditRecord
+			l := AuditLogFn(func(_ context.Context, r AuditRecord) error {
+				records = append(records, r)
+				return tc.args.appErr
+			})
+
+			p := NewWORMAuditPublisher(tc.args.p, l)
+			published, err := p.PublishConnection(context.Background(), o, conn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.records, len(records)); diff != "" {
+				t.Errorf("\n%s\nrecords: -want, +got:\n%s", tc.reason, diff)
+			}
+			for _, r := range records {
+				b, _ := json.Marshal(r)
+				if bytes.Contains(b, conn["password"]) {
+					t.Errorf("\n%s\nrecord contains plaintext connection detail value: %s", tc.reason, b)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditRecord(t *testing.T) {
+	o := &fake.Composite{ObjectMeta: metav1.ObjectMeta{UID: "cool-uid"}}
+
+	a := auditRecord(AuditActionPublish, o, managed.ConnectionDetails{"a": []byte("b"), "c": []byte("d")})
+	b := auditRecord(AuditActionPublish, o, managed.ConnectionDetails{"c": []byte("d"), "a": []byte("b")})
+	if diff := cmp.Diff(a, b, cmpopts.IgnoreFields(AuditRecord{}, "Time")); diff != "" {
+		t.Errorf("auditRecord(...): identical connection details should produce identical records: -want, +got:\n%s", diff)
+	}
+
+	c := auditRecord(AuditActionPublish, o, managed.ConnectionDetails{"a": []byte("bc"), "c": []byte("d")})
+	if a.Digest == c.Digest {
+		t.Errorf("auditRecord(...): different connection details should produce different digests")
+	}
+}
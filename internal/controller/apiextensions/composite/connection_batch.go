@@ -0,0 +1,270 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errBatchPublisherClosed = "cannot publish connection details: batching publisher is closed"
+	errFmtBatchFlushStore   = "cannot flush pending connection details to store %q"
+)
+
+// DefaultMaxPendingPublishes is the default number of pending publishes a
+// BatchingConnectionPublisher will buffer before flushing.
+const DefaultMaxPendingPublishes = 100
+
+// A PendingConnectionPublish is a buffered request to publish connection
+// details.
+type PendingConnectionPublish struct {
+	// Owner of the connection details.
+	Owner resource.ConnectionSecretOwner
+
+	// ConnectionDetails to publish.
+	ConnectionDetails managed.ConnectionDetails
+}
+
+// A BatchConnectionPublisher can publish connection details for several owners
+// in one operation, for example a single transaction against its store. All
+// supplied publishes target the same store.
+type BatchConnectionPublisher interface {
+	PublishConnections(ctx context.Context, p []PendingConnectionPublish) error
+}
+
+// A BatchingConnectionPublisherOption configures a
+// BatchingConnectionPublisher.
+type BatchingConnectionPublisherOption func(*BatchingConnectionPublisher)
+
+// WithDeferredPublish configures a BatchingConnectionPublisher to buffer
+// publishes until it is flushed, rather than passing them straight through.
+func WithDeferredPublish() BatchingConnectionPublisherOption {
+	return func(p *BatchingConnectionPublisher) {
+		p.deferred = true
+	}
+}
+
+// WithMaxPendingPublishes configures how many publishes a deferring
+// BatchingConnectionPublisher will buffer before it automatically flushes.
+func WithMaxPendingPublishes(n int) BatchingConnectionPublisherOption {
+	return func(p *BatchingConnectionPublisher) {
+		p.max = n
+	}
+}
+
+// A BatchingConnectionPublisher buffers connection publishes so that tooling
+// that configures many composite resources may flush them in batches, grouped
+// by store. By default it passes publishes straight through to the wrapped
+// publisher, which is the behaviour a reconcile loop expects.
+type BatchingConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	deferred  bool
+	max       int
+
+	mu      sync.Mutex
+	closed  bool
+	pending map[string]map[string]PendingConnectionPublish
+	count   int
+}
+
+// NewBatchingConnectionPublisher returns a BatchingConnectionPublisher that
+// wraps the supplied publisher. If the wrapped publisher satisfies
+// BatchConnectionPublisher each store's pending publishes will be flushed in
+// one call.
+func NewBatchingConnectionPublisher(p managed.ConnectionPublisher, o ...BatchingConnectionPublisherOption) *BatchingConnectionPublisher {
+	bp := &BatchingConnectionPublisher{
+		publisher: p,
+		max:       DefaultMaxPendingPublishes,
+		pending:   map[string]map[string]PendingConnectionPublish{},
+	}
+	for _, fn := range o {
+		fn(bp)
+	}
+	return bp
+}
+
+// PublishConnection details for the supplied resource. When deferring,
+// publishes to the same owner are merged in the buffer and published is true
+// if the details were buffered. The buffer is flushed if it is full.
+func (p *BatchingConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if !p.deferred {
+		return p.publisher.PublishConnection(ctx, o, c)
+	}
+
+	// This resource does not want to expose a connection secret.
+	pt := o.GetPublishConnectionDetailsTo()
+	if pt == nil {
+		return false, nil
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return false, errors.New(errBatchPublisherClosed)
+	}
+	p.enqueue(storeName(o), PendingConnectionPublish{Owner: o, ConnectionDetails: c})
+	full := p.count >= p.max
+	p.mu.Unlock()
+
+	if full {
+		return true, p.Flush(ctx)
+	}
+	return true, nil
+}
+
+// UnpublishConnection details for the supplied resource. Any pending publishes
+// for the resource are discarded.
+func (p *BatchingConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	p.mu.Lock()
+	id := ownerID(o)
+	for store, owners := range p.pending {
+		if _, ok := owners[id]; ok {
+			delete(owners, id)
+			p.count--
+		}
+		if len(owners) == 0 {
+			delete(p.pending, store)
+		}
+	}
+	p.mu.Unlock()
+	return p.publisher.UnpublishConnection(ctx, o, c)
+}
+
+// Flush all pending publishes, grouped by store. Publishes that could not be
+// flushed remain pending so that a subsequent Flush may retry them.
+func (p *BatchingConnectionPublisher) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = map[string]map[string]PendingConnectionPublish{}
+	p.count = 0
+	p.mu.Unlock()
+
+	stores := make([]string, 0, len(pending))
+	for s := range pending {
+		stores = append(stores, s)
+	}
+	sort.Strings(stores)
+
+	for i, s := range stores {
+		if err := p.flushStore(ctx, pending[s]); err != nil {
+			p.requeue(pending, stores[i:])
+			return errors.Wrapf(err, errFmtBatchFlushStore, s)
+		}
+	}
+	return nil
+}
+
+// Close flushes any pending publishes. A closed BatchingConnectionPublisher
+// refuses deferred publishes.
+func (p *BatchingConnectionPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	return p.Flush(ctx)
+}
+
+func (p *BatchingConnectionPublisher) flushStore(ctx context.Context, owners map[string]PendingConnectionPublish) error {
+	ids := make([]string, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	batch := make([]PendingConnectionPublish, len(ids))
+	for i, id := range ids {
+		batch[i] = owners[id]
+	}
+
+	if bp, ok := p.publisher.(BatchConnectionPublisher); ok {
+		return bp.PublishConnections(ctx, batch)
+	}
+
+	for i := range batch {
+		if _, err := p.publisher.PublishConnection(ctx, batch[i].Owner, batch[i].ConnectionDetails); err != nil {
+			return err
+		}
+		// Don't requeue what we've already published.
+		delete(owners, ids[i])
+	}
+	return nil
+}
+
+// requeue the supplied stores' pending publishes. Anything published to the
+// buffer since they were removed takes precedence.
+func (p *BatchingConnectionPublisher) requeue(pending map[string]map[string]PendingConnectionPublish, stores []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range stores {
+		for _, pp := range pending[s] {
+			newer := p.pending[s][ownerID(pp.Owner)]
+			p.enqueue(s, pp)
+			if newer.Owner != nil {
+				p.enqueue(s, newer)
+			}
+		}
+	}
+}
+
+// enqueue merges the supplied publish into the buffer. Publishing is
+// additive, so later details override earlier details for the same owner.
+// Callers must hold the lock.
+func (p *BatchingConnectionPublisher) enqueue(store string, pp PendingConnectionPublish) {
+	owners, ok := p.pending[store]
+	if !ok {
+		owners = map[string]PendingConnectionPublish{}
+		p.pending[store] = owners
+	}
+	id := ownerID(pp.Owner)
+	existing, ok := owners[id]
+	if !ok {
+		p.count++
+		existing = PendingConnectionPublish{ConnectionDetails: managed.ConnectionDetails{}}
+	}
+	merged := make(managed.ConnectionDetails, len(existing.ConnectionDetails)+len(pp.ConnectionDetails))
+	for k, v := range existing.ConnectionDetails {
+		merged[k] = v
+	}
+	for k, v := range pp.ConnectionDetails {
+		merged[k] = v
+	}
+	owners[id] = PendingConnectionPublish{Owner: pp.Owner, ConnectionDetails: merged}
+}
+
+// storeName returns the name of the secret store config the supplied owner
+// publishes connection details to, if any.
+func storeName(o resource.ConnectionSecretOwner) string {
+	pt := o.GetPublishConnectionDetailsTo()
+	if pt == nil || pt.SecretStoreConfigRef == nil {
+		return ""
+	}
+	return pt.SecretStoreConfigRef.Name
+}
+
+// ownerID uniquely identifies the supplied connection secret owner.
+func ownerID(o resource.ConnectionSecretOwner) string {
+	if uid := o.GetUID(); uid != "" {
+		return string(uid)
+	}
+	return o.GetNamespace() + "/" + o.GetName()
+}
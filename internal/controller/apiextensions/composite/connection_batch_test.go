@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &BatchingConnectionPublisher{}
+
+// A recordingBatchPublisher records the batches it is asked to publish.
+type recordingBatchPublisher struct {
+	managed.ConnectionPublisherFns
+	batches [][]PendingConnectionPublish
+	err     error
+}
+
+func (p *recordingBatchPublisher) PublishConnections(_ context.Context, b []PendingConnectionPublish) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.batches = append(p.batches, b)
+	return nil
+}
+
+func batchOwner(uid, store string) resource.ConnectionSecretOwner {
+	o := &fake.Composed{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{
+		To: &xpv1.PublishConnectionDetailsTo{Name: uid, SecretStoreConfigRef: &xpv1.Reference{Name: store}},
+	}}
+	o.SetUID(types.UID(uid))
+	return o
+}
+
+func TestBatchingConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	t.Run("PassThrough", func(t *testing.T) {
+		calls := 0
+		p := NewBatchingConnectionPublisher(managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+				calls++
+				return true, nil
+			},
+		})
+		if _, err := p.PublishConnection(context.Background(), batchOwner("a", "s"), managed.ConnectionDetails{"k": []byte("v")}); err != nil {
+			t.Fatalf("PublishConnection(...): %s", err)
+		}
+		if diff := cmp.Diff(1, calls); diff != "" {
+			t.Errorf("PublishConnection(...): should pass through when not deferring: -want calls, +got calls:\n%s", diff)
+		}
+	})
+
+	t.Run("FlushGroupsByStore", func(t *testing.T) {
+		bp := &recordingBatchPublisher{}
+		p := NewBatchingConnectionPublisher(bp, WithDeferredPublish())
+		a, b, c := batchOwner("a", "vault"), batchOwner("b", "vault"), batchOwner("c", "kubernetes")
+
+		_, _ = p.PublishConnection(context.Background(), a, managed.ConnectionDetails{"k1": []byte("v1")})
+		_, _ = p.PublishConnection(context.Background(), a, managed.ConnectionDetails{"k2": []byte("v2")})
+		_, _ = p.PublishConnection(context.Background(), b, managed.ConnectionDetails{"k": []byte("v")})
+		_, _ = p.PublishConnection(context.Background(), c, managed.ConnectionDetails{"k": []byte("v")})
+
+		if len(bp.batches) != 0 {
+			t.Fatalf("PublishConnection(...): should not publish until flushed")
+		}
+		if err := p.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush(...): %s", err)
+		}
+
+		want := [][]PendingConnectionPublish{
+			{{Owner: c, ConnectionDetails: managed.ConnectionDetails{"k": []byte("v")}}},
+			{
+				{Owner: a, ConnectionDetails: managed.ConnectionDetails{"k1": []byte("v1"), "k2": []byte("v2")}},
+				{Owner: b, ConnectionDetails: managed.ConnectionDetails{"k": []byte("v")}},
+			},
+		}
+		if diff := cmp.Diff(want, bp.batches); diff != "" {
+			t.Errorf("Flush(...): -want batches, +got batches:\n%s", diff)
+		}
+	})
+
+	t.Run("FlushWhenFull", func(t *testing.T) {
+		bp := &recordingBatchPublisher{}
+		p := NewBatchingConnectionPublisher(bp, WithDeferredPublish(), WithMaxPendingPublishes(2))
+		_, _ = p.PublishConnection(context.Background(), batchOwner("a", "s"), managed.ConnectionDetails{})
+		_, _ = p.PublishConnection(context.Background(), batchOwner("b", "s"), managed.ConnectionDetails{})
+		if diff := cmp.Diff(1, len(bp.batches)); diff != "" {
+			t.Errorf("PublishConnection(...): should flush when buffer is full: -want batches, +got batches:\n%s", diff)
+		}
+	})
+
+	t.Run("FlushErrorKeepsPending", func(t *testing.T) {
+		bp := &recordingBatchPublisher{err: errBoom}
+		p := NewBatchingConnectionPublisher(bp, WithDeferredPublish())
+		a := batchOwner("a", "s")
+		_, _ = p.PublishConnection(context.Background(), a, managed.ConnectionDetails{"k": []byte("v")})
+
+		err := p.Flush(context.Background())
+		if diff := cmp.Diff(errors.Wrapf(errBoom, errFmtBatchFlushStore, "s"), err, test.EquateErrors()); diff != "" {
+			t.Errorf("Flush(...): -want error, +got error:\n%s", diff)
+		}
+
+		bp.err = nil
+		if err := p.Close(context.Background()); err != nil {
+			t.Fatalf("Close(...): %s", err)
+		}
+		want := [][]PendingConnectionPublish{{{Owner: a, ConnectionDetails: managed.ConnectionDetails{"k": []byte("v")}}}}
+		if diff := cmp.Diff(want, bp.batches); diff != "" {
+			t.Errorf("Close(...): should flush publishes that previously failed: -want batches, +got batches:\n%s", diff)
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		p := NewBatchingConnectionPublisher(&recordingBatchPublisher{}, WithDeferredPublish())
+		_ = p.Close(context.Background())
+		_, err := p.PublishConnection(context.Background(), batchOwner("a", "s"), managed.ConnectionDetails{})
+		if diff := cmp.Diff(errors.New(errBatchPublisherClosed), err, test.EquateErrors()); diff != "" {
+			t.Errorf("PublishConnection(...): -want error, +got error:\n%s", diff)
+		}
+	})
+}
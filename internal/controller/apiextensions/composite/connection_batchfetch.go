@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// Error strings.
+const (
+	errFmtBatchResults = "batch connection details fetcher returned connection details for %d composed resources, want %d"
+)
+
+// A BatchConnectionDetailsFetcher can fetch the connection details of several
+// composed resources at once, for example because its backend supports batch
+// reads. ConnectionDetailsFetchers may optionally implement it. The PTComposer
+// fetches the connection details of all of a composite resource's composed
+// resources in one batch if its fetcher does.
+type BatchConnectionDetailsFetcher interface {
+	// FetchConnectionDetailsBatch returns the connection details of each of
+	// the supplied composed resources, in the same order. The supplied
+	// templates are the templates of each composed resource, in the same
+	// order.
+	FetchConnectionDetailsBatch(ctx context.Context, cds []resource.Composed, ts []v1.ComposedTemplate) ([]managed.ConnectionDetails, error)
+}
+
+// fetchBatch fetches the connection details of the supplied composed resources
+// that were successfully rendered in one batch. It returns a slice of the same
+// length as the supplied composed resources, whose elements are the
+// connection details of the composed resource at the same index.
+func fetchBatch(ctx context.Context, f BatchConnectionDetailsFetcher, cds []ComposedResourceState) ([]managed.ConnectionDetails, error) {
+	idx := make([]int, 0, len(cds))
+	rs := make([]resource.Composed, 0, len(cds))
+	ts := make([]v1.ComposedTemplate, 0, len(cds))
+	for i := range cds {
+		if cds[i].TemplateRenderErr != nil {
+			continue
+		}
+		idx = append(idx, i)
+		rs = append(rs, cds[i].Resource)
+		ts = append(ts, *cds[i].Template)
+	}
+
+	conn, err := f.FetchConnectionDetailsBatch(ctx, rs, ts)
+	if err != nil {
+		return nil, err
+	}
+	if len(conn) != len(rs) {
+		return nil, errors.Errorf(errFmtBatchResults, len(conn), len(rs))
+	}
+
+	out := make([]managed.ConnectionDetails, len(cds))
+	for j, i := range idx {
+		out[i] = conn[j]
+	}
+	return out, nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+var (
+	_ managed.ConnectionDetailsFetcher = &roundTripFetcher{}
+	_ BatchConnectionDetailsFetcher    = &roundTripFetcher{}
+)
+
+// A roundTripFetcher returns the name of each composed resource as its
+// connection details. Each call costs one backend round trip, regardless of
+// how many composed resources it fetches.
+type roundTripFetcher struct {
+	latency    time.Duration
+	roundTrips int
+	results    int
+	err        error
+}
+
+func (f *roundTripFetcher) roundTrip() error {
+	f.roundTrips++
+	time.Sleep(f.latency)
+	return f.err
+}
+
+func (f *roundTripFetcher) FetchConnection(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if err := f.roundTrip(); err != nil {
+		return nil, err
+	}
+	return managed.ConnectionDetails{"name": []byte(o.GetName())}, nil
+}
+
+func (f *roundTripFetcher) FetchConnectionDetailsBatch(_ context.Context, cds []resource.Composed, _ []v1.ComposedTemplate) ([]managed.ConnectionDetails, error) {
+	if err := f.roundTrip(); err != nil {
+		return nil, err
+	}
+	n := len(cds)
+	if f.results != 0 {
+		n = f.results
+	}
+	out := make([]managed.ConnectionDetails, n)
+	for i := range out {
+		out[i] = managed.ConnectionDetails{"name": []byte(cds[i].GetName())}
+	}
+	return out, nil
+}
+
+func TestFetchBatch(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	state := func(name string, err error) ComposedResourceState {
+		cd := &fake.Composed{}
+		cd.SetName(name)
+		return ComposedResourceState{Resource: cd, Template: &v1.ComposedTemplate{}, TemplateRenderErr: err}
+	}
+
+	type args struct {
+		f   *roundTripFetcher
+		cds []ComposedResourceState
+	}
+	type want struct {
+		conn       []managed.ConnectionDetails
+		roundTrips int
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Success": {
+			reason: "We should fetch the connection details of every composed resource in one round trip.",
+			args: args{
+				f:   &roundTripFetcher{},
+				cds: []ComposedResourceState{state("a", nil), state("b", nil), state("c", nil)},
+			},
+			want: want{
+				conn: []managed.ConnectionDetails{
+					{"name": []byte("a")},
+					{"name": []byte("b")},
+					{"name": []byte("c")},
+				},
+				roundTrips: 1,
+			},
+		},
+		"SkipUnrendered": {
+			reason: "We should not fetch the connection details of composed resources we couldn't render, and should associate the rest with the right composed resources.",
+			args: args{
+				f:   &roundTripFetcher{},
+				cds: []ComposedResourceState{state("a", errBoom), state("b", nil), state("c", errBoom), state("d", nil)},
+			},
+			want: want{
+				conn: []managed.ConnectionDetails{
+					nil,
+					{"name": []byte("b")},
+					nil,
+					{"name": []byte("d")},
+				},
+				roundTrips: 1,
+			},
+		},
+		"FetchError": {
+			reason: "We should return any error encountered fetching the batch.",
+			args: args{
+				f:   &roundTripFetcher{err: errBoom},
+				cds: []ComposedResourceState{state("a", nil)},
+			},
+			want: want{
+				roundTrips: 1,
+				err:        errBoom,
+			},
+		},
+		"WrongResultCount": {
+			reason: "We should return an error if the fetcher doesn't return connection details for every composed resource, since we can't tell which are which.",
+			args: args{
+				f:   &roundTripFetcher{results: 1},
+				cds: []ComposedResourceState{state("a", nil), state("b", nil)},
+			},
+			want: want{
+				roundTrips: 1,
+				err:        errors.Errorf(errFmtBatchResults, 1, 2),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := fetchBatch(context.Background(), tc.args.f, tc.args.cds)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nfetchBatch(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nfetchBatch(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.roundTrips, tc.args.f.roundTrips); diff != "" {
+				t.Errorf("\n%s\nfetchBatch(...): -want round trips, +got round trips:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func BenchmarkFetchBatch(b *testing.B) {
+	cds := make([]ComposedResourceState, 50)
+	for i := range cds {
+		cd := &fake.Composed{}
+		cd.SetName(fmt.Sprintf("cd-%d", i))
+		cds[i] = ComposedResourceState{Resource: cd, Template: &v1.ComposedTemplate{}}
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		f := &roundTripFetcher{latency: 100 * time.Microsecond}
+		for i := 0; i < b.N; i++ {
+			for j := range cds {
+				_, _ = f.FetchConnection(context.Background(), cds[j].Resource)
+			}
+		}
+		b.ReportMetric(float64(f.roundTrips)/float64(b.N), "round-trips/op")
+	})
+
+	b.Run("Batch", func(b *testing.B) {
+		f := &roundTripFetcher{latency: 100 * time.Microsecond}
+		for i := 0; i < b.N; i++ {
+			_, _ = fetchBatch(context.Background(), f, cds)
+		}
+		b.ReportMetric(float64(f.roundTrips)/float64(b.N), "round-trips/op")
+	})
+}
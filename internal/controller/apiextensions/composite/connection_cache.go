@@ -0,0 +1,293 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A CompositeConnectionDetails entry is the connection details previously
+// assembled for a composite resource.
+type CompositeConnectionDetails struct {
+	// ConnectionDetails assembled from all composed resources.
+	ConnectionDetails managed.ConnectionDetails
+
+	// Sources of each connection detail key.
+	Sources map[string]string
+
+	// ConnectionSources summarizes the connection details fetched from each
+	// composed resource.
+	ConnectionSources []ConnectionSourceStatus
+}
+
+type cachedConnectionDetails struct {
+	key string
+	CompositeConnectionDetails
+}
+
+// A CompositeConnectionDetailsCache caches the connection details assembled
+// for each composite resource. Each composite resource has at most one entry,
+// which is served only while its key matches. Keys are derived from the state
+// the connection details were assembled from, so any relevant change
+// invalidates the entry. See CompositeConnectionDetailsCacheKey.
+type CompositeConnectionDetailsCache struct {
+	mu      sync.RWMutex
+	entries map[types.UID]cachedConnectionDetails
+}
+
+// NewCompositeConnectionDetailsCache returns an empty cache.
+func NewCompositeConnectionDetailsCache() *CompositeConnectionDetailsCache {
+	return &CompositeConnectionDetailsCache{entries: map[types.UID]cachedConnectionDetails{}}
+}
+
+// Get the connection details cached for the supplied composite resource UID,
+// if they were cached with the supplied key.
+func (c *CompositeConnectionDetailsCache) Get(uid types.UID, key string) (CompositeConnectionDetails, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[uid]
+	if !ok || e.key != key {
+		return CompositeConnectionDetails{}, false
+	}
+
+	// Callers may modify what we return, so we return copies.
+	out := CompositeConnectionDetails{
+		ConnectionDetails: make(managed.ConnectionDetails, len(e.ConnectionDetails)),
+		Sources:           make(map[string]string, len(e.Sources)),
+	}
+	for k, v := range e.ConnectionDetails {
+		out.ConnectionDetails[k] = v
+	}
+	for k, v := range e.Sources {
+		out.Sources[k] = v
+	}
+	out.ConnectionSources = append(out.ConnectionSources, e.ConnectionSources...)
+	return out, true
+}
+
+// Set the connection details cached for the supplied composite resource UID,
+// replacing any previously cached connection details.
+func (c *CompositeConnectionDetailsCache) Set(uid types.UID, key string, d CompositeConnectionDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[uid] = cachedConnectionDetails{key: key, CompositeConnectionDetails: d}
+}
+
+// Forget any connection details cached for the supplied composite resource
+// UID, for example because it was deleted.
+func (c *CompositeConnectionDetailsCache) Forget(uid types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, uid)
+}
+
+// A ConnectionDetailsCacheSource is a composed resource whose connection
+// details are assembled into a composite resource's connection details.
+type ConnectionDetailsCacheSource struct {
+	// Resource is the composed resource. It is nil if the composed resource
+	// could not be rendered.
+	Resource resource.Object
+
+	// SecretResourceVersion is the resource version of the connection secret
+	// the composed resource writes, if any. A provider may rotate a secret
+	// without changing the composed resource that writes it.
+	SecretResourceVersion string
+}
+
+// CompositeConnectionDetailsCacheKey derives a cache key from the state the
+// supplied composite resource's connection details are assembled from; its
+// generation, its composition (or composition revision), and the resource
+// version of each of its composed resources and their connection secrets.
+// Composed resources are supplied in the order their connection details are
+// assembled in, because later resources take precedence.
+func CompositeConnectionDetailsCacheKey(xr resource.Composite, comp resource.Object, srcs ...ConnectionDetailsCacheSource) string {
+	b := &strings.Builder{}
+	b.WriteString(strconv.FormatInt(xr.GetGeneration(), 10))
+	if ref := xr.GetCompositionRevisionReference(); ref != nil {
+		b.WriteString("|" + ref.Name)
+	}
+	if comp != nil {
+		b.WriteString("|" + comp.GetName() + "@" + comp.GetResourceVersion())
+	}
+	for _, src := range srcs {
+		if src.Resource == nil {
+			b.WriteString("|!")
+			continue
+		}
+		b.WriteString("|" + src.Resource.GetNamespace() + "/" + src.Resource.GetName() + "@" + src.Resource.GetResourceVersion())
+		b.WriteString("#" + src.SecretResourceVersion)
+	}
+	return b.String()
+}
+
+// ConnectionSecretResourceVersion returns the resource version of the
+// connection secret the supplied composed resource writes. It returns an
+// empty string if the composed resource doesn't write a connection secret, or
+// hasn't written it yet.
+func ConnectionSecretResourceVersion(ctx context.Context, c client.Reader, cd resource.Object) (string, error) {
+	w, ok := cd.(resource.ConnectionSecretWriterTo)
+	if !ok {
+		return "", nil
+	}
+	ref := w.GetWriteConnectionSecretToReference()
+	if ref == nil {
+		return "", nil
+	}
+	s := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+		return "", errors.Wrap(resource.IgnoreNotFound(err), errGetSecret)
+	}
+	return s.GetResourceVersion(), nil
+}
+
+// DefaultFetchCacheMaxSize is the default number of composed resources whose
+// connection details a CachingConnectionDetailsFetcher caches.
+const DefaultFetchCacheMaxSize = 1024
+
+// A CachingConnectionDetailsFetcherOption configures a
+// CachingConnectionDetailsFetcher.
+type CachingConnectionDetailsFetcherOption func(*CachingConnectionDetailsFetcher)
+
+// WithFetchCacheMaxSize bounds the number of composed resources whose
+// connection details a CachingConnectionDetailsFetcher caches. The least
+// recently used entry is evicted to make room for a new one.
+func WithFetchCacheMaxSize(n int) CachingConnectionDetailsFetcherOption {
+	return func(f *CachingConnectionDetailsFetcher) {
+		f.max = n
+	}
+}
+
+type fetchedConnectionDetails struct {
+	resourceVersion string
+	conn            managed.ConnectionDetails
+	used            uint64
+}
+
+// A CachingConnectionDetailsFetcher memoizes the connection details fetched
+// for each composed resource, keyed by its namespaced name. An entry is
+// served only while the composed resource's resource version matches the
+// version it was fetched at. Errors are not cached. It is safe for concurrent
+// use, but concurrent fetches of an uncached composed resource may each call
+// the underlying fetcher.
+//
+// It can't detect a connection secret that changed without its composed
+// resource changing. It's intended to be used for a single reconcile, or to be
+// Reset between reconciles.
+type CachingConnectionDetailsFetcher struct {
+	fetcher managed.ConnectionDetailsFetcher
+	max     int
+
+	mu      sync.Mutex
+	entries map[types.NamespacedName]*fetchedConnectionDetails
+	tick    uint64
+}
+
+// NewCachingConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// caches the connection details fetched by the supplied fetcher.
+func NewCachingConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, o ...CachingConnectionDetailsFetcherOption) *CachingConnectionDetailsFetcher {
+	cf := &CachingConnectionDetailsFetcher{
+		fetcher: f,
+		max:     DefaultFetchCacheMaxSize,
+		entries: make(map[types.NamespacedName]*fetchedConnectionDetails),
+	}
+	for _, fn := range o {
+		fn(cf)
+	}
+	return cf
+}
+
+// FetchConnection details of the supplied composed resource, from the cache if
+// they were fetched at its current resource version.
+func (f *CachingConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	nn := types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}
+	rv := o.GetResourceVersion()
+
+	f.mu.Lock()
+	if e, ok := f.entries[nn]; ok && e.resourceVersion == rv {
+		f.tick++
+		e.used = f.tick
+		conn := copyConnectionDetails(e.conn)
+		f.mu.Unlock()
+		return conn, nil
+	}
+	f.mu.Unlock()
+
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.entries[nn]; !ok && len(f.entries) >= f.max {
+		f.evictLeastRecentlyUsed()
+	}
+	f.tick++
+	f.entries[nn] = &fetchedConnectionDetails{resourceVersion: rv, conn: copyConnectionDetails(conn), used: f.tick}
+	return conn, nil
+}
+
+// Reset the cache, for example at the start of a reconcile.
+func (f *CachingConnectionDetailsFetcher) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = make(map[types.NamespacedName]*fetchedConnectionDetails)
+}
+
+// Len returns the number of cached composed resources.
+func (f *CachingConnectionDetailsFetcher) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.entries)
+}
+
+// evictLeastRecentlyUsed must be called with the lock held.
+func (f *CachingConnectionDetailsFetcher) evictLeastRecentlyUsed() {
+	var oldest types.NamespacedName
+	var used uint64
+	found := false
+	for nn, e := range f.entries {
+		if !found || e.used < used {
+			oldest, used, found = nn, e.used, true
+		}
+	}
+	if found {
+		delete(f.entries, oldest)
+	}
+}
+
+func copyConnectionDetails(c managed.ConnectionDetails) managed.ConnectionDetails {
+	if c == nil {
+		return nil
+	}
+	out := make(managed.ConnectionDetails, len(c))
+	for k, v := range c {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+func TestCompositeConnectionDetailsCache(t *testing.T) {
+	uid := types.UID("cool-xr")
+
+	type state struct {
+		xr   *fake.Composite
+		comp *v1.Composition
+		cds  []ConnectionDetailsCacheSource
+	}
+	initial := func() state {
+		return state{
+			xr: &fake.Composite{
+				ObjectMeta:                    metav1.ObjectMeta{UID: uid, Generation: 1},
+				CompositionRevisionReferencer: fake.CompositionRevisionReferencer{Ref: &corev1.ObjectReference{Name: "cool-rev-1"}},
+			},
+			comp: &v1.Composition{ObjectMeta: metav1.ObjectMeta{Name: "cool", ResourceVersion: "1"}},
+			cds: []ConnectionDetailsCacheSource{
+				{Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "a", ResourceVersion: "1"}}, SecretResourceVersion: "1"},
+				{Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "b", ResourceVersion: "1"}}},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		change func(s *state)
+		hit    bool
+	}{
+		"Unchanged": {
+			reason: "We should serve cached connection details if nothing changed.",
+			change: func(_ *state) {},
+			hit:    true,
+		},
+		"CompositeGenerationChanged": {
+			reason: "A change to the XR's spec should invalidate the cache.",
+			change: func(s *state) { s.xr.SetGeneration(2) },
+		},
+		"CompositionRevisionChanged": {
+			reason: "A change to the XR's composition revision should invalidate the cache.",
+			change: func(s *state) {
+				s.xr.SetCompositionRevisionReference(&corev1.ObjectReference{Name: "cool-rev-2"})
+			},
+		},
+		"CompositionChanged": {
+			reason: "A change to the XR's composition should invalidate the cache.",
+			change: func(s *state) { s.comp.SetResourceVersion("2") },
+		},
+		"ComposedResourceChanged": {
+			reason: "A change to any composed resource should invalidate the cache.",
+			change: func(s *state) { s.cds[1].Resource.SetResourceVersion("2") },
+		},
+		"ConnectionSecretRotated": {
+			reason: "A change to any connection secret should invalidate the cache, even if the composed resource that writes it didn't change.",
+			change: func(s *state) { s.cds[0].SecretResourceVersion = "2" },
+		},
+		"ConnectionSecretWritten": {
+			reason: "A composed resource writing its connection secret for the first time should invalidate the cache.",
+			change: func(s *state) { s.cds[1].SecretResourceVersion = "1" },
+		},
+		"ComposedResourceAdded": {
+			reason: "A new composed resource should invalidate the cache.",
+			change: func(s *state) {
+				s.cds = append(s.cds, ConnectionDetailsCacheSource{Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "c", ResourceVersion: "1"}}})
+			},
+		},
+		"ComposedResourcesReordered": {
+			reason: "Reordering composed resources should invalidate the cache, because order determines precedence.",
+			change: func(s *state) { s.cds[0], s.cds[1] = s.cds[1], s.cds[0] },
+		},
+		"ComposedResourceNotRendered": {
+			reason: "A composed resource that could no longer be rendered should invalidate the cache.",
+			change: func(s *state) { s.cds[1] = ConnectionDetailsCacheSource{} },
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewCompositeConnectionDetailsCache()
+			want := CompositeConnectionDetails{
+				ConnectionDetails: managed.ConnectionDetails{"password": []byte("secret")},
+				Sources:           map[string]string{"password": "a"},
+			}
+
+			s := initial()
+			c.Set(uid, CompositeConnectionDetailsCacheKey(s.xr, s.comp, s.cds...), want)
+
+			s = initial()
+			tc.change(&s)
+			got, hit := c.Get(uid, CompositeConnectionDetailsCacheKey(s.xr, s.comp, s.cds...))
+			if hit != tc.hit {
+				t.Fatalf("\n%s\nc.Get(...): want hit %t, got %t", tc.reason, tc.hit, hit)
+			}
+			if !hit {
+				return
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nc.Get(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+
+	t.Run("Forget", func(t *testing.T) {
+		c := NewCompositeConnectionDetailsCache()
+		s := initial()
+		key := CompositeConnectionDetailsCacheKey(s.xr, s.comp, s.cds...)
+		c.Set(uid, key, CompositeConnectionDetails{})
+		c.Forget(uid)
+		if _, hit := c.Get(uid, key); hit {
+			t.Errorf("c.Get(...): want miss after c.Forget(...)")
+		}
+	})
+
+	t.Run("OtherComposite", func(t *testing.T) {
+		c := NewCompositeConnectionDetailsCache()
+		s := initial()
+		key := CompositeConnectionDetailsCacheKey(s.xr, s.comp, s.cds...)
+		c.Set(uid, key, CompositeConnectionDetails{})
+		if _, hit := c.Get(types.UID("other-xr"), key); hit {
+			t.Errorf("c.Get(...): want miss for a different composite")
+		}
+	})
+}
+
+func TestConnectionSecretResourceVersion(t *testing.T) {
+	errBoom := errors.New("boom")
+	writer := &fake.Composed{
+		ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: &xpv1.SecretReference{Namespace: "default", Name: "cool"}},
+	}
+
+	type args struct {
+		c  client.Reader
+		cd resource.Object
+	}
+	type want struct {
+		rv  string
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotAWriter": {
+			reason: "A resource that can't write a connection secret has no connection secret version.",
+			args: args{
+				cd: &fake.Object{},
+			},
+		},
+		"NoSecretReference": {
+			reason: "A composed resource that doesn't write a connection secret has no connection secret version.",
+			args: args{
+				cd: &fake.Composed{},
+			},
+		},
+		"SecretNotFound": {
+			reason: "A connection secret that hasn't been written yet has no version.",
+			args: args{
+				c:  &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "cool"))},
+				cd: writer,
+			},
+		},
+		"GetError": {
+			reason: "We should return any other error encountered getting the connection secret.",
+			args: args{
+				c:  &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				cd: writer,
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetSecret),
+			},
+		},
+		"Success": {
+			reason: "We should return the connection secret's resource version.",
+			args: args{
+				c: &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					if key.Namespace != "default" || key.Name != "cool" {
+						return errBoom
+					}
+					obj.SetResourceVersion("42")
+					return nil
+				}},
+				cd: writer,
+			},
+			want: want{
+				rv: "42",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rv, err := ConnectionSecretResourceVersion(context.Background(), tc.args.c, tc.args.cd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConnectionSecretResourceVersion(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.rv, rv); diff != "" {
+				t.Errorf("\n%s\nConnectionSecretResourceVersion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// A countingFetcher returns the resource version of each composed resource as
+// its connection details, and counts how often it is called.
+type countingFetcher struct {
+	calls int64
+	err   error
+}
+
+func (f *countingFetcher) FetchConnection(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	atomic.AddInt64(&f.calls, 1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return managed.ConnectionDetails{"version": []byte(o.GetResourceVersion())}, nil
+}
+
+func composedAt(name, rv string) *fake.Composed {
+	return &fake.Composed{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, ResourceVersion: rv}}
+}
+
+func TestCachingConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		conn  managed.ConnectionDetails
+		calls int64
+		len   int
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		o      []CachingConnectionDetailsFetcherOption
+		err    error
+		fetch  []*fake.Composed
+		reset  bool
+		want   want
+	}{
+		"Cached": {
+			reason: "We should only call the underlying fetcher once for a composed resource that hasn't changed.",
+			fetch:  []*fake.Composed{composedAt("a", "1"), composedAt("a", "1"), composedAt("a", "1")},
+			want: want{
+				conn:  managed.ConnectionDetails{"version": []byte("1")},
+				calls: 1,
+				len:   1,
+			},
+		},
+		"ResourceVersionChanged": {
+			reason: "We should fetch the connection details of a composed resource again when its resource version changes.",
+			fetch:  []*fake.Composed{composedAt("a", "1"), composedAt("a", "2")},
+			want: want{
+				conn:  managed.ConnectionDetails{"version": []byte("2")},
+				calls: 2,
+				len:   1,
+			},
+		},
+		"ErrorsNotCached": {
+			reason: "We should not cache errors.",
+			err:    errBoom,
+			fetch:  []*fake.Composed{composedAt("a", "1"), composedAt("a", "1")},
+			want: want{
+				calls: 2,
+				err:   errBoom,
+			},
+		},
+		"Bounded": {
+			reason: "We should evict the least recently used composed resource when the cache is full.",
+			o:      []CachingConnectionDetailsFetcherOption{WithFetchCacheMaxSize(2)},
+			fetch:  []*fake.Composed{composedAt("a", "1"), composedAt("b", "1"), composedAt("a", "1"), composedAt("c", "1"), composedAt("a", "1")},
+			want: want{
+				conn:  managed.ConnectionDetails{"version": []byte("1")},
+				calls: 3,
+				len:   2,
+			},
+		},
+		"Reset": {
+			reason: "We should fetch the connection details of every composed resource again after the cache is reset.",
+			fetch:  []*fake.Composed{composedAt("a", "1"), composedAt("b", "1")},
+			reset:  true,
+			want: want{
+				conn:  managed.ConnectionDetails{"version": []byte("1")},
+				calls: 4,
+				len:   2,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cf := &countingFetcher{err: tc.err}
+			f := NewCachingConnectionDetailsFetcher(cf, tc.o...)
+
+			passes := 1
+			if tc.reset {
+				passes = 2
+			}
+			var conn managed.ConnectionDetails
+			var err error
+			for i := 0; i < passes; i++ {
+				f.Reset()
+				for _, cd := range tc.fetch {
+					conn, err = f.FetchConnection(context.Background(), cd)
+				}
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.calls, atomic.LoadInt64(&cf.calls)); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.len, f.Len()); diff != "" {
+				t.Errorf("\n%s\nLen(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCachingConnectionDetailsFetcherConcurrent(t *testing.T) {
+	cf := &countingFetcher{}
+	f := NewCachingConnectionDetailsFetcher(cf, WithFetchCacheMaxSize(8))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cd := composedAt(fmt.Sprintf("cd-%d", i%10), "1")
+			for j := 0; j < 10; j++ {
+				conn, err := f.FetchConnection(context.Background(), cd)
+				if err != nil {
+					t.Errorf("FetchConnection(...): %s", err)
+				}
+				if diff := cmp.Diff(managed.ConnectionDetails{"version": []byte("1")}, conn); diff != "" {
+					t.Errorf("FetchConnection(...): -want, +got:\n%s", diff)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if f.Len() > 8 {
+		t.Errorf("Len(): want at most 8 cached composed resources, got %d", f.Len())
+	}
+}
+
+// BenchmarkCachingConnectionDetailsFetcher reports how many times the
+// underlying fetcher is called when a composite resource with 50 composed
+// resources is reconciled, with each composed resource's connection details
+// being fetched 3 times per reconcile.
+func BenchmarkCachingConnectionDetailsFetcher(b *testing.B) {
+	cds := make([]*fake.Composed, 50)
+	for i := range cds {
+		cds[i] = composedAt(fmt.Sprintf("cd-%d", i), "1")
+	}
+
+	fetchers := map[string]func(managed.ConnectionDetailsFetcher) managed.ConnectionDetailsFetcher{
+		"Uncached": func(f managed.ConnectionDetailsFetcher) managed.ConnectionDetailsFetcher { return f },
+		"Cached": func(f managed.ConnectionDetailsFetcher) managed.ConnectionDetailsFetcher {
+			return NewCachingConnectionDetailsFetcher(f)
+		},
+	}
+
+	for name, wrap := range fetchers {
+		b.Run(name, func(b *testing.B) {
+			cf := &countingFetcher{}
+			for i := 0; i < b.N; i++ {
+				f := wrap(cf)
+				for j := 0; j < 3; j++ {
+					for _, cd := range cds {
+						_, _ = f.FetchConnection(context.Background(), cd)
+					}
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&cf.calls))/float64(b.N), "backend-calls/op")
+		})
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtCatalogLookup = "cannot look up service catalog entry %q"
+)
+
+// AnnotationKeyServiceCatalogEntry is the annotation a ServiceCatalogEnricher
+// reads from a composite resource by default to determine which service
+// catalog entry to enrich its connection details with.
+const AnnotationKeyServiceCatalogEntry = "crossplane.io/service-catalog-entry"
+
+// A CatalogEnricher enriches the connection details assembled for a composite
+// resource before they're published.
+type CatalogEnricher interface {
+	EnrichConnectionDetails(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error)
+}
+
+// A CatalogEnricherFn is a function that satisfies the CatalogEnricher
+// interface.
+type CatalogEnricherFn func(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error)
+
+// EnrichConnectionDetails enriches the supplied connection details.
+func (fn CatalogEnricherFn) EnrichConnectionDetails(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	return fn(ctx, xr, conn)
+}
+
+// A ServiceCatalog knows the canonical connection details of shared services.
+type ServiceCatalog interface {
+	// Lookup returns the connection details of the supplied catalog entry.
+	// Errors that are expected to be transient should satisfy IsRetryable.
+	Lookup(ctx context.Context, entry string) (managed.ConnectionDetails, error)
+}
+
+// A ServiceCatalogLookupFn is a function that satisfies the ServiceCatalog
+// interface.
+type ServiceCatalogLookupFn func(ctx context.Context, entry string) (managed.ConnectionDetails, error)
+
+// Lookup returns the connection details of the supplied catalog entry.
+func (fn ServiceCatalogLookupFn) Lookup(ctx context.Context, entry string) (managed.ConnectionDetails, error) {
+	return fn(ctx, entry)
+}
+
+// A ServiceCatalogEnricherOption configures a ServiceCatalogEnricher.
+type ServiceCatalogEnricherOption func(*ServiceCatalogEnricher)
+
+// WithServiceCatalogEntryAnnotation configures the composite resource
+// annotation a ServiceCatalogEnricher reads the catalog entry from.
+func WithServiceCatalogEntryAnnotation(a string) ServiceCatalogEnricherOption {
+	return func(e *ServiceCatalogEnricher) {
+		e.annotation = a
+	}
+}
+
+// WithServiceCatalogOverrides configures the connection detail keys that
+// catalog values may override. Catalog values never override connection
+// details assembled from composed resources unless their keys are explicitly
+// allowed.
+func WithServiceCatalogOverrides(keys ...string) ServiceCatalogEnricherOption {
+	return func(e *ServiceCatalogEnricher) {
+		e.overrides = make(map[string]bool, len(keys))
+		for _, k := range keys {
+			e.overrides[k] = true
+		}
+	}
+}
+
+// WithServiceCatalogLogger configures the logger a ServiceCatalogEnricher uses
+// to record overridden and ignored connection detail keys.
+func WithServiceCatalogLogger(l logging.Logger) ServiceCatalogEnricherOption {
+	return func(e *ServiceCatalogEnricher) {
+		e.log = l
+	}
+}
+
+// A ServiceCatalogEnricher enriches a composite resource's connection details
+// with values from a service catalog. The catalog entry is read from an
+// annotation of the composite resource. Composite resources without the
+// annotation are not enriched.
+type ServiceCatalogEnricher struct {
+	catalog    ServiceCatalog
+	annotation string
+	overrides  map[string]bool
+	log        logging.Logger
+}
+
+// NewServiceCatalogEnricher returns a CatalogEnricher that enriches connection
+// details using the supplied service catalog.
+func NewServiceCatalogEnricher(c ServiceCatalog, o ...ServiceCatalogEnricherOption) *ServiceCatalogEnricher {
+	e := &ServiceCatalogEnricher{
+		catalog:    c,
+		annotation: AnnotationKeyServiceCatalogEntry,
+		log:        logging.NewNopLogger(),
+	}
+	for _, fn := range o {
+		fn(e)
+	}
+	return e
+}
+
+// EnrichConnectionDetails returns the supplied connection details, with any
+// keys from the composite resource's service catalog entry added. Keys that
+// already exist are only overridden if they're explicitly allowed to be. Lookup
+// errors are retryable if the underlying catalog error is.
+func (e *ServiceCatalogEnricher) EnrichConnectionDetails(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	entry := xr.GetAnnotations()[e.annotation]
+	if entry == "" {
+		return conn, nil
+	}
+
+	cat, err := e.catalog.Lookup(ctx, entry)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtCatalogLookup, entry)
+	}
+
+	out := make(managed.ConnectionDetails, len(conn)+len(cat))
+	for k, v := range conn {
+		out[k] = v
+	}
+	for k, v := range cat {
+		if _, exists := out[k]; exists {
+			if !e.overrides[k] {
+				e.log.Debug("Ignoring service catalog value for existing connection detail key", "entry", entry, "key", k)
+				continue
+			}
+			e.log.Debug("Overriding connection detail key with service catalog value", "entry", entry, "key", k)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ CatalogEnricher = &ServiceCatalogEnricher{}
+
+func TestServiceCatalogEnricher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	xr := &fake.Composite{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyServiceCatalogEntry: "postgres"}}}
+	catalog := func(c managed.ConnectionDetails, err error) ServiceCatalog {
+		return ServiceCatalogLookupFn(func(_ context.Context, entry string) (managed.ConnectionDetails, error) {
+			if entry != "postgres" {
+				t.Errorf("Lookup(...): wrong entry %q", entry)
+			}
+			return c, err
+		})
+	}
+
+	type args struct {
+		c    ServiceCatalog
+		xr   resource.Composite
+		conn managed.ConnectionDetails
+		opts []ServiceCatalogEnricherOption
+	}
+	type want struct {
+		conn      managed.ConnectionDetails
+		err       error
+		retryable bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoEntry": {
+			reason: "We should not enrich connection details of a composite resource without a catalog entry.",
+			args: args{
+				xr:   &fake.Composite{},
+				conn: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"Added": {
+			reason: "We should add catalog keys, but not override existing keys that aren't explicitly allowed.",
+			args: args{
+				c:    catalog(managed.ConnectionDetails{"endpoint": []byte("db.example.org"), "port": []byte("5432")}, nil),
+				xr:   xr,
+				conn: managed.ConnectionDetails{"port": []byte("5433")},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"endpoint": []byte("db.example.org"), "port": []byte("5433")},
+			},
+		},
+		"Overridden": {
+			reason: "We should override existing keys that are explicitly allowed.",
+			args: args{
+				c:    catalog(managed.ConnectionDetails{"port": []byte("5432")}, nil),
+				xr:   xr,
+				conn: managed.ConnectionDetails{"port": []byte("5433")},
+				opts: []ServiceCatalogEnricherOption{WithServiceCatalogOverrides("port")},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"port": []byte("5432")},
+			},
+		},
+		"LookupError": {
+			reason: "We should return a non-retryable error if the catalog lookup fails permanently.",
+			args: args{
+				c:  catalog(nil, errBoom),
+				xr: xr,
+			},
+			want: want{
+				err: errors.Wrapf(errBoom, errFmtCatalogLookup, "postgres"),
+			},
+		},
+		"RetryableLookupError": {
+			reason: "We should return a retryable error if the catalog lookup fails transiently.",
+			args: args{
+				c:  catalog(nil, Retryable(errBoom)),
+				xr: xr,
+			},
+			want: want{
+				err:       errors.Wrapf(Retryable(errBoom), errFmtCatalogLookup, "postgres"),
+				retryable: true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := NewServiceCatalogEnricher(tc.args.c, tc.args.opts...)
+			got, err := e.EnrichConnectionDetails(context.Background(), tc.args.xr, tc.args.conn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.EnrichConnectionDetails(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.retryable, IsRetryable(err)); diff != "" {
+				t.Errorf("\n%s\nIsRetryable(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\ne.EnrichConnectionDetails(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
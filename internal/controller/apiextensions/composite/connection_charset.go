@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtDisallowedCharacter = "connection detail %q contains a disallowed character at byte %d"
+	errFmtCharacterSetPolicy  = "connection detail %q has unknown character set policy %q"
+)
+
+// A CharacterSetPolicy determines what happens to connection detail values
+// that contain disallowed characters.
+type CharacterSetPolicy string
+
+// Character set policies.
+const (
+	// CharacterSetPolicyReject returns an error if a value contains a
+	// disallowed character.
+	CharacterSetPolicyReject CharacterSetPolicy = "Reject"
+
+	// CharacterSetPolicySanitize removes disallowed characters from a value.
+	CharacterSetPolicySanitize CharacterSetPolicy = "Sanitize"
+)
+
+// A CharacterSetRule limits the characters a connection detail value may
+// contain.
+type CharacterSetRule struct {
+	// DisallowControl disallows ASCII control characters, including
+	// newlines and NUL bytes.
+	DisallowControl bool
+
+	// Disallow any of these bytes.
+	Disallow string
+
+	// Policy determines what happens to values that contain disallowed
+	// characters. Values are rejected by default.
+	Policy CharacterSetPolicy
+}
+
+func (r CharacterSetRule) disallowed(b byte) bool {
+	if r.DisallowControl && (b < 0x20 || b == 0x7f) {
+		return true
+	}
+	return strings.IndexByte(r.Disallow, b) >= 0
+}
+
+// A CharacterSetConnectionDetailsFetcherOption configures a
+// CharacterSetConnectionDetailsFetcher.
+type CharacterSetConnectionDetailsFetcherOption func(*CharacterSetConnectionDetailsFetcher)
+
+// WithCharacterSetRules configures the character set rule applied to each
+// connection detail key.
+func WithCharacterSetRules(r map[string]CharacterSetRule) CharacterSetConnectionDetailsFetcherOption {
+	return func(f *CharacterSetConnectionDetailsFetcher) {
+		f.rules = r
+	}
+}
+
+// WithDefaultCharacterSetRule configures the character set rule applied to
+// connection detail keys that don't have an explicit rule.
+func WithDefaultCharacterSetRule(r CharacterSetRule) CharacterSetConnectionDetailsFetcherOption {
+	return func(f *CharacterSetConnectionDetailsFetcher) {
+		f.fallback = &r
+	}
+}
+
+// WithBinaryKeys declares connection detail keys that hold binary values.
+// Binary values are exempt from character set rules.
+func WithBinaryKeys(keys ...string) CharacterSetConnectionDetailsFetcherOption {
+	return func(f *CharacterSetConnectionDetailsFetcher) {
+		f.binary = make(map[string]bool, len(keys))
+		for _, k := range keys {
+			f.binary[k] = true
+		}
+	}
+}
+
+// A CharacterSetConnectionDetailsFetcher limits the characters the connection
+// details fetched by another fetcher may contain. This catches values that
+// would break consumers, for example environment variables that can't
+// contain control characters, before they're published.
+type CharacterSetConnectionDetailsFetcher struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	rules    map[string]CharacterSetRule
+	fallback *CharacterSetRule
+	binary   map[string]bool
+}
+
+// NewCharacterSetConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that applies character set rules to the connection details fetched by the
+// supplied fetcher.
+func NewCharacterSetConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, o ...CharacterSetConnectionDetailsFetcherOption) *CharacterSetConnectionDetailsFetcher {
+	c := &CharacterSetConnectionDetailsFetcher{fetcher: f}
+	for _, fn := range o {
+		fn(c)
+	}
+	return c
+}
+
+// FetchConnection details of the supplied composed resource, applying
+// character set rules to them. Errors identify the offending key and byte
+// position, never the value.
+func (f *CharacterSetConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || len(conn) == 0 {
+		return conn, err
+	}
+
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		r, ok := f.rule(k)
+		if !ok {
+			out[k] = v
+			continue
+		}
+		nv, err := r.apply(k, v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = nv
+	}
+	return out, nil
+}
+
+func (f *CharacterSetConnectionDetailsFetcher) rule(key string) (CharacterSetRule, bool) {
+	if f.binary[key] {
+		return CharacterSetRule{}, false
+	}
+	if r, ok := f.rules[key]; ok {
+		return r, true
+	}
+	if f.fallback != nil {
+		return *f.fallback, true
+	}
+	return CharacterSetRule{}, false
+}
+
+func (r CharacterSetRule) apply(key string, v []byte) ([]byte, error) {
+	switch r.Policy {
+	case "", CharacterSetPolicyReject:
+		for i, b := range v {
+			if r.disallowed(b) {
+				return nil, errors.Errorf(errFmtDisallowedCharacter, key, i)
+			}
+		}
+		return v, nil
+	case CharacterSetPolicySanitize:
+		out := make([]byte, 0, len(v))
+		for _, b := range v {
+			if !r.disallowed(b) {
+				out = append(out, b)
+			}
+		}
+		return out, nil
+	default:
+		return nil, errors.Errorf(errFmtCharacterSetPolicy, key, r.Policy)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &CharacterSetConnectionDetailsFetcher{}
+
+func TestCharacterSetConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	noControl := CharacterSetRule{DisallowControl: true}
+
+	fetch := func(c managed.ConnectionDetails, err error) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return c, err
+		})
+	}
+
+	type args struct {
+		f    managed.ConnectionDetailsFetcher
+		opts []CharacterSetConnectionDetailsFetcherOption
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FetchError": {
+			reason: "We should return errors encountered fetching connection details.",
+			args: args{
+				f:    fetch(nil, errBoom),
+				opts: []CharacterSetConnectionDetailsFetcherOption{WithDefaultCharacterSetRule(noControl)},
+			},
+			want: want{err: errBoom},
+		},
+		"NoRules": {
+			reason: "We should not validate connection details if no rules are configured.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"password": []byte("a\nb")}, nil),
+			},
+			want: want{conn: managed.ConnectionDetails{"password": []byte("a\nb")}},
+		},
+		"Rejected": {
+			reason: "We should return an error identifying the key and byte position of a disallowed character.",
+			args: args{
+				f:    fetch(managed.ConnectionDetails{"password": []byte("ab\x00c")}, nil),
+				opts: []CharacterSetConnectionDetailsFetcherOption{WithCharacterSetRules(map[string]CharacterSetRule{"password": noControl})},
+			},
+			want: want{err: errors.Errorf(errFmtDisallowedCharacter, "password", 2)},
+		},
+		"Sanitized": {
+			reason: "We should remove disallowed characters if the rule's policy is to sanitize.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"password": []byte("a\r\nb$c")}, nil),
+				opts: []CharacterSetConnectionDetailsFetcherOption{WithCharacterSetRules(map[string]CharacterSetRule{
+					"password": {DisallowControl: true, Disallow: "$", Policy: CharacterSetPolicySanitize},
+				})},
+			},
+			want: want{conn: managed.ConnectionDetails{"password": []byte("abc")}},
+		},
+		"BinaryExempt": {
+			reason: "We should not apply character set rules to binary keys.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"keystore": []byte("\x00\x01"), "password": []byte("ok")}, nil),
+				opts: []CharacterSetConnectionDetailsFetcherOption{
+					WithDefaultCharacterSetRule(noControl),
+					WithBinaryKeys("keystore"),
+				},
+			},
+			want: want{conn: managed.ConnectionDetails{"keystore": []byte("\x00\x01"), "password": []byte("ok")}},
+		},
+		"UnknownPolicy": {
+			reason: "We should return an error if a rule has an unknown policy.",
+			args: args{
+				f:    fetch(managed.ConnectionDetails{"password": []byte("ok")}, nil),
+				opts: []CharacterSetConnectionDetailsFetcherOption{WithDefaultCharacterSetRule(CharacterSetRule{Policy: "Escape"})},
+			},
+			want: want{err: errors.Errorf(errFmtCharacterSetPolicy, "password", "Escape")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewCharacterSetConnectionDetailsFetcher(tc.args.f, tc.args.opts...)
+			got, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil && strings.Contains(err.Error(), "\x00") {
+				t.Errorf("\n%s\nf.FetchConnection(...): error must not contain the value: %q", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
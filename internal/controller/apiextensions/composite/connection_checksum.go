@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// DefaultChecksumKey is the default connection detail key a
+// ChecksumConnectionPublisher publishes its checksum to.
+const DefaultChecksumKey = "__checksum__"
+
+// A ChecksumConnectionPublisherOption configures a
+// ChecksumConnectionPublisher.
+type ChecksumConnectionPublisherOption func(*ChecksumConnectionPublisher)
+
+// WithChecksumKey configures the connection detail key a
+// ChecksumConnectionPublisher publishes its checksum to.
+func WithChecksumKey(k string) ChecksumConnectionPublisherOption {
+	return func(p *ChecksumConnectionPublisher) {
+		p.key = k
+	}
+}
+
+// A ChecksumConnectionPublisher adds a checksum of all other connection
+// details to the connection details it publishes. The checksum changes
+// whenever any connection detail changes, so consumers can watch a single
+// value (for example by copying it to a Deployment's pod template
+// annotations) to detect changes.
+type ChecksumConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	key       string
+}
+
+// NewChecksumConnectionPublisher returns a ConnectionPublisher that adds a
+// checksum to the connection details it publishes using the supplied
+// publisher. If the supplied publisher filters connection details the
+// checksum key must be allowed by its filter.
+func NewChecksumConnectionPublisher(p managed.ConnectionPublisher, o ...ChecksumConnectionPublisherOption) *ChecksumConnectionPublisher {
+	cp := &ChecksumConnectionPublisher{publisher: p, key: DefaultChecksumKey}
+	for _, fn := range o {
+		fn(cp)
+	}
+	return cp
+}
+
+// PublishConnection details for the supplied resource, along with their
+// checksum. Any existing value of the checksum key is excluded from, and
+// replaced by, the checksum.
+func (p *ChecksumConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	out := make(managed.ConnectionDetails, len(c)+1)
+	for k, v := range c {
+		if k == p.key {
+			continue
+		}
+		out[k] = v
+	}
+	out[p.key] = []byte(connectionDetailsDigest(out))
+	return p.publisher.PublishConnection(ctx, o, out)
+}
+
+// UnpublishConnection details for the supplied resource.
+func (p *ChecksumConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return p.publisher.UnpublishConnection(ctx, o, c)
+}
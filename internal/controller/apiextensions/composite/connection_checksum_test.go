@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+var _ managed.ConnectionPublisher = &ChecksumConnectionPublisher{}
+
+func TestChecksumConnectionPublisher(t *testing.T) {
+	publish := func(t *testing.T, c managed.ConnectionDetails, o ...ChecksumConnectionPublisherOption) managed.ConnectionDetails {
+		t.Helper()
+		var published managed.ConnectionDetails
+		p := NewChecksumConnectionPublisher(managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+				published = c
+				return true, nil
+			},
+		}, o...)
+		if _, err := p.PublishConnection(context.Background(), &fake.Composite{}, c); err != nil {
+			t.Fatalf("p.PublishConnection(...): %s", err)
+		}
+		return published
+	}
+
+	base := publish(t, managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")})
+
+	cases := map[string]struct {
+		reason  string
+		c       managed.ConnectionDetails
+		o       []ChecksumConnectionPublisherOption
+		key     string
+		changed bool
+	}{
+		"Unchanged": {
+			reason: "The checksum should be stable if no connection detail changes.",
+			c:      managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			key:    DefaultChecksumKey,
+		},
+		"ExistingChecksumExcluded": {
+			reason: "An existing checksum key should be excluded from the checksum.",
+			c:      managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b"), DefaultChecksumKey: []byte("stale")},
+			key:    DefaultChecksumKey,
+		},
+		"ValueChanged": {
+			reason:  "The checksum should change if a connection detail value changes.",
+			c:       managed.ConnectionDetails{"a": []byte("a"), "b": []byte("c")},
+			key:     DefaultChecksumKey,
+			changed: true,
+		},
+		"KeyAdded": {
+			reason:  "The checksum should change if a connection detail is added.",
+			c:       managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b"), "c": []byte("c")},
+			key:     DefaultChecksumKey,
+			changed: true,
+		},
+		"CustomKey": {
+			reason: "The checksum should be published to the configured key.",
+			c:      managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			o:      []ChecksumConnectionPublisherOption{WithChecksumKey("checksum")},
+			key:    "checksum",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := publish(t, tc.c, tc.o...)
+			sum, ok := got[tc.key]
+			if !ok {
+				t.Fatalf("\n%s\np.PublishConnection(...): checksum key %q was not published", tc.reason, tc.key)
+			}
+			if diff := cmp.Diff(tc.changed, string(sum) != string(base[DefaultChecksumKey])); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			for k, v := range tc.c {
+				if k == tc.key {
+					continue
+				}
+				if diff := cmp.Diff(v, got[k]); diff != "" {
+					t.Errorf("\n%s\np.PublishConnection(...): -want %q, +got %q:\n%s", tc.reason, k, k, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A ConditionRequirement requires a composed resource to have a condition of
+// the supplied type with the supplied status.
+type ConditionRequirement struct {
+	Type   xpv1.ConditionType
+	Status corev1.ConditionStatus
+}
+
+// DefaultConditionRequirements require a composed resource to be both synced
+// and ready.
+func DefaultConditionRequirements() []ConditionRequirement {
+	return []ConditionRequirement{
+		{Type: xpv1.TypeSynced, Status: corev1.ConditionTrue},
+		{Type: xpv1.TypeReady, Status: corev1.ConditionTrue},
+	}
+}
+
+// A ConditionGatedConnectionDetailsFetcherOption configures a
+// ConditionGatedConnectionDetailsFetcher.
+type ConditionGatedConnectionDetailsFetcherOption func(*ConditionGatedConnectionDetailsFetcher)
+
+// WithConditionRequirements configures the conditions a composed resource must
+// meet before its connection details are fetched. All requirements must be
+// met.
+func WithConditionRequirements(r ...ConditionRequirement) ConditionGatedConnectionDetailsFetcherOption {
+	return func(f *ConditionGatedConnectionDetailsFetcher) {
+		f.requirements = r
+	}
+}
+
+// WithConditionGateLogger configures the logger a
+// ConditionGatedConnectionDetailsFetcher uses to record skipped fetches.
+func WithConditionGateLogger(l logging.Logger) ConditionGatedConnectionDetailsFetcherOption {
+	return func(f *ConditionGatedConnectionDetailsFetcher) {
+		f.log = l
+	}
+}
+
+// A ConditionGatedConnectionDetailsFetcher only fetches the connection details
+// of composed resources that meet its condition requirements. Until they're
+// met the composed resource's connection details are treated as not yet
+// present, rather than as an error.
+type ConditionGatedConnectionDetailsFetcher struct {
+	fetcher      managed.ConnectionDetailsFetcher
+	requirements []ConditionRequirement
+	log          logging.Logger
+}
+
+// NewConditionGatedConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that only uses the supplied fetcher once a composed resource meets its
+// condition requirements. By default composed resources must be synced and
+// ready.
+func NewConditionGatedConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, o ...ConditionGatedConnectionDetailsFetcherOption) *ConditionGatedConnectionDetailsFetcher {
+	g := &ConditionGatedConnectionDetailsFetcher{
+		fetcher:      f,
+		requirements: DefaultConditionRequirements(),
+		log:          logging.NewNopLogger(),
+	}
+	for _, fn := range o {
+		fn(g)
+	}
+	return g
+}
+
+// FetchConnection details of the supplied composed resource, if it meets the
+// condition requirements.
+func (g *ConditionGatedConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if len(g.requirements) == 0 {
+		return g.fetcher.FetchConnection(ctx, o)
+	}
+
+	c, ok := o.(interface {
+		GetCondition(ct xpv1.ConditionType) xpv1.Condition
+	})
+	if !ok {
+		g.log.Debug("Skipping connection details fetch: composed resource has no conditions", "composed", o.GetName())
+		return nil, nil
+	}
+	for _, r := range g.requirements {
+		if got := c.GetCondition(r.Type).Status; got != r.Status {
+			g.log.Debug("Skipping connection details fetch: composed resource does not meet condition requirement", "composed", o.GetName(), "type", r.Type, "want", r.Status, "got", got)
+			return nil, nil
+		}
+	}
+	return g.fetcher.FetchConnection(ctx, o)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &ConditionGatedConnectionDetailsFetcher{}
+
+func TestConditionGatedConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	conn := managed.ConnectionDetails{"password": []byte("secret")}
+
+	fetch := func(c managed.ConnectionDetails, err error) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return c, err
+		})
+	}
+	composed := func(c ...xpv1.Condition) resource.ConnectionSecretOwner {
+		cd := &fake.Composed{}
+		cd.SetConditions(c...)
+		return cd
+	}
+
+	type args struct {
+		f    managed.ConnectionDetailsFetcher
+		o    resource.ConnectionSecretOwner
+		opts []ConditionGatedConnectionDetailsFetcherOption
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotReady": {
+			reason: "We should skip fetching if the composed resource is synced but not ready.",
+			args: args{
+				f: fetch(conn, nil),
+				o: composed(xpv1.ReconcileSuccess(), xpv1.Creating()),
+			},
+			want: want{},
+		},
+		"NoConditions": {
+			reason: "We should skip fetching if the composed resource has no conditions yet.",
+			args: args{
+				f: fetch(conn, nil),
+				o: composed(),
+			},
+			want: want{},
+		},
+		"SyncedAndReady": {
+			reason: "We should fetch if the composed resource is synced and ready.",
+			args: args{
+				f: fetch(conn, nil),
+				o: composed(xpv1.ReconcileSuccess(), xpv1.Available()),
+			},
+			want: want{conn: conn},
+		},
+		"FetchError": {
+			reason: "Errors fetching connection details should be returned once requirements are met.",
+			args: args{
+				f: fetch(nil, errBoom),
+				o: composed(xpv1.ReconcileSuccess(), xpv1.Available()),
+			},
+			want: want{err: errBoom},
+		},
+		"CustomRequirements": {
+			reason: "We should honor custom condition requirements.",
+			args: args{
+				f:    fetch(conn, nil),
+				o:    composed(xpv1.Creating()),
+				opts: []ConditionGatedConnectionDetailsFetcherOption{WithConditionRequirements(ConditionRequirement{Type: xpv1.TypeReady, Status: corev1.ConditionFalse})},
+			},
+			want: want{conn: conn},
+		},
+		"NoRequirements": {
+			reason: "We should always fetch if there are no condition requirements.",
+			args: args{
+				f:    fetch(conn, nil),
+				o:    composed(),
+				opts: []ConditionGatedConnectionDetailsFetcherOption{WithConditionRequirements()},
+			},
+			want: want{conn: conn},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewConditionGatedConnectionDetailsFetcher(tc.args.f, tc.args.opts...)
+			got, err := g.FetchConnection(context.Background(), tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ng.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\ng.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
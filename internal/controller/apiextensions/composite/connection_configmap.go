@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errApplyStatusConfigMap = "cannot apply connection status ConfigMap"
+	errMarshalSources       = "cannot marshal connection detail sources"
+)
+
+// Keys of a connection status ConfigMap.
+const (
+	ConnectionStatusKeyKeys            = "keys"
+	ConnectionStatusKeySources         = "sources"
+	ConnectionStatusKeyLastPublishTime = "lastPublishTime"
+)
+
+// ConnectionStatusConfigMapSuffix is appended to the name of a connection
+// secret to name its companion connection status ConfigMap.
+const ConnectionStatusConfigMapSuffix = "-connection-status"
+
+// An APISecretAndStatusConfigMapPublisher publishes connection details to a
+// Secret, like an APIFilteredSecretPublisher, and publishes metadata about
+// them to a companion ConfigMap. The ConfigMap contains the published keys,
+// the source of each key if known, and the time they were last published. It
+// never contains connection detail values. It aids debugging and policy
+// without exposing secrets.
+type APISecretAndStatusConfigMapPublisher struct {
+	secrets *APIFilteredSecretPublisher
+	client  resource.Applicator
+	filter  []string
+}
+
+// NewAPISecretAndStatusConfigMapPublisher returns a ConnectionPublisher that
+// publishes connection secret keys that are included in the supplied filter,
+// and a companion ConfigMap describing them.
+func NewAPISecretAndStatusConfigMapPublisher(c client.Client, filter []string) *APISecretAndStatusConfigMapPublisher {
+	return &APISecretAndStatusConfigMapPublisher{
+		secrets: NewAPIFilteredSecretPublisher(c, filter),
+		client:  resource.NewAPIPatchingApplicator(c),
+		filter:  filter,
+	}
+}
+
+// PublishConnection publishes the supplied ConnectionDetails to the Secret
+// referenced in the resource, then describes them in a companion ConfigMap.
+// The ConfigMap is only written once the Secret has been, so that it reflects
+// the Secret. Both are controlled by the resource, and thus garbage collected
+// with it.
+func (a *APISecretAndStatusConfigMapPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	ref := o.GetWriteConnectionSecretToReference()
+	if ref == nil {
+		return false, nil
+	}
+
+	published, err := a.secrets.PublishConnection(ctx, o, c)
+	if err != nil {
+		return false, err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ref.Name + ConnectionStatusConfigMapSuffix,
+			Namespace:       ref.Namespace,
+			OwnerReferences: []metav1.OwnerReference{meta.AsController(meta.TypedReferenceTo(o, o.GetObjectKind().GroupVersionKind()))},
+		},
+		Data: map[string]string{
+			ConnectionStatusKeyKeys:            strings.Join(OrderedKeys(filterConnectionDetails(c, a.filter), AlphabeticalKeyOrder()), ","),
+			ConnectionStatusKeyLastPublishTime: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	if s := publishedSources(o); len(s) > 0 {
+		b, err := json.Marshal(s)
+		if err != nil {
+			return false, errors.Wrap(err, errMarshalSources)
+		}
+		cm.Data[ConnectionStatusKeySources] = string(b)
+	}
+
+	err = a.client.Apply(ctx, cm,
+		resource.MustBeControllableBy(o.GetUID()),
+		resource.AllowUpdateIf(func(current, desired runtime.Object) bool {
+			// The ConfigMap should only be updated if the Secret was, or
+			// if it doesn't yet describe the Secret.
+			cd, dd := current.(*corev1.ConfigMap).Data, desired.(*corev1.ConfigMap).Data
+			return published || cd[ConnectionStatusKeyKeys] != dd[ConnectionStatusKeyKeys] || cd[ConnectionStatusKeySources] != dd[ConnectionStatusKeySources]
+		}),
+	)
+	if err != nil && !resource.IsNotAllowed(err) {
+		return false, errors.Wrap(err, errApplyStatusConfigMap)
+	}
+	return published, nil
+}
+
+// UnpublishConnection is a no-op since PublishConnection only creates
+// resources that will be garbage collected by Kubernetes when the resource is
+// deleted.
+func (a *APISecretAndStatusConfigMapPublisher) UnpublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	return nil
+}
+
+// publishedSources returns the source of each connection detail key, as
+// annotated by AnnotateConnectionDetailSources.
+func publishedSources(o resource.ConnectionSecretOwner) map[string]string {
+	p := o.GetPublishConnectionDetailsTo()
+	if p == nil || p.Metadata == nil {
+		return nil
+	}
+	s := map[string]string{}
+	for k, v := range p.Metadata.Annotations {
+		if key := strings.TrimPrefix(k, AnnotationKeyPrefixConnectionDetailSource); key != k {
+			s[key] = v
+		}
+	}
+	return s
+}
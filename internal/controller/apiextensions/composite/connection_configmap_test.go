@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &APISecretAndStatusConfigMapPublisher{}
+
+func TestAPISecretAndStatusConfigMapPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	owner := &fake.Composite{
+		ObjectMeta:               metav1.ObjectMeta{Name: "cool-xr", UID: "cool-uid"},
+		ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: &xpv1.SecretReference{Name: "cool", Namespace: "default"}},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{
+			Name: "cool",
+			Metadata: &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{
+				AnnotationKeyPrefixConnectionDetailSource + "password": "cool-db",
+			}},
+		}},
+	}
+	conn := managed.ConnectionDetails{"password": []byte("secret"), "username": []byte("admin"), "internal": []byte("x")}
+
+	type args struct {
+		o      resource.ConnectionSecretOwner
+		create func(obj client.Object) error
+	}
+	type want struct {
+		published bool
+		err       error
+		created   []client.Object
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoConnectionSecret": {
+			reason: "We should not publish anything if the owner does not want a connection secret.",
+			args: args{
+				o: &fake.Composite{},
+			},
+		},
+		"SecretError": {
+			reason: "We should not write the ConfigMap if we can't write the Secret.",
+			args: args{
+				o: owner,
+				create: func(obj client.Object) error {
+					if _, ok := obj.(*corev1.Secret); ok {
+						return errBoom
+					}
+					return nil
+				},
+			},
+			want: want{
+				err: errors.Wrap(errors.Wrap(errBoom, "cannot create object"), errApplySecret),
+			},
+		},
+		"Published": {
+			reason: "We should write the Secret with values, and the ConfigMap with metadata only.",
+			args: args{
+				o: owner,
+			},
+			want: want{
+				published: true,
+				created: []client.Object{
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "cool", Namespace: "default"},
+						Data:       map[string][]byte{"password": []byte("secret"), "username": []byte("admin")},
+					},
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "cool" + ConnectionStatusConfigMapSuffix, Namespace: "default"},
+						Data: map[string]string{
+							ConnectionStatusKeyKeys:    "password,username",
+							ConnectionStatusKeySources: `{"password":"cool-db"}`,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var created []client.Object
+			kube := &test.MockClient{
+				MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					if tc.args.create != nil {
+						if err := tc.args.create(obj); err != nil {
+							return err
+						}
+					}
+					created = append(created, obj)
+					return nil
+				},
+			}
+
+			a := NewAPISecretAndStatusConfigMapPublisher(kube, []string{"password", "username"})
+			published, err := a.PublishConnection(context.Background(), tc.args.o, conn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\na.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\na.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+
+			ignore := []cmp.Option{
+				cmpopts.IgnoreFields(metav1.ObjectMeta{}, "OwnerReferences"),
+				cmpopts.IgnoreFields(corev1.Secret{}, "Type"),
+				cmpopts.IgnoreMapEntries(func(k string, _ string) bool { return k == ConnectionStatusKeyLastPublishTime }),
+			}
+			if diff := cmp.Diff(tc.want.created, created, ignore...); diff != "" {
+				t.Errorf("\n%s\na.PublishConnection(...): -want created, +got created:\n%s", tc.reason, diff)
+			}
+			for _, obj := range created {
+				cm, ok := obj.(*corev1.ConfigMap)
+				if !ok {
+					continue
+				}
+				if len(cm.GetOwnerReferences()) != 1 || cm.GetOwnerReferences()[0].UID != owner.GetUID() {
+					t.Errorf("\n%s\na.PublishConnection(...): ConfigMap should be controlled by its owner, got %v", tc.reason, cm.GetOwnerReferences())
+				}
+				for _, v := range cm.Data {
+					if strings.Contains(v, "secret") || strings.Contains(v, "admin") {
+						t.Errorf("\n%s\na.PublishConnection(...): ConfigMap must not contain values: %v", tc.reason, cm.Data)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtConflictingKey  = "connection detail %q is produced by several sources: %s"
+	errFmtResolveConflict = "cannot resolve conflicting connection detail %q"
+)
+
+// A SourcedValue is a connection detail value, and the name of the source it
+// was extracted from.
+type SourcedValue struct {
+	Source string
+	Value  []byte
+
+	// Resource the value was extracted from, if known.
+	Resource resource.Composed
+}
+
+// A ConflictResolver resolves a connection detail key that several sources
+// produce. Candidates are supplied in source order. It returns the value to
+// use for the key.
+type ConflictResolver func(key string, candidates []SourcedValue) ([]byte, error)
+
+// FirstSourceWins resolves conflicts by using the value of the first source.
+func FirstSourceWins() ConflictResolver {
+	return func(_ string, candidates []SourcedValue) ([]byte, error) {
+		return candidates[0].Value, nil
+	}
+}
+
+// LastSourceWins resolves conflicts by using the value of the last source,
+// consistent with the MergeAssembler.
+func LastSourceWins() ConflictResolver {
+	return func(_ string, candidates []SourcedValue) ([]byte, error) {
+		return candidates[len(candidates)-1].Value, nil
+	}
+}
+
+// ErrorOnConflict refuses to resolve conflicts.
+func ErrorOnConflict() ConflictResolver {
+	return func(key string, candidates []SourcedValue) ([]byte, error) {
+		names := make([]string, len(candidates))
+		for i, c := range candidates {
+			names[i] = c.Source
+		}
+		return nil, errors.Errorf(errFmtConflictingKey, key, strings.Join(names, ", "))
+	}
+}
+
+// A SourceOrderFn returns true if candidate a is older than candidate b.
+type SourceOrderFn func(a, b SourcedValue) bool
+
+// CreationTimeOrder orders candidates by the creation time of the resource
+// they were extracted from. Candidates without a resource are older than
+// those with one.
+func CreationTimeOrder() SourceOrderFn {
+	return func(a, b SourcedValue) bool {
+		if a.Resource == nil || b.Resource == nil {
+			return a.Resource == nil && b.Resource != nil
+		}
+		at, bt := a.Resource.GetCreationTimestamp(), b.Resource.GetCreationTimestamp()
+		return at.Before(&bt)
+	}
+}
+
+// NewestSourceWins resolves conflicts by using the value of the newest
+// candidate, per the supplied order. Candidates are ordered by creation time
+// if no order is supplied. Candidates the order considers equal are ordered by
+// source name, so that resolution is deterministic.
+func NewestSourceWins(o SourceOrderFn) ConflictResolver {
+	if o == nil {
+		o = CreationTimeOrder()
+	}
+	return func(_ string, candidates []SourcedValue) ([]byte, error) {
+		sorted := make([]SourcedValue, len(candidates))
+		copy(sorted, candidates)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			switch {
+			case o(sorted[i], sorted[j]):
+				return true
+			case o(sorted[j], sorted[i]):
+				return false
+			default:
+				return sorted[i].Source < sorted[j].Source
+			}
+		})
+		return sorted[len(sorted)-1].Value, nil
+	}
+}
+
+// ResolvingAssembler assembles connection details from the supplied sources,
+// calling the supplied ConflictResolver for each key that more than one
+// source produces. Each key is attributed to the last source whose value the
+// resolver chose. Keys whose resolved value matches no candidate, for example
+// because the resolver concatenated them, are attributed to all candidates.
+func ResolvingAssembler(r ConflictResolver) Assembler {
+	return AssemblerFn(func(_ context.Context, s []AssemblySource) (managed.ConnectionDetails, map[string]string, error) {
+		candidates := map[string][]SourcedValue{}
+		for _, src := range s {
+			for _, k := range OrderedKeys(src.ConnectionDetails, AlphabeticalKeyOrder()) {
+				candidates[k] = append(candidates[k], SourcedValue{Source: src.Name, Value: src.ConnectionDetails[k], Resource: src.Resource})
+			}
+		}
+
+		conn := make(managed.ConnectionDetails, len(candidates))
+		sources := make(map[string]string, len(candidates))
+		for k, c := range candidates {
+			if len(c) == 1 {
+				conn[k] = c[0].Value
+				sources[k] = c[0].Source
+				continue
+			}
+
+			v, err := r(k, c)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, errFmtResolveConflict, k)
+			}
+			conn[k] = v
+			sources[k] = resolvedSource(v, c)
+		}
+		return conn, sources, nil
+	})
+}
+
+func resolvedSource(v []byte, candidates []SourcedValue) string {
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if bytes.Equal(v, candidates[i].Value) {
+			return candidates[i].Source
+		}
+	}
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Source
+	}
+	return strings.Join(names, ",")
+}
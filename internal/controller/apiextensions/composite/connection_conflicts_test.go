@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestResolvingAssembler(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	s := []AssemblySource{
+		{Name: "a", ConnectionDetails: managed.ConnectionDetails{"shared": []byte("a"), "only-a": []byte("a")}},
+		{Name: "b", ConnectionDetails: managed.ConnectionDetails{"shared": []byte("b")}},
+	}
+
+	type want struct {
+		conn    managed.ConnectionDetails
+		sources map[string]string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		r      ConflictResolver
+		want   want
+	}{
+		"FirstSourceWins": {
+			reason: "We should use the first source's value for conflicting keys.",
+			r:      FirstSourceWins(),
+			want: want{
+				conn:    managed.ConnectionDetails{"shared": []byte("a"), "only-a": []byte("a")},
+				sources: map[string]string{"shared": "a", "only-a": "a"},
+			},
+		},
+		"LastSourceWins": {
+			reason: "We should use the last source's value for conflicting keys.",
+			r:      LastSourceWins(),
+			want: want{
+				conn:    managed.ConnectionDetails{"shared": []byte("b"), "only-a": []byte("a")},
+				sources: map[string]string{"shared": "b", "only-a": "a"},
+			},
+		},
+		"ErrorOnConflict": {
+			reason: "We should return an error identifying the conflicting key and its sources.",
+			r:      ErrorOnConflict(),
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtConflictingKey, "shared", "a, b"), errFmtResolveConflict, "shared"),
+			},
+		},
+		"CustomResolverError": {
+			reason: "We should return errors from custom resolvers.",
+			r: func(_ string, _ []SourcedValue) ([]byte, error) {
+				return nil, errBoom
+			},
+			want: want{
+				err: errors.Wrapf(errBoom, errFmtResolveConflict, "shared"),
+			},
+		},
+		"CustomResolver": {
+			reason: "We should attribute values that match no single candidate to all candidates.",
+			r: func(_ string, candidates []SourcedValue) ([]byte, error) {
+				out := []byte{}
+				for _, c := range candidates {
+					out = append(out, c.Value...)
+				}
+				return out, nil
+			},
+			want: want{
+				conn:    managed.ConnectionDetails{"shared": []byte("ab"), "only-a": []byte("a")},
+				sources: map[string]string{"shared": "a,b", "only-a": "a"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, sources, err := ResolvingAssembler(tc.r).Assemble(context.Background(), s)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.sources, sources); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want sources, +got sources:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewestSourceWins(t *testing.T) {
+	at := func(name string, sec int64) SourcedValue {
+		cd := &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.Unix(sec, 0)}}
+		return SourcedValue{Source: name, Value: []byte(name), Resource: cd}
+	}
+
+	cases := map[string]struct {
+		reason     string
+		o          SourceOrderFn
+		candidates []SourcedValue
+		want       []byte
+	}{
+		"NewestByCreationTime": {
+			reason:     "We should use the value of the most recently created resource, regardless of source order.",
+			candidates: []SourcedValue{at("b", 3), at("a", 1), at("c", 2)},
+			want:       []byte("b"),
+		},
+		"TiesBrokenBySourceName": {
+			reason:     "We should break ties by source name, regardless of source order.",
+			candidates: []SourcedValue{at("b", 1), at("c", 1), at("a", 1)},
+			want:       []byte("c"),
+		},
+		"NoResourceIsOldest": {
+			reason:     "We should consider candidates without a resource older than those with one.",
+			candidates: []SourcedValue{at("a", 1), {Source: "z", Value: []byte("z")}},
+			want:       []byte("a"),
+		},
+		"CustomOrder": {
+			reason: "We should use the supplied order, if any.",
+			o: func(a, b SourcedValue) bool {
+				return len(a.Value) < len(b.Value)
+			},
+			candidates: []SourcedValue{{Source: "long", Value: []byte("looong")}, {Source: "short", Value: []byte("s")}},
+			want:       []byte("looong"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewestSourceWins(tc.o)("key", tc.candidates)
+			if err != nil {
+				t.Fatalf("\n%s\nNewestSourceWins(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nNewestSourceWins(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
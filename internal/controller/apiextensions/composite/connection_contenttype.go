@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtUnknownContentType = "connection detail %q has unknown content type %q"
+	errFmtContentTypeCollide = "connection details %q and %q would both be published as %q"
+)
+
+// A ConnectionDetailContentType declares the type of content a connection
+// detail holds.
+type ConnectionDetailContentType string
+
+// Connection detail content types.
+const (
+	ConnectionDetailContentTypePEM  ConnectionDetailContentType = "PEM"
+	ConnectionDetailContentTypeJSON ConnectionDetailContentType = "JSON"
+	ConnectionDetailContentTypeYAML ConnectionDetailContentType = "YAML"
+	ConnectionDetailContentTypeText ConnectionDetailContentType = "Text"
+)
+
+// contentTypeExtensions maps each content type to the file extension its
+// connection details are published with.
+var contentTypeExtensions = map[ConnectionDetailContentType]string{
+	ConnectionDetailContentTypePEM:  ".crt",
+	ConnectionDetailContentTypeJSON: ".json",
+	ConnectionDetailContentTypeYAML: ".yaml",
+	ConnectionDetailContentTypeText: ".txt",
+}
+
+// A ContentTypeConnectionPublisher publishes connection details under keys
+// that are suffixed with a file extension matching their declared content
+// type. For example a 'ca' connection detail of type PEM is published as
+// 'ca.crt'. This makes secrets that are mounted as files directly usable by
+// tools that expect particular filenames. Connection details without a
+// declared content type, or whose key already has the expected extension, are
+// published unchanged.
+type ContentTypeConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	types     map[string]ConnectionDetailContentType
+}
+
+// NewContentTypeConnectionPublisher returns a ConnectionPublisher that
+// publishes connection details using the supplied publisher, after renaming
+// them according to the supplied map of connection detail key to content
+// type.
+func NewContentTypeConnectionPublisher(p managed.ConnectionPublisher, types map[string]ConnectionDetailContentType) *ContentTypeConnectionPublisher {
+	return &ContentTypeConnectionPublisher{publisher: p, types: types}
+}
+
+// PublishConnection details for the supplied resource.
+func (p *ContentTypeConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	rc, err := p.rename(c)
+	if err != nil {
+		return false, err
+	}
+	return p.publisher.PublishConnection(ctx, o, rc)
+}
+
+// UnpublishConnection details for the supplied resource.
+func (p *ContentTypeConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	rc, err := p.rename(c)
+	if err != nil {
+		return err
+	}
+	return p.publisher.UnpublishConnection(ctx, o, rc)
+}
+
+// rename returns a copy of the supplied connection details keyed by filename.
+// It returns an error if two connection details would be published under the
+// same filename.
+func (p *ContentTypeConnectionPublisher) rename(c managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	if len(p.types) == 0 {
+		return c, nil
+	}
+
+	out := make(managed.ConnectionDetails, len(c))
+	from := make(map[string]string, len(c))
+	for _, k := range OrderedKeys(c, AlphabeticalKeyOrder()) {
+		name := k
+		if t, ok := p.types[k]; ok {
+			ext, ok := contentTypeExtensions[t]
+			if !ok {
+				return nil, errors.Errorf(errFmtUnknownContentType, k, t)
+			}
+			if !strings.HasSuffix(k, ext) {
+				name = k + ext
+			}
+		}
+		if other, ok := from[name]; ok {
+			return nil, errors.Errorf(errFmtContentTypeCollide, other, k, name)
+		}
+		from[name] = k
+		out[name] = c[k]
+	}
+	return out, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &ContentTypeConnectionPublisher{}
+
+func TestContentTypeConnectionPublisher(t *testing.T) {
+	type args struct {
+		types map[string]ConnectionDetailContentType
+		c     managed.ConnectionDetails
+	}
+	type want struct {
+		published managed.ConnectionDetails
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoContentTypes": {
+			reason: "We should publish connection details unchanged if no content types are declared.",
+			args: args{
+				c: managed.ConnectionDetails{"ca": []byte("pem")},
+			},
+			want: want{
+				published: managed.ConnectionDetails{"ca": []byte("pem")},
+			},
+		},
+		"Renamed": {
+			reason: "We should suffix connection details with the extension of their declared content type.",
+			args: args{
+				types: map[string]ConnectionDetailContentType{
+					"ca":     ConnectionDetailContentTypePEM,
+					"config": ConnectionDetailContentTypeJSON,
+				},
+				c: managed.ConnectionDetails{
+					"ca":       []byte("pem"),
+					"config":   []byte("{}"),
+					"password": []byte("secret"),
+				},
+			},
+			want: want{
+				published: managed.ConnectionDetails{
+					"ca.crt":      []byte("pem"),
+					"config.json": []byte("{}"),
+					"password":    []byte("secret"),
+				},
+			},
+		},
+		"AlreadySuffixed": {
+			reason: "We should not suffix connection details that already have the expected extension.",
+			args: args{
+				types: map[string]ConnectionDetailContentType{"ca.crt": ConnectionDetailContentTypePEM},
+				c:     managed.ConnectionDetails{"ca.crt": []byte("pem")},
+			},
+			want: want{
+				published: managed.ConnectionDetails{"ca.crt": []byte("pem")},
+			},
+		},
+		"Collision": {
+			reason: "We should return an error if two connection details would be published under the same key.",
+			args: args{
+				types: map[string]ConnectionDetailContentType{"ca": ConnectionDetailContentTypePEM},
+				c: managed.ConnectionDetails{
+					"ca":     []byte("pem"),
+					"ca.crt": []byte("pem"),
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtContentTypeCollide, "ca", "ca.crt", "ca.crt"),
+			},
+		},
+		"UnknownContentType": {
+			reason: "We should return an error if a connection detail declares an unknown content type.",
+			args: args{
+				types: map[string]ConnectionDetailContentType{"ca": "DER"},
+				c:     managed.ConnectionDetails{"ca": []byte("der")},
+			},
+			want: want{
+				err: errors.Errorf(errFmtUnknownContentType, "ca", "DER"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var published managed.ConnectionDetails
+			p := NewContentTypeConnectionPublisher(managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+					published = c
+					return true, nil
+				},
+			}, tc.args.types)
+
+			_, err := p.PublishConnection(context.Background(), &fake.Composite{}, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
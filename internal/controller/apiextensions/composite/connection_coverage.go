@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// Warning strings.
+const (
+	warnFmtUncoveredConnectionDetail = "connection detail %q of resource template %s is of type %q, which no connection details fetcher supports; it will never resolve"
+)
+
+// A ConnectionDetailTypesReporter reports the types of connection detail it
+// supports. ConnectionDetailsFetchers may optionally implement it so that a
+// Composition can be checked for connection details that will never resolve.
+type ConnectionDetailTypesReporter interface {
+	SupportedTypes() []v1.ConnectionDetailType
+}
+
+// SupportedTypes returns the types of connection detail a
+// SecretConnectionDetailsFetcher supports. It reads only connection secrets,
+// so it supports only FromConnectionSecretKey.
+func (cdf *SecretConnectionDetailsFetcher) SupportedTypes() []v1.ConnectionDetailType {
+	return []v1.ConnectionDetailType{v1.ConnectionDetailTypeFromConnectionSecretKey}
+}
+
+// SupportedTypes returns the types of connection detail a
+// NopConnectionDetailsFetcher supports, which is none.
+func (NopConnectionDetailsFetcher) SupportedTypes() []v1.ConnectionDetailType {
+	return nil
+}
+
+// SupportedConnectionDetailTypes returns the set of connection detail types
+// the supplied ConnectionDetailsFetcher supports. Fetcher chains, and fetchers
+// that wrap another fetcher, support the types supported by any fetcher they
+// call. A fetcher that doesn't implement ConnectionDetailTypesReporter is
+// assumed to support every type.
+func SupportedConnectionDetailTypes(f managed.ConnectionDetailsFetcher) map[v1.ConnectionDetailType]bool {
+	out := make(map[v1.ConnectionDetailType]bool)
+	addSupportedTypes(out, f)
+	return out
+}
+
+func addSupportedTypes(out map[v1.ConnectionDetailType]bool, f managed.ConnectionDetailsFetcher) {
+	var fetchers []managed.ConnectionDetailsFetcher
+	switch fc := f.(type) {
+	case ConnectionDetailsFetcherChain:
+		fetchers = fc
+	case FirstWinsConnectionDetailsFetcherChain:
+		fetchers = fc
+	case StrictConnectionDetailsFetcherChain:
+		fetchers = fc
+	case *CollectingConnectionDetailsFetcherChain:
+		fetchers = fc.fetchers
+	case *CachingConnectionDetailsFetcher:
+		fetchers = []managed.ConnectionDetailsFetcher{fc.fetcher}
+	case *FilteredConnectionDetailsFetcher:
+		fetchers = []managed.ConnectionDetailsFetcher{fc.fetcher}
+	case *SeededConnectionDetailsFetcher:
+		fetchers = []managed.ConnectionDetailsFetcher{fc.fetcher}
+	case *TimeoutConnectionDetailsFetcher:
+		fetchers = []managed.ConnectionDetailsFetcher{fc.fetcher}
+	case ConnectionDetailTypesReporter:
+		for _, t := range fc.SupportedTypes() {
+			out[t] = true
+		}
+		return
+	default:
+		out[v1.ConnectionDetailTypeFromConnectionSecretKey] = true
+		out[v1.ConnectionDetailTypeFromFieldPath] = true
+		out[v1.ConnectionDetailTypeFromValue] = true
+		return
+	}
+	for _, cf := range fetchers {
+		addSupportedTypes(out, cf)
+	}
+}
+
+// CheckConnectionDetailCoverage walks the connection details of the supplied
+// Composition's resource templates and returns a warning for each connection
+// detail whose type isn't supported by the supplied ConnectionDetailsFetcher,
+// typically a chain. Such connection details will never resolve. It returns
+// no warnings if every connection detail is covered. FromFieldPath and
+// FromValue connection details are always covered, because they're extracted
+// from the composed resource and the template rather than fetched.
+func CheckConnectionDetailCoverage(comp *v1.Composition, f managed.ConnectionDetailsFetcher) []string {
+	supported := SupportedConnectionDetailTypes(f)
+
+	var warnings []string
+	for i := range comp.Spec.Resources {
+		t := &comp.Spec.Resources[i]
+		for _, cfg := range ExtractConfigsFromTemplate(t) {
+			if cfg.Type == ConnectionDetailTypeFromFieldPath || cfg.Type == ConnectionDetailTypeFromValue {
+				continue
+			}
+			if supported[v1.ConnectionDetailType(cfg.Type)] {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf(warnFmtUncoveredConnectionDetail, cfg.Name, templateID(t, i), cfg.Type))
+		}
+	}
+	return warnings
+}
+
+// templateID identifies the supplied resource template by name, or by its
+// index if it is anonymous.
+func templateID(t *v1.ComposedTemplate, i int) string {
+	if t.Name != nil {
+		return strconv.Quote(*t.Name)
+	}
+	return strconv.Itoa(i)
+}
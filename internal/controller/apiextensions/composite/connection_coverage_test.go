@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/pointer"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+type typesReportingFetcher []v1.ConnectionDetailType
+
+func (f typesReportingFetcher) FetchConnection(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	return nil, nil
+}
+
+func (f typesReportingFetcher) SupportedTypes() []v1.ConnectionDetailType {
+	return f
+}
+
+func TestSupportedConnectionDetailTypes(t *testing.T) {
+	unreported := ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		return nil, nil
+	})
+	all := map[v1.ConnectionDetailType]bool{
+		v1.ConnectionDetailTypeFromConnectionSecretKey: true,
+		v1.ConnectionDetailTypeFromFieldPath:           true,
+		v1.ConnectionDetailTypeFromValue:               true,
+	}
+
+	cases := map[string]struct {
+		reason string
+		f      managed.ConnectionDetailsFetcher
+		want   map[v1.ConnectionDetailType]bool
+	}{
+		"Unreported": {
+			reason: "A fetcher that doesn't report its supported types should be assumed to support all types.",
+			f:      unreported,
+			want:   all,
+		},
+		"SecretFetcher": {
+			reason: "A SecretConnectionDetailsFetcher should support only FromConnectionSecretKey.",
+			f:      NewSecretConnectionDetailsFetcher(nil),
+			want:   map[v1.ConnectionDetailType]bool{v1.ConnectionDetailTypeFromConnectionSecretKey: true},
+		},
+		"NopFetcher": {
+			reason: "A NopConnectionDetailsFetcher should support no types.",
+			f:      NopConnectionDetailsFetcher{},
+			want:   map[v1.ConnectionDetailType]bool{},
+		},
+		"Chain": {
+			reason: "A chain should support the types supported by any of its fetchers.",
+			f: NewConnectionDetailsFetcherChain(ChainMergeLastWins,
+				typesReportingFetcher{v1.ConnectionDetailTypeFromValue},
+				NewCachingConnectionDetailsFetcher(NewSecretConnectionDetailsFetcher(nil)),
+			),
+			want: map[v1.ConnectionDetailType]bool{
+				v1.ConnectionDetailTypeFromConnectionSecretKey: true,
+				v1.ConnectionDetailTypeFromValue:               true,
+			},
+		},
+		"CollectingChain": {
+			reason: "A collecting chain should support the types supported by any of its fetchers.",
+			f: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeFirstWins, ChainErrorCollect,
+				NopConnectionDetailsFetcher{},
+				NewFilteredConnectionDetailsFetcher(typesReportingFetcher{v1.ConnectionDetailTypeFromFieldPath}, nil),
+			),
+			want: map[v1.ConnectionDetailType]bool{v1.ConnectionDetailTypeFromFieldPath: true},
+		},
+		"ChainWithUnreportedFetcher": {
+			reason: "A chain including a fetcher that doesn't report its supported types should support all types.",
+			f:      NewConnectionDetailsFetcherChain(ChainMergeStrict, NopConnectionDetailsFetcher{}, unreported),
+			want:   all,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := SupportedConnectionDetailTypes(tc.f)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nSupportedConnectionDetailTypes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckConnectionDetailCoverage(t *testing.T) {
+	fromSecret := v1.ConnectionDetailTypeFromConnectionSecretKey
+	unknown := v1.ConnectionDetailType("FromSomewhere")
+
+	comp := &v1.Composition{
+		Spec: v1.CompositionSpec{
+			Resources: []v1.ComposedTemplate{
+				{
+					Name: pointer.String("db"),
+					ConnectionDetails: []v1.ConnectionDetail{
+						{FromConnectionSecretKey: pointer.String("password")},
+						{Name: pointer.String("endpoint"), FromFieldPath: pointer.String("status.endpoint")},
+						{Name: pointer.String("port"), Value: pointer.String("5432")},
+					},
+				},
+				{
+					ConnectionDetails: []v1.ConnectionDetail{
+						{Name: pointer.String("user"), Type: &fromSecret, FromConnectionSecretKey: pointer.String("username")},
+					},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		reason string
+		comp   *v1.Composition
+		f      managed.ConnectionDetailsFetcher
+		want   []string
+	}{
+		"Covered": {
+			reason: "We should return no warnings if the chain supports every type the Composition uses.",
+			comp:   comp,
+			f:      NewConnectionDetailsFetcherChain(ChainMergeLastWins, NewSecretConnectionDetailsFetcher(nil)),
+		},
+		"ExtractedTypesAlwaysCovered": {
+			reason: "FromFieldPath and FromValue connection details are extracted rather than fetched, so they should always be covered.",
+			comp: &v1.Composition{Spec: v1.CompositionSpec{Resources: []v1.ComposedTemplate{{
+				ConnectionDetails: []v1.ConnectionDetail{
+					{Name: pointer.String("endpoint"), FromFieldPath: pointer.String("status.endpoint")},
+					{Name: pointer.String("port"), Value: pointer.String("5432")},
+				},
+			}}}},
+			f: NopConnectionDetailsFetcher{},
+		},
+		"Uncovered": {
+			reason: "We should warn about each connection detail whose type no fetcher in the chain supports.",
+			comp:   comp,
+			f:      NewConnectionDetailsFetcherChain(ChainMergeLastWins, NopConnectionDetailsFetcher{}),
+			want: []string{
+				fmt.Sprintf(warnFmtUncoveredConnectionDetail, "password", `"db"`, v1.ConnectionDetailTypeFromConnectionSecretKey),
+				fmt.Sprintf(warnFmtUncoveredConnectionDetail, "user", "1", v1.ConnectionDetailTypeFromConnectionSecretKey),
+			},
+		},
+		"UnknownType": {
+			reason: "We should warn about connection details of a type no fetcher supports, even if fetchers don't report their supported types.",
+			comp: &v1.Composition{Spec: v1.CompositionSpec{Resources: []v1.ComposedTemplate{{
+				Name: pointer.String("db"),
+				ConnectionDetails: []v1.ConnectionDetail{
+					{Name: pointer.String("cool"), Type: &unknown},
+				},
+			}}}},
+			f: ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+				return nil, nil
+			}),
+			want: []string{
+				fmt.Sprintf(warnFmtUncoveredConnectionDetail, "cool", `"db"`, unknown),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := CheckConnectionDetailCoverage(tc.comp, tc.f)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCheckConnectionDetailCoverage(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtResolveCredentials = "cannot resolve store credentials for ProviderConfig %q"
+	errFmtScopedFetcher      = "cannot create connection details fetcher for ProviderConfig %q"
+	errProviderConfigRef     = "cannot get ProviderConfig reference of composed resource"
+)
+
+// DefaultCredentialsTTL is the default time for which a
+// CredentialScopedConnectionDetailsFetcher reuses resolved credentials.
+const DefaultCredentialsTTL = 5 * time.Minute
+
+// A CredentialsResolver resolves the credentials used to read from a secret
+// store on behalf of the supplied ProviderConfig.
+type CredentialsResolver interface {
+	ResolveCredentials(ctx context.Context, providerConfig string) ([]byte, error)
+}
+
+// A CredentialsResolverFn is a function that satisfies the
+// CredentialsResolver interface.
+type CredentialsResolverFn func(ctx context.Context, providerConfig string) ([]byte, error)
+
+// ResolveCredentials for the supplied ProviderConfig.
+func (fn CredentialsResolverFn) ResolveCredentials(ctx context.Context, providerConfig string) ([]byte, error) {
+	return fn(ctx, providerConfig)
+}
+
+// A ScopedFetcherFn returns a ConnectionDetailsFetcher that reads from a secret
+// store using the supplied credentials.
+type ScopedFetcherFn func(ctx context.Context, creds []byte) (managed.ConnectionDetailsFetcher, error)
+
+// A CredentialScopedConnectionDetailsFetcherOption configures a
+// CredentialScopedConnectionDetailsFetcher.
+type CredentialScopedConnectionDetailsFetcherOption func(*CredentialScopedConnectionDetailsFetcher)
+
+// WithCredentialsTTL configures how long a
+// CredentialScopedConnectionDetailsFetcher reuses resolved credentials before
+// resolving them again, for example to honor credential rotation.
+func WithCredentialsTTL(ttl time.Duration) CredentialScopedConnectionDetailsFetcherOption {
+	return func(f *CredentialScopedConnectionDetailsFetcher) {
+		f.ttl = ttl
+	}
+}
+
+type scopedFetcher struct {
+	fetcher managed.ConnectionDetailsFetcher
+	expires time.Time
+}
+
+// A CredentialScopedConnectionDetailsFetcher fetches the connection details of
+// each composed resource using credentials resolved from the composed
+// resource's ProviderConfig, rather than shared credentials. This prevents
+// credentials being reused across tenants of a multi-tenant control plane.
+// Composed resources without a ProviderConfig reference have no connection
+// details fetched.
+type CredentialScopedConnectionDetailsFetcher struct {
+	resolver   CredentialsResolver
+	newFetcher ScopedFetcherFn
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu       sync.Mutex
+	fetchers map[string]scopedFetcher
+}
+
+// NewCredentialScopedConnectionDetailsFetcher returns a
+// ConnectionDetailsFetcher that resolves credentials using the supplied
+// resolver, and fetches connection details using fetchers created with them.
+func NewCredentialScopedConnectionDetailsFetcher(r CredentialsResolver, fn ScopedFetcherFn, o ...CredentialScopedConnectionDetailsFetcherOption) *CredentialScopedConnectionDetailsFetcher {
+	f := &CredentialScopedConnectionDetailsFetcher{
+		resolver:   r,
+		newFetcher: fn,
+		ttl:        DefaultCredentialsTTL,
+		now:        time.Now,
+		fetchers:   make(map[string]scopedFetcher),
+	}
+	for _, fn := range o {
+		fn(f)
+	}
+	return f
+}
+
+// FetchConnection details of the supplied composed resource using credentials
+// scoped to its ProviderConfig.
+func (f *CredentialScopedConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	pc, err := providerConfigName(o)
+	if err != nil {
+		return nil, err
+	}
+	if pc == "" {
+		return nil, nil
+	}
+	sf, err := f.fetcher(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+	return sf.FetchConnection(ctx, o)
+}
+
+// fetcher returns a fetcher scoped to the supplied ProviderConfig, resolving
+// its credentials if they're not cached or have expired.
+func (f *CredentialScopedConnectionDetailsFetcher) fetcher(ctx context.Context, pc string) (managed.ConnectionDetailsFetcher, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if sf, ok := f.fetchers[pc]; ok && f.now().Before(sf.expires) {
+		return sf.fetcher, nil
+	}
+
+	creds, err := f.resolver.ResolveCredentials(ctx, pc)
+	if err != nil {
+		delete(f.fetchers, pc)
+		return nil, errors.Wrapf(err, errFmtResolveCredentials, pc)
+	}
+	sf, err := f.newFetcher(ctx, creds)
+	if err != nil {
+		delete(f.fetchers, pc)
+		return nil, errors.Wrapf(err, errFmtScopedFetcher, pc)
+	}
+	f.fetchers[pc] = scopedFetcher{fetcher: sf, expires: f.now().Add(f.ttl)}
+	return sf, nil
+}
+
+// providerConfigName returns the name of the supplied composed resource's
+// ProviderConfig, or an empty string if it has none.
+func providerConfigName(o resource.ConnectionSecretOwner) (string, error) {
+	if r, ok := o.(resource.ProviderConfigReferencer); ok {
+		if ref := r.GetProviderConfigReference(); ref != nil {
+			return ref.Name, nil
+		}
+		return "", nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return "", errors.Wrap(err, errProviderConfigRef)
+	}
+	name, err := fieldpath.Pave(u).GetString("spec.providerConfigRef.name")
+	if fieldpath.IsNotFound(err) {
+		return "", nil
+	}
+	return name, errors.Wrap(err, errProviderConfigRef)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &CredentialScopedConnectionDetailsFetcher{}
+
+func TestCredentialScopedConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	withProviderConfig := func(name string) resource.ConnectionSecretOwner {
+		return withContent(map[string]any{"spec": map[string]any{"providerConfigRef": map[string]any{"name": name}}})
+	}
+
+	// Scoped fetchers return the credentials they were created with.
+	scoped := func(_ context.Context, creds []byte) (managed.ConnectionDetailsFetcher, error) {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return managed.ConnectionDetails{"creds": creds}, nil
+		}), nil
+	}
+
+	type fetch struct {
+		o       resource.ConnectionSecretOwner
+		advance time.Duration
+	}
+	type args struct {
+		resolve func(pc string, n int) ([]byte, error)
+		fn      ScopedFetcherFn
+		fetches []fetch
+	}
+	type want struct {
+		conn     []managed.ConnectionDetails
+		err      error
+		resolves int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoProviderConfig": {
+			reason: "We should fetch nothing for a composed resource without a ProviderConfig.",
+			args: args{
+				fn:      scoped,
+				fetches: []fetch{{o: &fake.Composed{}}},
+			},
+			want: want{conn: []managed.ConnectionDetails{nil}},
+		},
+		"ResolveError": {
+			reason: "We should return a clear error if credentials can't be resolved.",
+			args: args{
+				resolve: func(_ string, _ int) ([]byte, error) { return nil, errBoom },
+				fn:      scoped,
+				fetches: []fetch{{o: withProviderConfig("tenant-a")}},
+			},
+			want: want{
+				err:      errors.Wrapf(errBoom, errFmtResolveCredentials, "tenant-a"),
+				resolves: 1,
+			},
+		},
+		"ScopedFetcherError": {
+			reason: "We should return a clear error if a scoped fetcher can't be created.",
+			args: args{
+				resolve: func(pc string, _ int) ([]byte, error) { return []byte(pc), nil },
+				fn: func(_ context.Context, _ []byte) (managed.ConnectionDetailsFetcher, error) {
+					return nil, errBoom
+				},
+				fetches: []fetch{{o: withProviderConfig("tenant-a")}},
+			},
+			want: want{
+				err:      errors.Wrapf(errBoom, errFmtScopedFetcher, "tenant-a"),
+				resolves: 1,
+			},
+		},
+		"ScopedPerProviderConfig": {
+			reason: "We should use credentials scoped to each composed resource's ProviderConfig, resolving them once per TTL.",
+			args: args{
+				resolve: func(pc string, _ int) ([]byte, error) { return []byte(pc), nil },
+				fn:      scoped,
+				fetches: []fetch{
+					{o: withProviderConfig("tenant-a")},
+					{o: withProviderConfig("tenant-b")},
+					{o: withProviderConfig("tenant-a")},
+				},
+			},
+			want: want{
+				conn: []managed.ConnectionDetails{
+					{"creds": []byte("tenant-a")},
+					{"creds": []byte("tenant-b")},
+					{"creds": []byte("tenant-a")},
+				},
+				resolves: 2,
+			},
+		},
+		"Rotated": {
+			reason: "We should resolve credentials again once the TTL expires, to honor rotation.",
+			args: args{
+				resolve: func(pc string, n int) ([]byte, error) { return []byte(pc + "-" + strconv.Itoa(n)), nil },
+				fn:      scoped,
+				fetches: []fetch{
+					{o: withProviderConfig("tenant-a")},
+					{o: withProviderConfig("tenant-a"), advance: DefaultCredentialsTTL},
+				},
+			},
+			want: want{
+				conn: []managed.ConnectionDetails{
+					{"creds": []byte("tenant-a-1")},
+					{"creds": []byte("tenant-a-2")},
+				},
+				resolves: 2,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resolves := 0
+			r := CredentialsResolverFn(func(_ context.Context, pc string) ([]byte, error) {
+				resolves++
+				return tc.args.resolve(pc, resolves)
+			})
+
+			now := time.Now()
+			f := NewCredentialScopedConnectionDetailsFetcher(r, tc.args.fn)
+			f.now = func() time.Time { return now }
+
+			var got []managed.ConnectionDetails
+			var err error
+			for _, fe := range tc.args.fetches {
+				now = now.Add(fe.advance)
+				var conn managed.ConnectionDetails
+				conn, err = f.FetchConnection(context.Background(), fe.o)
+				if err != nil {
+					break
+				}
+				got = append(got, conn)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.resolves, resolves); diff != "" {
+				t.Errorf("\n%s\nResolveCredentials(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
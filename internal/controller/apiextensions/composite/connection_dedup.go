@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// A secretReadKey uniquely identifies a source secret.
+type secretReadKey struct {
+	Store     string
+	Namespace string
+	Name      string
+}
+
+// A secretRead is the result of reading a source secret.
+type secretRead struct {
+	secret *corev1.Secret
+	err    error
+}
+
+// secretReads deduplicates reads of identical source secrets.
+type secretReads struct {
+	mu    sync.Mutex
+	reads map[secretReadKey]secretRead
+}
+
+type secretReadsKey struct{}
+
+// WithSecretReadDeduplication returns a context that deduplicates reads of
+// source secrets. While the context is in use, each distinct source secret is
+// read at most once, even if several composed resources reference it. The
+// context should be scoped to a single reconcile, so that changes to source
+// secrets are observed by the next reconcile.
+func WithSecretReadDeduplication(ctx context.Context) context.Context {
+	return context.WithValue(ctx, secretReadsKey{}, &secretReads{reads: make(map[secretReadKey]secretRead)})
+}
+
+// readSecret returns the source secret identified by the supplied key, calling
+// the supplied read function only if the secret has not already been read
+// using the supplied context.
+func readSecret(ctx context.Context, k secretReadKey, read func() (*corev1.Secret, error)) (*corev1.Secret, error) {
+	if ctx == nil {
+		return read()
+	}
+	sr, ok := ctx.Value(secretReadsKey{}).(*secretReads)
+	if !ok {
+		return read()
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if r, ok := sr.reads[k]; ok {
+		return r.secret.DeepCopy(), r.err
+	}
+	s, err := read()
+	sr.reads[k] = secretRead{secret: s.DeepCopy(), err: err}
+	return s, err
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestSecretReadDeduplication(t *testing.T) {
+	shared := &xpv1.SecretReference{Name: "shared", Namespace: "default"}
+	other := &xpv1.SecretReference{Name: "other", Namespace: "default"}
+
+	type args struct {
+		ctx context.Context
+		cds []resource.ConnectionSecretOwner
+	}
+	type want struct {
+		reads int
+		conn  []managed.ConnectionDetails
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Deduplicated": {
+			reason: "We should read each distinct connection secret once when deduplication is enabled.",
+			args: args{
+				ctx: WithSecretReadDeduplication(context.Background()),
+				cds: []resource.ConnectionSecretOwner{
+					&fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: shared}},
+					&fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: shared}},
+					&fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: other}},
+				},
+			},
+			want: want{
+				reads: 2,
+				conn: []managed.ConnectionDetails{
+					{"name": []byte("shared")},
+					{"name": []byte("shared")},
+					{"name": []byte("other")},
+				},
+			},
+		},
+		"NotDeduplicated": {
+			reason: "We should read connection secrets once per composed resource when deduplication is not enabled.",
+			args: args{
+				ctx: context.Background(),
+				cds: []resource.ConnectionSecretOwner{
+					&fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: shared}},
+					&fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: shared}},
+				},
+			},
+			want: want{
+				reads: 2,
+				conn: []managed.ConnectionDetails{
+					{"name": []byte("shared")},
+					{"name": []byte("shared")},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reads := 0
+			kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+				reads++
+				obj.(*corev1.Secret).Data = map[string][]byte{"name": []byte(key.Name)}
+				return nil
+			}}
+			f := NewSecretConnectionDetailsFetcher(kube)
+
+			got := make([]managed.ConnectionDetails, len(tc.args.cds))
+			for i, cd := range tc.args.cds {
+				conn, err := f.FetchConnection(tc.args.ctx, cd)
+				if err != nil {
+					t.Fatalf("f.FetchConnection(...): %s", err)
+				}
+				got[i] = conn
+			}
+			if diff := cmp.Diff(tc.want.reads, reads); diff != "" {
+				t.Errorf("\n%s\nreads: -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+// DiffConnectionDetails returns the keys that were added, changed, and removed
+// between the supplied connection details, each in alphabetical order. A key
+// is changed if its value differs byte for byte. Either connection details may
+// be nil.
+func DiffConnectionDetails(before, after managed.ConnectionDetails) (added, changed, removed []string) {
+	for k, v := range after {
+		ov, ok := before[k]
+		switch {
+		case !ok:
+			added = append(added, k)
+		case !bytes.Equal(ov, v):
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}
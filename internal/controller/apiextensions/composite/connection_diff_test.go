@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+func TestDiffConnectionDetails(t *testing.T) {
+	type args struct {
+		before managed.ConnectionDetails
+		after  managed.ConnectionDetails
+	}
+	type want struct {
+		added   []string
+		changed []string
+		removed []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"BothNil": {
+			reason: "There should be no differences between nil connection details.",
+		},
+		"NilBefore": {
+			reason: "Every key should be added if there were no connection details before.",
+			args: args{
+				after: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+			want: want{
+				added: []string{"password", "user"},
+			},
+		},
+		"NilAfter": {
+			reason: "Every key should be removed if there are no connection details after.",
+			args: args{
+				before: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+			want: want{
+				removed: []string{"password", "user"},
+			},
+		},
+		"Equal": {
+			reason: "Keys present in both with equal bytes should not differ.",
+			args: args{
+				before: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+				after:  managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+		},
+		"EmptyAndNilValues": {
+			reason: "An empty value should equal a nil value.",
+			args: args{
+				before: managed.ConnectionDetails{"empty": nil},
+				after:  managed.ConnectionDetails{"empty": []byte{}},
+			},
+		},
+		"Changed": {
+			reason: "Keys present in both with different bytes should be changed.",
+			args: args{
+				before: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+				after:  managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("rotated")},
+			},
+			want: want{
+				changed: []string{"password"},
+			},
+		},
+		"BinaryChanged": {
+			reason: "Values should be compared byte for byte, even if they aren't valid strings.",
+			args: args{
+				before: managed.ConnectionDetails{"key": {0x00, 0xff, 0xfe}},
+				after:  managed.ConnectionDetails{"key": {0x00, 0xff, 0xfd}},
+			},
+			want: want{
+				changed: []string{"key"},
+			},
+		},
+		"PresentInOneOnly": {
+			reason: "Keys present only after should be added, and keys present only before should be removed.",
+			args: args{
+				before: managed.ConnectionDetails{"user": []byte("admin"), "token": []byte("t"), "ca": []byte("a")},
+				after:  managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret"), "endpoint": []byte("e")},
+			},
+			want: want{
+				added:   []string{"endpoint", "password"},
+				removed: []string{"ca", "token"},
+			},
+		},
+		"AllKinds": {
+			reason: "We should report added, changed, and removed keys together.",
+			args: args{
+				before: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret"), "token": []byte("t")},
+				after:  managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("rotated"), "endpoint": []byte("e")},
+			},
+			want: want{
+				added:   []string{"endpoint"},
+				changed: []string{"password"},
+				removed: []string{"token"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			added, changed, removed := DiffConnectionDetails(tc.args.before, tc.args.after)
+			if diff := cmp.Diff(tc.want.added, added); diff != "" {
+				t.Errorf("\n%s\nDiffConnectionDetails(...): -want added, +got added:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("\n%s\nDiffConnectionDetails(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.removed, removed); diff != "" {
+				t.Errorf("\n%s\nDiffConnectionDetails(...): -want removed, +got removed:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
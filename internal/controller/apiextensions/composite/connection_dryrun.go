@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtDryRunPublisher = "cannot dry run publishing connection details using publisher %d of chain"
+)
+
+// A DryRunConnectionPublisher can compute the connection details it would
+// publish without publishing them.
+type DryRunConnectionPublisher interface {
+	// PublishConnectionDryRun returns the connection details that would be
+	// published for the supplied resource, without publishing them.
+	PublishConnectionDryRun(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (managed.ConnectionDetails, error)
+}
+
+// WithDryRun configures a SecretStoreConnectionPublisher to filter, rename,
+// and validate connection details without publishing them to the
+// SecretStore. It won't unpublish connection details either. Use PublishConnectionDryRun to get the connection details that
+// would be published.
+func WithDryRun() SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.dryRun = true
+	}
+}
+
+// PublishConnectionDryRun returns the connection details the publisher would
+// publish to the SecretStore, without publishing them. It doesn't call the
+// SecretStore, so unlike PublishConnection it can't check who owns the store
+// entry. It returns nil if the resource doesn't want to publish connection
+// details.
+func (p *SecretStoreConnectionPublisher) PublishConnectionDryRun(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	if o.GetPublishConnectionDetailsTo() == nil {
+		return nil, nil
+	}
+	data, _, err := p.prepare(c)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DryRunPublisherChain returns the connection details each publisher in the
+// supplied chain would publish, without publishing them. Results are returned
+// in chain order. The result for a publisher that can't dry run is nil.
+func DryRunPublisherChain(ctx context.Context, pc managed.PublisherChain, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) ([]managed.ConnectionDetails, error) {
+	results := make([]managed.ConnectionDetails, len(pc))
+	for i, p := range pc {
+		dp, ok := p.(DryRunConnectionPublisher)
+		if !ok {
+			continue
+		}
+		conn, err := dp.PublishConnectionDryRun(ctx, o, c)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtDryRunPublisher, i)
+		}
+		results[i] = conn
+	}
+	return results, nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+// A backendNotCalled is a SecretStore backend that fails the test if it is
+// called.
+type backendNotCalled struct{ t *testing.T }
+
+func (b backendNotCalled) FetchConnection(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	b.t.Errorf("FetchConnection(...): unexpected call to backend")
+	return nil, nil
+}
+
+func (b backendNotCalled) PublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+	b.t.Errorf("PublishConnection(...): unexpected call to backend")
+	return true, nil
+}
+
+func (b backendNotCalled) UnpublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	b.t.Errorf("UnpublishConnection(...): unexpected call to backend")
+	return nil
+}
+
+func TestPublishConnectionDryRun(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type args struct {
+		o      resource.ConnectionSecretOwner
+		filter []string
+		remap  map[string]string
+		opts   []SecretStoreConnectionPublisherOption
+		c      managed.ConnectionDetails
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotPublishing": {
+			reason: "We should return no connection details if the resource doesn't want to publish any.",
+			args: args{
+				o: &fake.Composite{},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"FilteredAndRenamed": {
+			reason: "We should return the filtered and renamed connection details that would be published.",
+			args: args{
+				o:      publishing,
+				filter: []string{"endpoint", "port"},
+				remap:  map[string]string{"endpoint": "DB_HOST"},
+				c:      managed.ConnectionDetails{"endpoint": []byte("db"), "port": []byte("5432"), "password": []byte("secret")},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"DB_HOST": []byte("db"), "port": []byte("5432")},
+			},
+		},
+		"Shared": {
+			reason: "We should return the same connection details for shared store entries.",
+			args: args{
+				o:    publishing,
+				opts: []SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries()},
+				c:    managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"Invalid": {
+			reason: "We should return an error if the connection details would fail validation.",
+			args: args{
+				o:    publishing,
+				opts: []SecretStoreConnectionPublisherOption{WithMaxConnectionDetailsSize(1)},
+				c:    managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtConnectionDetailsTooLarge, 2, 1, `"a" (2 bytes)`), errPublishStoreEntry),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSecretStoreConnectionPublisherWithRemap(backendNotCalled{t: t}, tc.args.filter, tc.args.remap, tc.args.opts...)
+			conn, err := p.PublishConnectionDryRun(context.Background(), tc.args.o, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionDryRun(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionDryRun(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPublishConnectionWithDryRun(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type want struct {
+		published bool
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []SecretStoreConnectionPublisherOption
+		want   want
+	}{
+		"DryRun": {
+			reason: "We should not call the backend when publishing in dry run mode.",
+			opts:   []SecretStoreConnectionPublisherOption{WithDryRun()},
+			want: want{
+				published: false,
+			},
+		},
+		"DryRunInvalid": {
+			reason: "We should still validate connection details when publishing in dry run mode.",
+			opts:   []SecretStoreConnectionPublisherOption{WithDryRun(), WithMaxConnectionDetailsSize(1)},
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtConnectionDetailsTooLarge, 2, 1, `"a" (2 bytes)`), errPublishStoreEntry),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSecretStoreConnectionPublisher(backendNotCalled{t: t}, nil, tc.opts...)
+			published, err := p.PublishConnection(context.Background(), publishing, managed.ConnectionDetails{"a": []byte("a")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if err := p.UnpublishConnection(context.Background(), publishing, managed.ConnectionDetails{"a": []byte("a")}); err != nil {
+				t.Errorf("\n%s\nUnpublishConnection(...): %s", tc.reason, err)
+			}
+		})
+	}
+}
+
+func TestDryRunPublisherChain(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type want struct {
+		results []managed.ConnectionDetails
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		pc     func(t *testing.T) managed.PublisherChain
+		want   want
+	}{
+		"CollectResults": {
+			reason: "We should collect the dry run results of each publisher in chain order, without calling any backend.",
+			pc: func(t *testing.T) managed.PublisherChain {
+				return managed.PublisherChain{
+					NewSecretStoreConnectionPublisher(backendNotCalled{t: t}, []string{"a"}, WithSharedSecretStoreEntries()),
+					backendNotCalled{t: t},
+					NewSecretStoreConnectionPublisher(backendNotCalled{t: t}, nil),
+				}
+			},
+			want: want{
+				results: []managed.ConnectionDetails{
+					{"a": []byte("a")},
+					nil,
+					{"a": []byte("a"), "b": []byte("b")},
+				},
+			},
+		},
+		"PublisherError": {
+			reason: "We should return an error identifying the publisher that could not dry run.",
+			pc: func(t *testing.T) managed.PublisherChain {
+				return managed.PublisherChain{
+					NewSecretStoreConnectionPublisher(backendNotCalled{t: t}, nil),
+					NewSecretStoreConnectionPublisherWithRemap(backendNotCalled{t: t}, nil, map[string]string{"a": "b"}),
+				}
+			},
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtRemapCollision, "a", "b", "b"), errFmtDryRunPublisher, 1),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			results, err := DryRunPublisherChain(context.Background(), tc.pc(t), publishing, managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nDryRunPublisherChain(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.results, results); diff != "" {
+				t.Errorf("\n%s\nDryRunPublisherChain(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
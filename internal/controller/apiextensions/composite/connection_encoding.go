@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtDecodeBase64 = "cannot decode base64 encoded connection detail %q"
+	errFmtEncoding     = "unknown connection detail encoding %q"
+)
+
+// A ConnectionDetailsEncoding declares how a source encodes connection detail
+// values.
+type ConnectionDetailsEncoding string
+
+// Connection details encodings.
+const (
+	// ConnectionDetailsEncodingNone indicates that values are already
+	// decoded. This is true of Kubernetes Secret data read using the Go
+	// client, which decodes it.
+	ConnectionDetailsEncodingNone ConnectionDetailsEncoding = "None"
+
+	// ConnectionDetailsEncodingBase64 indicates that values are base64
+	// encoded strings, for example base64 stored in a ConfigMap.
+	ConnectionDetailsEncodingBase64 ConnectionDetailsEncoding = "Base64"
+)
+
+// A DecodingConnectionDetailsFetcher normalizes the connection details fetched
+// by another fetcher so that they're always decoded bytes, per the encoding
+// declared for that fetcher. This allows sources that encode their values
+// differently to be chained without double encoding or decoding them.
+type DecodingConnectionDetailsFetcher struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	encoding ConnectionDetailsEncoding
+}
+
+// NewDecodingConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// decodes the connection details fetched by the supplied fetcher, which are
+// encoded using the supplied encoding.
+func NewDecodingConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, e ConnectionDetailsEncoding) (*DecodingConnectionDetailsFetcher, error) {
+	switch e {
+	case ConnectionDetailsEncodingNone, ConnectionDetailsEncodingBase64:
+	default:
+		return nil, errors.Errorf(errFmtEncoding, e)
+	}
+	return &DecodingConnectionDetailsFetcher{fetcher: f, encoding: e}, nil
+}
+
+// FetchConnection details of the supplied composed resource, decoding them if
+// necessary.
+func (f *DecodingConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || f.encoding == ConnectionDetailsEncodingNone {
+		return conn, err
+	}
+
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		d := make([]byte, base64.StdEncoding.DecodedLen(len(v)))
+		n, err := base64.StdEncoding.Decode(d, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtDecodeBase64, k)
+		}
+		out[k] = d[:n]
+	}
+	return out, nil
+}
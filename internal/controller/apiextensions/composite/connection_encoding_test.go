@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+var _ managed.ConnectionDetailsFetcher = &DecodingConnectionDetailsFetcher{}
+
+func TestDecodingConnectionDetailsFetcher(t *testing.T) {
+	// A value that would be mangled if it were decoded or encoded twice.
+	raw := []byte("p@ss/word+==")
+	encoded := []byte(base64.StdEncoding.EncodeToString(raw))
+
+	source := func(c managed.ConnectionDetails) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return c, nil
+		})
+	}
+
+	type want struct {
+		conn managed.ConnectionDetails
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		fetcher func(t *testing.T) managed.ConnectionDetailsFetcher
+		want    want
+	}{
+		"Decoded": {
+			reason: "Values from a source that is already decoded (e.g. a Secret) should be returned as is.",
+			fetcher: func(t *testing.T) managed.ConnectionDetailsFetcher {
+				return mustDecoding(t, source(managed.ConnectionDetails{"password": raw}), ConnectionDetailsEncodingNone)
+			},
+			want: want{conn: managed.ConnectionDetails{"password": raw}},
+		},
+		"Base64": {
+			reason: "Values from a base64 encoded source (e.g. a ConfigMap) should be decoded.",
+			fetcher: func(t *testing.T) managed.ConnectionDetailsFetcher {
+				return mustDecoding(t, source(managed.ConnectionDetails{"password": encoded}), ConnectionDetailsEncodingBase64)
+			},
+			want: want{conn: managed.ConnectionDetails{"password": raw}},
+		},
+		"MixedSources": {
+			reason: "Chained sources with different encodings should produce identical decoded bytes.",
+			fetcher: func(t *testing.T) managed.ConnectionDetailsFetcher {
+				return ConnectionDetailsFetcherChain{
+					mustDecoding(t, source(managed.ConnectionDetails{"secret": raw}), ConnectionDetailsEncodingNone),
+					mustDecoding(t, source(managed.ConnectionDetails{"configmap": encoded}), ConnectionDetailsEncodingBase64),
+				}
+			},
+			want: want{conn: managed.ConnectionDetails{"secret": raw, "configmap": raw}},
+		},
+		"InvalidBase64": {
+			reason: "We should return an error if a value of a base64 encoded source is not valid base64.",
+			fetcher: func(t *testing.T) managed.ConnectionDetailsFetcher {
+				return mustDecoding(t, source(managed.ConnectionDetails{"password": raw}), ConnectionDetailsEncodingBase64)
+			},
+			want: want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := tc.fetcher(t).FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+
+	t.Run("UnknownEncoding", func(t *testing.T) {
+		if _, err := NewDecodingConnectionDetailsFetcher(source(nil), "Hex"); err == nil {
+			t.Errorf("NewDecodingConnectionDetailsFetcher(...): want error for unknown encoding")
+		}
+	})
+}
+
+func mustDecoding(t *testing.T, f managed.ConnectionDetailsFetcher, e ConnectionDetailsEncoding) managed.ConnectionDetailsFetcher {
+	t.Helper()
+	d, err := NewDecodingConnectionDetailsFetcher(f, e)
+	if err != nil {
+		t.Fatalf("NewDecodingConnectionDetailsFetcher(...): %s", err)
+	}
+	return d
+}
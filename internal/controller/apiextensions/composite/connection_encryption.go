@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtEncryptValue = "cannot encrypt connection detail %q"
+	errFmtDecryptValue = "cannot decrypt connection detail %q"
+)
+
+// EncryptedValuePrefix prefixes connection detail values that were encrypted
+// by an EncryptingConnectionPublisher. Values without it are treated as
+// plaintext, so that secrets published before encryption was enabled remain
+// readable.
+const EncryptedValuePrefix = "xp-encrypted:v1:"
+
+// An Encrypter symmetrically encrypts and decrypts connection detail values.
+// Key management is the responsibility of the Encrypter.
+type Encrypter interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// EncrypterFns is a set of functions that satisfy the Encrypter interface.
+type EncrypterFns struct {
+	EncryptFn func(ctx context.Context, plaintext []byte) ([]byte, error)
+	DecryptFn func(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Encrypt the supplied plaintext.
+func (fn EncrypterFns) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return fn.EncryptFn(ctx, plaintext)
+}
+
+// Decrypt the supplied ciphertext.
+func (fn EncrypterFns) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return fn.DecryptFn(ctx, ciphertext)
+}
+
+// An EncryptingConnectionPublisher encrypts connection detail values before
+// they're published, so that plaintext is never written to the secret store.
+type EncryptingConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	encrypter Encrypter
+}
+
+// NewEncryptingConnectionPublisher returns a ConnectionPublisher that
+// encrypts connection detail values using the supplied Encrypter, then
+// publishes them using the supplied publisher.
+func NewEncryptingConnectionPublisher(p managed.ConnectionPublisher, e Encrypter) *EncryptingConnectionPublisher {
+	return &EncryptingConnectionPublisher{publisher: p, encrypter: e}
+}
+
+// PublishConnection encrypts and publishes the supplied connection details.
+// Note that if the Encrypter is not deterministic (e.g. it uses a random
+// nonce) the published connection details will change each time they're
+// published.
+func (p *EncryptingConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	out := make(managed.ConnectionDetails, len(c))
+	for k, v := range c {
+		ct, err := p.encrypter.Encrypt(ctx, v)
+		if err != nil {
+			return false, errors.Wrapf(err, errFmtEncryptValue, k)
+		}
+		out[k] = append([]byte(EncryptedValuePrefix), ct...)
+	}
+	return p.publisher.PublishConnection(ctx, o, out)
+}
+
+// UnpublishConnection details for the supplied resource. Values are not
+// encrypted, since they're only used to identify what to unpublish.
+func (p *EncryptingConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return p.publisher.UnpublishConnection(ctx, o, c)
+}
+
+// A DecryptingConnectionDetailsFetcher decrypts connection detail values that
+// were encrypted by an EncryptingConnectionPublisher. Values that weren't are
+// returned unchanged.
+type DecryptingConnectionDetailsFetcher struct {
+	fetcher   managed.ConnectionDetailsFetcher
+	encrypter Encrypter
+}
+
+// NewDecryptingConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that decrypts the connection details fetched by the supplied fetcher using
+// the supplied Encrypter.
+func NewDecryptingConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, e Encrypter) *DecryptingConnectionDetailsFetcher {
+	return &DecryptingConnectionDetailsFetcher{fetcher: f, encrypter: e}
+}
+
+// FetchConnection details of the supplied composed resource, decrypting any
+// encrypted values.
+func (f *DecryptingConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || conn == nil {
+		return conn, err
+	}
+
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		if !bytes.HasPrefix(v, []byte(EncryptedValuePrefix)) {
+			out[k] = v
+			continue
+		}
+		pt, err := f.encrypter.Decrypt(ctx, bytes.TrimPrefix(v, []byte(EncryptedValuePrefix)))
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtDecryptValue, k)
+		}
+		out[k] = pt
+	}
+	return out, nil
+}
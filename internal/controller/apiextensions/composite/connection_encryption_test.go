@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var (
+	_ managed.ConnectionPublisher      = &EncryptingConnectionPublisher{}
+	_ managed.ConnectionDetailsFetcher = &DecryptingConnectionDetailsFetcher{}
+	_ Encrypter                        = EncrypterFns{}
+)
+
+// reverser is a toy Encrypter that reverses bytes.
+func reverser(err error) Encrypter {
+	rev := func(_ context.Context, in []byte) ([]byte, error) {
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(in))
+		for i := range in {
+			out[len(in)-1-i] = in[i]
+		}
+		return out, nil
+	}
+	return EncrypterFns{EncryptFn: rev, DecryptFn: rev}
+}
+
+func TestEncryptingConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		published managed.ConnectionDetails
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		e      Encrypter
+		want   want
+	}{
+		"EncryptError": {
+			reason: "We should not publish anything if we can't encrypt a value.",
+			e:      reverser(errBoom),
+			want: want{
+				err: errors.Wrapf(errBoom, errFmtEncryptValue, "password"),
+			},
+		},
+		"Encrypted": {
+			reason: "We should publish prefixed, encrypted values.",
+			e:      reverser(nil),
+			want: want{
+				published: managed.ConnectionDetails{"password": []byte(EncryptedValuePrefix + "terces")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var published managed.ConnectionDetails
+			p := NewEncryptingConnectionPublisher(managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+					published = c
+					return true, nil
+				},
+			}, tc.e)
+			_, err := p.PublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{"password": []byte("secret")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDecryptingConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type args struct {
+		e    Encrypter
+		conn managed.ConnectionDetails
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"DecryptError": {
+			reason: "We should return an error if we can't decrypt a value.",
+			args: args{
+				e:    reverser(errBoom),
+				conn: managed.ConnectionDetails{"password": []byte(EncryptedValuePrefix + "terces")},
+			},
+			want: want{
+				err: errors.Wrapf(errBoom, errFmtDecryptValue, "password"),
+			},
+		},
+		"Mixed": {
+			reason: "We should decrypt encrypted values, and return legacy plaintext values unchanged.",
+			args: args{
+				e: reverser(nil),
+				conn: managed.ConnectionDetails{
+					"password": []byte(EncryptedValuePrefix + "terces"),
+					"username": []byte("admin"),
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"password": []byte("secret"),
+					"username": []byte("admin"),
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewDecryptingConnectionDetailsFetcher(ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+				return tc.args.conn, nil
+			}), tc.args.e)
+			conn, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	store := managed.ConnectionDetails{}
+	p := NewEncryptingConnectionPublisher(managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+			for k, v := range c {
+				store[k] = v
+			}
+			return true, nil
+		},
+	}, reverser(nil))
+	f := NewDecryptingConnectionDetailsFetcher(ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		return store, nil
+	}), reverser(nil))
+
+	want := managed.ConnectionDetails{"password": []byte("secret")}
+	if _, err := p.PublishConnection(context.Background(), &fake.Composite{}, want); err != nil {
+		t.Fatalf("p.PublishConnection(...): %s", err)
+	}
+	if bytes.Contains(store["password"], []byte("secret")) {
+		t.Errorf("p.PublishConnection(...): published plaintext %q", store["password"])
+	}
+	got, err := f.FetchConnection(context.Background(), &fake.Composed{})
+	if err != nil {
+		t.Fatalf("f.FetchConnection(...): %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("f.FetchConnection(...): -want, +got:\n%s", diff)
+	}
+}
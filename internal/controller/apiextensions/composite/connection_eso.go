@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// LabelKeyExternalSecretsCompatible is the label an
+// ExternalSecretsConnectionPublisher adds to published connection secrets by
+// default. External Secrets Operator (ESO) ExternalSecrets and PushSecrets can
+// select secrets using it.
+const LabelKeyExternalSecretsCompatible = "crossplane.io/external-secrets-compatible"
+
+// An ExternalSecretsConnectionPublisherOption configures an
+// ExternalSecretsConnectionPublisher.
+type ExternalSecretsConnectionPublisherOption func(*ExternalSecretsConnectionPublisher)
+
+// WithExternalSecretsLabels configures the labels an
+// ExternalSecretsConnectionPublisher adds to published connection secrets. The
+// supplied labels replace the default labels.
+func WithExternalSecretsLabels(l map[string]string) ExternalSecretsConnectionPublisherOption {
+	return func(p *ExternalSecretsConnectionPublisher) {
+		p.labels = l
+	}
+}
+
+// WithExternalSecretsAnnotations configures the annotations an
+// ExternalSecretsConnectionPublisher adds to published connection secrets.
+func WithExternalSecretsAnnotations(a map[string]string) ExternalSecretsConnectionPublisherOption {
+	return func(p *ExternalSecretsConnectionPublisher) {
+		p.annotations = a
+	}
+}
+
+// An ExternalSecretsConnectionPublisher publishes connection details shaped so
+// that External Secrets Operator can consume or mirror them. It adds
+// configurable labels and annotations to published connection secrets, and
+// publishes them as Opaque secrets unless a type is explicitly configured.
+// Labels and annotations explicitly configured by the connection secret owner
+// take precedence. The owner itself is never modified.
+type ExternalSecretsConnectionPublisher struct {
+	publisher   managed.ConnectionPublisher
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// NewExternalSecretsConnectionPublisher returns a ConnectionPublisher that
+// publishes ESO compatible connection secrets using the supplied publisher.
+func NewExternalSecretsConnectionPublisher(p managed.ConnectionPublisher, o ...ExternalSecretsConnectionPublisherOption) *ExternalSecretsConnectionPublisher {
+	e := &ExternalSecretsConnectionPublisher{
+		publisher: p,
+		labels:    map[string]string{LabelKeyExternalSecretsCompatible: "true"},
+	}
+	for _, fn := range o {
+		fn(e)
+	}
+	return e
+}
+
+// PublishConnection details for the supplied resource.
+func (e *ExternalSecretsConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	return e.publisher.PublishConnection(ctx, e.shape(o), c)
+}
+
+// UnpublishConnection details for the supplied resource.
+func (e *ExternalSecretsConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return e.publisher.UnpublishConnection(ctx, e.shape(o), c)
+}
+
+func (e *ExternalSecretsConnectionPublisher) shape(o resource.ConnectionSecretOwner) resource.ConnectionSecretOwner {
+	if o.GetPublishConnectionDetailsTo() == nil {
+		return o
+	}
+
+	// We operate on a copy because we don't want to persist any of our
+	// labels or annotations to the connection secret owner.
+	so, ok := copyOwner(o)
+	if !ok {
+		return o
+	}
+	p := so.GetPublishConnectionDetailsTo()
+	if p.Metadata == nil {
+		p.Metadata = &xpv1.ConnectionSecretMetadata{}
+	}
+	p.Metadata.Labels = withDefaults(p.Metadata.Labels, e.labels)
+	p.Metadata.Annotations = withDefaults(p.Metadata.Annotations, e.annotations)
+	if p.Metadata.Type == nil {
+		t := corev1.SecretTypeOpaque
+		p.Metadata.Type = &t
+	}
+	so.SetPublishConnectionDetailsTo(p)
+	return so
+}
+
+// withDefaults returns the supplied map with any missing keys set to the
+// supplied defaults.
+func withDefaults(m, defaults map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m)+len(defaults))
+	for k, v := range defaults {
+		out[k] = v
+	}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
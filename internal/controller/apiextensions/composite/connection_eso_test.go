@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+var _ managed.ConnectionPublisher = &ExternalSecretsConnectionPublisher{}
+
+func TestExternalSecretsConnectionPublisher(t *testing.T) {
+	opaque := corev1.SecretTypeOpaque
+	tls := corev1.SecretTypeTLS
+
+	type args struct {
+		o    resource.ConnectionSecretOwner
+		opts []ExternalSecretsConnectionPublisherOption
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *xpv1.PublishConnectionDetailsTo
+	}{
+		"NotPublishing": {
+			reason: "We should not shape anything if the owner doesn't publish connection details.",
+			args: args{
+				o: &fake.Composite{},
+			},
+		},
+		"Defaults": {
+			reason: "We should add the default label and publish an Opaque secret.",
+			args: args{
+				o: &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}},
+			},
+			want: &xpv1.PublishConnectionDetailsTo{
+				Name: "cool",
+				Metadata: &xpv1.ConnectionSecretMetadata{
+					Labels: map[string]string{LabelKeyExternalSecretsCompatible: "true"},
+					Type:   &opaque,
+				},
+			},
+		},
+		"Configured": {
+			reason: "Configured labels and annotations should be added, without overriding the owner's metadata.",
+			args: args{
+				o: &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{
+					Name: "cool",
+					Metadata: &xpv1.ConnectionSecretMetadata{
+						Labels: map[string]string{"team": "a"},
+						Type:   &tls,
+					},
+				}}},
+				opts: []ExternalSecretsConnectionPublisherOption{
+					WithExternalSecretsLabels(map[string]string{"team": "b", "mirror": "true"}),
+					WithExternalSecretsAnnotations(map[string]string{"example.org/refresh": "1h"}),
+				},
+			},
+			want: &xpv1.PublishConnectionDetailsTo{
+				Name: "cool",
+				Metadata: &xpv1.ConnectionSecretMetadata{
+					Labels:      map[string]string{"team": "a", "mirror": "true"},
+					Annotations: map[string]string{"example.org/refresh": "1h"},
+					Type:        &tls,
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			original := tc.args.o.GetPublishConnectionDetailsTo().DeepCopy()
+
+			var got *xpv1.PublishConnectionDetailsTo
+			pub := managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, o resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+					got = o.GetPublishConnectionDetailsTo()
+					return true, nil
+				},
+			}
+			e := NewExternalSecretsConnectionPublisher(pub, tc.args.opts...)
+			if _, err := e.PublishConnection(context.Background(), tc.args.o, nil); err != nil {
+				t.Fatalf("e.PublishConnection(...): %s", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(original, tc.args.o.GetPublishConnectionDetailsTo()); diff != "" {
+				t.Errorf("\n%s\ne.PublishConnection(...): owner should not be modified: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A FilteredConnectionDetailsFetcher only returns the fetched connection
+// details its filter allows. It matches keys like a
+// SecretStoreConnectionPublisher; an empty filter allows all keys.
+type FilteredConnectionDetailsFetcher struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	filter   []string
+	exact    map[string]bool
+	patterns []func(key string) bool
+}
+
+// NewFilteredConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// returns the connection details fetched by the supplied fetcher whose keys
+// exactly match one of the supplied filter's keys.
+func NewFilteredConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, filter []string) *FilteredConnectionDetailsFetcher {
+	ff := &FilteredConnectionDetailsFetcher{fetcher: f, filter: filter, exact: make(map[string]bool, len(filter))}
+	for _, key := range filter {
+		ff.exact[key] = true
+	}
+	return ff
+}
+
+// NewFilteredConnectionDetailsFetcherWithFilterMode returns a
+// FilteredConnectionDetailsFetcher that matches connection detail keys against
+// its filter using the supplied mode. Patterns are compiled once, and an error
+// is returned if any are invalid.
+func NewFilteredConnectionDetailsFetcherWithFilterMode(f managed.ConnectionDetailsFetcher, filter []string, mode FilterMode) (*FilteredConnectionDetailsFetcher, error) {
+	ff := NewFilteredConnectionDetailsFetcher(f, filter)
+	if mode == FilterModeExact {
+		return ff, nil
+	}
+	patterns, err := compileFilterPatterns(filter, mode)
+	if err != nil {
+		return nil, err
+	}
+	ff.exact = nil
+	ff.patterns = patterns
+	return ff, nil
+}
+
+// FetchConnection details of the supplied composed resource, dropping any
+// that the filter doesn't allow.
+func (f *FilteredConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || len(f.filter) == 0 {
+		return conn, err
+	}
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		if f.allows(k) {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func (f *FilteredConnectionDetailsFetcher) allows(key string) bool {
+	if f.exact[key] {
+		return true
+	}
+	for _, match := range f.patterns {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
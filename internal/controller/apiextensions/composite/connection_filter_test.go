@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestFilteredConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	c := managed.ConnectionDetails{
+		"endpoint":        []byte("db"),
+		"port":            []byte("5432"),
+		"node-0-password": []byte("a"),
+		"node-1-password": []byte("b"),
+	}
+
+	type args struct {
+		filter []string
+		mode   FilterMode
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"EmptyFilter": {
+			reason: "An empty filter should allow all keys.",
+			args: args{
+				mode: FilterModeExact,
+			},
+			want: want{
+				conn: c,
+			},
+		},
+		"Exact": {
+			reason: "An exact filter should only allow keys with exactly the same name.",
+			args: args{
+				filter: []string{"endpoint", "port", "password"},
+				mode:   FilterModeExact,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"endpoint": []byte("db"), "port": []byte("5432")},
+			},
+		},
+		"Glob": {
+			reason: "A glob filter should allow keys that match any of its patterns.",
+			args: args{
+				filter: []string{"node-*-password", "port"},
+				mode:   FilterModeGlob,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"port": []byte("5432"), "node-0-password": []byte("a"), "node-1-password": []byte("b")},
+			},
+		},
+		"Regex": {
+			reason: "A regex filter should allow keys that entirely match any of its expressions.",
+			args: args{
+				filter: []string{`node-\d-password`, "end"},
+				mode:   FilterModeRegex,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"node-0-password": []byte("a"), "node-1-password": []byte("b")},
+			},
+		},
+		"InvalidPattern": {
+			reason: "We should return an error if a pattern is invalid.",
+			args: args{
+				filter: []string{"node-[-password"},
+				mode:   FilterModeGlob,
+			},
+			want: want{
+				err: errors.Wrapf(path.ErrBadPattern, errFmtInvalidFilterPattern, "node-[-password"),
+			},
+		},
+		"UnknownMode": {
+			reason: "We should return an error if the filter mode is unknown.",
+			args: args{
+				filter: []string{"port"},
+				mode:   FilterMode("Fuzzy"),
+			},
+			want: want{
+				err: errors.Errorf(errFmtUnknownFilterMode, "Fuzzy"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// The fetcher should filter keys exactly like the publisher.
+			s := &memoryConnectionStore{}
+			p, perr := NewSecretStoreConnectionPublisherWithFilterMode(s, tc.args.filter, tc.args.mode, WithSharedSecretStoreEntries())
+			if diff := cmp.Diff(tc.want.err, perr, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewSecretStoreConnectionPublisherWithFilterMode(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if perr == nil {
+				if _, err := p.PublishConnection(context.Background(), publishing, c); err != nil {
+					t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+				}
+			}
+
+			f := ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+				return c, nil
+			})
+			ff, err := NewFilteredConnectionDetailsFetcherWithFilterMode(f, tc.args.filter, tc.args.mode)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewFilteredConnectionDetailsFetcherWithFilterMode(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			conn, err := ff.FetchConnection(context.Background(), &fake.Composed{})
+			if err != nil {
+				t.Fatalf("\n%s\nFetchConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(s.data, conn, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want published, +got fetched:\n%s", tc.reason, diff)
+			}
+		})
+	}
+
+	t.Run("FetchError", func(t *testing.T) {
+		f := ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return nil, errBoom
+		})
+		_, err := NewFilteredConnectionDetailsFetcher(f, []string{"port"}).FetchConnection(context.Background(), &fake.Composed{})
+		if diff := cmp.Diff(errBoom, err, test.EquateErrors()); diff != "" {
+			t.Errorf("\nWe should return any error encountered fetching connection details.\nFetchConnection(...): -want error, +got error:\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errParseURLTemplate = "cannot parse connection details URL template"
+	errNewHTTPRequest   = "cannot create connection details HTTP request"
+	errAuthorizeHTTP    = "cannot authorize connection details HTTP request"
+	errGetHTTP          = "cannot get connection details from HTTP endpoint"
+	errReadHTTP         = "cannot read connection details HTTP response"
+	errDecodeHTTP       = "cannot decode connection details HTTP response as JSON"
+
+	errFmtHTTPStatus = "connection details HTTP endpoint returned status %d"
+
+	errGetBearerTokenSecret  = "cannot get bearer token secret"
+	errFmtBearerTokenMissing = "bearer token secret %s/%s has no key %q"
+)
+
+// DefaultHTTPConnectionDetailsTimeout is the default timeout for connection
+// details HTTP requests.
+const DefaultHTTPConnectionDetailsTimeout = 10 * time.Second
+
+// A retryableError is an error that is expected to be transient.
+type retryableError struct {
+	error
+}
+
+func (e retryableError) Unwrap() error { return e.error }
+
+// Retryable marks the supplied error as expected to be transient.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err}
+}
+
+// IsRetryable returns true if the supplied error is expected to be transient,
+// such that retrying the operation that produced it may succeed.
+func IsRetryable(err error) bool {
+	return errors.As(err, &retryableError{})
+}
+
+// An HTTPAuthorizer authorizes an HTTP request, for example by adding an
+// Authorization header.
+type HTTPAuthorizer func(ctx context.Context, req *http.Request) error
+
+// BearerTokenFromSecret returns an HTTPAuthorizer that authorizes requests
+// using a bearer token read from the supplied key of the supplied secret. The
+// secret is read for every request, so rotated tokens are used immediately.
+func BearerTokenFromSecret(c client.Reader, ref xpv1.SecretKeySelector) HTTPAuthorizer {
+	return func(ctx context.Context, req *http.Request) error {
+		s := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+			return errors.Wrap(err, errGetBearerTokenSecret)
+		}
+		token, ok := s.Data[ref.Key]
+		if !ok {
+			return errors.Errorf(errFmtBearerTokenMissing, ref.Namespace, ref.Name, ref.Key)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(bytes.TrimSpace(token)))
+		return nil
+	}
+}
+
+// An HTTPConnectionDetailsFetcherOption configures an
+// HTTPConnectionDetailsFetcher.
+type HTTPConnectionDetailsFetcherOption func(*HTTPConnectionDetailsFetcher)
+
+// WithHTTPClient configures the HTTP client used to fetch connection details,
+// for example to configure TLS.
+func WithHTTPClient(c *http.Client) HTTPConnectionDetailsFetcherOption {
+	return func(f *HTTPConnectionDetailsFetcher) {
+		f.client = c
+	}
+}
+
+// WithHTTPAuthorizer configures how connection details HTTP requests are
+// authorized.
+func WithHTTPAuthorizer(a HTTPAuthorizer) HTTPConnectionDetailsFetcherOption {
+	return func(f *HTTPConnectionDetailsFetcher) {
+		f.authorize = a
+	}
+}
+
+// WithHTTPTimeout configures the timeout for each connection details HTTP
+// request.
+func WithHTTPTimeout(d time.Duration) HTTPConnectionDetailsFetcherOption {
+	return func(f *HTTPConnectionDetailsFetcher) {
+		f.timeout = d
+	}
+}
+
+// An HTTPConnectionDetailsFetcher fetches connection details from an HTTP
+// endpoint, for composed resources that expose them that way rather than via
+// a connection secret.
+type HTTPConnectionDetailsFetcher struct {
+	client    *http.Client
+	url       *template.Template
+	fields    map[string]string
+	authorize HTTPAuthorizer
+	timeout   time.Duration
+}
+
+// NewHTTPConnectionDetailsFetcher returns a ConnectionDetailsFetcher that GETs
+// JSON from the supplied URL template and maps fields of it to connection
+// details. The URL template is a Go text/template that is executed against
+// the composed resource, e.g. https://{{ .status.atProvider.host }}/meta.
+// Fields maps connection detail keys to field paths within the JSON response.
+func NewHTTPConnectionDetailsFetcher(url string, fields map[string]string, o ...HTTPConnectionDetailsFetcherOption) (*HTTPConnectionDetailsFetcher, error) {
+	t, err := template.New("url").Option("missingkey=error").Parse(url)
+	if err != nil {
+		return nil, errors.Wrap(err, errParseURLTemplate)
+	}
+	f := &HTTPConnectionDetailsFetcher{
+		client:    http.DefaultClient,
+		url:       t,
+		fields:    fields,
+		authorize: func(_ context.Context, _ *http.Request) error { return nil },
+		timeout:   DefaultHTTPConnectionDetailsTimeout,
+	}
+	for _, fn := range o {
+		fn(f)
+	}
+	return f, nil
+}
+
+// FetchConnection details of the supplied composed resource from its HTTP
+// endpoint. Composed resources the URL template can't be rendered for (e.g.
+// because they don't yet have the fields it references) have no connection
+// details. Errors reaching the endpoint and non-2xx responses are retryable.
+func (f *HTTPConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, errors.Wrap(err, errPaveObject)
+	}
+	url := &bytes.Buffer{}
+	if err := f.url.Execute(url, obj); err != nil {
+		// We presume the composed resource doesn't yet have the fields our
+		// template requires, or isn't one this fetcher applies to.
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewHTTPRequest)
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := f.authorize(ctx, req); err != nil {
+		return nil, errors.Wrap(err, errAuthorizeHTTP)
+	}
+
+	rsp, err := f.client.Do(req)
+	if err != nil {
+		return nil, retryableError{errors.Wrap(err, errGetHTTP)}
+	}
+	defer rsp.Body.Close() //nolint:errcheck // Nothing useful to do with this error.
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return nil, retryableError{errors.Errorf(errFmtHTTPStatus, rsp.StatusCode)}
+	}
+
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, retryableError{errors.Wrap(err, errReadHTTP)}
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrap(err, errDecodeHTTP)
+	}
+
+	p := fieldpath.Pave(m)
+	conn := managed.ConnectionDetails{}
+	for key, path := range f.fields {
+		if s, err := p.GetString(path); err == nil {
+			conn[key] = []byte(s)
+			continue
+		}
+		// Like FromFieldPath connection details, we silently skip fields
+		// that don't exist. They may exist in future.
+		v, err := p.GetValue(path)
+		if err != nil {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		conn[key] = b
+	}
+	return conn, nil
+}
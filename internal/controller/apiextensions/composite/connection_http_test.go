@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &HTTPConnectionDetailsFetcher{}
+
+func TestHTTPConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/meta":
+			if r.Header.Get("Authorization") != "Bearer cool" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"endpoint":"db.example.org","port":5432,"auth":{"user":"admin"}}`))
+		case "/broken":
+			_, _ = w.Write([]byte(`{`))
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	authorized := WithHTTPAuthorizer(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer cool")
+		return nil
+	})
+
+	withURL := func(url string) resource.ConnectionSecretOwner {
+		cd := composed.New()
+		cd.SetAnnotations(map[string]string{"endpoint": url})
+		return cd
+	}
+	withEndpoint := func(path string) resource.ConnectionSecretOwner {
+		return withURL(srv.URL + path)
+	}
+
+	tokenSecret := &test.MockClient{MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+		obj.(*corev1.Secret).Data = map[string][]byte{"token": []byte("cool\n")}
+		return nil
+	})}
+
+	fields := map[string]string{
+		"endpoint": "endpoint",
+		"port":     "port",
+		"username": "auth.user",
+		"missing":  "nope",
+	}
+
+	type args struct {
+		o    resource.ConnectionSecretOwner
+		opts []HTTPConnectionDetailsFetcherOption
+	}
+	type want struct {
+		conn      managed.ConnectionDetails
+		err       bool
+		retryable bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"CannotRenderURL": {
+			reason: "We should return no connection details if the URL template can't be rendered for the composed resource.",
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{},
+		},
+		"AuthorizeError": {
+			reason: "We should return any error encountered authorizing the request.",
+			args: args{
+				o: withEndpoint("/meta"),
+				opts: []HTTPConnectionDetailsFetcherOption{WithHTTPAuthorizer(func(_ context.Context, _ *http.Request) error {
+					return errBoom
+				})},
+			},
+			want: want{err: true},
+		},
+		"Non2xx": {
+			reason: "We should return a retryable error if the endpoint returns a non-2xx status.",
+			args: args{
+				o: withEndpoint("/meta"),
+			},
+			want: want{err: true, retryable: true},
+		},
+		"Unreachable": {
+			reason: "We should return a retryable error if the endpoint can't be reached.",
+			args: args{
+				o:    withURL(unreachable.URL + "/meta"),
+				opts: []HTTPConnectionDetailsFetcherOption{authorized},
+			},
+			want: want{err: true, retryable: true},
+		},
+		"DeadlineExceeded": {
+			reason: "We should return a retryable error if the endpoint doesn't respond before the deadline.",
+			args: args{
+				o:    withEndpoint("/slow"),
+				opts: []HTTPConnectionDetailsFetcherOption{authorized, WithHTTPTimeout(10 * time.Millisecond)},
+			},
+			want: want{err: true, retryable: true},
+		},
+		"NotJSON": {
+			reason: "We should return a non-retryable error if the endpoint returns invalid JSON.",
+			args: args{
+				o:    withEndpoint("/broken"),
+				opts: []HTTPConnectionDetailsFetcherOption{authorized},
+			},
+			want: want{err: true},
+		},
+		"Success": {
+			reason: "We should map the configured JSON fields to connection details, skipping missing fields.",
+			args: args{
+				o:    withEndpoint("/meta"),
+				opts: []HTTPConnectionDetailsFetcherOption{authorized},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"endpoint": []byte("db.example.org"),
+					"port":     []byte("5432"),
+					"username": []byte("admin"),
+				},
+			},
+		},
+		"BearerTokenFromSecret": {
+			reason: "We should authorize requests using a bearer token read from a secret.",
+			args: args{
+				o: withEndpoint("/meta"),
+				opts: []HTTPConnectionDetailsFetcherOption{WithHTTPAuthorizer(BearerTokenFromSecret(tokenSecret, xpv1.SecretKeySelector{
+					SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "api-token"},
+					Key:             "token",
+				}))},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"endpoint": []byte("db.example.org"),
+					"port":     []byte("5432"),
+					"username": []byte("admin"),
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := NewHTTPConnectionDetailsFetcher("{{ .metadata.annotations.endpoint }}", fields, tc.args.opts...)
+			if err != nil {
+				t.Fatalf("NewHTTPConnectionDetailsFetcher(...): %s", err)
+			}
+			conn, err := f.FetchConnection(context.Background(), tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.retryable, IsRetryable(err)); diff != "" {
+				t.Errorf("\n%s\nIsRetryable(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestBearerTokenFromSecret(t *testing.T) {
+	errBoom := errors.New("boom")
+	ref := xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "api-token"},
+		Key:             "token",
+	}
+
+	type want struct {
+		header string
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		c      client.Reader
+		want   want
+	}{
+		"GetSecretError": {
+			reason: "We should return any error encountered getting the secret.",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			want: want{
+				err: errors.Wrap(errBoom, errGetBearerTokenSecret),
+			},
+		},
+		"KeyMissing": {
+			reason: "We should return an error if the secret doesn't have the key.",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			want: want{
+				err: errors.Errorf(errFmtBearerTokenMissing, "crossplane-system", "api-token", "token"),
+			},
+		},
+		"Success": {
+			reason: "We should set the Authorization header to the token, trimming surrounding whitespace.",
+			c: &test.MockClient{MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+				obj.(*corev1.Secret).Data = map[string][]byte{"token": []byte(" cool\n")}
+				return nil
+			})},
+			want: want{
+				header: "Bearer cool",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://example.org", nil)
+			err := BearerTokenFromSecret(tc.c, ref)(context.Background(), req)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nBearerTokenFromSecret(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.header, req.Header.Get("Authorization")); diff != "" {
+				t.Errorf("\n%s\nBearerTokenFromSecret(...): -want header, +got header:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+// Error strings.
+const (
+	errFmtLockedKey = "layer %q cannot override connection detail %q, which is locked by layer %q"
+)
+
+// LayeredAssembler assembles connection details from ordered layers, for
+// example platform defaults, then composition values, then per-composite
+// overrides. Later layers override earlier layers per key, except for keys an
+// earlier layer locked. A layer locks a key only if it sets it. Attempting to
+// override a locked key returns an error identifying the key and the layer
+// that tried.
+func LayeredAssembler() Assembler {
+	return AssemblerFn(func(_ context.Context, layers []AssemblySource) (managed.ConnectionDetails, map[string]string, error) {
+		conn := managed.ConnectionDetails{}
+		sources := map[string]string{}
+		locked := map[string]string{}
+
+		for _, l := range layers {
+			for _, k := range OrderedKeys(l.ConnectionDetails, AlphabeticalKeyOrder()) {
+				if by, ok := locked[k]; ok {
+					return nil, nil, errors.Errorf(errFmtLockedKey, l.Name, k, by)
+				}
+				conn[k] = l.ConnectionDetails[k]
+				sources[k] = l.Name
+			}
+			for _, k := range l.Locked {
+				if _, ok := l.ConnectionDetails[k]; ok {
+					locked[k] = l.Name
+				}
+			}
+		}
+		return conn, sources, nil
+	})
+}
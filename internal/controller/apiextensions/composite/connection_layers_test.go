@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestLayeredAssembler(t *testing.T) {
+	platform := AssemblySource{
+		Name:              "platform",
+		ConnectionDetails: managed.ConnectionDetails{"region": []byte("us-east-1"), "port": []byte("5432"), "tls": []byte("required")},
+		Locked:            []string{"tls", "unset"},
+	}
+
+	type want struct {
+		conn    managed.ConnectionDetails
+		sources map[string]string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		layers []AssemblySource
+		want   want
+	}{
+		"Overridden": {
+			reason: "Later layers should override earlier layers per key.",
+			layers: []AssemblySource{
+				platform,
+				{Name: "composition", ConnectionDetails: managed.ConnectionDetails{"port": []byte("5433"), "unset": []byte("ok")}},
+				{Name: "composite", ConnectionDetails: managed.ConnectionDetails{"region": []byte("eu-west-1")}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"region": []byte("eu-west-1"),
+					"port":   []byte("5433"),
+					"tls":    []byte("required"),
+					"unset":  []byte("ok"),
+				},
+				sources: map[string]string{
+					"region": "composite",
+					"port":   "composition",
+					"tls":    "platform",
+					"unset":  "composition",
+				},
+			},
+		},
+		"LockedKey": {
+			reason: "We should return an error identifying the key and layer that tried to override a locked key.",
+			layers: []AssemblySource{
+				platform,
+				{Name: "composition", ConnectionDetails: managed.ConnectionDetails{"port": []byte("5433")}},
+				{Name: "composite", ConnectionDetails: managed.ConnectionDetails{"tls": []byte("disabled")}},
+			},
+			want: want{
+				err: errors.Errorf(errFmtLockedKey, "composite", "tls", "platform"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, sources, err := LayeredAssembler().Assemble(context.Background(), tc.layers)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.sources, sources); diff != "" {
+				t.Errorf("\n%s\nAssemble(...): -want sources, +got sources:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
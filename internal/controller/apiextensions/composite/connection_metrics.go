@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A PublishRecorder records the outcome of publishing the connection details
+// of a composite resource of the supplied kind.
+type PublishRecorder interface {
+	// RecordPublished records that publishing changed connection details.
+	RecordPublished(gvk schema.GroupVersionKind)
+
+	// RecordNoOp records that publishing changed nothing.
+	RecordNoOp(gvk schema.GroupVersionKind)
+
+	// RecordFilteredKeys records that the supplied number of keys were not
+	// published because a filter did not allow them.
+	RecordFilteredKeys(gvk schema.GroupVersionKind, n int)
+
+	// RecordPublishError records that publishing failed.
+	RecordPublishError(gvk schema.GroupVersionKind)
+}
+
+// A NopPublishRecorder does nothing.
+type NopPublishRecorder struct{}
+
+// RecordPublished does nothing.
+func (NopPublishRecorder) RecordPublished(_ schema.GroupVersionKind) {}
+
+// RecordNoOp does nothing.
+func (NopPublishRecorder) RecordNoOp(_ schema.GroupVersionKind) {}
+
+// RecordFilteredKeys does nothing.
+func (NopPublishRecorder) RecordFilteredKeys(_ schema.GroupVersionKind, _ int) {}
+
+// RecordPublishError does nothing.
+func (NopPublishRecorder) RecordPublishError(_ schema.GroupVersionKind) {}
+
+// PublishMetrics are Prometheus metrics that describe the outcome of
+// publishing connection details, labelled by composite resource kind.
+type PublishMetrics struct {
+	published *prometheus.CounterVec
+	noops     *prometheus.CounterVec
+	filtered  *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+}
+
+// NewPublishMetrics returns new connection details publish metrics. They must
+// be registered, for example with controller-runtime's metrics.Registry.
+func NewPublishMetrics() *PublishMetrics {
+	labels := []string{"group", "version", "kind"}
+	return &PublishMetrics{
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_published_total",
+			Help:      "The number of times publishing changed connection details.",
+		}, labels),
+		noops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_publish_noops_total",
+			Help:      "The number of times publishing connection details changed nothing.",
+		}, labels),
+		filtered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_filtered_keys_total",
+			Help:      "The number of connection detail keys that were not published because a filter did not allow them.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_publish_errors_total",
+			Help:      "The number of times publishing connection details failed.",
+		}, labels),
+	}
+}
+
+// RecordPublished records that publishing changed connection details.
+func (m *PublishMetrics) RecordPublished(gvk schema.GroupVersionKind) {
+	m.published.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// RecordNoOp records that publishing changed nothing.
+func (m *PublishMetrics) RecordNoOp(gvk schema.GroupVersionKind) {
+	m.noops.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// RecordFilteredKeys records that the supplied number of keys were filtered.
+func (m *PublishMetrics) RecordFilteredKeys(gvk schema.GroupVersionKind, n int) {
+	m.filtered.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Add(float64(n))
+}
+
+// RecordPublishError records that publishing failed.
+func (m *PublishMetrics) RecordPublishError(gvk schema.GroupVersionKind) {
+	m.errors.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// Describe sends the metric descriptors to the supplied channel.
+func (m *PublishMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.published.Describe(ch)
+	m.noops.Describe(ch)
+	m.filtered.Describe(ch)
+	m.errors.Describe(ch)
+}
+
+// Collect sends the metrics to the supplied channel.
+func (m *PublishMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.published.Collect(ch)
+	m.noops.Collect(ch)
+	m.filtered.Collect(ch)
+	m.errors.Collect(ch)
+}
+
+// recordPublish records the outcome of a publish using the supplied recorder.
+func recordPublish(r PublishRecorder, o resource.ConnectionSecretOwner, published bool, err error) {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	switch {
+	case err != nil:
+		r.RecordPublishError(gvk)
+	case published:
+		r.RecordPublished(gvk)
+	default:
+		r.RecordNoOp(gvk)
+	}
+}
+
+// A MetricsConnectionPublisher records the outcome of publishing connection
+// details, for example using a chain of publishers.
+type MetricsConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	recorder  PublishRecorder
+}
+
+// NewMetricsConnectionPublisher returns a ConnectionPublisher that publishes
+// connection details using the supplied publisher, and records the outcome
+// using the supplied recorder.
+func NewMetricsConnectionPublisher(p managed.ConnectionPublisher, r PublishRecorder) *MetricsConnectionPublisher {
+	return &MetricsConnectionPublisher{publisher: p, recorder: r}
+}
+
+// PublishConnection details for the supplied resource, and record the
+// outcome.
+func (p *MetricsConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published, err := p.publisher.PublishConnection(ctx, o, c)
+	recordPublish(p.recorder, o, published, err)
+	return published, err
+}
+
+// UnpublishConnection details for the supplied resource.
+func (p *MetricsConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return p.publisher.UnpublishConnection(ctx, o, c)
+}
+
+// A FetchRecorder records the connection details fetched for resources of the
+// supplied kind.
+type FetchRecorder interface {
+	// RecordFetchedKeys records that the supplied number of connection detail
+	// keys were fetched.
+	RecordFetchedKeys(gvk schema.GroupVersionKind, n int)
+}
+
+// A NopFetchRecorder does nothing.
+type NopFetchRecorder struct{}
+
+// RecordFetchedKeys does nothing.
+func (NopFetchRecorder) RecordFetchedKeys(_ schema.GroupVersionKind, _ int) {}
+
+// FetchedKeysBuckets are the buckets of the fetched connection detail keys
+// histogram. Most resources have a handful of connection details, but some
+// have hundreds.
+var FetchedKeysBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200}
+
+// FetchMetrics are Prometheus metrics that describe the connection details
+// fetched, labelled by resource kind.
+type FetchMetrics struct {
+	keys *prometheus.HistogramVec
+}
+
+// NewFetchMetrics returns new connection details fetch metrics. They must be
+// registered, for example with controller-runtime's metrics.Registry.
+func NewFetchMetrics() *FetchMetrics {
+	return &FetchMetrics{
+		keys: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_fetched_keys",
+			Help:      "The number of connection detail keys fetched for a resource.",
+			Buckets:   FetchedKeysBuckets,
+		}, []string{"group", "version", "kind"}),
+	}
+}
+
+// RecordFetchedKeys records that the supplied number of keys were fetched.
+func (m *FetchMetrics) RecordFetchedKeys(gvk schema.GroupVersionKind, n int) {
+	m.keys.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Observe(float64(n))
+}
+
+// Describe sends the metric descriptors to the supplied channel.
+func (m *FetchMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.keys.Describe(ch)
+}
+
+// Collect sends the metrics to the supplied channel.
+func (m *FetchMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.keys.Collect(ch)
+}
+
+// A MetricsConnectionDetailsFetcher records how many connection detail keys
+// are fetched, for example using a chain of fetchers.
+type MetricsConnectionDetailsFetcher struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	recorder FetchRecorder
+}
+
+// NewMetricsConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// fetches connection details using the supplied fetcher, and records how many
+// keys it fetched using the supplied recorder.
+func NewMetricsConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, r FetchRecorder) *MetricsConnectionDetailsFetcher {
+	return &MetricsConnectionDetailsFetcher{fetcher: f, recorder: r}
+}
+
+// FetchConnection details of the supplied resource, and record how many keys
+// were fetched. Nothing is recorded if fetching fails.
+func (f *MetricsConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		return conn, err
+	}
+	f.recorder.RecordFetchedKeys(o.GetObjectKind().GroupVersionKind(), len(conn))
+	return conn, nil
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+)
+
+var (
+	_ prometheus.Collector        = &PublishMetrics{}
+	_ PublishRecorder             = &PublishMetrics{}
+	_ PublishRecorder             = NopPublishRecorder{}
+	_ managed.ConnectionPublisher = &MetricsConnectionPublisher{}
+
+	_ prometheus.Collector             = &FetchMetrics{}
+	_ FetchRecorder                    = &FetchMetrics{}
+	_ FetchRecorder                    = NopFetchRecorder{}
+	_ managed.ConnectionDetailsFetcher = &MetricsConnectionDetailsFetcher{}
+)
+
+func TestPublishMetrics(t *testing.T) {
+	errBoom := errors.New("boom")
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "XDatabase"}
+
+	xr := composite.New(composite.WithGroupVersionKind(gvk))
+	xr.SetPublishConnectionDetailsTo(&xpv1.PublishConnectionDetailsTo{Name: "cool"})
+
+	type publish struct {
+		c   managed.ConnectionDetails
+		err error
+	}
+	type args struct {
+		filter    []string
+		publishes []publish
+	}
+	type want struct {
+		published float64
+		noops     float64
+		filtered  float64
+		errors    float64
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"PublishedThenNoOp": {
+			reason: "We should count publishes that change connection details, and those that don't.",
+			args: args{
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a")}},
+					{c: managed.ConnectionDetails{"a": []byte("a")}},
+				},
+			},
+			want: want{published: 1, noops: 1},
+		},
+		"Filtered": {
+			reason: "We should count the keys the filter doesn't allow.",
+			args: args{
+				filter: []string{"a"},
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b"), "c": []byte("c")}},
+				},
+			},
+			want: want{published: 1, filtered: 2},
+		},
+		"Error": {
+			reason: "We should count publish errors.",
+			args: args{
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a")}, err: errBoom},
+				},
+			},
+			want: want{errors: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := NewPublishMetrics()
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisher(s, tc.args.filter, WithPublishRecorder(m), WithSharedSecretStoreEntries())
+			for _, pub := range tc.args.publishes {
+				s.errPublish = pub.err
+				_, _ = p.PublishConnection(context.Background(), xr, pub.c)
+			}
+
+			for metric, want := range map[string]struct {
+				want float64
+				c    *prometheus.CounterVec
+			}{
+				"published": {tc.want.published, m.published},
+				"noops":     {tc.want.noops, m.noops},
+				"filtered":  {tc.want.filtered, m.filtered},
+				"errors":    {tc.want.errors, m.errors},
+			} {
+				if diff := cmp.Diff(want.want, testutil.ToFloat64(want.c.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind))); diff != "" {
+					t.Errorf("\n%s\nPublishConnection(...): -want %s, +got %s:\n%s", tc.reason, metric, metric, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsConnectionPublisher(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "XDatabase"}
+	xr := composite.New(composite.WithGroupVersionKind(gvk))
+
+	m := NewPublishMetrics()
+	published := true
+	p := NewMetricsConnectionPublisher(managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			return published, nil
+		},
+	}, m)
+
+	_, _ = p.PublishConnection(context.Background(), xr, nil)
+	published = false
+	_, _ = p.PublishConnection(context.Background(), xr, nil)
+
+	if diff := cmp.Diff(float64(1), testutil.ToFloat64(m.published.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind))); diff != "" {
+		t.Errorf("PublishConnection(...): -want published, +got published:\n%s", diff)
+	}
+	if diff := cmp.Diff(float64(1), testutil.ToFloat64(m.noops.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind))); diff != "" {
+		t.Errorf("PublishConnection(...): -want noops, +got noops:\n%s", diff)
+	}
+}
+
+// A fetchRecorder records the number of fetched keys.
+type fetchRecorder struct {
+	keys []int
+}
+
+func (r *fetchRecorder) RecordFetchedKeys(_ schema.GroupVersionKind, n int) {
+	r.keys = append(r.keys, n)
+}
+
+func TestMetricsConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "XDatabase"}
+	xr := composite.New(composite.WithGroupVersionKind(gvk))
+
+	type fetch struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		fetches []fetch
+		want    []int
+	}{
+		"OncePerFetch": {
+			reason: "We should record the number of keys fetched once per fetch.",
+			fetches: []fetch{
+				{conn: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")}},
+				{conn: managed.ConnectionDetails{"a": []byte("a")}},
+			},
+			want: []int{2, 1},
+		},
+		"NoConnectionDetails": {
+			reason:  "We should record fetches that return no connection details.",
+			fetches: []fetch{{}},
+			want:    []int{0},
+		},
+		"Error": {
+			reason:  "We should not record fetches that fail.",
+			fetches: []fetch{{err: errBoom}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &fetchRecorder{}
+			i := 0
+			f := NewMetricsConnectionDetailsFetcher(ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+				defer func() { i++ }()
+				return tc.fetches[i].conn, tc.fetches[i].err
+			}), r)
+			for range tc.fetches {
+				_, _ = f.FetchConnection(context.Background(), xr)
+			}
+			if diff := cmp.Diff(tc.want, r.keys); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want recorded keys, +got recorded keys:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFetchMetrics(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: "XDatabase"}
+
+	m := NewFetchMetrics()
+	m.RecordFetchedKeys(gvk, 3)
+	m.RecordFetchedKeys(gvk, 150)
+
+	want := `
+# HELP composite_connection_details_fetched_keys The number of connection detail keys fetched for a resource.
+# TYPE composite_connection_details_fetched_keys histogram
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="0"} 0
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="1"} 0
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="2"} 0
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="5"} 1
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="10"} 1
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="20"} 1
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="50"} 1
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="100"} 1
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="200"} 2
+composite_connection_details_fetched_keys_bucket{group="example.org",kind="XDatabase",version="v1",le="+Inf"} 2
+composite_connection_details_fetched_keys_sum{group="example.org",kind="XDatabase",version="v1"} 153
+composite_connection_details_fetched_keys_count{group="example.org",kind="XDatabase",version="v1"} 2
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(want)); err != nil {
+		t.Errorf("RecordFetchedKeys(...): %s", err)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errMigrateFetchSource   = "cannot fetch connection details from source store"
+	errMigrateFetchTarget   = "cannot fetch connection details from target store"
+	errMigratePublishTarget = "cannot publish connection details to target store"
+	errMigrateUnpublishSrc  = "cannot unpublish connection details from source store"
+	errFmtMigrateVerifyCopy = "connection details were not copied to target store: keys [%s] are missing or differ"
+)
+
+// A ConnectionDetailsStore can both publish and fetch connection details.
+type ConnectionDetailsStore interface {
+	managed.ConnectionPublisher
+	managed.ConnectionDetailsFetcher
+}
+
+// MigrateOptions configure how connection details are migrated.
+type MigrateOptions struct {
+	// UnpublishSource unpublishes the connection details from the source
+	// store once they've been verified to exist in the target store.
+	UnpublishSource bool
+}
+
+// A MigrateOption configures how connection details are migrated.
+type MigrateOption func(o *MigrateOptions)
+
+// WithUnpublishSource unpublishes connection details from the source store
+// once their copy to the target store has been verified.
+func WithUnpublishSource() MigrateOption {
+	return func(o *MigrateOptions) {
+		o.UnpublishSource = true
+	}
+}
+
+// A MigrationResult describes the outcome of a connection details migration.
+// It never contains connection detail values.
+type MigrationResult struct {
+	// Copied is the sorted set of keys that were written to the target store.
+	// Keys that were already present with identical values are not copied.
+	Copied []string
+
+	// Verified is the sorted set of keys that were verified to exist with
+	// identical values in the target store.
+	Verified []string
+
+	// Unpublished is true if connection details were unpublished from the
+	// source store.
+	Unpublished bool
+}
+
+// MigrateConnectionDetails copies the connection details of the supplied
+// owner from one store to another. The copy is verified by reading it back
+// from the target store before the source is (optionally) unpublished.
+//
+// Migration is idempotent and resumable: keys that already exist in the
+// target store with identical values are not rewritten, and a source with no
+// connection details (for example because a previous migration unpublished
+// it) is a no-op. Errors name keys, but never include their values.
+func MigrateConnectionDetails(ctx context.Context, o resource.ConnectionSecretOwner, from managed.ConnectionDetailsFetcher, to ConnectionDetailsStore, opts ...MigrateOption) (MigrationResult, error) {
+	mo := &MigrateOptions{}
+	for _, fn := range opts {
+		fn(mo)
+	}
+
+	src, err := from.FetchConnection(ctx, o)
+	if err != nil {
+		return MigrationResult{}, errors.Wrap(err, errMigrateFetchSource)
+	}
+	if len(src) == 0 {
+		return MigrationResult{}, nil
+	}
+
+	dst, err := to.FetchConnection(ctx, o)
+	if err != nil {
+		return MigrationResult{}, errors.Wrap(err, errMigrateFetchTarget)
+	}
+
+	res := MigrationResult{Copied: differingKeys(src, dst)}
+	if len(res.Copied) > 0 {
+		// Publishing is additive, so we only need to publish the keys that
+		// are missing or differ in the target store.
+		c := make(managed.ConnectionDetails, len(res.Copied))
+		for _, k := range res.Copied {
+			c[k] = src[k]
+		}
+		if _, err := to.PublishConnection(ctx, o, c); err != nil {
+			return MigrationResult{}, errors.Wrap(err, errMigratePublishTarget)
+		}
+	}
+
+	dst, err = to.FetchConnection(ctx, o)
+	if err != nil {
+		return MigrationResult{}, errors.Wrap(err, errMigrateFetchTarget)
+	}
+	if d := differingKeys(src, dst); len(d) > 0 {
+		return MigrationResult{}, errors.Errorf(errFmtMigrateVerifyCopy, strings.Join(d, ", "))
+	}
+	res.Verified = sortedKeys(src)
+
+	if !mo.UnpublishSource {
+		return res, nil
+	}
+	up, ok := from.(managed.ConnectionPublisher)
+	if !ok {
+		return res, nil
+	}
+	if err := up.UnpublishConnection(ctx, o, src); err != nil {
+		return res, errors.Wrap(err, errMigrateUnpublishSrc)
+	}
+	res.Unpublished = true
+	return res, nil
+}
+
+// differingKeys returns the sorted keys of want that are missing from, or have
+// a different value in, got.
+func differingKeys(want, got managed.ConnectionDetails) []string {
+	out := make([]string, 0)
+	for k, v := range want {
+		if gv, ok := got[k]; !ok || !bytes.Equal(v, gv) {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedKeys(c managed.ConnectionDetails) []string {
+	out := make([]string, 0, len(c))
+	for k := range c {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
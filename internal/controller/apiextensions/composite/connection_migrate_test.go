@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/types"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+// A memoryConnectionStore is a trivial in-memory ConnectionDetailsStore. It
+// also stores the metadata of the last publish.
+type memoryConnectionStore struct {
+	data        managed.ConnectionDetails
+	metadata    *xpv1.ConnectionSecretMetadata
+	publishes   int
+	dropWrites  bool
+	errFetch    error
+	errPublish  error
+	unpublished bool
+}
+
+func (s *memoryConnectionStore) FetchConnection(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if s.errFetch != nil {
+		return nil, s.errFetch
+	}
+	out := make(managed.ConnectionDetails, len(s.data))
+	for k, v := range s.data {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (s *memoryConnectionStore) FetchConnectionMetadata(_ context.Context, _ resource.ConnectionSecretOwner) (*xpv1.ConnectionSecretMetadata, error) {
+	if s.errFetch != nil {
+		return nil, s.errFetch
+	}
+	return s.metadata, nil
+}
+
+func (s *memoryConnectionStore) PublishConnection(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if s.errPublish != nil {
+		return false, s.errPublish
+	}
+	s.publishes++
+	if s.dropWrites {
+		return true, nil
+	}
+	if to := o.GetPublishConnectionDetailsTo(); to != nil && to.Metadata != nil {
+		s.metadata = to.Metadata.DeepCopy()
+	}
+	if s.data == nil {
+		s.data = managed.ConnectionDetails{}
+	}
+	for k, v := range c {
+		s.data[k] = v
+	}
+	return true, nil
+}
+
+// UnpublishConnection deletes the supplied keys, or all keys if none are
+// supplied, like a Kubernetes secret store.
+func (s *memoryConnectionStore) UnpublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	for k := range c {
+		delete(s.data, k)
+	}
+	if len(c) == 0 || len(s.data) == 0 {
+		s.data = nil
+	}
+	s.unpublished = true
+	return nil
+}
+
+// ownedBy returns connection secret metadata that records the supplied UID as
+// the owner of a store entry.
+func ownedBy(uid string) *xpv1.ConnectionSecretMetadata {
+	md := &xpv1.ConnectionSecretMetadata{}
+	md.SetOwnerUID(types.UID(uid))
+	return md
+}
+
+func TestMigrateConnectionDetails(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type args struct {
+		from *memoryConnectionStore
+		to   *memoryConnectionStore
+		opts []MigrateOption
+	}
+	type want struct {
+		res       MigrationResult
+		err       error
+		to        managed.ConnectionDetails
+		publishes int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FetchSourceError": {
+			reason: "We should return an error if we can't fetch from the source store.",
+			args: args{
+				from: &memoryConnectionStore{errFetch: errBoom},
+				to:   &memoryConnectionStore{},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errMigrateFetchSource),
+			},
+		},
+		"NothingToMigrate": {
+			reason: "We should do nothing if the source store has no connection details.",
+			args: args{
+				from: &memoryConnectionStore{},
+				to:   &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b")}},
+			},
+			want: want{
+				to: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"PublishTargetError": {
+			reason: "We should return an error if we can't publish to the target store.",
+			args: args{
+				from: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b")}},
+				to:   &memoryConnectionStore{errPublish: errBoom},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errMigratePublishTarget),
+			},
+		},
+		"VerifyFailed": {
+			reason: "We should return an error naming the keys (but not values) that failed to copy.",
+			args: args{
+				from: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("secret"), "b": []byte("secret")}},
+				to:   &memoryConnectionStore{dropWrites: true},
+			},
+			want: want{
+				err:       errors.Errorf(errFmtMigrateVerifyCopy, "a, b"),
+				publishes: 1,
+			},
+		},
+		"Resumed": {
+			reason: "We should only copy keys that are missing or differ in the target store.",
+			args: args{
+				from: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b"), "c": []byte("d"), "e": []byte("f")}},
+				to:   &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b"), "c": []byte("old")}},
+			},
+			want: want{
+				res: MigrationResult{
+					Copied:   []string{"c", "e"},
+					Verified: []string{"a", "c", "e"},
+				},
+				to:        managed.ConnectionDetails{"a": []byte("b"), "c": []byte("d"), "e": []byte("f")},
+				publishes: 1,
+			},
+		},
+		"AlreadyMigrated": {
+			reason: "We should not publish if the target store already has identical connection details.",
+			args: args{
+				from: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b")}},
+				to:   &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b")}},
+			},
+			want: want{
+				res: MigrationResult{
+					Copied:   []string{},
+					Verified: []string{"a"},
+				},
+				to: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"UnpublishSource": {
+			reason: "We should unpublish from the source store once the copy is verified, if asked to.",
+			args: args{
+				from: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("b")}},
+				to:   &memoryConnectionStore{},
+				opts: []MigrateOption{WithUnpublishSource()},
+			},
+			want: want{
+				res: MigrationResult{
+					Copied:      []string{"a"},
+					Verified:    []string{"a"},
+					Unpublished: true,
+				},
+				to:        managed.ConnectionDetails{"a": []byte("b")},
+				publishes: 1,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := MigrateConnectionDetails(context.Background(), &fake.Composed{}, tc.args.from, tc.args.to, tc.args.opts...)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nMigrateConnectionDetails(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.res, res); diff != "" {
+				t.Errorf("\n%s\nMigrateConnectionDetails(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.to, tc.args.to.data, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nMigrateConnectionDetails(...): -want target, +got target:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.publishes, tc.args.to.publishes); diff != "" {
+				t.Errorf("\n%s\nMigrateConnectionDetails(...): -want publishes, +got publishes:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.res.Unpublished, tc.args.from.unpublished); diff != "" {
+				t.Errorf("\n%s\nMigrateConnectionDetails(...): -want unpublished, +got unpublished:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errMultiPartPrefix  = "multi-part connection value must specify a key prefix"
+	errMultiPartKey     = "multi-part connection value must specify an output key"
+	errFmtMultiPartGaps = "multi-part connection value %q has unknown gap policy %q"
+	errFmtMultiPartGap  = "multi-part connection value %q is missing part %q"
+)
+
+// A MultiPartGapPolicy determines how gaps in the sequence of parts of a
+// multi-part connection value are handled.
+type MultiPartGapPolicy string
+
+// Multi-part gap policies.
+const (
+	// MultiPartGapPolicyError returns an error if a part is missing.
+	MultiPartGapPolicyError MultiPartGapPolicy = "Error"
+
+	// MultiPartGapPolicyIgnore concatenates the parts that are present.
+	MultiPartGapPolicyIgnore MultiPartGapPolicy = "Ignore"
+)
+
+// A MultiPartValue configures how a connection value that a provider splits
+// across numbered keys is reassembled. For example a value split across keys
+// chain-0, chain-1, and chain-2 has the prefix 'chain-'.
+type MultiPartValue struct {
+	// Prefix of the keys the value is split across. Each key is the prefix
+	// followed by a zero-based index.
+	Prefix string
+
+	// Key the reassembled value is written to.
+	Key string
+
+	// Gaps determines how gaps in the sequence of parts are handled. Gaps
+	// return an error by default.
+	Gaps MultiPartGapPolicy
+}
+
+// A MultiPartConnectionDetailsFetcher reassembles connection values that a
+// provider splits across numbered keys, for example to fit per-key size
+// limits. Parts are concatenated in index order, and replaced by a single
+// reassembled value.
+type MultiPartConnectionDetailsFetcher struct {
+	fetcher managed.ConnectionDetailsFetcher
+	values  []MultiPartValue
+}
+
+// NewMultiPartConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// reassembles the supplied multi-part values from the connection details
+// fetched by the supplied fetcher.
+func NewMultiPartConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, v ...MultiPartValue) (*MultiPartConnectionDetailsFetcher, error) {
+	values := make([]MultiPartValue, len(v))
+	for i, mp := range v {
+		if mp.Prefix == "" {
+			return nil, errors.New(errMultiPartPrefix)
+		}
+		if mp.Key == "" {
+			return nil, errors.New(errMultiPartKey)
+		}
+		switch mp.Gaps {
+		case "":
+			mp.Gaps = MultiPartGapPolicyError
+		case MultiPartGapPolicyError, MultiPartGapPolicyIgnore:
+		default:
+			return nil, errors.Errorf(errFmtMultiPartGaps, mp.Key, mp.Gaps)
+		}
+		values[i] = mp
+	}
+	return &MultiPartConnectionDetailsFetcher{fetcher: f, values: values}, nil
+}
+
+// FetchConnection details of the supplied composed resource, reassembling any
+// multi-part values.
+func (f *MultiPartConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || len(conn) == 0 || len(f.values) == 0 {
+		return conn, err
+	}
+
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		out[k] = v
+	}
+	for _, mp := range f.values {
+		if err := reassemble(out, mp); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// reassemble the supplied multi-part value in place. Keys that have the
+// value's prefix but aren't followed by a canonical index (e.g. chain-01) are
+// left untouched.
+func reassemble(conn managed.ConnectionDetails, mp MultiPartValue) error {
+	parts := map[int]string{}
+	for k := range conn {
+		if !strings.HasPrefix(k, mp.Prefix) {
+			continue
+		}
+		suffix := strings.TrimPrefix(k, mp.Prefix)
+		i, err := strconv.Atoi(suffix)
+		if err != nil || i < 0 || strconv.Itoa(i) != suffix {
+			continue
+		}
+		parts[i] = k
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	idx := make([]int, 0, len(parts))
+	for i := range parts {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	if mp.Gaps == MultiPartGapPolicyError {
+		for want, got := range idx {
+			if got != want {
+				return errors.Errorf(errFmtMultiPartGap, mp.Key, mp.Prefix+strconv.Itoa(want))
+			}
+		}
+	}
+
+	var v []byte
+	for _, i := range idx {
+		v = append(v, conn[parts[i]]...)
+		delete(conn, parts[i])
+	}
+	conn[mp.Key] = v
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &MultiPartConnectionDetailsFetcher{}
+
+func TestMultiPartConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	fetch := func(c managed.ConnectionDetails, err error) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return c, err
+		})
+	}
+
+	type args struct {
+		f managed.ConnectionDetailsFetcher
+		v []MultiPartValue
+	}
+	type want struct {
+		conn   managed.ConnectionDetails
+		err    error
+		newErr error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoPrefix": {
+			reason: "We should return an error if a multi-part value has no prefix.",
+			args: args{
+				v: []MultiPartValue{{Key: "chain"}},
+			},
+			want: want{newErr: errors.New(errMultiPartPrefix)},
+		},
+		"UnknownGapPolicy": {
+			reason: "We should return an error if a multi-part value has an unknown gap policy.",
+			args: args{
+				v: []MultiPartValue{{Prefix: "chain-", Key: "chain", Gaps: "Sometimes"}},
+			},
+			want: want{newErr: errors.Errorf(errFmtMultiPartGaps, "chain", "Sometimes")},
+		},
+		"FetchError": {
+			reason: "We should return errors encountered fetching connection details.",
+			args: args{
+				f: fetch(nil, errBoom),
+				v: []MultiPartValue{{Prefix: "chain-", Key: "chain"}},
+			},
+			want: want{err: errBoom},
+		},
+		"Reassembled": {
+			reason: "We should concatenate parts in index order and replace them with the reassembled value.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{
+					"chain-10":  []byte("k"),
+					"chain-2":   []byte("c"),
+					"chain-0":   []byte("a"),
+					"chain-1":   []byte("b"),
+					"chain-3":   []byte("d"),
+					"chain-4":   []byte("e"),
+					"chain-5":   []byte("f"),
+					"chain-6":   []byte("g"),
+					"chain-7":   []byte("h"),
+					"chain-8":   []byte("i"),
+					"chain-9":   []byte("j"),
+					"chain-ttl": []byte("60"),
+					"password":  []byte("secret"),
+				}, nil),
+				v: []MultiPartValue{{Prefix: "chain-", Key: "chain"}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"chain":     []byte("abcdefghijk"),
+					"chain-ttl": []byte("60"),
+					"password":  []byte("secret"),
+				},
+			},
+		},
+		"Gap": {
+			reason: "We should return an error if a part is missing.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"chain-0": []byte("a"), "chain-2": []byte("c")}, nil),
+				v: []MultiPartValue{{Prefix: "chain-", Key: "chain"}},
+			},
+			want: want{err: errors.Errorf(errFmtMultiPartGap, "chain", "chain-1")},
+		},
+		"GapIgnored": {
+			reason: "We should concatenate the parts that are present if gaps are ignored.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"chain-0": []byte("a"), "chain-2": []byte("c")}, nil),
+				v: []MultiPartValue{{Prefix: "chain-", Key: "chain", Gaps: MultiPartGapPolicyIgnore}},
+			},
+			want: want{conn: managed.ConnectionDetails{"chain": []byte("ac")}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := NewMultiPartConnectionDetailsFetcher(tc.args.f, tc.args.v...)
+			if diff := cmp.Diff(tc.want.newErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewMultiPartConnectionDetailsFetcher(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			got, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
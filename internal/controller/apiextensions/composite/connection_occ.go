@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errReadVersioned      = "cannot read versioned connection details"
+	errWriteVersioned     = "cannot write versioned connection details"
+	errFmtConflictRetries = "connection details were concurrently modified %d times"
+)
+
+// DefaultMaxConflictRetries is the default number of times an
+// OptimisticConnectionPublisher retries a write that conflicts with a
+// concurrent write.
+const DefaultMaxConflictRetries = 3
+
+// A conflictError indicates a write was rejected because its version
+// precondition was not met.
+type conflictError struct {
+	error
+}
+
+func (e conflictError) Unwrap() error { return e.error }
+
+// Conflict marks the supplied error as a concurrent modification conflict.
+// Conflicts are retryable.
+func Conflict(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{conflictError{err}}
+}
+
+// IsConflict returns true if the supplied error indicates that a write was
+// rejected because of a concurrent modification.
+func IsConflict(err error) bool {
+	return errors.As(err, &conflictError{}) || kerrors.IsConflict(err)
+}
+
+// A VersionedConnectionStore is a secret store that supports optimistic
+// concurrency control, for example using resource versions or ETags.
+type VersionedConnectionStore interface {
+	// ReadVersioned returns the published connection details of the
+	// supplied owner, and their current version.
+	ReadVersioned(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, string, error)
+
+	// WriteVersioned writes the supplied connection details for the
+	// supplied owner, only if their current version matches the supplied
+	// version. It returns an error that satisfies IsConflict if it doesn't.
+	WriteVersioned(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails, version string) error
+}
+
+// An OptimisticConnectionPublisherOption configures an
+// OptimisticConnectionPublisher.
+type OptimisticConnectionPublisherOption func(*OptimisticConnectionPublisher)
+
+// WithMaxConflictRetries configures how many times an
+// OptimisticConnectionPublisher retries a write that conflicts with a
+// concurrent write.
+func WithMaxConflictRetries(n int) OptimisticConnectionPublisherOption {
+	return func(p *OptimisticConnectionPublisher) {
+		p.maxRetries = n
+	}
+}
+
+// An OptimisticConnectionPublisher publishes connection details to a store
+// that other controllers or processes may also write to, without losing their
+// updates. It reads the current connection details and their version, merges
+// in the connection details to be published, then writes them only if the
+// version is unchanged. If another writer wins it re-reads, re-merges, and
+// retries. Stores that don't support versioning are published to on a best
+// effort basis.
+type OptimisticConnectionPublisher struct {
+	publisher  managed.ConnectionPublisher
+	maxRetries int
+}
+
+// NewOptimisticConnectionPublisher returns a ConnectionPublisher that
+// publishes using optimistic concurrency control if the supplied publisher is
+// a VersionedConnectionStore.
+func NewOptimisticConnectionPublisher(p managed.ConnectionPublisher, o ...OptimisticConnectionPublisherOption) *OptimisticConnectionPublisher {
+	op := &OptimisticConnectionPublisher{publisher: p, maxRetries: DefaultMaxConflictRetries}
+	for _, fn := range o {
+		fn(op)
+	}
+	return op
+}
+
+// PublishConnection details for the supplied resource. Publishing is additive;
+// existing keys that aren't being published are preserved. A retryable error
+// is returned if the write still conflicts after all retries.
+func (p *OptimisticConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	vs, ok := p.publisher.(VersionedConnectionStore)
+	if !ok {
+		return p.publisher.PublishConnection(ctx, o, c)
+	}
+
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		current, version, err := vs.ReadVersioned(ctx, o)
+		if err != nil {
+			return false, errors.Wrap(err, errReadVersioned)
+		}
+
+		merged := make(managed.ConnectionDetails, len(current)+len(c))
+		for k, v := range current {
+			merged[k] = v
+		}
+		changed := false
+		for k, v := range c {
+			if cv, ok := current[k]; !ok || !bytes.Equal(cv, v) {
+				changed = true
+			}
+			merged[k] = v
+		}
+		if !changed {
+			return false, nil
+		}
+
+		err = vs.WriteVersioned(ctx, o, merged, version)
+		if err == nil {
+			return true, nil
+		}
+		if !IsConflict(err) {
+			return false, errors.Wrap(err, errWriteVersioned)
+		}
+	}
+	return false, Conflict(errors.Errorf(errFmtConflictRetries, p.maxRetries+1))
+}
+
+// UnpublishConnection details for the supplied resource.
+func (p *OptimisticConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return p.publisher.UnpublishConnection(ctx, o, c)
+}
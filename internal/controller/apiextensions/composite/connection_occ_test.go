@@ -0,0 +1,194 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var (
+	_ managed.ConnectionPublisher = &OptimisticConnectionPublisher{}
+	_ VersionedConnectionStore    = &versionedStore{}
+)
+
+// A versionedStore is an in-memory VersionedConnectionStore. Each time it's
+// read, a concurrent writer adds a key until it has done so conflicts times.
+type versionedStore struct {
+	managed.ConnectionPublisherFns
+
+	data      managed.ConnectionDetails
+	version   int
+	conflicts int
+	writeErr  error
+}
+
+func (s *versionedStore) ReadVersioned(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, string, error) {
+	v := strconv.Itoa(s.version)
+	out := managed.ConnectionDetails{}
+	for k, val := range s.data {
+		out[k] = val
+	}
+	if s.conflicts > 0 {
+		s.conflicts--
+		s.data["concurrent-"+v] = []byte("x")
+		s.version++
+	}
+	return out, v, nil
+}
+
+func (s *versionedStore) WriteVersioned(_ context.Context, _ resource.ConnectionSecretOwner, c managed.ConnectionDetails, version string) error {
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	if version != strconv.Itoa(s.version) {
+		return Conflict(errors.New("version mismatch"))
+	}
+	s.data = c
+	s.version++
+	return nil
+}
+
+func TestOptimisticConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type args struct {
+		s    *versionedStore
+		c    managed.ConnectionDetails
+		opts []OptimisticConnectionPublisherOption
+	}
+	type want struct {
+		published bool
+		err       error
+		conflict  bool
+		data      managed.ConnectionDetails
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Unchanged": {
+			reason: "We should not write connection details that are already published.",
+			args: args{
+				s: &versionedStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"Published": {
+			reason: "We should merge connection details into those already published.",
+			args: args{
+				s: &versionedStore{data: managed.ConnectionDetails{"other": []byte("o")}},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				published: true,
+				data:      managed.ConnectionDetails{"a": []byte("a"), "other": []byte("o")},
+			},
+		},
+		"RetriedConflict": {
+			reason: "We should re-read and re-merge after a conflict, preserving concurrent writes.",
+			args: args{
+				s: &versionedStore{data: managed.ConnectionDetails{}, conflicts: 2},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				published: true,
+				data: managed.ConnectionDetails{
+					"a":            []byte("a"),
+					"concurrent-0": []byte("x"),
+					"concurrent-1": []byte("x"),
+				},
+			},
+		},
+		"TooManyConflicts": {
+			reason: "We should return a retryable conflict error once retries are exhausted.",
+			args: args{
+				s:    &versionedStore{data: managed.ConnectionDetails{}, conflicts: 10},
+				c:    managed.ConnectionDetails{"a": []byte("a")},
+				opts: []OptimisticConnectionPublisherOption{WithMaxConflictRetries(1)},
+			},
+			want: want{
+				err:      Conflict(errors.Errorf(errFmtConflictRetries, 2)),
+				conflict: true,
+				data: managed.ConnectionDetails{
+					"concurrent-0": []byte("x"),
+					"concurrent-1": []byte("x"),
+				},
+			},
+		},
+		"WriteError": {
+			reason: "We should not retry errors that aren't conflicts.",
+			args: args{
+				s: &versionedStore{data: managed.ConnectionDetails{}, writeErr: errBoom},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: want{
+				err:  errors.Wrap(errBoom, errWriteVersioned),
+				data: managed.ConnectionDetails{},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewOptimisticConnectionPublisher(tc.args.s, tc.args.opts...)
+			published, err := p.PublishConnection(context.Background(), &fake.Composite{}, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conflict, IsConflict(err) && IsRetryable(err)); diff != "" {
+				t.Errorf("\n%s\nIsConflict(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, tc.args.s.data); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want data, +got data:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestOptimisticConnectionPublisherBestEffort(t *testing.T) {
+	called := false
+	p := NewOptimisticConnectionPublisher(managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			called = true
+			return true, nil
+		},
+	})
+	if _, err := p.PublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{}); err != nil {
+		t.Fatalf("p.PublishConnection(...): %s", err)
+	}
+	if !called {
+		t.Errorf("p.PublishConnection(...): should fall back to publishing without a version precondition")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+// A KeyOrderer determines the order in which connection detail keys are
+// written, for secret stores that preserve order (e.g. stores that serialize
+// connection details). Kubernetes Secrets ignore order.
+type KeyOrderer interface {
+	// OrderKeys returns the supplied keys in the order they should be
+	// written. It must return each supplied key exactly once.
+	OrderKeys(keys []string) []string
+}
+
+// A KeyOrdererFn is a function that satisfies the KeyOrderer interface.
+type KeyOrdererFn func(keys []string) []string
+
+// OrderKeys returns the supplied keys in the order they should be written.
+func (fn KeyOrdererFn) OrderKeys(keys []string) []string {
+	return fn(keys)
+}
+
+// AlphabeticalKeyOrder orders connection detail keys alphabetically. It is the
+// default KeyOrderer.
+func AlphabeticalKeyOrder() KeyOrderer {
+	return KeyOrdererFn(func(keys []string) []string {
+		out := make([]string, len(keys))
+		copy(out, keys)
+		sort.Strings(out)
+		return out
+	})
+}
+
+// DeclarationKeyOrder orders connection detail keys in the order they're
+// declared by the supplied extract configs, i.e. as in the Composition. Keys
+// that aren't declared are ordered alphabetically after those that are.
+func DeclarationKeyOrder(cfgs []ConnectionDetailExtractConfig) KeyOrderer {
+	pos := make(map[string]int, len(cfgs))
+	for i, cfg := range cfgs {
+		if _, ok := pos[cfg.Name]; !ok {
+			pos[cfg.Name] = i
+		}
+	}
+	return KeyOrdererFn(func(keys []string) []string {
+		out := make([]string, len(keys))
+		copy(out, keys)
+		sort.SliceStable(out, func(i, j int) bool {
+			pi, iok := pos[out[i]]
+			pj, jok := pos[out[j]]
+			switch {
+			case iok && jok:
+				return pi < pj
+			case iok != jok:
+				return iok
+			default:
+				return out[i] < out[j]
+			}
+		})
+		return out
+	})
+}
+
+// OrderedKeys returns the keys of the supplied connection details in the order
+// determined by the supplied KeyOrderer. Keys are ordered alphabetically if the
+// KeyOrderer is nil.
+func OrderedKeys(c managed.ConnectionDetails, o KeyOrderer) []string {
+	if o == nil {
+		o = AlphabeticalKeyOrder()
+	}
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return o.OrderKeys(keys)
+}
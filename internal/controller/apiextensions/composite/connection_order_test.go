@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+func TestOrderedKeys(t *testing.T) {
+	conn := managed.ConnectionDetails{
+		"username": []byte("admin"),
+		"password": []byte("secret"),
+		"endpoint": []byte("db.example.org"),
+		"port":     []byte("5432"),
+	}
+
+	type args struct {
+		c managed.ConnectionDetails
+		o KeyOrderer
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []string
+	}{
+		"Default": {
+			reason: "Keys should be ordered alphabetically by default.",
+			args: args{
+				c: conn,
+			},
+			want: []string{"endpoint", "password", "port", "username"},
+		},
+		"Declaration": {
+			reason: "Keys should be ordered as declared, with undeclared keys ordered alphabetically after them.",
+			args: args{
+				c: conn,
+				o: DeclarationKeyOrder([]ConnectionDetailExtractConfig{
+					{Name: "username"},
+					{Name: "password"},
+					{Name: "unused"},
+				}),
+			},
+			want: []string{"username", "password", "endpoint", "port"},
+		},
+		"Custom": {
+			reason: "Custom KeyOrderers should be respected.",
+			args: args{
+				c: conn,
+				o: KeyOrdererFn(func(keys []string) []string {
+					out := AlphabeticalKeyOrder().OrderKeys(keys)
+					for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+						out[i], out[j] = out[j], out[i]
+					}
+					return out
+				}),
+			},
+			want: []string{"username", "port", "password", "endpoint"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := OrderedKeys(tc.args.c, tc.args.o)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nOrderedKeys(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sort"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errListStoreEntries = "cannot list secret store entries"
+	errListNotLister    = "secret store cannot list its entries"
+	errListShared       = "secret store entry ownership is not recorded when entries are shared"
+)
+
+// ErrUnsupported is returned when a secret store does not support an
+// operation. Use errors.Is to test for it.
+var ErrUnsupported = errors.New("operation is not supported by the secret store")
+
+// A ConnectionStoreLister is a secret store that can list its entries.
+type ConnectionStoreLister interface {
+	// ListConnectionMetadata returns the metadata of every entry in the
+	// store the supplied owner publishes to, keyed by entry identifier.
+	ListConnectionMetadata(ctx context.Context, o resource.ConnectionSecretOwner) (map[string]*xpv1.ConnectionSecretMetadata, error)
+}
+
+// ListOwned returns the identifiers of the SecretStore entries owned by the
+// supplied resource, in alphabetical order. Entries are owned by the resource
+// whose UID their metadata's owner label records, so entries are found even if the
+// resource has since been renamed or now publishes elsewhere. It returns an
+// error satisfying errors.Is(err, ErrUnsupported) if the underlying publisher
+// can't list its entries, or if ownership isn't recorded because entries are
+// shared.
+func (p *SecretStoreConnectionPublisher) ListOwned(ctx context.Context, o resource.ConnectionSecretOwner) ([]string, error) {
+	if p.shared {
+		return nil, errors.Wrap(ErrUnsupported, errListShared)
+	}
+	l, ok := p.publisher.(ConnectionStoreLister)
+	if !ok {
+		return nil, errors.Wrap(ErrUnsupported, errListNotLister)
+	}
+	entries, err := l.ListConnectionMetadata(ctx, o)
+	if err != nil {
+		return nil, errors.Wrap(err, errListStoreEntries)
+	}
+
+	var owned []string
+	for id, md := range entries {
+		if md != nil && md.GetOwnerUID() == string(o.GetUID()) {
+			owned = append(owned, id)
+		}
+	}
+	sort.Strings(owned)
+	return owned, nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ ConnectionStoreLister = &listingConnectionStore{}
+
+// listingConnectionStore is an in-memory secret store whose entries are named
+// for the name their owner publishes to.
+type listingConnectionStore struct {
+	entries  map[string]managed.ConnectionDetails
+	metadata map[string]*xpv1.ConnectionSecretMetadata
+	errList  error
+}
+
+func (s *listingConnectionStore) FetchConnection(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	return s.entries[o.GetPublishConnectionDetailsTo().Name], nil
+}
+
+func (s *listingConnectionStore) PublishConnection(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if s.entries == nil {
+		s.entries = map[string]managed.ConnectionDetails{}
+	}
+	name := o.GetPublishConnectionDetailsTo().Name
+	if s.entries[name] == nil {
+		s.entries[name] = managed.ConnectionDetails{}
+	}
+	for k, v := range c {
+		s.entries[name][k] = v
+	}
+	if s.metadata == nil {
+		s.metadata = map[string]*xpv1.ConnectionSecretMetadata{}
+	}
+	s.metadata[name] = o.GetPublishConnectionDetailsTo().Metadata.DeepCopy()
+	return true, nil
+}
+
+func (s *listingConnectionStore) UnpublishConnection(_ context.Context, o resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	delete(s.entries, o.GetPublishConnectionDetailsTo().Name)
+	delete(s.metadata, o.GetPublishConnectionDetailsTo().Name)
+	return nil
+}
+
+func (s *listingConnectionStore) ListConnectionMetadata(_ context.Context, _ resource.ConnectionSecretOwner) (map[string]*xpv1.ConnectionSecretMetadata, error) {
+	return s.metadata, s.errList
+}
+
+func TestSecretStoreConnectionPublisherListOwned(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	owner := func(uid, name string) *fake.Composite {
+		return &fake.Composite{
+			ObjectMeta:                   metav1.ObjectMeta{UID: types.UID(uid)},
+			ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: name}},
+		}
+	}
+
+	type args struct {
+		p       managed.ConnectionPublisher
+		o       []SecretStoreConnectionPublisherOption
+		publish []*fake.Composite
+		errList error
+	}
+	type want struct {
+		owned []string
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Owned": {
+			reason: "We should list every entry whose metadata records the owner's UID, including entries it published under a previous name.",
+			args: args{
+				publish: []*fake.Composite{
+					owner("cool-uid", "old-name"),
+					owner("other-uid", "other"),
+					owner("cool-uid", "new-name"),
+				},
+			},
+			want: want{
+				owned: []string{"new-name", "old-name"},
+			},
+		},
+		"NoneOwned": {
+			reason: "We should list nothing if the owner doesn't own any entries.",
+			args: args{
+				publish: []*fake.Composite{owner("other-uid", "other")},
+			},
+			want: want{},
+		},
+		"ListError": {
+			reason: "We should return any error encountered listing entries.",
+			args: args{
+				errList: errBoom,
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errListStoreEntries),
+			},
+		},
+		"NotLister": {
+			reason: "We should return ErrUnsupported if the store can't list its entries.",
+			args: args{
+				p: &memoryConnectionStore{},
+			},
+			want: want{
+				err: errors.Wrap(ErrUnsupported, errListNotLister),
+			},
+		},
+		"Shared": {
+			reason: "We should return ErrUnsupported if ownership isn't recorded because entries are shared.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries()},
+			},
+			want: want{
+				err: errors.Wrap(ErrUnsupported, errListShared),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &listingConnectionStore{errList: tc.args.errList}
+			var pub managed.ConnectionPublisher = s
+			if tc.args.p != nil {
+				pub = tc.args.p
+			}
+			p := NewSecretStoreConnectionPublisher(pub, nil, tc.args.o...)
+			for _, o := range tc.args.publish {
+				if _, err := p.PublishConnection(context.Background(), o, managed.ConnectionDetails{"a": []byte("b")}); err != nil {
+					t.Fatalf("PublishConnection(...): %v", err)
+				}
+			}
+
+			owned, err := p.ListOwned(context.Background(), owner("cool-uid", "new-name"))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nListOwned(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.owned, owned); diff != "" {
+				t.Errorf("\n%s\nListOwned(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if want, got := errors.Is(tc.want.err, ErrUnsupported), errors.Is(err, ErrUnsupported); got != want {
+				t.Errorf("\n%s\nerrors.Is(err, ErrUnsupported): want %t, got %t", tc.reason, want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtChainPublish   = "cannot publish connection details using publisher %d of chain"
+	errFmtChainUnpublish = "cannot unpublish connection details using publisher %d of chain"
+)
+
+// A ConnectionPublisherChainOption configures a ConnectionPublisherChain.
+type ConnectionPublisherChainOption func(*ConnectionPublisherChain)
+
+// WithConcurrentPublish configures a ConnectionPublisherChain to call its
+// publishers concurrently, calling at most the supplied number of publishers
+// at once. There is no limit if the supplied number is not positive.
+// Publishers are called serially, in order, by default.
+func WithConcurrentPublish(limit int) ConnectionPublisherChainOption {
+	return func(c *ConnectionPublisherChain) {
+		c.concurrent = true
+		c.limit = limit
+	}
+}
+
+// WithFailFastPublish configures a ConnectionPublisherChain to return the
+// first error any publisher returns. Publishers that haven't been called yet
+// won't be, and the context of any that are being called concurrently is
+// cancelled. By default every publisher is called, and their errors are
+// aggregated.
+func WithFailFastPublish() ConnectionPublisherChainOption {
+	return func(c *ConnectionPublisherChain) {
+		c.failFast = true
+	}
+}
+
+// A ConnectionPublisherChain chains multiple ConnectionPublishers. Unlike
+// managed.PublisherChain it can call its publishers concurrently, which is
+// faster when they publish to independent backends.
+type ConnectionPublisherChain struct {
+	publishers []managed.ConnectionPublisher
+	concurrent bool
+	limit      int
+	failFast   bool
+}
+
+// NewConnectionPublisherChain returns a ConnectionPublisherChain of the
+// supplied publishers.
+func NewConnectionPublisherChain(p []managed.ConnectionPublisher, o ...ConnectionPublisherChainOption) *ConnectionPublisherChain {
+	c := &ConnectionPublisherChain{publishers: p}
+	for _, fn := range o {
+		fn(c)
+	}
+	return c
+}
+
+// PublishConnection details using each publisher in the chain. It returns
+// true if any publisher published connection details.
+func (c *ConnectionPublisherChain) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, conn managed.ConnectionDetails) (bool, error) {
+	mu := sync.Mutex{}
+	published := false
+	err := c.each(ctx, errFmtChainPublish, func(ctx context.Context, p managed.ConnectionPublisher) error {
+		pb, err := p.PublishConnection(ctx, o, conn)
+		if pb {
+			mu.Lock()
+			published = true
+			mu.Unlock()
+		}
+		return err
+	})
+	return published, err
+}
+
+// UnpublishConnection details using each publisher in the chain.
+func (c *ConnectionPublisherChain) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, conn managed.ConnectionDetails) error {
+	return c.each(ctx, errFmtChainUnpublish, func(ctx context.Context, p managed.ConnectionPublisher) error {
+		return p.UnpublishConnection(ctx, o, conn)
+	})
+}
+
+// each calls the supplied function with each publisher in the chain. Errors
+// are wrapped using the supplied format, which must accept the index of the
+// publisher that returned the error.
+func (c *ConnectionPublisherChain) each(ctx context.Context, format string, fn func(ctx context.Context, p managed.ConnectionPublisher) error) error {
+	errs := make([]error, len(c.publishers))
+
+	if !c.concurrent {
+		for i, p := range c.publishers {
+			if err := fn(ctx, p); err != nil {
+				errs[i] = errors.Wrapf(err, format, i)
+				if c.failFast {
+					return errs[i]
+				}
+			}
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+
+	g := &errgroup.Group{}
+	gctx := ctx
+	if c.failFast {
+		g, gctx = errgroup.WithContext(ctx)
+	}
+	if c.limit > 0 {
+		g.SetLimit(c.limit)
+	}
+	for i, p := range c.publishers {
+		i, p := i, p
+		g.Go(func() error {
+			// Don't call publishers that are doomed to fail.
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(gctx, p); err != nil {
+				errs[i] = errors.Wrapf(err, format, i)
+				return errs[i]
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil && c.failFast {
+		return err
+	}
+	return utilerrors.NewAggregate(errs)
+}
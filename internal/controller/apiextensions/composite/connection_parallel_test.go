@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func publisher(published bool, err error) managed.ConnectionPublisher {
+	return managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			return published, err
+		},
+		UnpublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+			return err
+		},
+	}
+}
+
+func TestConnectionPublisherChain(t *testing.T) {
+	errBoom := errors.New("boom")
+	errOther := errors.New("other")
+
+	notCalled := managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			t.Errorf("PublishConnection(...): unexpected call to publisher after an error")
+			return false, nil
+		},
+		UnpublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+			t.Errorf("UnpublishConnection(...): unexpected call to publisher after an error")
+			return nil
+		},
+	}
+
+	// waitForCancel blocks until its context is cancelled, which fail-fast
+	// chains do when another publisher returns an error.
+	waitForCancel := managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(ctx context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(10 * time.Second):
+				return true, nil
+			}
+		},
+		UnpublishConnectionFn: func(ctx context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Second):
+				return nil
+			}
+		},
+	}
+
+	type want struct {
+		published    bool
+		err          error
+		unpublishErr error
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      []managed.ConnectionPublisher
+		o      []ConnectionPublisherChainOption
+		want   want
+	}{
+		"Empty": {
+			reason: "An empty chain should publish nothing.",
+			want: want{
+				published: false,
+			},
+		},
+		"SerialPublished": {
+			reason: "A serial chain should report that connection details were published if any publisher published them.",
+			p:      []managed.ConnectionPublisher{publisher(false, nil), publisher(true, nil), publisher(false, nil)},
+			want: want{
+				published: true,
+			},
+		},
+		"SerialAggregate": {
+			reason: "A serial chain should call every publisher and aggregate their errors.",
+			p:      []managed.ConnectionPublisher{publisher(false, errBoom), publisher(true, nil), publisher(false, errOther)},
+			want: want{
+				published:    true,
+				err:          utilerrors.NewAggregate([]error{errors.Wrapf(errBoom, errFmtChainPublish, 0), errors.Wrapf(errOther, errFmtChainPublish, 2)}),
+				unpublishErr: utilerrors.NewAggregate([]error{errors.Wrapf(errBoom, errFmtChainUnpublish, 0), errors.Wrapf(errOther, errFmtChainUnpublish, 2)}),
+			},
+		},
+		"SerialFailFast": {
+			reason: "A serial fail-fast chain should return the first error without calling later publishers.",
+			p:      []managed.ConnectionPublisher{publisher(true, nil), publisher(false, errBoom), notCalled},
+			o:      []ConnectionPublisherChainOption{WithFailFastPublish()},
+			want: want{
+				published:    true,
+				err:          errors.Wrapf(errBoom, errFmtChainPublish, 1),
+				unpublishErr: errors.Wrapf(errBoom, errFmtChainUnpublish, 1),
+			},
+		},
+		"ConcurrentPublished": {
+			reason: "A concurrent chain should report that connection details were published if any publisher published them.",
+			p:      []managed.ConnectionPublisher{publisher(false, nil), publisher(true, nil), publisher(false, nil)},
+			o:      []ConnectionPublisherChainOption{WithConcurrentPublish(2)},
+			want: want{
+				published: true,
+			},
+		},
+		"ConcurrentAggregate": {
+			reason: "A concurrent chain should call every publisher and aggregate their errors in chain order.",
+			p:      []managed.ConnectionPublisher{publisher(false, errBoom), publisher(true, nil), publisher(false, errOther)},
+			o:      []ConnectionPublisherChainOption{WithConcurrentPublish(0)},
+			want: want{
+				published:    true,
+				err:          utilerrors.NewAggregate([]error{errors.Wrapf(errBoom, errFmtChainPublish, 0), errors.Wrapf(errOther, errFmtChainPublish, 2)}),
+				unpublishErr: utilerrors.NewAggregate([]error{errors.Wrapf(errBoom, errFmtChainUnpublish, 0), errors.Wrapf(errOther, errFmtChainUnpublish, 2)}),
+			},
+		},
+		"ConcurrentFailFast": {
+			reason: "A concurrent fail-fast chain should return the first error and cancel the remaining publishers.",
+			p:      []managed.ConnectionPublisher{waitForCancel, publisher(false, errBoom)},
+			o:      []ConnectionPublisherChainOption{WithConcurrentPublish(0), WithFailFastPublish()},
+			want: want{
+				err:          errors.Wrapf(errBoom, errFmtChainPublish, 1),
+				unpublishErr: errors.Wrapf(errBoom, errFmtChainUnpublish, 1),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewConnectionPublisherChain(tc.p, tc.o...)
+			published, err := c.PublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			err = c.UnpublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{})
+			if diff := cmp.Diff(tc.want.unpublishErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionPublisherChainConcurrencyLimit(t *testing.T) {
+	mu := sync.Mutex{}
+	running, peak := 0, 0
+	p := managed.ConnectionPublisherFns{
+		PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return true, nil
+		},
+	}
+
+	c := NewConnectionPublisherChain([]managed.ConnectionPublisher{p, p, p, p, p}, WithConcurrentPublish(2))
+	if _, err := c.PublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{}); err != nil {
+		t.Errorf("PublishConnection(...): %s", err)
+	}
+	if peak > 2 {
+		t.Errorf("PublishConnection(...): want at most 2 concurrent publishes, got %d", peak)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtApplyKeyPolicies = "cannot apply access policies to connection detail keys: %s"
+)
+
+// A KeyPolicyApplier applies an access policy to a published connection detail
+// key, for example by translating it to a Vault ACL policy that governs the
+// path the key was written to. Policy names are opaque to Crossplane and
+// interpreted by the applier.
+type KeyPolicyApplier interface {
+	ApplyKeyPolicy(ctx context.Context, o resource.ConnectionSecretOwner, key, policy string) error
+}
+
+// A KeyPolicyApplierFn is a function that satisfies the KeyPolicyApplier
+// interface.
+type KeyPolicyApplierFn func(ctx context.Context, o resource.ConnectionSecretOwner, key, policy string) error
+
+// ApplyKeyPolicy applies the supplied access policy to the supplied key.
+func (fn KeyPolicyApplierFn) ApplyKeyPolicy(ctx context.Context, o resource.ConnectionSecretOwner, key, policy string) error {
+	return fn(ctx, o, key, policy)
+}
+
+// A KeyPolicyError reports the connection detail keys whose access policies
+// could not be applied.
+type KeyPolicyError struct {
+	// Keys maps each key to the error encountered applying its policy.
+	Keys map[string]error
+}
+
+// Error returns the keys whose policies could not be applied, in alphabetical
+// order.
+func (e *KeyPolicyError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for k := range e.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = fmt.Sprintf("%s: %s", k, e.Keys[k])
+	}
+	return fmt.Sprintf(errFmtApplyKeyPolicies, strings.Join(msgs, "; "))
+}
+
+// A KeyPolicyConnectionPublisherOption configures a
+// KeyPolicyConnectionPublisher.
+type KeyPolicyConnectionPublisherOption func(*KeyPolicyConnectionPublisher)
+
+// WithKeyPolicies configures the access policy applied to each connection
+// detail key.
+func WithKeyPolicies(p map[string]string) KeyPolicyConnectionPublisherOption {
+	return func(k *KeyPolicyConnectionPublisher) {
+		k.policies = p
+	}
+}
+
+// WithDefaultKeyPolicy configures the access policy applied to connection
+// detail keys that don't have an explicit policy. Keys without an explicit
+// policy have no policy applied if no default is configured.
+func WithDefaultKeyPolicy(p string) KeyPolicyConnectionPublisherOption {
+	return func(k *KeyPolicyConnectionPublisher) {
+		k.fallback = p
+	}
+}
+
+// A KeyPolicyConnectionPublisher applies per-key access policies to the
+// connection details it publishes, allowing a single published secret to
+// enforce differentiated access at the store level.
+type KeyPolicyConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	applier   KeyPolicyApplier
+	policies  map[string]string
+	fallback  string
+}
+
+// NewKeyPolicyConnectionPublisher returns a ConnectionPublisher that publishes
+// connection details using the supplied publisher, then applies their access
+// policies using the supplied applier.
+func NewKeyPolicyConnectionPublisher(p managed.ConnectionPublisher, a KeyPolicyApplier, o ...KeyPolicyConnectionPublisherOption) *KeyPolicyConnectionPublisher {
+	k := &KeyPolicyConnectionPublisher{publisher: p, applier: a}
+	for _, fn := range o {
+		fn(k)
+	}
+	return k
+}
+
+// PublishConnection details for the supplied resource, then apply the access
+// policy of each published key. Policies are applied on every publish, so that
+// policies that failed to apply are retried. A *KeyPolicyError is returned if
+// any policy could not be applied.
+func (k *KeyPolicyConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published, err := k.publisher.PublishConnection(ctx, o, c)
+	if err != nil {
+		return published, err
+	}
+
+	failed := map[string]error{}
+	for _, key := range OrderedKeys(c, AlphabeticalKeyOrder()) {
+		p, ok := k.policies[key]
+		if !ok {
+			p = k.fallback
+		}
+		if p == "" {
+			continue
+		}
+		if err := k.applier.ApplyKeyPolicy(ctx, o, key, p); err != nil {
+			failed[key] = err
+		}
+	}
+	if len(failed) > 0 {
+		return published, &KeyPolicyError{Keys: failed}
+	}
+	return published, nil
+}
+
+// UnpublishConnection details for the supplied resource.
+func (k *KeyPolicyConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return k.publisher.UnpublishConnection(ctx, o, c)
+}
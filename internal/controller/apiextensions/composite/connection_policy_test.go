@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &KeyPolicyConnectionPublisher{}
+
+func TestKeyPolicyConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+	conn := managed.ConnectionDetails{
+		"password": []byte("secret"),
+		"username": []byte("admin"),
+		"endpoint": []byte("db.example.org"),
+	}
+
+	publish := func(err error) managed.ConnectionPublisher {
+		return managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+				return err == nil, err
+			},
+		}
+	}
+
+	type args struct {
+		p    managed.ConnectionPublisher
+		fail map[string]bool
+		opts []KeyPolicyConnectionPublisherOption
+	}
+	type want struct {
+		applied map[string]string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"PublishError": {
+			reason: "We should not apply policies if publishing fails.",
+			args: args{
+				p:    publish(errBoom),
+				opts: []KeyPolicyConnectionPublisherOption{WithDefaultKeyPolicy("readers")},
+			},
+			want: want{
+				applied: map[string]string{},
+				err:     errBoom,
+			},
+		},
+		"NoPolicies": {
+			reason: "We should not apply policies if none are configured.",
+			args: args{
+				p: publish(nil),
+			},
+			want: want{
+				applied: map[string]string{},
+			},
+		},
+		"PerKeyAndDefault": {
+			reason: "We should apply explicit per-key policies, and the default policy to other keys.",
+			args: args{
+				p: publish(nil),
+				opts: []KeyPolicyConnectionPublisherOption{
+					WithKeyPolicies(map[string]string{"password": "admins"}),
+					WithDefaultKeyPolicy("readers"),
+				},
+			},
+			want: want{
+				applied: map[string]string{
+					"password": "admins",
+					"username": "readers",
+					"endpoint": "readers",
+				},
+			},
+		},
+		"ApplyErrors": {
+			reason: "We should report every key whose policy could not be applied.",
+			args: args{
+				p:    publish(nil),
+				fail: map[string]bool{"password": true, "username": true},
+				opts: []KeyPolicyConnectionPublisherOption{WithDefaultKeyPolicy("readers")},
+			},
+			want: want{
+				applied: map[string]string{"endpoint": "readers"},
+				err:     &KeyPolicyError{Keys: map[string]error{"password": errBoom, "username": errBoom}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			applied := map[string]string{}
+			a := KeyPolicyApplierFn(func(_ context.Context, _ resource.ConnectionSecretOwner, key, policy string) error {
+				if tc.args.fail[key] {
+					return errBoom
+				}
+				applied[key] = policy
+				return nil
+			})
+
+			k := NewKeyPolicyConnectionPublisher(tc.args.p, a, tc.args.opts...)
+			_, err := k.PublishConnection(context.Background(), &fake.Composite{}, conn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nk.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\n%s\nk.PublishConnection(...): -want applied, +got applied:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Pool defaults.
+const (
+	DefaultPoolMaxSize     = 64
+	DefaultPoolIdleTimeout = 10 * time.Minute
+)
+
+// A PooledConnectionDetailsFetcherOption configures a
+// PooledConnectionDetailsFetcher.
+type PooledConnectionDetailsFetcherOption func(*PooledConnectionDetailsFetcher)
+
+// WithPoolMaxSize bounds the number of store clients a
+// PooledConnectionDetailsFetcher keeps. The least recently used client is
+// evicted to make room for a new one.
+func WithPoolMaxSize(n int) PooledConnectionDetailsFetcherOption {
+	return func(f *PooledConnectionDetailsFetcher) {
+		f.max = n
+	}
+}
+
+// WithPoolIdleTimeout configures how long a PooledConnectionDetailsFetcher
+// keeps a store client that isn't used.
+func WithPoolIdleTimeout(d time.Duration) PooledConnectionDetailsFetcherOption {
+	return func(f *PooledConnectionDetailsFetcher) {
+		f.idle = d
+	}
+}
+
+// WithPoolCredentialsTTL configures how long a PooledConnectionDetailsFetcher
+// trusts credentials before resolving them again to detect rotation.
+func WithPoolCredentialsTTL(ttl time.Duration) PooledConnectionDetailsFetcherOption {
+	return func(f *PooledConnectionDetailsFetcher) {
+		f.ttl = ttl
+	}
+}
+
+type pooledClient struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	creds    []byte
+	checked  time.Time
+	lastUsed time.Time
+	borrowed int
+	evicted  bool
+}
+
+// A PooledConnectionDetailsFetcher fetches the connection details of each
+// composed resource using a store client scoped to the composed resource's
+// ProviderConfig. Store clients are created lazily, the first time a
+// ProviderConfig is used, and shared by all reconciles until they're evicted.
+// Clients are evicted when they're idle, when the pool is full, or when their
+// credentials rotate. Evicted clients that implement io.Closer are closed once
+// no fetch is using them. Composed resources without a ProviderConfig
+// reference have no connection details fetched.
+type PooledConnectionDetailsFetcher struct {
+	resolver  CredentialsResolver
+	newClient ScopedFetcherFn
+	max       int
+	idle      time.Duration
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+// NewPooledConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// resolves credentials using the supplied resolver, and fetches connection
+// details using pooled store clients created with them. It should be shared
+// by all reconciles.
+func NewPooledConnectionDetailsFetcher(r CredentialsResolver, fn ScopedFetcherFn, o ...PooledConnectionDetailsFetcherOption) *PooledConnectionDetailsFetcher {
+	f := &PooledConnectionDetailsFetcher{
+		resolver:  r,
+		newClient: fn,
+		max:       DefaultPoolMaxSize,
+		idle:      DefaultPoolIdleTimeout,
+		ttl:       DefaultCredentialsTTL,
+		now:       time.Now,
+		clients:   make(map[string]*pooledClient),
+	}
+	for _, fn := range o {
+		fn(f)
+	}
+	return f
+}
+
+// FetchConnection details of the supplied composed resource using a pooled
+// store client scoped to its ProviderConfig.
+func (f *PooledConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	pc, err := providerConfigName(o)
+	if err != nil {
+		return nil, err
+	}
+	if pc == "" {
+		return nil, nil
+	}
+	c, err := f.borrow(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+	defer f.release(c)
+	return c.fetcher.FetchConnection(ctx, o)
+}
+
+// Evict the store client of the supplied ProviderConfig, if any, for example
+// because its credentials are known to have rotated.
+func (f *PooledConnectionDetailsFetcher) Evict(pc string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.clients[pc]; ok {
+		f.evict(pc, c)
+	}
+}
+
+// Len returns the number of pooled store clients.
+func (f *PooledConnectionDetailsFetcher) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.clients)
+}
+
+func (f *PooledConnectionDetailsFetcher) borrow(ctx context.Context, pc string) (*pooledClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.evictIdle(now)
+
+	c, ok := f.clients[pc]
+	if ok && now.Sub(c.checked) < f.ttl {
+		c.borrowed++
+		c.lastUsed = now
+		return c, nil
+	}
+
+	creds, err := f.resolver.ResolveCredentials(ctx, pc)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtResolveCredentials, pc)
+	}
+	if ok && bytes.Equal(creds, c.creds) {
+		c.checked = now
+		c.borrowed++
+		c.lastUsed = now
+		return c, nil
+	}
+	if ok {
+		// The credentials rotated.
+		f.evict(pc, c)
+	}
+
+	sf, err := f.newClient(ctx, creds)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtScopedFetcher, pc)
+	}
+	if f.max > 0 && len(f.clients) >= f.max {
+		f.evictLeastRecentlyUsed()
+	}
+	c = &pooledClient{fetcher: sf, creds: creds, checked: now, lastUsed: now, borrowed: 1}
+	f.clients[pc] = c
+	return c, nil
+}
+
+func (f *PooledConnectionDetailsFetcher) release(c *pooledClient) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c.borrowed--
+	if c.evicted && c.borrowed == 0 {
+		closeClient(c)
+	}
+}
+
+func (f *PooledConnectionDetailsFetcher) evictIdle(now time.Time) {
+	if f.idle <= 0 {
+		return
+	}
+	for pc, c := range f.clients {
+		if c.borrowed == 0 && now.Sub(c.lastUsed) >= f.idle {
+			f.evict(pc, c)
+		}
+	}
+}
+
+func (f *PooledConnectionDetailsFetcher) evictLeastRecentlyUsed() {
+	lru := ""
+	for pc, c := range f.clients {
+		if lru == "" || c.lastUsed.Before(f.clients[lru].lastUsed) {
+			lru = pc
+		}
+	}
+	if lru != "" {
+		f.evict(lru, f.clients[lru])
+	}
+}
+
+// evict must be called with the lock held.
+func (f *PooledConnectionDetailsFetcher) evict(pc string, c *pooledClient) {
+	delete(f.clients, pc)
+	c.evicted = true
+	if c.borrowed == 0 {
+		closeClient(c)
+	}
+}
+
+func closeClient(c *pooledClient) {
+	if cl, ok := c.fetcher.(io.Closer); ok {
+		_ = cl.Close()
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &PooledConnectionDetailsFetcher{}
+
+// A closingFetcher returns the credentials it was created with, and records
+// when it's closed.
+type closingFetcher struct {
+	creds  []byte
+	closed *[]string
+}
+
+func (f *closingFetcher) FetchConnection(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	return managed.ConnectionDetails{"creds": f.creds}, nil
+}
+
+func (f *closingFetcher) Close() error {
+	*f.closed = append(*f.closed, string(f.creds))
+	return nil
+}
+
+func TestPooledConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	withProviderConfig := func(name string) resource.ConnectionSecretOwner {
+		return withContent(map[string]any{"spec": map[string]any{"providerConfigRef": map[string]any{"name": name}}})
+	}
+
+	type fetch struct {
+		pc      string
+		creds   string
+		advance time.Duration
+		evict   string
+	}
+	type args struct {
+		opts    []PooledConnectionDetailsFetcherOption
+		resolve error
+		fetches []fetch
+	}
+	type want struct {
+		conn    []managed.ConnectionDetails
+		err     error
+		created int
+		closed  []string
+		len     int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"ResolveError": {
+			reason: "We should return a clear error if credentials can't be resolved.",
+			args: args{
+				resolve: errBoom,
+				fetches: []fetch{{pc: "tenant-a"}},
+			},
+			want: want{
+				err: errors.Wrapf(errBoom, errFmtResolveCredentials, "tenant-a"),
+			},
+		},
+		"SharedClient": {
+			reason: "We should lazily create one client per ProviderConfig, and share it.",
+			args: args{
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a"},
+					{pc: "tenant-b", creds: "b"},
+					{pc: "tenant-a", creds: "a"},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a")}, {"creds": []byte("b")}, {"creds": []byte("a")}},
+				created: 2,
+				len:     2,
+			},
+		},
+		"CredentialsUnchanged": {
+			reason: "We should keep using a client if its credentials haven't rotated when we resolve them again.",
+			args: args{
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a"},
+					{pc: "tenant-a", creds: "a", advance: DefaultCredentialsTTL},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a")}, {"creds": []byte("a")}},
+				created: 1,
+				len:     1,
+			},
+		},
+		"CredentialsRotated": {
+			reason: "We should evict and close a client when its credentials rotate.",
+			args: args{
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a1"},
+					{pc: "tenant-a", creds: "a2", advance: DefaultCredentialsTTL},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a1")}, {"creds": []byte("a2")}},
+				created: 2,
+				closed:  []string{"a1"},
+				len:     1,
+			},
+		},
+		"Idle": {
+			reason: "We should evict and close clients that are idle.",
+			args: args{
+				opts: []PooledConnectionDetailsFetcherOption{WithPoolIdleTimeout(time.Minute), WithPoolCredentialsTTL(time.Hour)},
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a"},
+					{pc: "tenant-b", creds: "b", advance: time.Minute},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a")}, {"creds": []byte("b")}},
+				created: 2,
+				closed:  []string{"a"},
+				len:     1,
+			},
+		},
+		"Full": {
+			reason: "We should evict and close the least recently used client when the pool is full.",
+			args: args{
+				opts: []PooledConnectionDetailsFetcherOption{WithPoolMaxSize(2)},
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a"},
+					{pc: "tenant-b", creds: "b", advance: time.Second},
+					{pc: "tenant-a", creds: "a", advance: time.Second},
+					{pc: "tenant-c", creds: "c", advance: time.Second},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a")}, {"creds": []byte("b")}, {"creds": []byte("a")}, {"creds": []byte("c")}},
+				created: 3,
+				closed:  []string{"b"},
+				len:     2,
+			},
+		},
+		"Evicted": {
+			reason: "We should create a new client for a ProviderConfig whose client was explicitly evicted.",
+			args: args{
+				fetches: []fetch{
+					{pc: "tenant-a", creds: "a"},
+					{pc: "tenant-a", creds: "a", evict: "tenant-a"},
+				},
+			},
+			want: want{
+				conn:    []managed.ConnectionDetails{{"creds": []byte("a")}, {"creds": []byte("a")}},
+				created: 2,
+				closed:  []string{"a"},
+				len:     1,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds := ""
+			r := CredentialsResolverFn(func(_ context.Context, _ string) ([]byte, error) {
+				return []byte(creds), tc.args.resolve
+			})
+
+			created := 0
+			var closed []string
+			fn := func(_ context.Context, creds []byte) (managed.ConnectionDetailsFetcher, error) {
+				created++
+				return &closingFetcher{creds: creds, closed: &closed}, nil
+			}
+
+			now := time.Now()
+			f := NewPooledConnectionDetailsFetcher(r, fn, tc.args.opts...)
+			f.now = func() time.Time { return now }
+
+			var got []managed.ConnectionDetails
+			var err error
+			for _, fe := range tc.args.fetches {
+				now = now.Add(fe.advance)
+				creds = fe.creds
+				if fe.evict != "" {
+					f.Evict(fe.evict)
+				}
+				var conn managed.ConnectionDetails
+				conn, err = f.FetchConnection(context.Background(), withProviderConfig(fe.pc))
+				if err != nil {
+					break
+				}
+				got = append(got, conn)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.created, created); diff != "" {
+				t.Errorf("\n%s\nScopedFetcherFn(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.closed, closed); diff != "" {
+				t.Errorf("\n%s\nClose(): -want closed, +got closed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.len, f.Len()); diff != "" {
+				t.Errorf("\n%s\nf.Len(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
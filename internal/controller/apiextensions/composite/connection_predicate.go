@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtPredicateEquals  = "value predicate at index %d of type %q requires an equals value"
+	errFmtPredicateRegexp  = "value predicate at index %d of type %q requires a regexp"
+	errFmtPredicateCompile = "cannot compile regexp of value predicate at index %d"
+	errFmtPredicateType    = "value predicate at index %d has unknown type %q"
+	errFmtPredicatePolicy  = "value predicate at index %d has unknown policy %q"
+)
+
+// A ConnectionValuePredicateType determines how a connection detail value is
+// matched.
+type ConnectionValuePredicateType string
+
+// ConnectionValuePredicate types.
+const (
+	// ConnectionValuePredicateTypeEquals matches values that are byte-for-byte
+	// equal to the configured value.
+	ConnectionValuePredicateTypeEquals ConnectionValuePredicateType = "Equals"
+
+	// ConnectionValuePredicateTypeRegexp matches values that match the
+	// configured regular expression. Values that are not valid UTF-8 never
+	// match a regular expression.
+	ConnectionValuePredicateTypeRegexp ConnectionValuePredicateType = "Regexp"
+)
+
+// A ConnectionValuePredicatePolicy determines what happens to a connection
+// detail when its value matches a predicate.
+type ConnectionValuePredicatePolicy string
+
+// ConnectionValuePredicate policies.
+const (
+	// ConnectionValuePredicatePolicyExcludeOnMatch drops matching values, and
+	// keeps all others.
+	ConnectionValuePredicatePolicyExcludeOnMatch ConnectionValuePredicatePolicy = "ExcludeOnMatch"
+
+	// ConnectionValuePredicatePolicyIncludeOnMatch keeps matching values, and
+	// drops all others.
+	ConnectionValuePredicatePolicyIncludeOnMatch ConnectionValuePredicatePolicy = "IncludeOnMatch"
+)
+
+// A ConnectionValuePredicate configures which fetched connection details are
+// kept based on their value, rather than their key.
+type ConnectionValuePredicate struct {
+	// Type of predicate.
+	Type ConnectionValuePredicateType
+
+	// Key the predicate applies to. The predicate applies to all keys if Key
+	// is empty.
+	Key string
+
+	// Equals is the value to match if the type is Equals.
+	Equals *string
+
+	// Regexp is the regular expression to match if the type is Regexp.
+	Regexp *string
+
+	// Policy determines whether matching values are kept or dropped. Matching
+	// values are dropped if no policy is set.
+	Policy ConnectionValuePredicatePolicy
+}
+
+type compiledValuePredicate struct {
+	key     string
+	match   func(v []byte) bool
+	include bool
+}
+
+// A ValuePredicateConnectionDetailsFetcher filters the connection details
+// fetched by another ConnectionDetailsFetcher based on their values.
+type ValuePredicateConnectionDetailsFetcher struct {
+	fetcher    managed.ConnectionDetailsFetcher
+	predicates []compiledValuePredicate
+}
+
+// NewValuePredicateConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that drops any connection details fetched by the supplied fetcher that are
+// rejected by any of the supplied predicates. Predicates are validated and
+// compiled once, at construction time.
+func NewValuePredicateConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, p ...ConnectionValuePredicate) (*ValuePredicateConnectionDetailsFetcher, error) {
+	cp := make([]compiledValuePredicate, len(p))
+	for i := range p {
+		c := compiledValuePredicate{key: p[i].Key}
+
+		switch p[i].Policy {
+		case ConnectionValuePredicatePolicyIncludeOnMatch:
+			c.include = true
+		case ConnectionValuePredicatePolicyExcludeOnMatch, "":
+		default:
+			return nil, errors.Errorf(errFmtPredicatePolicy, i, p[i].Policy)
+		}
+
+		switch t := p[i].Type; t {
+		case ConnectionValuePredicateTypeEquals:
+			if p[i].Equals == nil {
+				return nil, errors.Errorf(errFmtPredicateEquals, i, t)
+			}
+			want := []byte(*p[i].Equals)
+			c.match = func(v []byte) bool { return bytes.Equal(v, want) }
+		case ConnectionValuePredicateTypeRegexp:
+			if p[i].Regexp == nil {
+				return nil, errors.Errorf(errFmtPredicateRegexp, i, t)
+			}
+			re, err := regexp.Compile(*p[i].Regexp)
+			if err != nil {
+				return nil, errors.Wrapf(err, errFmtPredicateCompile, i)
+			}
+			// Go's regexp package will happily match arbitrary bytes, but a
+			// regular expression is written against text. We don't want a
+			// binary value to match (or not match) by accident.
+			c.match = func(v []byte) bool { return utf8.Valid(v) && re.Match(v) }
+		default:
+			return nil, errors.Errorf(errFmtPredicateType, i, t)
+		}
+
+		cp[i] = c
+	}
+	return &ValuePredicateConnectionDetailsFetcher{fetcher: f, predicates: cp}, nil
+}
+
+// FetchConnection details using the wrapped fetcher, keeping only those that
+// are accepted by all predicates.
+func (f *ValuePredicateConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil || conn == nil {
+		return conn, err
+	}
+
+	out := make(managed.ConnectionDetails, len(conn))
+	for k, v := range conn {
+		if f.accept(k, v) {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func (f *ValuePredicateConnectionDetailsFetcher) accept(key string, value []byte) bool {
+	for _, p := range f.predicates {
+		if p.key != "" && p.key != key {
+			continue
+		}
+		if p.match(value) != p.include {
+			return false
+		}
+	}
+	return true
+}
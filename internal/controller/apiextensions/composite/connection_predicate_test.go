@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/utils/pointer"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &ValuePredicateConnectionDetailsFetcher{}
+
+func TestValuePredicateConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	fetched := ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		return managed.ConnectionDetails{
+			"status":   []byte("pending"),
+			"endpoint": []byte("example.org"),
+			"cert":     {0xff, 0xfe, 0x00},
+		}, nil
+	})
+
+	type params struct {
+		f managed.ConnectionDetailsFetcher
+		p []ConnectionValuePredicate
+	}
+	type want struct {
+		newErr error
+		conn   managed.ConnectionDetails
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params params
+		want   want
+	}{
+		"MissingEquals": {
+			reason: "We should return an error if an Equals predicate has no value.",
+			params: params{
+				p: []ConnectionValuePredicate{{Type: ConnectionValuePredicateTypeEquals}},
+			},
+			want: want{
+				newErr: errors.Errorf(errFmtPredicateEquals, 0, ConnectionValuePredicateTypeEquals),
+			},
+		},
+		"MissingRegexp": {
+			reason: "We should return an error if a Regexp predicate has no regexp.",
+			params: params{
+				p: []ConnectionValuePredicate{{Type: ConnectionValuePredicateTypeRegexp}},
+			},
+			want: want{
+				newErr: errors.Errorf(errFmtPredicateRegexp, 0, ConnectionValuePredicateTypeRegexp),
+			},
+		},
+		"InvalidRegexp": {
+			reason: "We should return an error if a Regexp predicate does not compile.",
+			params: params{
+				p: []ConnectionValuePredicate{{Type: ConnectionValuePredicateTypeRegexp, Regexp: pointer.String("(")}},
+			},
+			want: want{
+				newErr: errors.Wrapf(errors.New("error parsing regexp: missing closing ): `(`"), errFmtPredicateCompile, 0),
+			},
+		},
+		"UnknownType": {
+			reason: "We should return an error if a predicate has an unknown type.",
+			params: params{
+				p: []ConnectionValuePredicate{{Type: "Wat"}},
+			},
+			want: want{
+				newErr: errors.Errorf(errFmtPredicateType, 0, "Wat"),
+			},
+		},
+		"UnknownPolicy": {
+			reason: "We should return an error if a predicate has an unknown policy.",
+			params: params{
+				p: []ConnectionValuePredicate{{Type: ConnectionValuePredicateTypeEquals, Equals: pointer.String("a"), Policy: "Wat"}},
+			},
+			want: want{
+				newErr: errors.Errorf(errFmtPredicatePolicy, 0, "Wat"),
+			},
+		},
+		"FetchError": {
+			reason: "We should return errors from the wrapped fetcher.",
+			params: params{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"ExcludeOnMatchForKey": {
+			reason: "We should drop a key whose value equals the configured value.",
+			params: params{
+				f: fetched,
+				p: []ConnectionValuePredicate{{
+					Type:   ConnectionValuePredicateTypeEquals,
+					Key:    "status",
+					Equals: pointer.String("pending"),
+				}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"endpoint": []byte("example.org"),
+					"cert":     {0xff, 0xfe, 0x00},
+				},
+			},
+		},
+		"IncludeOnMatchForAllKeys": {
+			reason: "We should keep only values that match when the policy is IncludeOnMatch. Binary values should never match a regexp.",
+			params: params{
+				f: fetched,
+				p: []ConnectionValuePredicate{{
+					Type:   ConnectionValuePredicateTypeRegexp,
+					Regexp: pointer.String(".*"),
+					Policy: ConnectionValuePredicatePolicyIncludeOnMatch,
+				}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"status":   []byte("pending"),
+					"endpoint": []byte("example.org"),
+				},
+			},
+		},
+		"EqualsIsBinarySafe": {
+			reason: "We should be able to match binary values byte-for-byte.",
+			params: params{
+				f: fetched,
+				p: []ConnectionValuePredicate{{
+					Type:   ConnectionValuePredicateTypeEquals,
+					Equals: pointer.String(string([]byte{0xff, 0xfe, 0x00})),
+				}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"status":   []byte("pending"),
+					"endpoint": []byte("example.org"),
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := NewValuePredicateConnectionDetailsFetcher(tc.params.f, tc.params.p...)
+			if diff := cmp.Diff(tc.want.newErr, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nNewValuePredicateConnectionDetailsFetcher(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			conn, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
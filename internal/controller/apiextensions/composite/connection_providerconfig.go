@@ -0,0 +1,212 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// Error strings.
+const (
+	errGetProviderConfig         = "cannot get provider config"
+	errFmtNoProviderConfig       = "composed resource %q does not reference a provider config, which is required for connection detail %q"
+	errFmtProviderConfigNotFound = "provider config %q does not exist, but is required for connection detail %q"
+	errFmtProviderConfigField    = "cannot get required connection detail %q from provider config %q"
+)
+
+// ProviderConfigKind is the conventional kind of a provider's ProviderConfig.
+const ProviderConfigKind = "ProviderConfig"
+
+// A ProviderConfigField configures a connection detail that is extracted from
+// a field of the ProviderConfig a composed resource uses.
+type ProviderConfigField struct {
+	// Name of the connection detail key.
+	Name string
+
+	// FromFieldPath is the path of the ProviderConfig field whose value is
+	// used.
+	FromFieldPath string
+
+	// Policy determines whether the field is required. Fields are optional
+	// by default. A required field returns an error if the composed resource
+	// does not reference a ProviderConfig, the ProviderConfig does not exist,
+	// or the field is not set.
+	Policy v1.FromFieldPathPolicy
+}
+
+func (f ProviderConfigField) required() bool {
+	return f.Policy == v1.FromFieldPathPolicyRequired
+}
+
+// A ProviderConfigGVKFn returns the kind of ProviderConfig the supplied
+// composed resource uses.
+type ProviderConfigGVKFn func(o resource.ConnectionSecretOwner) schema.GroupVersionKind
+
+// DefaultProviderConfigGVK assumes a composed resource uses a ProviderConfig
+// of the same API group and version.
+func DefaultProviderConfigGVK(o resource.ConnectionSecretOwner) schema.GroupVersionKind {
+	return o.GetObjectKind().GroupVersionKind().GroupVersion().WithKind(ProviderConfigKind)
+}
+
+// A ProviderConfigConnectionDetailsFetcherOption configures a
+// ProviderConfigConnectionDetailsFetcher.
+type ProviderConfigConnectionDetailsFetcherOption func(*ProviderConfigConnectionDetailsFetcher)
+
+// WithProviderConfigGVK configures how a
+// ProviderConfigConnectionDetailsFetcher determines the kind of ProviderConfig
+// a composed resource uses.
+func WithProviderConfigGVK(fn ProviderConfigGVKFn) ProviderConfigConnectionDetailsFetcherOption {
+	return func(f *ProviderConfigConnectionDetailsFetcher) {
+		f.gvk = fn
+	}
+}
+
+// A ProviderConfigConnectionDetailsFetcher fetches connection details from
+// the ProviderConfig a composed resource uses, for example a regional API
+// endpoint that is configured per ProviderConfig rather than per resource.
+type ProviderConfigConnectionDetailsFetcher struct {
+	client client.Reader
+	fields []ProviderConfigField
+	gvk    ProviderConfigGVKFn
+}
+
+// NewProviderConfigConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that extracts the supplied fields from the ProviderConfig a composed
+// resource uses.
+func NewProviderConfigConnectionDetailsFetcher(c client.Reader, fields []ProviderConfigField, o ...ProviderConfigConnectionDetailsFetcherOption) *ProviderConfigConnectionDetailsFetcher {
+	f := &ProviderConfigConnectionDetailsFetcher{client: c, fields: fields, gvk: DefaultProviderConfigGVK}
+	for _, fn := range o {
+		fn(f)
+	}
+	return f
+}
+
+// FetchConnection details of the supplied composed resource from its
+// ProviderConfig.
+func (f *ProviderConfigConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if len(f.fields) == 0 {
+		return nil, nil
+	}
+
+	name, err := providerConfigName(o)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		for _, fld := range f.fields {
+			if fld.required() {
+				return nil, errors.Errorf(errFmtNoProviderConfig, o.GetName(), fld.Name)
+			}
+		}
+		return nil, nil
+	}
+
+	gvk := f.gvk(o)
+	pc, err := readProviderConfig(ctx, providerConfigReadKey{GVK: gvk, Name: name}, func() (*unstructured.Unstructured, error) {
+		pc := &unstructured.Unstructured{}
+		pc.SetGroupVersionKind(gvk)
+		return pc, f.client.Get(ctx, types.NamespacedName{Name: name}, pc)
+	})
+	if kerrors.IsNotFound(err) {
+		for _, fld := range f.fields {
+			if fld.required() {
+				return nil, errors.Errorf(errFmtProviderConfigNotFound, name, fld.Name)
+			}
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errGetProviderConfig)
+	}
+
+	conn := managed.ConnectionDetails{}
+	for _, fld := range f.fields {
+		v, err := fromFieldPath(pc, fld.FromFieldPath)
+		if err != nil {
+			if fld.required() {
+				return nil, errors.Wrapf(err, errFmtProviderConfigField, fld.Name, name)
+			}
+			continue
+		}
+		conn[fld.Name] = v
+	}
+	return conn, nil
+}
+
+// A providerConfigReadKey uniquely identifies a ProviderConfig.
+type providerConfigReadKey struct {
+	GVK  schema.GroupVersionKind
+	Name string
+}
+
+// A providerConfigRead is the result of reading a ProviderConfig.
+type providerConfigRead struct {
+	pc  *unstructured.Unstructured
+	err error
+}
+
+// providerConfigReads deduplicates reads of identical ProviderConfigs.
+type providerConfigReads struct {
+	mu    sync.Mutex
+	reads map[providerConfigReadKey]providerConfigRead
+}
+
+type providerConfigReadsKey struct{}
+
+// WithProviderConfigReadDeduplication returns a context that deduplicates
+// reads of ProviderConfigs. While the context is in use, each distinct
+// ProviderConfig is read at most once. The context should be scoped to a
+// single reconcile, so that changes to ProviderConfigs are observed by the
+// next reconcile.
+func WithProviderConfigReadDeduplication(ctx context.Context) context.Context {
+	return context.WithValue(ctx, providerConfigReadsKey{}, &providerConfigReads{reads: make(map[providerConfigReadKey]providerConfigRead)})
+}
+
+// readProviderConfig returns the ProviderConfig identified by the supplied
+// key, calling the supplied read function only if the ProviderConfig has not
+// already been read using the supplied context.
+func readProviderConfig(ctx context.Context, k providerConfigReadKey, read func() (*unstructured.Unstructured, error)) (*unstructured.Unstructured, error) {
+	if ctx == nil {
+		return read()
+	}
+	pr, ok := ctx.Value(providerConfigReadsKey{}).(*providerConfigReads)
+	if !ok {
+		return read()
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if r, ok := pr.reads[k]; ok {
+		return r.pc.DeepCopy(), r.err
+	}
+	pc, err := read()
+	pr.reads[k] = providerConfigRead{pc: pc.DeepCopy(), err: err}
+	return pc, err
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+var _ managed.ConnectionDetailsFetcher = &ProviderConfigConnectionDetailsFetcher{}
+
+func TestProviderConfigConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	composed := func(pc string) resource.ConnectionSecretOwner {
+		content := map[string]any{"apiVersion": "example.org/v1", "kind": "Bucket", "metadata": map[string]any{"name": "cool-bucket"}}
+		if pc != "" {
+			content["spec"] = map[string]any{"providerConfigRef": map[string]any{"name": pc}}
+		}
+		return withContent(content)
+	}
+	pc := func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		u := obj.(*unstructured.Unstructured)
+		if u.GroupVersionKind() != (schema.GroupVersionKind{Group: "example.org", Version: "v1", Kind: ProviderConfigKind}) || key.Name != "cool-pc" {
+			return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+		}
+		u.Object["spec"] = map[string]any{"region": "us-east-1", "endpoint": map[string]any{"url": "https://example.org"}}
+		return nil
+	}
+	optional := ProviderConfigField{Name: "region", FromFieldPath: "spec.region"}
+	required := ProviderConfigField{Name: "endpoint", FromFieldPath: "spec.endpoint.url", Policy: v1.FromFieldPathPolicyRequired}
+
+	type args struct {
+		get    test.MockGetFn
+		fields []ProviderConfigField
+		o      resource.ConnectionSecretOwner
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoProviderConfigOptional": {
+			reason: "We should return no connection details if there's no provider config and all fields are optional.",
+			args: args{
+				fields: []ProviderConfigField{optional},
+				o:      composed(""),
+			},
+		},
+		"NoProviderConfigRequired": {
+			reason: "We should return an error if there's no provider config and a field is required.",
+			args: args{
+				fields: []ProviderConfigField{optional, required},
+				o:      composed(""),
+			},
+			want: want{
+				err: errors.Errorf(errFmtNoProviderConfig, "cool-bucket", "endpoint"),
+			},
+		},
+		"ProviderConfigNotFoundOptional": {
+			reason: "We should return no connection details if the provider config doesn't exist and all fields are optional.",
+			args: args{
+				get:    pc,
+				fields: []ProviderConfigField{optional},
+				o:      composed("missing-pc"),
+			},
+		},
+		"ProviderConfigNotFoundRequired": {
+			reason: "We should return an error if the provider config doesn't exist and a field is required.",
+			args: args{
+				get:    pc,
+				fields: []ProviderConfigField{required},
+				o:      composed("missing-pc"),
+			},
+			want: want{
+				err: errors.Errorf(errFmtProviderConfigNotFound, "missing-pc", "endpoint"),
+			},
+		},
+		"GetError": {
+			reason: "We should return errors encountered getting the provider config.",
+			args: args{
+				get:    test.NewMockGetFn(errBoom),
+				fields: []ProviderConfigField{optional},
+				o:      composed("cool-pc"),
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetProviderConfig),
+			},
+		},
+		"RequiredFieldMissing": {
+			reason: "We should return an error if a required field is not set.",
+			args: args{
+				get:    pc,
+				fields: []ProviderConfigField{{Name: "zone", FromFieldPath: "spec.zone", Policy: v1.FromFieldPathPolicyRequired}},
+				o:      composed("cool-pc"),
+			},
+			want: want{
+				err: errors.Wrapf(errors.New("spec.zone: no such field"), errFmtProviderConfigField, "zone", "cool-pc"),
+			},
+		},
+		"Success": {
+			reason: "We should extract configured fields, skipping optional fields that aren't set.",
+			args: args{
+				get:    pc,
+				fields: []ProviderConfigField{optional, required, {Name: "zone", FromFieldPath: "spec.zone"}},
+				o:      composed("cool-pc"),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"region": []byte("us-east-1"), "endpoint": []byte("https://example.org")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewProviderConfigConnectionDetailsFetcher(&test.MockClient{MockGet: tc.args.get}, tc.args.fields)
+			conn, err := f.FetchConnection(context.Background(), tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProviderConfigReadDeduplication(t *testing.T) {
+	reads := 0
+	c := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		reads++
+		obj.(*unstructured.Unstructured).Object["spec"] = map[string]any{"region": "us-east-1"}
+		return nil
+	}}
+	f := NewProviderConfigConnectionDetailsFetcher(c, []ProviderConfigField{{Name: "region", FromFieldPath: "spec.region"}})
+	cd := withContent(map[string]any{"apiVersion": "example.org/v1", "kind": "Bucket", "spec": map[string]any{"providerConfigRef": map[string]any{"name": "cool-pc"}}})
+
+	ctx := WithProviderConfigReadDeduplication(context.Background())
+	for i := 0; i < 3; i++ {
+		conn, err := f.FetchConnection(ctx, cd)
+		if err != nil {
+			t.Fatalf("f.FetchConnection(...): %s", err)
+		}
+		if diff := cmp.Diff(managed.ConnectionDetails{"region": []byte("us-east-1")}, conn); diff != "" {
+			t.Errorf("f.FetchConnection(...): -want, +got:\n%s", diff)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("f.FetchConnection(...): want 1 provider config read, got %d", reads)
+	}
+}
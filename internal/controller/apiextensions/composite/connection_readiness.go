@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errPublishPredicate = "cannot determine whether to publish connection details"
+)
+
+// A PublishPredicate determines whether the connection details of the
+// supplied resource should be published yet.
+type PublishPredicate func(ctx context.Context, o resource.ConnectionSecretOwner) (bool, error)
+
+// AlwaysPublish is a PublishPredicate that always publishes connection
+// details.
+func AlwaysPublish() PublishPredicate {
+	return func(_ context.Context, _ resource.ConnectionSecretOwner) (bool, error) {
+		return true, nil
+	}
+}
+
+// PublishWhenReady is a PublishPredicate that only publishes the connection
+// details of resources whose Ready condition is True. Resources without
+// conditions are never considered ready.
+func PublishWhenReady() PublishPredicate {
+	return func(_ context.Context, o resource.ConnectionSecretOwner) (bool, error) {
+		c, ok := o.(interface {
+			GetCondition(ct xpv1.ConditionType) xpv1.Condition
+		})
+		if !ok {
+			return false, nil
+		}
+		return c.GetCondition(xpv1.TypeReady).Status == corev1.ConditionTrue, nil
+	}
+}
+
+// WithPublishPredicate configures a SecretStoreConnectionPublisher to only
+// publish connection details when the supplied predicate is satisfied, for
+// example to avoid exposing the credentials of a resource that's only partly
+// provisioned. Connection details are always published by default.
+func WithPublishPredicate(fn PublishPredicate) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.predicate = fn
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestSecretStoreConnectionPublisherPublishPredicate(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	xr := func(c ...xpv1.Condition) *fake.Composite {
+		return &fake.Composite{
+			ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+			ConditionedStatus:            xpv1.ConditionedStatus{Conditions: c},
+			ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+		}
+	}
+
+	type args struct {
+		o  []SecretStoreConnectionPublisherOption
+		xr resource.ConnectionSecretOwner
+	}
+	type want struct {
+		published bool
+		publishes int
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Default": {
+			reason: "We should publish the connection details of a resource that isn't ready by default.",
+			args: args{
+				xr: xr(xpv1.Creating()),
+			},
+			want: want{
+				published: true,
+				publishes: 1,
+			},
+		},
+		"Ready": {
+			reason: "We should publish the connection details of a ready resource.",
+			args: args{
+				o:  []SecretStoreConnectionPublisherOption{WithPublishPredicate(PublishWhenReady())},
+				xr: xr(xpv1.Available()),
+			},
+			want: want{
+				published: true,
+				publishes: 1,
+			},
+		},
+		"NotReady": {
+			reason: "We should not publish the connection details of a resource that isn't ready.",
+			args: args{
+				o:  []SecretStoreConnectionPublisherOption{WithPublishPredicate(PublishWhenReady())},
+				xr: xr(xpv1.Creating()),
+			},
+			want: want{
+				published: false,
+				publishes: 0,
+			},
+		},
+		"NoReadyCondition": {
+			reason: "We should not publish the connection details of a resource that doesn't yet have a Ready condition.",
+			args: args{
+				o:  []SecretStoreConnectionPublisherOption{WithPublishPredicate(PublishWhenReady())},
+				xr: xr(),
+			},
+			want: want{
+				published: false,
+				publishes: 0,
+			},
+		},
+		"PredicateError": {
+			reason: "We should return any error encountered evaluating the predicate, and not publish.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithPublishPredicate(func(_ context.Context, _ resource.ConnectionSecretOwner) (bool, error) {
+					return false, errBoom
+				})},
+				xr: xr(xpv1.Available()),
+			},
+			want: want{
+				publishes: 0,
+				err:       errors.Wrap(errBoom, errPublishPredicate),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisher(s, nil, tc.args.o...)
+			published, err := p.PublishConnection(context.Background(), tc.args.xr, managed.ConnectionDetails{"a": []byte("a")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.publishes, s.publishes); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want publishes, +got publishes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// ErrReadOnly is returned when a ReadOnlyClient is asked to write.
+var ErrReadOnly = errors.New("client is read-only")
+
+// Error strings.
+const (
+	errFmtReadOnlyWrite = "refusing to %s %q"
+)
+
+// A ReadOnlyClient is a client.Client that refuses to write. Any attempt to
+// create, update, patch or delete an object (or one of its subresources)
+// returns an error that wraps ErrReadOnly. Use it to construct fetchers for
+// inspection or audit tooling that must be guaranteed not to mutate anything,
+// for example:
+//
+//	NewSecretConnectionDetailsFetcher(NewReadOnlyClient(c))
+type ReadOnlyClient struct {
+	client.Client
+}
+
+// NewReadOnlyClient returns a client that reads using the supplied client, and
+// refuses to write.
+func NewReadOnlyClient(c client.Client) *ReadOnlyClient {
+	return &ReadOnlyClient{Client: c}
+}
+
+// Create refuses to create the supplied object.
+func (c *ReadOnlyClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	return refuse("create", obj)
+}
+
+// Delete refuses to delete the supplied object.
+func (c *ReadOnlyClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	return refuse("delete", obj)
+}
+
+// Update refuses to update the supplied object.
+func (c *ReadOnlyClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return refuse("update", obj)
+}
+
+// Patch refuses to patch the supplied object.
+func (c *ReadOnlyClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return refuse("patch", obj)
+}
+
+// DeleteAllOf refuses to delete objects of the supplied type.
+func (c *ReadOnlyClient) DeleteAllOf(_ context.Context, obj client.Object, _ ...client.DeleteAllOfOption) error {
+	return refuse("delete all of", obj)
+}
+
+// Status returns a status writer that refuses to write.
+func (c *ReadOnlyClient) Status() client.SubResourceWriter {
+	return readOnlySubResourceWriter{}
+}
+
+// SubResource returns a subresource client that reads using the underlying
+// client, and refuses to write.
+func (c *ReadOnlyClient) SubResource(subResource string) client.SubResourceClient {
+	return readOnlySubResourceClient{SubResourceReader: c.Client.SubResource(subResource)}
+}
+
+type readOnlySubResourceWriter struct{}
+
+func (readOnlySubResourceWriter) Create(_ context.Context, obj client.Object, _ client.Object, _ ...client.SubResourceCreateOption) error {
+	return refuse("create subresource of", obj)
+}
+
+func (readOnlySubResourceWriter) Update(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	return refuse("update subresource of", obj)
+}
+
+func (readOnlySubResourceWriter) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	return refuse("patch subresource of", obj)
+}
+
+type readOnlySubResourceClient struct {
+	client.SubResourceReader
+	readOnlySubResourceWriter
+}
+
+func refuse(verb string, obj client.Object) error {
+	return errors.Wrapf(ErrReadOnly, errFmtReadOnlyWrite, verb, obj.GetName())
+}
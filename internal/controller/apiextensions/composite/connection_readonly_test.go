@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ client.Client = &ReadOnlyClient{}
+
+func TestReadOnlyClient(t *testing.T) {
+	s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cool"}}
+	mc := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{"k": []byte("v")}
+			return nil
+		},
+		MockCreate:       test.NewMockCreateFn(nil),
+		MockUpdate:       test.NewMockUpdateFn(nil),
+		MockPatch:        test.NewMockPatchFn(nil),
+		MockDelete:       test.NewMockDeleteFn(nil),
+		MockDeleteAllOf:  test.NewMockDeleteAllOfFn(nil),
+		MockStatusUpdate: test.NewMockSubResourceUpdateFn(nil),
+		MockStatusPatch:  test.NewMockSubResourcePatchFn(nil),
+	}
+	c := NewReadOnlyClient(mc)
+	ctx := context.Background()
+
+	writes := map[string]error{
+		"Create":       c.Create(ctx, s),
+		"Update":       c.Update(ctx, s),
+		"Patch":        c.Patch(ctx, s, client.MergeFrom(s)),
+		"Delete":       c.Delete(ctx, s),
+		"DeleteAllOf":  c.DeleteAllOf(ctx, s),
+		"StatusUpdate": c.Status().Update(ctx, s),
+		"StatusPatch":  c.Status().Patch(ctx, s, client.MergeFrom(s)),
+		"StatusCreate": c.Status().Create(ctx, s, s),
+	}
+	for verb, err := range writes {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s(...): want error wrapping ErrReadOnly, got %v", verb, err)
+		}
+	}
+
+	// A fetcher built on a read-only client should still be able to read.
+	f := NewSecretConnectionDetailsFetcher(c)
+	conn, err := f.FetchConnection(ctx, &fake.Composed{ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: &xpv1.SecretReference{Name: "cool"}}})
+	if err != nil {
+		t.Fatalf("FetchConnection(...): %s", err)
+	}
+	if diff := cmp.Diff(managed.ConnectionDetails{"k": []byte("v")}, conn); diff != "" {
+		t.Errorf("FetchConnection(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errAssemble            = "cannot assemble connection details"
+	errFmtFetchSource      = "cannot fetch connection details of connection source %q"
+	errFmtExtractSource    = "cannot extract connection details of connection source %q"
+	errFmtTransformDetails = "cannot apply connection details transform at index %d"
+	errFmtSourceTimedOut   = "timed out after %s fetching connection details of connection source %q"
+)
+
+// A ConnectionSource is a composed resource whose connection details
+// contribute to those of a composite resource.
+type ConnectionSource struct {
+	// Name of the composed resource, typically its resource template name.
+	Name string
+
+	// Resource that is the source of connection details.
+	Resource resource.Composed
+
+	// Extract configures which connection details are extracted from the
+	// resource.
+	Extract []ConnectionDetailExtractConfig
+
+	// Locked keys may not be overridden by later sources. Only honored by
+	// the LayeredAssembler.
+	Locked []string
+
+	// Timeout bounds how long fetching the resource's connection details
+	// may take. Sources that time out are omitted from assembly and reported
+	// as timed out, rather than stalling or failing the whole assembly. No
+	// timeout is enforced if it is zero.
+	Timeout time.Duration
+
+	// Optional sources may fail without failing the whole assembly. Only
+	// honored when the ConnectionReconciler aggregates source errors.
+	Optional bool
+}
+
+// An AssemblySource is the connection details extracted from a
+// ConnectionSource.
+type AssemblySource struct {
+	Name              string
+	ConnectionDetails managed.ConnectionDetails
+
+	// Resource the connection details were extracted from, if any.
+	Resource resource.Composed
+
+	// Locked keys may not be overridden by later sources. Only honored by
+	// the LayeredAssembler.
+	Locked []string
+}
+
+// An Assembler assembles the connection details of a composite resource from
+// those extracted from its connection sources.
+type Assembler interface {
+	// Assemble the supplied sources into a composite resource's connection
+	// details. It also returns the name of the source of each key.
+	Assemble(ctx context.Context, s []AssemblySource) (managed.ConnectionDetails, map[string]string, error)
+}
+
+// An AssemblerFn is a function that satisfies the Assembler interface.
+type AssemblerFn func(ctx context.Context, s []AssemblySource) (managed.ConnectionDetails, map[string]string, error)
+
+// Assemble the supplied sources into a composite resource's connection
+// details.
+func (fn AssemblerFn) Assemble(ctx context.Context, s []AssemblySource) (managed.ConnectionDetails, map[string]string, error) {
+	return fn(ctx, s)
+}
+
+// MergeAssembler merges the supplied sources in order. If several sources
+// produce the same key the last one wins, consistent with P&T Composition.
+func MergeAssembler() Assembler {
+	return AssemblerFn(func(_ context.Context, s []AssemblySource) (managed.ConnectionDetails, map[string]string, error) {
+		conn := managed.ConnectionDetails{}
+		sources := map[string]string{}
+		for _, src := range s {
+			for k, v := range src.ConnectionDetails {
+				conn[k] = v
+				sources[k] = src.Name
+			}
+		}
+		return conn, sources, nil
+	})
+}
+
+// A ConnectionDetailsTransformer transforms assembled connection details
+// before they're published.
+type ConnectionDetailsTransformer interface {
+	TransformConnectionDetails(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error)
+}
+
+// A ConnectionDetailsTransformerFn is a function that satisfies the
+// ConnectionDetailsTransformer interface. A CatalogEnricher's
+// EnrichConnectionDetails method may be used as one.
+type ConnectionDetailsTransformerFn func(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error)
+
+// TransformConnectionDetails transforms the supplied connection details.
+func (fn ConnectionDetailsTransformerFn) TransformConnectionDetails(ctx context.Context, xr resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+	return fn(ctx, xr, conn)
+}
+
+// A ConnectionReconcileResult is the result of reconciling a composite
+// resource's connection details.
+type ConnectionReconcileResult struct {
+	// Published is true if publishing changed the published connection
+	// details.
+	Published bool
+
+	// Keys that were published, in alphabetical order.
+	Keys []string
+
+	// Sources maps each published key to the name of the connection source
+	// it was assembled from, if any.
+	Sources map[string]string
+
+	// ConnectionSources summarizes the connection details fetched from each
+	// connection source. It may be returned along with an error.
+	ConnectionSources []ConnectionSourceStatus
+
+	// TimedOut is the names of the connection sources that timed out. Their
+	// connection details were not assembled or published. Callers should
+	// requeue to fetch them again.
+	TimedOut []string
+
+	// SourceErrors are the errors encountered fetching or extracting the
+	// connection details of each connection source, if the
+	// ConnectionReconciler aggregates source errors. Connection details are
+	// published despite errors only if none of them are fatal.
+	SourceErrors SourceErrors
+}
+
+// A ConnectionReconcilerOption configures a ConnectionReconciler.
+type ConnectionReconcilerOption func(*ConnectionReconciler)
+
+// WithConnectionDetailsExtractor configures how a ConnectionReconciler
+// extracts connection details from the details fetched from each source.
+func WithConnectionDetailsExtractor(e ConnectionDetailsExtractor) ConnectionReconcilerOption {
+	return func(r *ConnectionReconciler) {
+		r.extractor = e
+	}
+}
+
+// WithAssembler configures how a ConnectionReconciler assembles connection
+// details.
+func WithAssembler(a Assembler) ConnectionReconcilerOption {
+	return func(r *ConnectionReconciler) {
+		r.assembler = a
+	}
+}
+
+// WithConnectionDetailsTransformers configures the transforms a
+// ConnectionReconciler applies, in order, to assembled connection details.
+func WithConnectionDetailsTransformers(t ...ConnectionDetailsTransformer) ConnectionReconcilerOption {
+	return func(r *ConnectionReconciler) {
+		r.transformers = t
+	}
+}
+
+// WithSourceErrorAggregation configures a ConnectionReconciler to fetch and
+// extract the connection details of all connection sources before reporting
+// any errors, rather than stopping at the first source that fails. Errors are
+// returned as SourceErrors. Connection details are assembled and published
+// without the sources that failed if all of them are optional.
+func WithSourceErrorAggregation() ConnectionReconcilerOption {
+	return func(r *ConnectionReconciler) {
+		r.aggregate = true
+	}
+}
+
+// A ConnectionReconciler reconciles the connection details of a composite
+// resource. It fetches connection details from each of the composite
+// resource's connection sources, extracts and assembles them, transforms the
+// result, then publishes it. Each stage may be replaced independently.
+type ConnectionReconciler struct {
+	fetcher      managed.ConnectionDetailsFetcher
+	extractor    ConnectionDetailsExtractor
+	assembler    Assembler
+	transformers []ConnectionDetailsTransformer
+	publisher    managed.ConnectionPublisher
+	aggregate    bool
+}
+
+// NewConnectionReconciler returns a ConnectionReconciler that fetches
+// connection details using the supplied fetcher and publishes them using the
+// supplied publisher. By default connection details are extracted per their
+// extract configs, merged in order, and not transformed.
+func NewConnectionReconciler(f managed.ConnectionDetailsFetcher, p managed.ConnectionPublisher, o ...ConnectionReconcilerOption) *ConnectionReconciler {
+	r := &ConnectionReconciler{
+		fetcher:   f,
+		extractor: ConnectionDetailsExtractorFn(ExtractConnectionDetails),
+		assembler: MergeAssembler(),
+		publisher: p,
+	}
+	for _, fn := range o {
+		fn(r)
+	}
+	return r
+}
+
+// Reconcile the connection details of the supplied composite resource, which
+// are assembled from the supplied connection sources. Connection details are
+// published even if some sources timed out; see ConnectionReconcileResult.
+func (r *ConnectionReconciler) Reconcile(ctx context.Context, xr resource.Composite, cs []ConnectionSource) (ConnectionReconcileResult, error) {
+	res := ConnectionReconcileResult{ConnectionSources: make([]ConnectionSourceStatus, 0, len(cs))}
+
+	as := make([]AssemblySource, 0, len(cs))
+	var errs SourceErrors
+	for _, s := range cs {
+		status := ConnectionSourceStatus{Name: s.Name, LastFetchTime: &metav1.Time{Time: time.Now()}}
+
+		conn, timedOut, err := r.fetch(ctx, s)
+		if timedOut {
+			status.Error = errors.Errorf(errFmtSourceTimedOut, s.Timeout, s.Name).Error()
+			res.ConnectionSources = append(res.ConnectionSources, status)
+			res.TimedOut = append(res.TimedOut, s.Name)
+			continue
+		}
+		if err != nil {
+			err = errors.Wrapf(err, errFmtFetchSource, s.Name)
+			status.Error = err.Error()
+			res.ConnectionSources = append(res.ConnectionSources, status)
+			if !r.aggregate {
+				return res, err
+			}
+			errs = append(errs, SourceError{Source: s.Name, Fatal: !s.Optional, Err: err})
+			continue
+		}
+		e, err := r.extractor.ExtractConnection(s.Resource, conn, s.Extract...)
+		if err != nil {
+			err = errors.Wrapf(err, errFmtExtractSource, s.Name)
+			status.Error = err.Error()
+			res.ConnectionSources = append(res.ConnectionSources, status)
+			if !r.aggregate {
+				return res, err
+			}
+			errs = append(errs, SourceError{Source: s.Name, Fatal: !s.Optional, Err: err})
+			continue
+		}
+
+		status.Keys = len(e)
+		res.ConnectionSources = append(res.ConnectionSources, status)
+		as = append(as, AssemblySource{Name: s.Name, ConnectionDetails: e, Resource: s.Resource, Locked: s.Locked})
+	}
+
+	res.SourceErrors = errs
+	if errs.Fatal() {
+		return res, errs
+	}
+
+	conn, sources, err := r.assembler.Assemble(ctx, as)
+	if err != nil {
+		return res, errors.Wrap(err, errAssemble)
+	}
+
+	for i, t := range r.transformers {
+		conn, err = t.TransformConnectionDetails(ctx, xr, conn)
+		if err != nil {
+			return res, errors.Wrapf(err, errFmtTransformDetails, i)
+		}
+	}
+
+	published, err := r.publisher.PublishConnection(ctx, xr, conn)
+	if err != nil {
+		return res, errors.Wrap(err, errPublish)
+	}
+
+	res.Published = published
+	res.Keys = OrderedKeys(conn, AlphabeticalKeyOrder())
+	res.Sources = make(map[string]string, len(conn))
+	for k := range conn {
+		if s, ok := sources[k]; ok {
+			res.Sources[k] = s
+		}
+	}
+	return res, nil
+}
+
+// fetch the connection details of the supplied source, enforcing its timeout.
+// It returns true if the source timed out. Timeouts of the parent context are
+// not considered timeouts of the source.
+func (r *ConnectionReconciler) fetch(ctx context.Context, s ConnectionSource) (managed.ConnectionDetails, bool, error) {
+	if s.Timeout <= 0 {
+		conn, err := r.fetcher.FetchConnection(ctx, s.Resource)
+		return conn, false, err
+	}
+
+	fctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	conn, err := r.fetcher.FetchConnection(fctx, s.Resource)
+	if err != nil && ctx.Err() == nil && errors.Is(fctx.Err(), context.DeadlineExceeded) {
+		return nil, true, err
+	}
+	return conn, false, err
+}
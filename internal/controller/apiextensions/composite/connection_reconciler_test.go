@@ -0,0 +1,278 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestConnectionReconciler(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	// Each composed resource's connection details are keyed by its name.
+	fetcher := ConnectionDetailsFetcherFn(func(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		return managed.ConnectionDetails{o.GetName(): []byte(o.GetName())}, nil
+	})
+	extractAll := WithConnectionDetailsExtractor(ConnectionDetailsExtractorFn(func(_ resource.Composed, conn managed.ConnectionDetails, _ ...ConnectionDetailExtractConfig) (managed.ConnectionDetails, error) {
+		return conn, nil
+	}))
+	publisher := func(err error) managed.ConnectionPublisher {
+		return managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+				return err == nil, err
+			},
+		}
+	}
+	source := func(name string) ConnectionSource {
+		return ConnectionSource{Name: name, Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: name}}}
+	}
+
+	type args struct {
+		f    managed.ConnectionDetailsFetcher
+		p    managed.ConnectionPublisher
+		o    []ConnectionReconcilerOption
+		srcs []ConnectionSource
+	}
+	type want struct {
+		res ConnectionReconcileResult
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FetchError": {
+			reason: "We should summarize and return errors encountered fetching connection details.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				srcs: []ConnectionSource{source("a")},
+			},
+			want: want{
+				res: ConnectionReconcileResult{ConnectionSources: []ConnectionSourceStatus{{Name: "a", Error: errors.Wrapf(errBoom, errFmtFetchSource, "a").Error()}}},
+				err: errors.Wrapf(errBoom, errFmtFetchSource, "a"),
+			},
+		},
+		"AssembleError": {
+			reason: "We should return errors encountered assembling connection details.",
+			args: args{
+				f: fetcher,
+				o: []ConnectionReconcilerOption{extractAll, WithAssembler(AssemblerFn(func(_ context.Context, _ []AssemblySource) (managed.ConnectionDetails, map[string]string, error) {
+					return nil, nil, errBoom
+				}))},
+				srcs: []ConnectionSource{source("a")},
+			},
+			want: want{
+				res: ConnectionReconcileResult{ConnectionSources: []ConnectionSourceStatus{{Name: "a", Keys: 1}}},
+				err: errors.Wrap(errBoom, errAssemble),
+			},
+		},
+		"TransformError": {
+			reason: "We should return errors encountered transforming connection details.",
+			args: args{
+				f: fetcher,
+				o: []ConnectionReconcilerOption{extractAll, WithConnectionDetailsTransformers(ConnectionDetailsTransformerFn(func(_ context.Context, _ resource.Composite, _ managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}))},
+				srcs: []ConnectionSource{source("a")},
+			},
+			want: want{
+				res: ConnectionReconcileResult{ConnectionSources: []ConnectionSourceStatus{{Name: "a", Keys: 1}}},
+				err: errors.Wrapf(errBoom, errFmtTransformDetails, 0),
+			},
+		},
+		"PublishError": {
+			reason: "We should return errors encountered publishing connection details.",
+			args: args{
+				f:    fetcher,
+				p:    publisher(errBoom),
+				o:    []ConnectionReconcilerOption{extractAll},
+				srcs: []ConnectionSource{source("a")},
+			},
+			want: want{
+				res: ConnectionReconcileResult{ConnectionSources: []ConnectionSourceStatus{{Name: "a", Keys: 1}}},
+				err: errors.Wrap(errBoom, errPublish),
+			},
+		},
+		"Success": {
+			reason: "We should fetch, assemble, transform, and publish connection details.",
+			args: args{
+				f: fetcher,
+				p: publisher(nil),
+				o: []ConnectionReconcilerOption{extractAll, WithConnectionDetailsTransformers(ConnectionDetailsTransformerFn(func(_ context.Context, _ resource.Composite, conn managed.ConnectionDetails) (managed.ConnectionDetails, error) {
+					conn["static"] = []byte("value")
+					return conn, nil
+				}))},
+				srcs: []ConnectionSource{source("b"), source("a")},
+			},
+			want: want{
+				res: ConnectionReconcileResult{
+					Published:         true,
+					Keys:              []string{"a", "b", "static"},
+					Sources:           map[string]string{"a": "a", "b": "b"},
+					ConnectionSources: []ConnectionSourceStatus{{Name: "b", Keys: 1}, {Name: "a", Keys: 1}},
+				},
+			},
+		},
+		"AggregatedFatalSourceErrors": {
+			reason: "We should report the errors of all failed sources, and not publish, if any required source failed.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					if o.GetName() != "ok" {
+						return nil, errBoom
+					}
+					return managed.ConnectionDetails{"ok": []byte("ok")}, nil
+				}),
+				p: publisher(errBoom),
+				o: []ConnectionReconcilerOption{extractAll, WithSourceErrorAggregation()},
+				srcs: []ConnectionSource{
+					source("required"),
+					source("ok"),
+					{Name: "optional", Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "optional"}}, Optional: true},
+				},
+			},
+			want: want{
+				res: ConnectionReconcileResult{
+					ConnectionSources: []ConnectionSourceStatus{
+						{Name: "required", Error: errors.Wrapf(errBoom, errFmtFetchSource, "required").Error()},
+						{Name: "ok", Keys: 1},
+						{Name: "optional", Error: errors.Wrapf(errBoom, errFmtFetchSource, "optional").Error()},
+					},
+					SourceErrors: SourceErrors{
+						{Source: "required", Fatal: true, Err: errors.Wrapf(errBoom, errFmtFetchSource, "required")},
+						{Source: "optional", Err: errors.Wrapf(errBoom, errFmtFetchSource, "optional")},
+					},
+				},
+				err: SourceErrors{
+					{Source: "required", Fatal: true, Err: errors.Wrapf(errBoom, errFmtFetchSource, "required")},
+					{Source: "optional", Err: errors.Wrapf(errBoom, errFmtFetchSource, "optional")},
+				},
+			},
+		},
+		"AggregatedTolerableSourceErrors": {
+			reason: "We should report the errors of failed optional sources, and publish connection details from the sources that didn't fail.",
+			args: args{
+				f: fetcher,
+				p: publisher(nil),
+				o: []ConnectionReconcilerOption{WithSourceErrorAggregation(), WithConnectionDetailsExtractor(ConnectionDetailsExtractorFn(func(cd resource.Composed, conn managed.ConnectionDetails, _ ...ConnectionDetailExtractConfig) (managed.ConnectionDetails, error) {
+					if cd.GetName() == "optional" {
+						return nil, errBoom
+					}
+					return conn, nil
+				}))},
+				srcs: []ConnectionSource{
+					source("a"),
+					{Name: "optional", Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "optional"}}, Optional: true},
+				},
+			},
+			want: want{
+				res: ConnectionReconcileResult{
+					Published: true,
+					Keys:      []string{"a"},
+					Sources:   map[string]string{"a": "a"},
+					ConnectionSources: []ConnectionSourceStatus{
+						{Name: "a", Keys: 1},
+						{Name: "optional", Error: errors.Wrapf(errBoom, errFmtExtractSource, "optional").Error()},
+					},
+					SourceErrors: SourceErrors{
+						{Source: "optional", Err: errors.Wrapf(errBoom, errFmtExtractSource, "optional")},
+					},
+				},
+			},
+		},
+		"SlowSourceTimedOut": {
+			reason: "We should report sources that time out, and publish connection details from the sources that didn't.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					if o.GetName() == "slow" {
+						<-ctx.Done()
+						return nil, ctx.Err()
+					}
+					return managed.ConnectionDetails{o.GetName(): []byte(o.GetName())}, nil
+				}),
+				p: publisher(nil),
+				o: []ConnectionReconcilerOption{extractAll},
+				srcs: []ConnectionSource{
+					source("a"),
+					{Name: "slow", Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "slow"}}, Timeout: time.Millisecond},
+					{Name: "b", Resource: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "b"}}, Timeout: time.Minute},
+				},
+			},
+			want: want{
+				res: ConnectionReconcileResult{
+					Published: true,
+					Keys:      []string{"a", "b"},
+					Sources:   map[string]string{"a": "a", "b": "b"},
+					ConnectionSources: []ConnectionSourceStatus{
+						{Name: "a", Keys: 1},
+						{Name: "slow", Error: errors.Errorf(errFmtSourceTimedOut, time.Millisecond, "slow").Error()},
+						{Name: "b", Keys: 1},
+					},
+					TimedOut: []string{"slow"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewConnectionReconciler(tc.args.f, tc.args.p, tc.args.o...)
+			res, err := r.Reconcile(context.Background(), &fake.Composite{}, tc.args.srcs)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nr.Reconcile(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.res, res, test.EquateErrors(), cmpopts.IgnoreFields(ConnectionSourceStatus{}, "LastFetchTime")); diff != "" {
+				t.Errorf("\n%s\nr.Reconcile(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMergeAssembler(t *testing.T) {
+	s := []AssemblySource{
+		{Name: "a", ConnectionDetails: managed.ConnectionDetails{"user": []byte("a"), "pass": []byte("a")}},
+		{Name: "b", ConnectionDetails: managed.ConnectionDetails{"pass": []byte("b")}},
+	}
+	wantConn := managed.ConnectionDetails{"user": []byte("a"), "pass": []byte("b")}
+	wantSources := map[string]string{"user": "a", "pass": "b"}
+
+	conn, sources, err := MergeAssembler().Assemble(context.Background(), s)
+	if err != nil {
+		t.Fatalf("Assemble(...): %s", err)
+	}
+	if diff := cmp.Diff(wantConn, conn); diff != "" {
+		t.Errorf("Assemble(...): later sources should win: -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(wantSources, sources); diff != "" {
+		t.Errorf("Assemble(...): -want sources, +got sources:\n%s", diff)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errFmtMissingConnectionDetails = "connection details are missing required keys: %s"
+	errFmtEmptyConnectionDetails   = "connection details have empty required keys: %s"
+)
+
+// WithRequiredConnectionDetails configures a SecretStoreConnectionPublisher to
+// refuse to publish connection details that don't include all of the supplied
+// keys, for example host, port, username, and password. Keys are checked after
+// they're filtered and renamed, i.e. as they would be published. No keys are
+// required by default.
+func WithRequiredConnectionDetails(keys ...string) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.required = keys
+	}
+}
+
+// WithNonEmptyRequiredConnectionDetails configures a
+// SecretStoreConnectionPublisher to also refuse to publish connection details
+// whose required keys have empty values.
+func WithNonEmptyRequiredConnectionDetails() SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.requireNonEmpty = true
+	}
+}
+
+// A RequiredConnectionDetailsError indicates connection details weren't
+// published because they didn't satisfy their requirements.
+type RequiredConnectionDetailsError struct {
+	// Missing required keys, in the order they were required.
+	Missing []string
+
+	// Empty required keys, in the order they were required. Only reported
+	// if required keys must not be empty.
+	Empty []string
+}
+
+func (e RequiredConnectionDetailsError) Error() string {
+	msgs := make([]string, 0, 2)
+	if len(e.Missing) > 0 {
+		msgs = append(msgs, fmt.Sprintf(errFmtMissingConnectionDetails, strings.Join(e.Missing, ", ")))
+	}
+	if len(e.Empty) > 0 {
+		msgs = append(msgs, fmt.Sprintf(errFmtEmptyConnectionDetails, strings.Join(e.Empty, ", ")))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// IsRequiredConnectionDetails returns the RequiredConnectionDetailsError the
+// supplied error wraps, if any.
+func IsRequiredConnectionDetails(err error) (RequiredConnectionDetailsError, bool) {
+	e := RequiredConnectionDetailsError{}
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// checkRequired returns a RequiredConnectionDetailsError if the supplied
+// connection details don't satisfy the publisher's requirements.
+func (p *SecretStoreConnectionPublisher) checkRequired(data map[string][]byte) error {
+	e := RequiredConnectionDetailsError{}
+	for _, k := range p.required {
+		v, ok := data[k]
+		switch {
+		case !ok:
+			e.Missing = append(e.Missing, k)
+		case p.requireNonEmpty && len(v) == 0:
+			e.Empty = append(e.Empty, k)
+		}
+	}
+	if len(e.Missing) == 0 && len(e.Empty) == 0 {
+		return nil
+	}
+	return e
+}
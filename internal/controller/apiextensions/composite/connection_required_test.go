@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestSecretStoreConnectionPublisherRequiredConnectionDetails(t *testing.T) {
+	complete := managed.ConnectionDetails{
+		"host":     []byte("db.example.org"),
+		"port":     []byte("5432"),
+		"username": []byte("admin"),
+		"password": []byte("secret"),
+	}
+	required := []string{"host", "port", "username", "password"}
+
+	type args struct {
+		filter []string
+		o      []SecretStoreConnectionPublisherOption
+		c      managed.ConnectionDetails
+	}
+	type want struct {
+		published bool
+		err       error
+		required  *RequiredConnectionDetailsError
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotRequired": {
+			reason: "We should publish any connection details if no keys are required.",
+			args: args{
+				c: managed.ConnectionDetails{"host": []byte("db.example.org")},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"Satisfied": {
+			reason: "We should publish connection details that include every required key.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithRequiredConnectionDetails(required...)},
+				c: complete,
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"Missing": {
+			reason: "We should refuse to publish connection details that are missing required keys, listing them.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithRequiredConnectionDetails(required...)},
+				c: managed.ConnectionDetails{"host": []byte("db.example.org"), "port": []byte("5432")},
+			},
+			want: want{
+				err:      errors.Wrap(RequiredConnectionDetailsError{Missing: []string{"username", "password"}}, errPublishStoreEntry),
+				required: &RequiredConnectionDetailsError{Missing: []string{"username", "password"}},
+			},
+		},
+		"MissingAfterFilter": {
+			reason: "Required keys are checked as they would be published, so keys the filter drops are missing.",
+			args: args{
+				filter: []string{"host", "port", "username"},
+				o:      []SecretStoreConnectionPublisherOption{WithRequiredConnectionDetails(required...)},
+				c:      complete,
+			},
+			want: want{
+				err:      errors.Wrap(RequiredConnectionDetailsError{Missing: []string{"password"}}, errPublishStoreEntry),
+				required: &RequiredConnectionDetailsError{Missing: []string{"password"}},
+			},
+		},
+		"EmptyAllowed": {
+			reason: "Required keys may be empty by default.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithRequiredConnectionDetails("password")},
+				c: managed.ConnectionDetails{"password": []byte("")},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"Empty": {
+			reason: "We should refuse to publish connection details with empty required keys if they must not be empty.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{
+					WithRequiredConnectionDetails(required...),
+					WithNonEmptyRequiredConnectionDetails(),
+				},
+				c: managed.ConnectionDetails{"host": []byte(""), "port": []byte("5432"), "username": []byte("")},
+			},
+			want: want{
+				err:      errors.Wrap(RequiredConnectionDetailsError{Missing: []string{"password"}, Empty: []string{"host", "username"}}, errPublishStoreEntry),
+				required: &RequiredConnectionDetailsError{Missing: []string{"password"}, Empty: []string{"host", "username"}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisher(s, tc.args.filter, tc.args.o...)
+			o := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+			published, err := p.PublishConnection(context.Background(), o, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if e, ok := IsRequiredConnectionDetails(err); ok || tc.want.required != nil {
+				if diff := cmp.Diff(tc.want.required, &e); diff != "" {
+					t.Errorf("\n%s\nIsRequiredConnectionDetails(...): -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+			if tc.want.err != nil && s.publishes != 0 {
+				t.Errorf("\n%s\nPublishConnection(...): published to the store despite error", tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"encoding/json"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composed"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// ResolveConnectionDetails returns the connection details the supplied
+// resource template would produce if its composed resource's connection
+// secret contained the supplied data. It doesn't read anything from the API
+// server, so tooling can use it to render connection details offline.
+// FromFieldPath connection details are resolved against the template's base,
+// so paths that are only set once the composed resource exists, for example
+// status fields, won't resolve.
+func ResolveConnectionDetails(data map[string][]byte, t v1.ComposedTemplate) (managed.ConnectionDetails, error) {
+	cd := composed.New()
+	if len(t.Base.Raw) > 0 {
+		if err := json.Unmarshal(t.Base.Raw, cd); err != nil {
+			return nil, errors.Wrap(err, errUnmarshal)
+		}
+	}
+	return ExtractConnectionDetails(cd, data, ExtractConfigsFromTemplate(&t)...)
+}
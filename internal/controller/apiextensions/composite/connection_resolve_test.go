@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+func TestResolveConnectionDetails(t *testing.T) {
+	base := runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.org/v1","kind":"Database","spec":{"port":"5432","empty":""}}`)}
+	fromValue := v1.ConnectionDetailTypeFromValue
+	fromKey := v1.ConnectionDetailTypeFromConnectionSecretKey
+	fromPath := v1.ConnectionDetailTypeFromFieldPath
+	missing := v1.ConnectionDetailEmptyPolicyMissing
+	fail := v1.ConnectionDetailEmptyPolicyError
+
+	type args struct {
+		data map[string][]byte
+		t    v1.ComposedTemplate
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoConnectionDetails": {
+			reason: "A template without connection details should produce none.",
+			args: args{
+				data: map[string][]byte{"password": []byte("secret")},
+				t:    v1.ComposedTemplate{Base: base},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"FromValue": {
+			reason: "FromValue connection details should produce their value.",
+			args: args{
+				t: v1.ComposedTemplate{
+					Base: base,
+					ConnectionDetails: []v1.ConnectionDetail{{
+						Name:  pointer.String("region"),
+						Type:  &fromValue,
+						Value: pointer.String("us-east-1"),
+					}},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"region": []byte("us-east-1")},
+			},
+		},
+		"FromConnectionSecretKey": {
+			reason: "FromConnectionSecretKey connection details should produce the named secret key, named for the key by default.",
+			args: args{
+				data: map[string][]byte{"password": []byte("secret"), "username": []byte("admin")},
+				t: v1.ComposedTemplate{
+					Base: base,
+					ConnectionDetails: []v1.ConnectionDetail{
+						{Name: pointer.String("pw"), Type: &fromKey, FromConnectionSecretKey: pointer.String("password")},
+						{FromConnectionSecretKey: pointer.String("username")},
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"pw": []byte("secret"), "username": []byte("admin")},
+			},
+		},
+		"FromConnectionSecretKeyMissing": {
+			reason: "FromConnectionSecretKey connection details should produce nothing if the secret doesn't contain the key.",
+			args: args{
+				t: v1.ComposedTemplate{
+					Base:              base,
+					ConnectionDetails: []v1.ConnectionDetail{{Type: &fromKey, FromConnectionSecretKey: pointer.String("password")}},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"FromFieldPath": {
+			reason: "FromFieldPath connection details should be resolved against the template's base.",
+			args: args{
+				t: v1.ComposedTemplate{
+					Base: base,
+					ConnectionDetails: []v1.ConnectionDetail{
+						{Name: pointer.String("port"), Type: &fromPath, FromFieldPath: pointer.String("spec.port")},
+						{Name: pointer.String("endpoint"), Type: &fromPath, FromFieldPath: pointer.String("status.endpoint")},
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"port": []byte("5432")},
+			},
+		},
+		"TreatEmptyAsMissing": {
+			reason: "Empty values should be omitted if the connection detail treats them as missing.",
+			args: args{
+				data: map[string][]byte{"password": {}},
+				t: v1.ComposedTemplate{
+					Base: base,
+					ConnectionDetails: []v1.ConnectionDetail{
+						{Type: &fromKey, FromConnectionSecretKey: pointer.String("password"), TreatEmptyAs: &missing},
+						{Name: pointer.String("empty"), Type: &fromPath, FromFieldPath: pointer.String("spec.empty"), TreatEmptyAs: &missing},
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"TreatEmptyAsError": {
+			reason: "Empty values should be an error if the connection detail treats them as such.",
+			args: args{
+				data: map[string][]byte{"password": {}},
+				t: v1.ComposedTemplate{
+					Base:              base,
+					ConnectionDetails: []v1.ConnectionDetail{{Type: &fromKey, FromConnectionSecretKey: pointer.String("password"), TreatEmptyAs: &fail}},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtConnDetailEmpty, "password"),
+			},
+		},
+		"MissingName": {
+			reason: "Connection details that aren't named, and can't be named for their secret key, should be an error.",
+			args: args{
+				t: v1.ComposedTemplate{
+					Base:              base,
+					ConnectionDetails: []v1.ConnectionDetail{{Type: &fromValue, Value: pointer.String("us-east-1")}},
+				},
+			},
+			want: want{
+				err: errors.New(errConnDetailName),
+			},
+		},
+		"InvalidBase": {
+			reason: "A template whose base can't be unmarshalled should be an error.",
+			args: args{
+				t: v1.ComposedTemplate{Base: runtime.RawExtension{Raw: []byte("{")}},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("unexpected end of JSON input"), errUnmarshal),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := ResolveConnectionDetails(tc.args.data, tc.args.t)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nResolveConnectionDetails(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nResolveConnectionDetails(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
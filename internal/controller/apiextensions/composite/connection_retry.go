@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtPublishAttempts   = "cannot publish connection details after %d attempts"
+	errFmtUnpublishAttempts = "cannot unpublish connection details after %d attempts"
+)
+
+// Retry defaults.
+const (
+	DefaultPublishAttempts       = 3
+	DefaultPublishInitialBackoff = 100 * time.Millisecond
+	DefaultPublishMaxBackoff     = 2 * time.Second
+)
+
+// A RetryingConnectionPublisherOption configures a
+// RetryingConnectionPublisher.
+type RetryingConnectionPublisherOption func(*RetryingConnectionPublisher)
+
+// WithPublishAttempts configures the maximum number of times a
+// RetryingConnectionPublisher attempts to publish or unpublish connection
+// details, including the first attempt.
+func WithPublishAttempts(n int) RetryingConnectionPublisherOption {
+	return func(p *RetryingConnectionPublisher) {
+		p.attempts = n
+	}
+}
+
+// WithPublishBackoff configures how long a RetryingConnectionPublisher waits
+// before its first retry. The wait doubles after each retry, up to the
+// supplied maximum.
+func WithPublishBackoff(initial, max time.Duration) RetryingConnectionPublisherOption {
+	return func(p *RetryingConnectionPublisher) {
+		p.initial = initial
+		p.max = max
+	}
+}
+
+// WithPublishRetryable configures which errors a RetryingConnectionPublisher
+// retries. Errors satisfying IsRetryable are retried by default.
+func WithPublishRetryable(fn func(err error) bool) RetryingConnectionPublisherOption {
+	return func(p *RetryingConnectionPublisher) {
+		p.retryable = fn
+	}
+}
+
+// A RetryingConnectionPublisher retries publishing and unpublishing
+// connection details when the publisher it wraps returns a transient error,
+// backing off exponentially between attempts. Other errors are returned
+// immediately. It won't start a retry that it can't finish waiting for before
+// the context's deadline.
+type RetryingConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	attempts  int
+	initial   time.Duration
+	max       time.Duration
+	retryable func(err error) bool
+}
+
+// NewRetryingConnectionPublisher returns a ConnectionPublisher that retries
+// the supplied publisher when it returns a transient error.
+func NewRetryingConnectionPublisher(p managed.ConnectionPublisher, o ...RetryingConnectionPublisherOption) *RetryingConnectionPublisher {
+	rp := &RetryingConnectionPublisher{
+		publisher: p,
+		attempts:  DefaultPublishAttempts,
+		initial:   DefaultPublishInitialBackoff,
+		max:       DefaultPublishMaxBackoff,
+		retryable: IsRetryable,
+	}
+	for _, fn := range o {
+		fn(rp)
+	}
+	return rp
+}
+
+// PublishConnection details for the supplied resource, retrying transient
+// errors.
+func (p *RetryingConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published := false
+	n, err := p.retry(ctx, func() error {
+		var err error
+		published, err = p.publisher.PublishConnection(ctx, o, c)
+		return err
+	})
+	if err != nil && n > 1 {
+		return false, errors.Wrapf(err, errFmtPublishAttempts, n)
+	}
+	return published, err
+}
+
+// UnpublishConnection details for the supplied resource, retrying transient
+// errors.
+func (p *RetryingConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	n, err := p.retry(ctx, func() error {
+		return p.publisher.UnpublishConnection(ctx, o, c)
+	})
+	if err != nil && n > 1 {
+		return errors.Wrapf(err, errFmtUnpublishAttempts, n)
+	}
+	return err
+}
+
+// retry calls the supplied function until it succeeds, returns an error that
+// isn't retryable, or the retry budget is exhausted. It returns the number of
+// attempts made and the last error.
+func (p *RetryingConnectionPublisher) retry(ctx context.Context, fn func() error) (int, error) {
+	wait := p.initial
+	for n := 1; ; n++ {
+		err := fn()
+		if err == nil || n >= p.attempts || !p.retryable(err) {
+			return n, err
+		}
+
+		// Don't start a retry we can't finish waiting for.
+		if d, ok := ctx.Deadline(); ok && time.Until(d) < wait {
+			return n, err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return n, err
+		case <-t.C:
+		}
+
+		wait *= 2
+		if p.max > 0 && wait > p.max {
+			wait = p.max
+		}
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+// flakyPublisher returns the supplied errors from successive calls, then
+// succeeds.
+type flakyPublisher struct {
+	errs  []error
+	calls int
+}
+
+func (p *flakyPublisher) next() error {
+	p.calls++
+	if p.calls > len(p.errs) {
+		return nil
+	}
+	return p.errs[p.calls-1]
+}
+
+func (p *flakyPublisher) PublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+	if err := p.next(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *flakyPublisher) UnpublishConnection(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	return p.next()
+}
+
+func TestRetryingConnectionPublisherPublishConnection(t *testing.T) {
+	errBoom := errors.New("boom")
+	transient := Retryable(errBoom)
+
+	type args struct {
+		ctx  context.Context
+		errs []error
+		o    []RetryingConnectionPublisherOption
+	}
+	type want struct {
+		published bool
+		calls     int
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Success": {
+			reason: "We should publish once if the first attempt succeeds.",
+			args: args{
+				ctx: context.Background(),
+			},
+			want: want{
+				published: true,
+				calls:     1,
+			},
+		},
+		"TransientError": {
+			reason: "We should retry a transient error that clears on the second attempt.",
+			args: args{
+				ctx:  context.Background(),
+				errs: []error{transient},
+				o:    []RetryingConnectionPublisherOption{WithPublishBackoff(time.Millisecond, time.Millisecond)},
+			},
+			want: want{
+				published: true,
+				calls:     2,
+			},
+		},
+		"PermanentError": {
+			reason: "We should fail fast on an error that isn't retryable.",
+			args: args{
+				ctx:  context.Background(),
+				errs: []error{errBoom},
+			},
+			want: want{
+				calls: 1,
+				err:   errBoom,
+			},
+		},
+		"AttemptsExhausted": {
+			reason: "We should give up once we've made the configured number of attempts.",
+			args: args{
+				ctx:  context.Background(),
+				errs: []error{transient, transient, transient},
+				o: []RetryingConnectionPublisherOption{
+					WithPublishAttempts(2),
+					WithPublishBackoff(time.Millisecond, time.Millisecond),
+				},
+			},
+			want: want{
+				calls: 2,
+				err:   errors.Wrapf(transient, errFmtPublishAttempts, 2),
+			},
+		},
+		"DeadlineTooSoon": {
+			reason: "We should not retry if the context's deadline would pass before we could.",
+			args: args{
+				ctx: func() context.Context {
+					ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+					t.Cleanup(cancel)
+					return ctx
+				}(),
+				errs: []error{transient},
+				o:    []RetryingConnectionPublisherOption{WithPublishBackoff(time.Hour, time.Hour)},
+			},
+			want: want{
+				calls: 1,
+				err:   transient,
+			},
+		},
+		"RetryablePredicate": {
+			reason: "We should retry errors that satisfy the supplied predicate.",
+			args: args{
+				ctx:  context.Background(),
+				errs: []error{errBoom},
+				o: []RetryingConnectionPublisherOption{
+					WithPublishBackoff(time.Millisecond, time.Millisecond),
+					WithPublishRetryable(func(err error) bool { return errors.Is(err, errBoom) }),
+				},
+			},
+			want: want{
+				published: true,
+				calls:     2,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fp := &flakyPublisher{errs: tc.args.errs}
+			p := NewRetryingConnectionPublisher(fp, tc.args.o...)
+			published, err := p.PublishConnection(tc.args.ctx, &fake.Composite{}, managed.ConnectionDetails{"a": []byte("b")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.calls, fp.calls); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRetryingConnectionPublisherUnpublishConnection(t *testing.T) {
+	errBoom := errors.New("boom")
+	transient := Retryable(errBoom)
+
+	type want struct {
+		calls int
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		errs   []error
+		want   want
+	}{
+		"TransientError": {
+			reason: "We should retry a transient error that clears on the second attempt.",
+			errs:   []error{transient},
+			want: want{
+				calls: 2,
+			},
+		},
+		"PermanentError": {
+			reason: "We should fail fast on an error that isn't retryable.",
+			errs:   []error{errBoom},
+			want: want{
+				calls: 1,
+				err:   errBoom,
+			},
+		},
+		"AttemptsExhausted": {
+			reason: "We should give up once we've made the configured number of attempts.",
+			errs:   []error{transient, transient, transient},
+			want: want{
+				calls: 3,
+				err:   errors.Wrapf(transient, errFmtUnpublishAttempts, 3),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fp := &flakyPublisher{errs: tc.errs}
+			p := NewRetryingConnectionPublisher(fp, WithPublishBackoff(time.Millisecond, time.Millisecond))
+			err := p.UnpublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{"a": []byte("b")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.calls, fp.calls); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// AnnotationKeyConnectionRotationVersion is the annotation that records the
+// rotation version of a resource's connection details. It should be bumped
+// whenever the resource's credentials are rotated.
+const AnnotationKeyConnectionRotationVersion = "crossplane.io/connection-rotation-version"
+
+// DefaultConnectionRotationVersionKey is the connection detail key under which
+// a RotationVersionConnectionDetailsFetcher exposes rotation versions by
+// default.
+const DefaultConnectionRotationVersionKey = "rotation-version"
+
+// BumpConnectionRotationVersion increments the connection rotation version of
+// the supplied object. A missing or non-numeric version is treated as zero.
+// Callers are responsible for persisting the object.
+func BumpConnectionRotationVersion(o metav1.Object) {
+	v, _ := strconv.ParseInt(o.GetAnnotations()[AnnotationKeyConnectionRotationVersion], 10, 64)
+	meta.AddAnnotations(o, map[string]string{AnnotationKeyConnectionRotationVersion: strconv.FormatInt(v+1, 10)})
+}
+
+// A RotationVersionConnectionDetailsFetcherOption configures a
+// RotationVersionConnectionDetailsFetcher.
+type RotationVersionConnectionDetailsFetcherOption func(*RotationVersionConnectionDetailsFetcher)
+
+// WithRotationVersionKey configures the connection detail key under which the
+// rotation version is exposed.
+func WithRotationVersionKey(k string) RotationVersionConnectionDetailsFetcherOption {
+	return func(f *RotationVersionConnectionDetailsFetcher) {
+		f.key = k
+	}
+}
+
+// WithRotationVersionAnnotation configures the annotation from which the
+// rotation version is read.
+func WithRotationVersionAnnotation(a string) RotationVersionConnectionDetailsFetcherOption {
+	return func(f *RotationVersionConnectionDetailsFetcher) {
+		f.annotation = a
+	}
+}
+
+// A RotationVersionConnectionDetailsFetcher propagates the rotation version of
+// a composed resource as a connection detail. Compositions may use this detail
+// like any other, for example by exposing it from their connection secret.
+// Because the version is published alongside the rotated credentials every
+// dependent composite's connection secret changes in the same publish, so
+// consumers observe the new credentials together rather than piecemeal.
+type RotationVersionConnectionDetailsFetcher struct {
+	fetcher    managed.ConnectionDetailsFetcher
+	annotation string
+	key        string
+}
+
+// NewRotationVersionConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that adds the rotation version of a composed resource to the connection
+// details fetched by the supplied fetcher.
+func NewRotationVersionConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, o ...RotationVersionConnectionDetailsFetcherOption) *RotationVersionConnectionDetailsFetcher {
+	rf := &RotationVersionConnectionDetailsFetcher{
+		fetcher:    f,
+		annotation: AnnotationKeyConnectionRotationVersion,
+		key:        DefaultConnectionRotationVersionKey,
+	}
+	for _, fn := range o {
+		fn(rf)
+	}
+	return rf
+}
+
+// FetchConnection details of the supplied resource, including its rotation
+// version if it has one.
+func (f *RotationVersionConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := o.GetAnnotations()[f.annotation]
+	if !ok {
+		return conn, nil
+	}
+
+	out := make(managed.ConnectionDetails, len(conn)+1)
+	for k, val := range conn {
+		out[k] = val
+	}
+	out[f.key] = []byte(v)
+	return out, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &RotationVersionConnectionDetailsFetcher{}
+
+func TestBumpConnectionRotationVersion(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		a      map[string]string
+		want   string
+	}{
+		"NoVersion": {
+			reason: "A resource without a rotation version should be bumped to version 1.",
+			want:   "1",
+		},
+		"InvalidVersion": {
+			reason: "A resource with a non-numeric rotation version should be bumped to version 1.",
+			a:      map[string]string{AnnotationKeyConnectionRotationVersion: "wat"},
+			want:   "1",
+		},
+		"ExistingVersion": {
+			reason: "A resource with a rotation version should have it incremented.",
+			a:      map[string]string{AnnotationKeyConnectionRotationVersion: "41"},
+			want:   "42",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &fake.Composed{ObjectMeta: metav1.ObjectMeta{Annotations: tc.a}}
+			BumpConnectionRotationVersion(o)
+			if diff := cmp.Diff(tc.want, o.GetAnnotations()[AnnotationKeyConnectionRotationVersion]); diff != "" {
+				t.Errorf("\n%s\nBumpConnectionRotationVersion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRotationVersionConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type args struct {
+		f    managed.ConnectionDetailsFetcher
+		opts []RotationVersionConnectionDetailsFetcherOption
+		o    resource.ConnectionSecretOwner
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	fetched := ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		return managed.ConnectionDetails{"password": []byte("secret")}, nil
+	})
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FetchError": {
+			reason: "We should return errors from the wrapped fetcher.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				o: &fake.Composed{},
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"NoRotationVersion": {
+			reason: "We should return the fetched details unchanged if the resource has no rotation version.",
+			args: args{
+				f: fetched,
+				o: &fake.Composed{},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret")},
+			},
+		},
+		"RotationVersion": {
+			reason: "We should expose the rotation version alongside the fetched details.",
+			args: args{
+				f: fetched,
+				o: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyConnectionRotationVersion: "2"}}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret"), DefaultConnectionRotationVersionKey: []byte("2")},
+			},
+		},
+		"CustomKeyAndAnnotation": {
+			reason: "We should honor a custom rotation version key and annotation.",
+			args: args{
+				f:    fetched,
+				opts: []RotationVersionConnectionDetailsFetcherOption{WithRotationVersionKey("v"), WithRotationVersionAnnotation("example.org/v")},
+				o:    &fake.Composed{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"example.org/v": "3"}}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret"), "v": []byte("3")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewRotationVersionConnectionDetailsFetcher(tc.args.f, tc.args.opts...)
+			conn, err := f.FetchConnection(context.Background(), tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtSchemaViolations = "connection details do not conform to schema: %s"
+
+	errViolationMissing    = "required key is missing"
+	errViolationUnexpected = "key is not in schema"
+	errFmtViolationFormat  = "value is not a valid %s"
+	errFmtViolationPattern = "value does not match pattern %q"
+	errFmtViolationMinLen  = "value is shorter than %d bytes"
+	errFmtViolationMaxLen  = "value is longer than %d bytes"
+	errFmtUnknownFormat    = "unknown value format %q"
+)
+
+// A ValueFormat is a format that a connection detail value must conform to.
+type ValueFormat string
+
+// Value formats.
+const (
+	// ValueFormatInteger values are base 10 integers.
+	ValueFormatInteger ValueFormat = "Integer"
+
+	// ValueFormatPort values are TCP or UDP port numbers.
+	ValueFormatPort ValueFormat = "Port"
+
+	// ValueFormatHostname values are DNS-1123 subdomains.
+	ValueFormatHostname ValueFormat = "Hostname"
+
+	// ValueFormatURI values are absolute URIs.
+	ValueFormatURI ValueFormat = "URI"
+
+	// ValueFormatPEM values contain at least one PEM block.
+	ValueFormatPEM ValueFormat = "PEM"
+
+	// ValueFormatJSON values are valid JSON.
+	ValueFormatJSON ValueFormat = "JSON"
+)
+
+// A KeySchema constrains a connection detail key and its value.
+type KeySchema struct {
+	// Required keys must be present.
+	Required bool
+
+	// Format the value must conform to, if any.
+	Format ValueFormat
+
+	// Pattern the value must match, if any.
+	Pattern *regexp.Regexp
+
+	// MinLength and MaxLength bound the length of the value in bytes. They
+	// are not enforced if they are zero.
+	MinLength int
+	MaxLength int
+}
+
+// A ConnectionDetailsSchema constrains a set of connection details.
+type ConnectionDetailsSchema struct {
+	// Keys maps each connection detail key to its schema.
+	Keys map[string]KeySchema
+
+	// Strict schemas don't allow keys that aren't in the schema.
+	Strict bool
+}
+
+// A SchemaViolation describes how a connection detail key violates a schema.
+// It never includes the key's value.
+type SchemaViolation struct {
+	Key    string
+	Reason string
+}
+
+// A SchemaViolationError reports the ways in which connection details violate
+// a schema.
+type SchemaViolationError struct {
+	// Violations, ordered by key.
+	Violations []SchemaViolation
+}
+
+// Error returns all violations.
+func (e *SchemaViolationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s: %s", v.Key, v.Reason)
+	}
+	return fmt.Sprintf(errFmtSchemaViolations, strings.Join(msgs, "; "))
+}
+
+// Validate the supplied connection details against the schema. It returns
+// the violations, if any, ordered by key.
+func (s ConnectionDetailsSchema) Validate(c managed.ConnectionDetails) []SchemaViolation {
+	var out []SchemaViolation
+
+	for k, ks := range s.Keys {
+		v, ok := c[k]
+		if !ok {
+			if ks.Required {
+				out = append(out, SchemaViolation{Key: k, Reason: errViolationMissing})
+			}
+			continue
+		}
+		out = append(out, ks.validate(k, v)...)
+	}
+
+	if s.Strict {
+		for k := range c {
+			if _, ok := s.Keys[k]; !ok {
+				out = append(out, SchemaViolation{Key: k, Reason: errViolationUnexpected})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (ks KeySchema) validate(k string, v []byte) []SchemaViolation {
+	var out []SchemaViolation
+	if ks.Format != "" {
+		if reason := validateFormat(ks.Format, v); reason != "" {
+			out = append(out, SchemaViolation{Key: k, Reason: reason})
+		}
+	}
+	if ks.Pattern != nil && !ks.Pattern.Match(v) {
+		out = append(out, SchemaViolation{Key: k, Reason: fmt.Sprintf(errFmtViolationPattern, ks.Pattern.String())})
+	}
+	if ks.MinLength > 0 && len(v) < ks.MinLength {
+		out = append(out, SchemaViolation{Key: k, Reason: fmt.Sprintf(errFmtViolationMinLen, ks.MinLength)})
+	}
+	if ks.MaxLength > 0 && len(v) > ks.MaxLength {
+		out = append(out, SchemaViolation{Key: k, Reason: fmt.Sprintf(errFmtViolationMaxLen, ks.MaxLength)})
+	}
+	return out
+}
+
+// validateFormat returns the reason the supplied value doesn't conform to the
+// supplied format, or an empty string if it does.
+func validateFormat(f ValueFormat, v []byte) string {
+	valid := false
+	switch f {
+	case ValueFormatInteger:
+		_, err := strconv.ParseInt(string(v), 10, 64)
+		valid = err == nil
+	case ValueFormatPort:
+		p, err := strconv.ParseUint(string(v), 10, 16)
+		valid = err == nil && p > 0
+	case ValueFormatHostname:
+		valid = len(validation.IsDNS1123Subdomain(string(v))) == 0
+	case ValueFormatURI:
+		u, err := url.Parse(string(v))
+		valid = err == nil && u.IsAbs()
+	case ValueFormatPEM:
+		b, _ := pem.Decode(v)
+		valid = b != nil
+	case ValueFormatJSON:
+		valid = json.Valid(v)
+	default:
+		return fmt.Sprintf(errFmtUnknownFormat, f)
+	}
+	if valid {
+		return ""
+	}
+	return fmt.Sprintf(errFmtViolationFormat, strings.ToLower(string(f)))
+}
+
+// A SchemaEnforcingConnectionPublisher refuses to publish connection details
+// that don't conform to a schema, regardless of how they were fetched.
+type SchemaEnforcingConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	schema    ConnectionDetailsSchema
+}
+
+// NewSchemaEnforcingConnectionPublisher returns a ConnectionPublisher that
+// validates connection details against the supplied schema before publishing
+// them using the supplied publisher.
+func NewSchemaEnforcingConnectionPublisher(p managed.ConnectionPublisher, s ConnectionDetailsSchema) *SchemaEnforcingConnectionPublisher {
+	return &SchemaEnforcingConnectionPublisher{publisher: p, schema: s}
+}
+
+// PublishConnection details for the supplied resource, if they conform to the
+// schema. A *SchemaViolationError is returned if they don't.
+func (s *SchemaEnforcingConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if v := s.schema.Validate(c); len(v) > 0 {
+		return false, &SchemaViolationError{Violations: v}
+	}
+	return s.publisher.PublishConnection(ctx, o, c)
+}
+
+// UnpublishConnection details for the supplied resource.
+func (s *SchemaEnforcingConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return s.publisher.UnpublishConnection(ctx, o, c)
+}
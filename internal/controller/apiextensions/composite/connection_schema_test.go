@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &SchemaEnforcingConnectionPublisher{}
+
+func TestSchemaEnforcingConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	schema := ConnectionDetailsSchema{Keys: map[string]KeySchema{
+		"endpoint": {Required: true, Format: ValueFormatHostname},
+		"port":     {Required: true, Format: ValueFormatPort},
+		"username": {Pattern: regexp.MustCompile(`^[a-z]+$`), MaxLength: 8},
+		"password": {Required: true, MinLength: 12},
+	}}
+
+	type args struct {
+		schema ConnectionDetailsSchema
+		err    error
+		c      managed.ConnectionDetails
+	}
+	type want struct {
+		published bool
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Conforms": {
+			reason: "We should publish connection details that conform to the schema.",
+			args: args{
+				schema: schema,
+				c: managed.ConnectionDetails{
+					"endpoint": []byte("db.example.org"),
+					"port":     []byte("5432"),
+					"password": []byte("correct-horse-battery"),
+					"extra":    []byte("allowed"),
+				},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"Violations": {
+			reason: "We should not publish connection details that violate the schema, and should report every violation.",
+			args: args{
+				schema: schema,
+				c: managed.ConnectionDetails{
+					"endpoint": []byte("Not A Hostname"),
+					"port":     []byte("70000"),
+					"username": []byte("Administrator"),
+				},
+			},
+			want: want{
+				err: &SchemaViolationError{Violations: []SchemaViolation{
+					{Key: "endpoint", Reason: fmt.Sprintf(errFmtViolationFormat, "hostname")},
+					{Key: "password", Reason: errViolationMissing},
+					{Key: "port", Reason: fmt.Sprintf(errFmtViolationFormat, "port")},
+					{Key: "username", Reason: fmt.Sprintf(errFmtViolationPattern, "^[a-z]+$")},
+					{Key: "username", Reason: fmt.Sprintf(errFmtViolationMaxLen, 8)},
+				}},
+			},
+		},
+		"Strict": {
+			reason: "We should not publish keys that aren't in a strict schema.",
+			args: args{
+				schema: ConnectionDetailsSchema{Strict: true, Keys: map[string]KeySchema{"url": {Format: ValueFormatURI}}},
+				c:      managed.ConnectionDetails{"url": []byte("https://example.org"), "extra": []byte("nope")},
+			},
+			want: want{
+				err: &SchemaViolationError{Violations: []SchemaViolation{{Key: "extra", Reason: errViolationUnexpected}}},
+			},
+		},
+		"PublishError": {
+			reason: "We should return errors encountered publishing conforming connection details.",
+			args: args{
+				err: errBoom,
+				c:   managed.ConnectionDetails{"any": []byte("thing")},
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSchemaEnforcingConnectionPublisher(managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+					return tc.args.err == nil, tc.args.err
+				},
+			}, tc.args.schema)
+			published, err := p.PublishConnection(context.Background(), &fake.Composite{}, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				for _, v := range tc.args.c {
+					if strings.Contains(err.Error(), string(v)) {
+						t.Errorf("\n%s\np.PublishConnection(...): error %q includes a connection detail value", tc.reason, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	cases := map[string]struct {
+		f     ValueFormat
+		valid []string
+		bad   []string
+	}{
+		"Integer": {f: ValueFormatInteger, valid: []string{"-1", "42"}, bad: []string{"4.2", "x"}},
+		"Port":    {f: ValueFormatPort, valid: []string{"1", "65535"}, bad: []string{"0", "65536", "http"}},
+		"URI":     {f: ValueFormatURI, valid: []string{"postgres://db:5432/app"}, bad: []string{"http://[::1", "/relative"}},
+		"PEM":     {f: ValueFormatPEM, valid: []string{"-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"}, bad: []string{"AAAA"}},
+		"JSON":    {f: ValueFormatJSON, valid: []string{`{"a":1}`, `"s"`}, bad: []string{`{a:1}`}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, v := range tc.valid {
+				if reason := validateFormat(tc.f, []byte(v)); reason != "" {
+					t.Errorf("validateFormat(%q, %q): want valid, got %q", tc.f, v, reason)
+				}
+			}
+			for _, v := range tc.bad {
+				if reason := validateFormat(tc.f, []byte(v)); reason == "" {
+					t.Errorf("validateFormat(%q, %q): want invalid, got valid", tc.f, v)
+				}
+			}
+		})
+	}
+}
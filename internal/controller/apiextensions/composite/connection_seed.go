@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A SeededConnectionDetailsFetcher merges static seed connection details, for
+// example a region or a documentation URL, into the connection details
+// fetched by another fetcher, typically a chain. Seeds have the lowest
+// precedence; they're only returned for keys no fetcher returned.
+type SeededConnectionDetailsFetcher struct {
+	seed    managed.ConnectionDetails
+	fetcher managed.ConnectionDetailsFetcher
+}
+
+// NewSeededConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// merges the supplied seed connection details into those fetched by the
+// supplied fetcher. The seed is copied, so the caller may reuse it.
+func NewSeededConnectionDetailsFetcher(seed managed.ConnectionDetails, f managed.ConnectionDetailsFetcher) *SeededConnectionDetailsFetcher {
+	return &SeededConnectionDetailsFetcher{seed: deepCopyConnectionDetails(seed), fetcher: f}
+}
+
+// FetchConnection details of the supplied resource, merging in any seed
+// connection details that weren't fetched. Seeds are merged into partial
+// connection details returned alongside an error, too.
+func (f *SeededConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil && conn == nil {
+		return nil, err
+	}
+	if len(f.seed) == 0 {
+		return conn, err
+	}
+
+	// We don't modify the fetched connection details in case the fetcher
+	// shares them, for example because it caches them.
+	out := deepCopyConnectionDetails(f.seed)
+	for k, v := range conn {
+		out[k] = v
+	}
+	return out, err
+}
+
+func deepCopyConnectionDetails(c managed.ConnectionDetails) managed.ConnectionDetails {
+	if c == nil {
+		return nil
+	}
+	out := make(managed.ConnectionDetails, len(c))
+	for k, v := range c {
+		out[k] = append([]byte(nil), v...)
+	}
+	return out
+}
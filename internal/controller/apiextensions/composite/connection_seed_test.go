@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &SeededConnectionDetailsFetcher{}
+
+func TestSeededConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	seed := managed.ConnectionDetails{
+		"region": []byte("us-east-1"),
+		"docs":   []byte("https://example.org/docs"),
+	}
+	fetcher := func(conn managed.ConnectionDetails, err error) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return conn, err
+		})
+	}
+
+	type args struct {
+		seed managed.ConnectionDetails
+		f    managed.ConnectionDetailsFetcher
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoSeed": {
+			reason: "We should return the fetched connection details unchanged if there is no seed.",
+			args: args{
+				f: fetcher(managed.ConnectionDetails{"password": []byte("secret")}, nil),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret")},
+			},
+		},
+		"NothingFetched": {
+			reason: "Seeds should surface when no fetcher provides their keys.",
+			args: args{
+				seed: seed,
+				f:    fetcher(nil, nil),
+			},
+			want: want{
+				conn: seed,
+			},
+		},
+		"OverriddenByFetcher": {
+			reason: "Fetched connection details should take precedence over seeds.",
+			args: args{
+				seed: seed,
+				f: NewConnectionDetailsFetcherChain(ChainMergeFirstWins,
+					fetcher(managed.ConnectionDetails{"region": []byte("eu-west-1")}, nil),
+					fetcher(managed.ConnectionDetails{"password": []byte("secret")}, nil),
+				),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"region":   []byte("eu-west-1"),
+					"docs":     []byte("https://example.org/docs"),
+					"password": []byte("secret"),
+				},
+			},
+		},
+		"Error": {
+			reason: "We should return any error encountered fetching connection details.",
+			args: args{
+				seed: seed,
+				f:    fetcher(nil, errBoom),
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"PartialError": {
+			reason: "We should merge seeds into partial connection details returned alongside an error.",
+			args: args{
+				seed: seed,
+				f:    fetcher(managed.ConnectionDetails{"password": []byte("secret")}, errBoom),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"region":   []byte("us-east-1"),
+					"docs":     []byte("https://example.org/docs"),
+					"password": []byte("secret"),
+				},
+				err: errBoom,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewSeededConnectionDetailsFetcher(tc.args.seed, tc.args.f)
+			conn, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSeededConnectionDetailsFetcherCopiesSeed(t *testing.T) {
+	seed := managed.ConnectionDetails{"region": []byte("us-east-1")}
+	f := NewSeededConnectionDetailsFetcher(seed, NopConnectionDetailsFetcher{})
+
+	// Mutating the caller's seed must not affect the fetcher.
+	seed["region"][0] = 'X'
+	seed["docs"] = []byte("https://example.org/docs")
+
+	conn, _ := f.FetchConnection(context.Background(), &fake.Composed{})
+	if diff := cmp.Diff(managed.ConnectionDetails{"region": []byte("us-east-1")}, conn); diff != "" {
+		t.Errorf("FetchConnection(...): -want, +got:\n%s", diff)
+	}
+
+	// Mutating fetched connection details must not affect the fetcher.
+	conn["region"][0] = 'X'
+	conn, _ = f.FetchConnection(context.Background(), &fake.Composed{})
+	if diff := cmp.Diff(managed.ConnectionDetails{"region": []byte("us-east-1")}, conn); diff != "" {
+		t.Errorf("FetchConnection(...): -want, +got:\n%s", diff)
+	}
+}
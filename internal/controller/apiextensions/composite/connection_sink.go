@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errWritePublishResult = "cannot write publish result"
+)
+
+// A PublishResult records the outcome of publishing connection details. It
+// never includes connection detail keys or values.
+type PublishResult struct {
+	// Time at which publishing started.
+	Time time.Time `json:"time"`
+
+	// Owner of the connection details, as namespace/name or UID.
+	Owner string `json:"owner"`
+
+	// Store to which the connection details were published.
+	Store string `json:"store,omitempty"`
+
+	// Keys is the number of connection detail keys that were published.
+	Keys int `json:"keys"`
+
+	// Changed is true if publishing changed the published connection details.
+	Changed bool `json:"changed"`
+
+	// Duration publishing took.
+	Duration time.Duration `json:"duration"`
+
+	// Error encountered publishing, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// A PublishResultSink records the results of publishing connection details,
+// for example to an observability pipeline.
+type PublishResultSink interface {
+	RecordPublish(ctx context.Context, r PublishResult)
+}
+
+// A PublishResultSinkFn is a function that satisfies the PublishResultSink
+// interface.
+type PublishResultSinkFn func(ctx context.Context, r PublishResult)
+
+// RecordPublish records the supplied publish result.
+func (fn PublishResultSinkFn) RecordPublish(ctx context.Context, r PublishResult) {
+	fn(ctx, r)
+}
+
+// A NopPublishResultSink discards publish results.
+type NopPublishResultSink struct{}
+
+// RecordPublish does nothing.
+func (NopPublishResultSink) RecordPublish(_ context.Context, _ PublishResult) {}
+
+// A JSONLPublishResultSink writes each publish result to an io.Writer, for
+// example a file, as a line of JSON.
+type JSONLPublishResultSink struct {
+	mu    sync.Mutex
+	w     io.Writer
+	onErr func(err error)
+}
+
+// NewJSONLPublishResultSink returns a PublishResultSink that writes publish
+// results as JSON lines to the supplied writer. Errors writing results are
+// passed to the supplied function, if any.
+func NewJSONLPublishResultSink(w io.Writer, onErr func(err error)) *JSONLPublishResultSink {
+	if onErr == nil {
+		onErr = func(_ error) {}
+	}
+	return &JSONLPublishResultSink{w: w, onErr: onErr}
+}
+
+// RecordPublish writes the supplied publish result as a line of JSON.
+func (s *JSONLPublishResultSink) RecordPublish(_ context.Context, r PublishResult) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		s.onErr(errors.Wrap(err, errWritePublishResult))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(b, '\n')); err != nil {
+		s.onErr(errors.Wrap(err, errWritePublishResult))
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+var (
+	_ PublishResultSink = NopPublishResultSink{}
+	_ PublishResultSink = &JSONLPublishResultSink{}
+)
+
+func TestJSONLPublishResultSink(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := NewJSONLPublishResultSink(b, func(err error) { t.Errorf("RecordPublish(...): %s", err) })
+
+	want := []PublishResult{
+		{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Owner: "a", Keys: 2, Changed: true, Duration: time.Second},
+		{Time: time.Date(2023, 1, 1, 0, 0, 1, 0, time.UTC), Owner: "b", Store: "vault", Error: "boom"},
+	}
+	for _, r := range want {
+		s.RecordPublish(context.Background(), r)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	got := make([]PublishResult, len(lines))
+	for i, l := range lines {
+		if err := json.Unmarshal([]byte(l), &got[i]); err != nil {
+			t.Fatalf("json.Unmarshal(%q): %s", l, err)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RecordPublish(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestSecretStoreConnectionPublisherSink(t *testing.T) {
+	errBoom := errors.New("boom")
+	o := &fake.Composite{
+		ObjectMeta: metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{
+			Name:                 "cool",
+			SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+		}},
+	}
+
+	type args struct {
+		p      managed.ConnectionPublisher
+		filter []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   PublishResult
+	}{
+		"Published": {
+			reason: "We should record the number of published keys and whether they changed.",
+			args: args{
+				p: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+						return true, nil
+					},
+				},
+				filter: []string{"a"},
+			},
+			want: PublishResult{Owner: "cool-uid", Store: "vault", Keys: 1, Changed: true},
+		},
+		"Error": {
+			reason: "We should record any error encountered publishing.",
+			args: args{
+				p: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+						return false, errBoom
+					},
+				},
+			},
+			want: PublishResult{Owner: "cool-uid", Store: "vault", Keys: 2, Error: errBoom.Error()},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got PublishResult
+			sink := PublishResultSinkFn(func(_ context.Context, r PublishResult) { got = r })
+			p := NewSecretStoreConnectionPublisher(tc.args.p, tc.args.filter, WithPublishResultSink(sink))
+			_, _ = p.PublishConnection(context.Background(), o, managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")})
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(PublishResult{}, "Time", "Duration")); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
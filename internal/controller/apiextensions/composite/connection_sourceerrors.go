@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+// Error strings.
+const (
+	errFmtSourceErrors = "cannot get connection details of %d connection source(s): %s"
+)
+
+// A SourceError is an error encountered getting the connection details of a
+// connection source.
+type SourceError struct {
+	// Source is the name of the connection source that failed.
+	Source string
+
+	// Fatal is true if the source is required, and false if it is optional
+	// and its failure can be tolerated.
+	Fatal bool
+
+	// Err is the error the source failed with.
+	Err error
+}
+
+func (e SourceError) Error() string {
+	if e.Fatal {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + " (optional)"
+}
+
+// Unwrap returns the error the source failed with.
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
+// SourceErrors are the errors encountered getting the connection details of
+// several connection sources, in source order.
+type SourceErrors []SourceError
+
+func (e SourceErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return fmt.Sprintf(errFmtSourceErrors, len(e), strings.Join(msgs, "; "))
+}
+
+// Fatal returns true if any of the errors are fatal.
+func (e SourceErrors) Fatal() bool {
+	for _, se := range e {
+		if se.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources returns the names of the failed connection sources, optionally
+// only those whose failures are fatal.
+func (e SourceErrors) Sources(fatalOnly bool) []string {
+	var names []string
+	for _, se := range e {
+		if fatalOnly && !se.Fatal {
+			continue
+		}
+		names = append(names, se.Source)
+	}
+	return names
+}
+
+// IsSourceErrors returns the SourceErrors the supplied error wraps, if any.
+func IsSourceErrors(err error) (SourceErrors, bool) {
+	e := SourceErrors{}
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+func TestSourceErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		msg     string
+		fatal   bool
+		sources []string
+		fatals  []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		errs   SourceErrors
+		want   want
+	}{
+		"Tolerable": {
+			reason: "Errors of optional sources should not be fatal.",
+			errs:   SourceErrors{{Source: "a", Err: errBoom}},
+			want: want{
+				msg:     "cannot get connection details of 1 connection source(s): boom (optional)",
+				sources: []string{"a"},
+			},
+		},
+		"Fatal": {
+			reason: "Any error of a required source should be fatal.",
+			errs:   SourceErrors{{Source: "a", Err: errBoom}, {Source: "b", Fatal: true, Err: errBoom}},
+			want: want{
+				msg:     "cannot get connection details of 2 connection source(s): boom (optional); boom",
+				fatal:   true,
+				sources: []string{"a", "b"},
+				fatals:  []string{"b"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want.msg, tc.errs.Error()); diff != "" {
+				t.Errorf("\n%s\nError(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.fatal, tc.errs.Fatal()); diff != "" {
+				t.Errorf("\n%s\nFatal(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.sources, tc.errs.Sources(false)); diff != "" {
+				t.Errorf("\n%s\nSources(false): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.fatals, tc.errs.Sources(true)); diff != "" {
+				t.Errorf("\n%s\nSources(true): -want, +got:\n%s", tc.reason, diff)
+			}
+			got, ok := IsSourceErrors(errors.Wrap(tc.errs, "wrapped"))
+			if !ok {
+				t.Errorf("\n%s\nIsSourceErrors(...): want true, got false", tc.reason)
+			}
+			if diff := cmp.Diff(tc.errs.Error(), got.Error()); diff != "" {
+				t.Errorf("\n%s\nIsSourceErrors(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
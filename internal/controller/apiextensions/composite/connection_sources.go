@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// AnnotationKeyPrefixConnectionDetailSource prefixes the annotations that
+// record which composed resource produced each key of a published connection
+// secret. For example crossplane.io/source-password: cool-db-8fe2x.
+const AnnotationKeyPrefixConnectionDetailSource = "crossplane.io/source-"
+
+// AnnotateConnectionDetailSources annotates the supplied connection secret
+// metadata with the source of each connection detail key. Source annotations
+// for keys that are no longer sourced are removed, so the number of source
+// annotations is bounded by the number of keys. Keys that would produce an
+// invalid annotation name (for example because they are too long) are not
+// annotated.
+func AnnotateConnectionDetailSources(p *xpv1.PublishConnectionDetailsTo, sources map[string]string) {
+	if p == nil {
+		return
+	}
+	if p.Metadata == nil {
+		p.Metadata = &xpv1.ConnectionSecretMetadata{}
+	}
+
+	a := make(map[string]string, len(p.Metadata.Annotations)+len(sources))
+	for k, v := range p.Metadata.Annotations {
+		if strings.HasPrefix(k, AnnotationKeyPrefixConnectionDetailSource) {
+			continue
+		}
+		a[k] = v
+	}
+	for key, source := range sources {
+		if source == "" {
+			continue
+		}
+		k := AnnotationKeyPrefixConnectionDetailSource + key
+		if len(validation.IsQualifiedName(k)) > 0 {
+			continue
+		}
+		a[k] = source
+	}
+
+	if len(a) == 0 {
+		a = nil
+	}
+	p.Metadata.Annotations = a
+}
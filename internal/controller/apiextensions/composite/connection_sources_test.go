@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+func TestAnnotateConnectionDetailSources(t *testing.T) {
+	type args struct {
+		p       *xpv1.PublishConnectionDetailsTo
+		sources map[string]string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *xpv1.PublishConnectionDetailsTo
+	}{
+		"NotPublishing": {
+			reason: "We should do nothing if connection details aren't published.",
+			args: args{
+				sources: map[string]string{"password": "cool-db"},
+			},
+		},
+		"AddSources": {
+			reason: "We should annotate each key with its source, preserving other annotations.",
+			args: args{
+				p: &xpv1.PublishConnectionDetailsTo{
+					Name:     "cool",
+					Metadata: &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{"example.org/a": "b"}},
+				},
+				sources: map[string]string{"password": "cool-db", "endpoint": "cool-lb", "port": ""},
+			},
+			want: &xpv1.PublishConnectionDetailsTo{
+				Name: "cool",
+				Metadata: &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{
+					"example.org/a":                 "b",
+					"crossplane.io/source-password": "cool-db",
+					"crossplane.io/source-endpoint": "cool-lb",
+				}},
+			},
+		},
+		"RemoveStaleSources": {
+			reason: "We should remove source annotations for keys that are no longer sourced.",
+			args: args{
+				p: &xpv1.PublishConnectionDetailsTo{
+					Name: "cool",
+					Metadata: &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{
+						"crossplane.io/source-password": "cool-db",
+						"crossplane.io/source-old":      "cool-old",
+					}},
+				},
+				sources: map[string]string{"password": "cool-db"},
+			},
+			want: &xpv1.PublishConnectionDetailsTo{
+				Name: "cool",
+				Metadata: &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{
+					"crossplane.io/source-password": "cool-db",
+				}},
+			},
+		},
+		"SkipInvalidKeys": {
+			reason: "We should not annotate keys that would produce an invalid annotation name.",
+			args: args{
+				p:       &xpv1.PublishConnectionDetailsTo{Name: "cool"},
+				sources: map[string]string{strings.Repeat("a", 64): "cool-db"},
+			},
+			want: &xpv1.PublishConnectionDetailsTo{
+				Name:     "cool",
+				Metadata: &xpv1.ConnectionSecretMetadata{},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			AnnotateConnectionDetailSources(tc.args.p, tc.args.sources)
+			if diff := cmp.Diff(tc.want, tc.args.p); diff != "" {
+				t.Errorf("\n%s\nAnnotateConnectionDetailSources(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/connection"
+	"github.com/crossplane/crossplane-runtime/pkg/connection/store"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errGetStoreConfig    = "cannot get store config"
+	errConnectStore      = "cannot connect to secret store"
+	errReadStoreMetadata = "cannot read secret store entry metadata"
+)
+
+var (
+	_ ConnectionMetadataFetcher = &StoreConnectionDetailsManager{}
+)
+
+// A StoreConnectionDetailsManagerOption configures a
+// StoreConnectionDetailsManager.
+type StoreConnectionDetailsManagerOption func(*StoreConnectionDetailsManager)
+
+// WithConnectionStoreBuilder configures how a StoreConnectionDetailsManager
+// connects to secret stores.
+func WithConnectionStoreBuilder(sb connection.StoreBuilderFn) StoreConnectionDetailsManagerOption {
+	return func(m *StoreConnectionDetailsManager) {
+		m.storeBuilder = sb
+	}
+}
+
+// A StoreConnectionDetailsManager is a connection.DetailsManager that can also
+// fetch the metadata of the SecretStore entries it publishes to, which records
+// their owner.
+type StoreConnectionDetailsManager struct {
+	*connection.DetailsManager
+
+	client       client.Client
+	newConfig    func() connection.StoreConfig
+	storeBuilder connection.StoreBuilderFn
+}
+
+// NewStoreConnectionDetailsManager returns a StoreConnectionDetailsManager
+// that connects to the secret stores configured by store configs of the
+// supplied kind.
+func NewStoreConnectionDetailsManager(c client.Client, of schema.GroupVersionKind, o ...StoreConnectionDetailsManagerOption) *StoreConnectionDetailsManager {
+	m := &StoreConnectionDetailsManager{
+		client: c,
+		newConfig: func() connection.StoreConfig {
+			return resource.MustCreateObject(of, c.Scheme()).(connection.StoreConfig)
+		},
+		storeBuilder: connection.RuntimeStoreBuilder,
+	}
+	for _, fn := range o {
+		fn(m)
+	}
+	m.DetailsManager = connection.NewDetailsManager(c, of, connection.WithStoreBuilder(m.storeBuilder))
+	return m
+}
+
+// FetchConnectionMetadata fetches the metadata of the SecretStore entry the
+// supplied resource publishes its connection details to. It returns nil
+// metadata if the entry doesn't exist.
+func (m *StoreConnectionDetailsManager) FetchConnectionMetadata(ctx context.Context, o resource.ConnectionSecretOwner) (*xpv1.ConnectionSecretMetadata, error) {
+	p := o.GetPublishConnectionDetailsTo()
+	if p == nil {
+		return nil, nil
+	}
+
+	sc, err := m.storeConfig(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := m.storeBuilder(ctx, m.client, sc.GetStoreConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, errConnectStore)
+	}
+
+	s := &store.Secret{}
+	err = ss.ReadKeyValues(ctx, store.ScopedName{Name: p.Name, Scope: o.GetNamespace()}, s)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return s.Metadata, errors.Wrap(err, errReadStoreMetadata)
+}
+
+func (m *StoreConnectionDetailsManager) storeConfig(ctx context.Context, p *xpv1.PublishConnectionDetailsTo) (connection.StoreConfig, error) {
+	sc := m.newConfig()
+	name := ""
+	if p.SecretStoreConfigRef != nil {
+		name = p.SecretStoreConfigRef.Name
+	}
+	return sc, errors.Wrap(m.client.Get(ctx, types.NamespacedName{Name: name}, sc), errGetStoreConfig)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errCopyPreviousOwner      = "cannot copy connection secret owner"
+	errFetchPreviousStore     = "cannot fetch connection details from previous secret store entry"
+	errUnpublishPreviousStore = "cannot unpublish connection details from previous secret store entry"
+	errUnpublishPrevious      = "cannot unpublish connection details from previous secret store"
+)
+
+// A PreviousStoreUnpublisher unpublishes connection details from the
+// SecretStore entry a resource used to publish to.
+type PreviousStoreUnpublisher interface {
+	// UnpublishPrevious unpublishes the supplied resource's connection
+	// details from the previous entry, if it differs from the current one.
+	// It returns true if it unpublished anything.
+	UnpublishPrevious(ctx context.Context, o resource.ConnectionSecretOwner, prev, cur *xpv1.PublishConnectionDetailsTo) (bool, error)
+}
+
+// UnpublishPrevious unpublishes the supplied resource's connection details
+// from the SecretStore entry described by prev, if it's a different entry
+// from the one described by cur, for example because the resource's
+// composition now publishes to a different store config. Without this the
+// previous entry lingers forever once the resource starts publishing to the
+// current one.
+//
+// Only entries owned by the resource are unpublished. It's safe to call
+// repeatedly; unpublishing an entry that no longer exists is a no-op. It
+// returns an error satisfying errors.Is(err, ErrUnsupported) if ownership
+// isn't recorded because entries are shared.
+func (p *SecretStoreConnectionPublisher) UnpublishPrevious(ctx context.Context, o resource.ConnectionSecretOwner, prev, cur *xpv1.PublishConnectionDetailsTo) (bool, error) {
+	if prev == nil || !storeEntryChanged(prev, cur) || p.dryRun {
+		return false, nil
+	}
+	if p.shared {
+		return false, errors.Wrap(ErrUnsupported, errListShared)
+	}
+
+	// We operate on a copy because the underlying publisher reads which
+	// entry to unpublish from the resource.
+	po, ok := copyOwner(o)
+	if !ok {
+		return false, errors.New(errCopyPreviousOwner)
+	}
+	po.SetPublishConnectionDetailsTo(prev.DeepCopy())
+
+	// There's nothing to unpublish if the previous entry is already gone.
+	if f, ok := p.publisher.(managed.ConnectionDetailsFetcher); ok {
+		current, err := f.FetchConnection(ctx, po)
+		if err != nil {
+			return false, errors.Wrap(resource.IgnoreNotFound(err), errFetchPreviousStore)
+		}
+		if len(current) == 0 {
+			return false, nil
+		}
+	}
+
+	// Ownership can only be enforced if the underlying publisher is also a
+	// ConnectionMetadataFetcher. We trust that entries whose owner we can't
+	// read are ours, just like PublishConnection does.
+	if f, ok := p.publisher.(ConnectionMetadataFetcher); ok {
+		md, err := f.FetchConnectionMetadata(ctx, po)
+		if err != nil {
+			return false, errors.Wrap(resource.IgnoreNotFound(err), errFetchPreviousStore)
+		}
+		if md != nil && md.GetOwnerUID() != "" && md.GetOwnerUID() != string(o.GetUID()) {
+			return false, nil
+		}
+	}
+
+	if err := p.UnpublishConnection(ctx, po, nil); err != nil {
+		return false, errors.Wrap(err, errUnpublishPreviousStore)
+	}
+	return true, nil
+}
+
+// storeEntryChanged returns true if the supplied PublishConnectionDetailsTo
+// describe different SecretStore entries.
+func storeEntryChanged(prev, cur *xpv1.PublishConnectionDetailsTo) bool {
+	if cur == nil {
+		return true
+	}
+	if prev.Name != cur.Name || storeConfigName(prev) != storeConfigName(cur) {
+		return true
+	}
+	return publishNamespace(prev) != publishNamespace(cur)
+}
+
+func storeConfigName(to *xpv1.PublishConnectionDetailsTo) string {
+	if to.SecretStoreConfigRef == nil {
+		return ""
+	}
+	return to.SecretStoreConfigRef.Name
+}
+
+func publishNamespace(to *xpv1.PublishConnectionDetailsTo) string {
+	if to.Metadata == nil {
+		return ""
+	}
+	return to.Metadata.Annotations[AnnotationKeyConnectionSecretNamespace]
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+// An entryStore is an in-memory SecretStore that stores connection details in
+// entries identified by store config and name.
+type entryStore struct {
+	entries  map[string]managed.ConnectionDetails
+	owners   map[string]string
+	errFetch error
+}
+
+func entryID(o resource.ConnectionSecretOwner) string {
+	to := o.GetPublishConnectionDetailsTo()
+	return storeConfigName(to) + "/" + to.Name
+}
+
+func (s *entryStore) FetchConnection(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if s.errFetch != nil {
+		return nil, s.errFetch
+	}
+	return s.entries[entryID(o)], nil
+}
+
+func (s *entryStore) FetchConnectionMetadata(_ context.Context, o resource.ConnectionSecretOwner) (*xpv1.ConnectionSecretMetadata, error) {
+	if s.errFetch != nil {
+		return nil, s.errFetch
+	}
+	owner, ok := s.owners[entryID(o)]
+	if !ok {
+		return nil, nil
+	}
+	return ownedBy(owner), nil
+}
+
+func (s *entryStore) PublishConnection(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	s.entries[entryID(o)] = c
+	return true, nil
+}
+
+func (s *entryStore) UnpublishConnection(_ context.Context, o resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	delete(s.entries, entryID(o))
+	return nil
+}
+
+// A previousStoreUnpublisherFn is a PreviousStoreUnpublisher.
+type previousStoreUnpublisherFn func(ctx context.Context, o resource.ConnectionSecretOwner, prev, cur *xpv1.PublishConnectionDetailsTo) (bool, error)
+
+func (fn previousStoreUnpublisherFn) UnpublishPrevious(ctx context.Context, o resource.ConnectionSecretOwner, prev, cur *xpv1.PublishConnectionDetailsTo) (bool, error) {
+	return fn(ctx, o, prev, cur)
+}
+
+func TestSecretStoreConnectionPublisherUnpublishPrevious(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	to := func(store, name string) *xpv1.PublishConnectionDetailsTo {
+		return &xpv1.PublishConnectionDetailsTo{Name: name, SecretStoreConfigRef: &xpv1.Reference{Name: store}}
+	}
+	entries := func() map[string]managed.ConnectionDetails {
+		return map[string]managed.ConnectionDetails{
+			"kubernetes/cool": {"a": []byte("a")},
+			"kubernetes/else": {"a": []byte("a")},
+			"vault/cool":      {"a": []byte("a")},
+		}
+	}
+	owners := map[string]string{
+		"kubernetes/cool": "cool-uid",
+		"kubernetes/else": "other-uid",
+		"vault/cool":      "cool-uid",
+	}
+
+	type args struct {
+		s    *entryStore
+		o    []SecretStoreConnectionPublisherOption
+		prev *xpv1.PublishConnectionDetailsTo
+		cur  *xpv1.PublishConnectionDetailsTo
+	}
+	type want struct {
+		unpublished bool
+		err         error
+		entries     []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoPrevious": {
+			reason: "We should do nothing if the resource didn't previously publish connection details.",
+			args: args{
+				s:   &entryStore{entries: entries(), owners: owners},
+				cur: to("vault", "cool"),
+			},
+			want: want{
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+		"Unchanged": {
+			reason: "We should do nothing if the resource still publishes to the same store entry.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				prev: to("kubernetes", "cool"),
+				cur:  to("kubernetes", "cool"),
+			},
+			want: want{
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+		"StoreNameChanged": {
+			reason: "We should unpublish the previous entry if the resource now publishes to an entry with a different name.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				prev: to("kubernetes", "cool"),
+				cur:  to("kubernetes", "new"),
+			},
+			want: want{
+				unpublished: true,
+				entries:     []string{"kubernetes/else", "vault/cool"},
+			},
+		},
+		"StoreConfigRefChanged": {
+			reason: "We should unpublish the previous entry if the resource now publishes to a different store config.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				prev: to("kubernetes", "cool"),
+				cur:  to("vault", "cool"),
+			},
+			want: want{
+				unpublished: true,
+				entries:     []string{"kubernetes/else", "vault/cool"},
+			},
+		},
+		"AlreadyUnpublished": {
+			reason: "We should do nothing if the previous entry no longer exists, for example because we already unpublished it.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				prev: to("kubernetes", "gone"),
+				cur:  to("vault", "cool"),
+			},
+			want: want{
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+		"NotOwned": {
+			reason: "We should not unpublish a previous entry owned by another resource.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				prev: to("kubernetes", "else"),
+				cur:  to("vault", "cool"),
+			},
+			want: want{
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+		"FetchError": {
+			reason: "We should return any error encountered fetching the previous entry.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners, errFetch: errBoom},
+				prev: to("kubernetes", "cool"),
+				cur:  to("vault", "cool"),
+			},
+			want: want{
+				err:     errors.Wrap(errBoom, errFetchPreviousStore),
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+		"Shared": {
+			reason: "We should not unpublish the previous entry if ownership of entries isn't recorded.",
+			args: args{
+				s:    &entryStore{entries: entries(), owners: owners},
+				o:    []SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries()},
+				prev: to("kubernetes", "cool"),
+				cur:  to("vault", "cool"),
+			},
+			want: want{
+				err:     errors.Wrap(ErrUnsupported, errListShared),
+				entries: []string{"kubernetes/cool", "kubernetes/else", "vault/cool"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xr := &fake.Composite{
+				ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+				ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: tc.args.cur},
+			}
+			p := NewSecretStoreConnectionPublisher(tc.args.s, nil, tc.args.o...)
+			unpublished, err := p.UnpublishPrevious(context.Background(), xr, tc.args.prev, tc.args.cur)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishPrevious(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.unpublished, unpublished); diff != "" {
+				t.Errorf("\n%s\nUnpublishPrevious(...): -want unpublished, +got unpublished:\n%s", tc.reason, diff)
+			}
+			got := make([]string, 0, len(tc.args.s.entries))
+			for id := range tc.args.s.entries {
+				got = append(got, id)
+			}
+			if diff := cmp.Diff(tc.want.entries, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("\n%s\nUnpublishPrevious(...): -want entries, +got entries:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.args.cur, xr.GetPublishConnectionDetailsTo()); diff != "" {
+				t.Errorf("\n%s\nUnpublishPrevious(...): -want resource unchanged, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
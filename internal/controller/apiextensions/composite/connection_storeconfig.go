@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	secretsv1alpha1 "github.com/crossplane/crossplane/apis/secrets/v1alpha1"
+)
+
+// Error strings.
+const (
+	errGetStoreConfig     = "cannot get secret store config"
+	errFmtStoreConfigName = "secret store config %q"
+)
+
+// ErrStoreConfigNotFound is returned when connection details can't be
+// published because the secret store config they should be published to
+// doesn't exist. Use errors.Is to test for it.
+var ErrStoreConfigNotFound = errors.New("secret store config not found")
+
+// ReasonStoreConfigNotFound indicates a composite resource's connection
+// details can't be published because its secret store config doesn't exist.
+const ReasonStoreConfigNotFound xpv1.ConditionReason = "StoreConfigNotFound"
+
+// StoreConfigNotFound returns a condition that indicates a composite resource
+// could not be reconciled because the secret store config its connection
+// details should be published to doesn't exist.
+func StoreConfigNotFound(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeSynced,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonStoreConfigNotFound,
+		Message:            err.Error(),
+	}
+}
+
+// WithStoreConfigCheck configures a SecretStoreConnectionPublisher to check
+// that the secret store config a resource publishes to exists before it
+// publishes, and to return an error satisfying
+// errors.Is(err, ErrStoreConfigNotFound) if it doesn't. Without this check a
+// missing store config surfaces as whatever error the underlying publisher
+// returns.
+func WithStoreConfigCheck(c client.Reader) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.storeConfigs = c
+	}
+}
+
+// checkStoreConfig returns an error if the secret store config the supplied
+// resource publishes to doesn't exist.
+func (p *SecretStoreConnectionPublisher) checkStoreConfig(ctx context.Context, o resource.ConnectionSecretOwner) error {
+	name := storeName(o)
+	if p.storeConfigs == nil || name == "" {
+		return nil
+	}
+	err := p.storeConfigs.Get(ctx, types.NamespacedName{Name: name}, &secretsv1alpha1.StoreConfig{})
+	if kerrors.IsNotFound(err) {
+		return errors.Wrapf(ErrStoreConfigNotFound, errFmtStoreConfigName, name)
+	}
+	return errors.Wrap(err, errGetStoreConfig)
+}
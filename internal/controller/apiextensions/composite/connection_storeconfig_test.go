@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	secretsv1alpha1 "github.com/crossplane/crossplane/apis/secrets/v1alpha1"
+)
+
+func TestSecretStoreConnectionPublisherStoreConfigCheck(t *testing.T) {
+	errBoom := errors.New("boom")
+	notFound := kerrors.NewNotFound(schema.GroupResource{Group: secretsv1alpha1.Group, Resource: "storeconfigs"}, "vault")
+
+	owner := func(store *xpv1.Reference) *fake.Composite {
+		return &fake.Composite{
+			ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{
+				Name:                 "cool",
+				SecretStoreConfigRef: store,
+			}},
+		}
+	}
+
+	type args struct {
+		o     *fake.Composite
+		check client.Reader
+	}
+	type want struct {
+		published bool
+		err       error
+		notFound  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoCheck": {
+			reason: "We should publish without checking the store config exists if we weren't asked to check.",
+			args: args{
+				o: owner(&xpv1.Reference{Name: "vault"}),
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"StoreConfigExists": {
+			reason: "We should publish if the store config exists.",
+			args: args{
+				o:     owner(&xpv1.Reference{Name: "vault"}),
+				check: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"NoStoreConfig": {
+			reason: "We should not check a store config exists if the resource doesn't reference one.",
+			args: args{
+				o:     owner(nil),
+				check: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			},
+			want: want{
+				published: true,
+			},
+		},
+		"StoreConfigNotFound": {
+			reason: "We should return ErrStoreConfigNotFound if the store config doesn't exist.",
+			args: args{
+				o:     owner(&xpv1.Reference{Name: "vault"}),
+				check: &test.MockClient{MockGet: test.NewMockGetFn(notFound)},
+			},
+			want: want{
+				err:      errors.Wrapf(ErrStoreConfigNotFound, errFmtStoreConfigName, "vault"),
+				notFound: true,
+			},
+		},
+		"GetStoreConfigError": {
+			reason: "We should return any other error encountered getting the store config.",
+			args: args{
+				o:     owner(&xpv1.Reference{Name: "vault"}),
+				check: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetStoreConfig),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			var o []SecretStoreConnectionPublisherOption
+			if tc.args.check != nil {
+				o = append(o, WithStoreConfigCheck(tc.args.check))
+			}
+			p := NewSecretStoreConnectionPublisher(s, nil, o...)
+			published, err := p.PublishConnection(context.Background(), tc.args.o, managed.ConnectionDetails{"a": []byte("b")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if got := errors.Is(err, ErrStoreConfigNotFound); got != tc.want.notFound {
+				t.Errorf("\n%s\nerrors.Is(err, ErrStoreConfigNotFound): want %t, got %t", tc.reason, tc.want.notFound, got)
+			}
+			if tc.want.err != nil && s.publishes != 0 {
+				t.Errorf("\n%s\nPublishConnection(...): published to the store despite error", tc.reason)
+			}
+		})
+	}
+}
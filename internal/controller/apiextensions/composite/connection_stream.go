@@ -0,0 +1,249 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errMarshalConnectionEvent = "cannot marshal connection details event"
+	errProduceConnectionEvent = "cannot produce connection details event"
+	errFmtEncryptEventValue   = "cannot encrypt connection details event value %q"
+	errFmtStreamFailurePolicy = "unknown stream failure policy %q"
+)
+
+// A ConnectionEvent describes a change to an owner's published connection
+// details. It includes connection detail values only if they're encrypted.
+type ConnectionEvent struct {
+	// Time at which the change was published.
+	Time time.Time `json:"time"`
+
+	// Owner of the connection details, as UID or namespace/name.
+	Owner string `json:"owner"`
+
+	// Store to which the connection details were published.
+	Store string `json:"store,omitempty"`
+
+	// Changed is the keys that were added or whose values changed, in
+	// alphabetical order.
+	Changed []string `json:"changed,omitempty"`
+
+	// Removed is the keys that are no longer published, in alphabetical
+	// order.
+	Removed []string `json:"removed,omitempty"`
+
+	// Hashes maps each changed key to the hex encoded SHA-256 hash of its
+	// value.
+	Hashes map[string]string `json:"hashes,omitempty"`
+
+	// Values maps each changed key to its encrypted value. Values are only
+	// included if the StreamConnectionPublisher has an Encrypter.
+	Values map[string][]byte `json:"values,omitempty"`
+}
+
+// A StreamProducer produces messages to a message stream, for example a Kafka
+// topic or NATS subject.
+type StreamProducer interface {
+	// Produce the supplied message. Messages with the same key should be
+	// delivered in order.
+	Produce(ctx context.Context, key string, message []byte) error
+}
+
+// A StreamProducerFn is a function that satisfies the StreamProducer
+// interface.
+type StreamProducerFn func(ctx context.Context, key string, message []byte) error
+
+// Produce the supplied message.
+func (fn StreamProducerFn) Produce(ctx context.Context, key string, message []byte) error {
+	return fn(ctx, key, message)
+}
+
+// A StreamFailurePolicy determines how a StreamConnectionPublisher handles
+// failures to produce events.
+type StreamFailurePolicy string
+
+// Stream failure policies.
+const (
+	// StreamFailurePolicyHard returns an error if an event can't be
+	// produced. The event is produced again the next time connection details
+	// are published.
+	StreamFailurePolicyHard StreamFailurePolicy = "Hard"
+
+	// StreamFailurePolicySoft logs failures to produce events, and
+	// otherwise ignores them. Events that can't be produced are lost.
+	StreamFailurePolicySoft StreamFailurePolicy = "Soft"
+)
+
+// A StreamConnectionPublisherOption configures a StreamConnectionPublisher.
+type StreamConnectionPublisherOption func(*StreamConnectionPublisher)
+
+// WithStreamFailurePolicy configures how a StreamConnectionPublisher handles
+// failures to produce events.
+func WithStreamFailurePolicy(p StreamFailurePolicy) StreamConnectionPublisherOption {
+	return func(s *StreamConnectionPublisher) {
+		s.policy = p
+	}
+}
+
+// WithStreamValueEncrypter configures a StreamConnectionPublisher to include
+// connection detail values in events, encrypted using the supplied Encrypter.
+func WithStreamValueEncrypter(e Encrypter) StreamConnectionPublisherOption {
+	return func(s *StreamConnectionPublisher) {
+		s.encrypter = e
+	}
+}
+
+// WithStreamLogger configures the logger a StreamConnectionPublisher uses to
+// log failures when its failure policy is soft.
+func WithStreamLogger(l logging.Logger) StreamConnectionPublisherOption {
+	return func(s *StreamConnectionPublisher) {
+		s.log = l
+	}
+}
+
+// A StreamConnectionPublisher produces an event to a message stream when an
+// owner's connection details change, so that consumers can react to changes
+// without watching secrets. Changes are detected by comparing the hashes of
+// the connection details being published to those of the last event produced
+// for the owner, which are held in memory. The first event produced for an
+// owner after a restart therefore reports all of its keys as changed.
+type StreamConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	producer  StreamProducer
+	policy    StreamFailurePolicy
+	encrypter Encrypter
+	log       logging.Logger
+
+	mu       sync.Mutex
+	produced map[string]map[string]string
+}
+
+// NewStreamConnectionPublisher returns a ConnectionPublisher that publishes
+// using the supplied publisher, then produces an event describing any changes
+// using the supplied producer. Failures to produce events are hard failures
+// by default.
+func NewStreamConnectionPublisher(p managed.ConnectionPublisher, sp StreamProducer, o ...StreamConnectionPublisherOption) (*StreamConnectionPublisher, error) {
+	s := &StreamConnectionPublisher{
+		publisher: p,
+		producer:  sp,
+		policy:    StreamFailurePolicyHard,
+		log:       logging.NewNopLogger(),
+		produced:  make(map[string]map[string]string),
+	}
+	for _, fn := range o {
+		fn(s)
+	}
+	switch s.policy {
+	case StreamFailurePolicyHard, StreamFailurePolicySoft:
+	default:
+		return nil, errors.Errorf(errFmtStreamFailurePolicy, s.policy)
+	}
+	return s, nil
+}
+
+// PublishConnection details for the supplied resource, then produce an event
+// if they changed since the last event produced for the resource.
+func (s *StreamConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published, err := s.publisher.PublishConnection(ctx, o, c)
+	if err != nil {
+		return published, err
+	}
+
+	id := ownerID(o)
+	hashes := make(map[string]string, len(c))
+	for k, v := range c {
+		h := sha256.Sum256(v)
+		hashes[k] = hex.EncodeToString(h[:])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := ConnectionEvent{Time: time.Now(), Owner: id, Store: storeName(o), Hashes: map[string]string{}}
+	last := s.produced[id]
+	for _, k := range OrderedKeys(c, AlphabeticalKeyOrder()) {
+		if last[k] == hashes[k] {
+			continue
+		}
+		e.Changed = append(e.Changed, k)
+		e.Hashes[k] = hashes[k]
+	}
+	for k := range last {
+		if _, ok := hashes[k]; !ok {
+			e.Removed = append(e.Removed, k)
+		}
+	}
+	sort.Strings(e.Removed)
+	if len(e.Changed) == 0 && len(e.Removed) == 0 {
+		return published, nil
+	}
+
+	if err := s.produce(ctx, c, e); err != nil {
+		if s.policy == StreamFailurePolicyHard {
+			return published, err
+		}
+		s.log.Info("Cannot produce connection details event", "owner", id, "error", err)
+	}
+
+	// We record what we produced even when a soft failure loses the event, so
+	// that we don't produce it again on every publish.
+	s.produced[id] = hashes
+	return published, nil
+}
+
+func (s *StreamConnectionPublisher) produce(ctx context.Context, c managed.ConnectionDetails, e ConnectionEvent) error {
+	if s.encrypter != nil {
+		e.Values = make(map[string][]byte, len(e.Changed))
+		for _, k := range e.Changed {
+			ct, err := s.encrypter.Encrypt(ctx, c[k])
+			if err != nil {
+				return errors.Wrapf(err, errFmtEncryptEventValue, k)
+			}
+			e.Values[k] = ct
+		}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, errMarshalConnectionEvent)
+	}
+	return errors.Wrap(s.producer.Produce(ctx, e.Owner, b), errProduceConnectionEvent)
+}
+
+// UnpublishConnection details for the supplied resource, and forget the last
+// event produced for it.
+func (s *StreamConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	if err := s.publisher.UnpublishConnection(ctx, o, c); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.produced, ownerID(o))
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionPublisher = &StreamConnectionPublisher{}
+
+func TestStreamConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	hash := func(v string) string {
+		h := sha256.Sum256([]byte(v))
+		return hex.EncodeToString(h[:])
+	}
+
+	type publish struct {
+		c       managed.ConnectionDetails
+		produce error
+		err     error
+	}
+	type args struct {
+		opts      []StreamConnectionPublisherOption
+		publishes []publish
+	}
+	type want struct {
+		events []ConnectionEvent
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"ChangesOnly": {
+			reason: "We should produce events only when connection details change, describing only what changed, without values.",
+			args: args{
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")}},
+					{c: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")}},
+					{c: managed.ConnectionDetails{"a": []byte("A")}},
+				},
+			},
+			want: want{
+				events: []ConnectionEvent{
+					{Owner: "cool-uid", Changed: []string{"a", "b"}, Hashes: map[string]string{"a": hash("a"), "b": hash("b")}},
+					{Owner: "cool-uid", Changed: []string{"a"}, Removed: []string{"b"}, Hashes: map[string]string{"a": hash("A")}},
+				},
+			},
+		},
+		"EncryptedValues": {
+			reason: "We should include encrypted values if configured to.",
+			args: args{
+				opts:      []StreamConnectionPublisherOption{WithStreamValueEncrypter(reverser(nil))},
+				publishes: []publish{{c: managed.ConnectionDetails{"a": []byte("abc")}}},
+			},
+			want: want{
+				events: []ConnectionEvent{
+					{Owner: "cool-uid", Changed: []string{"a"}, Hashes: map[string]string{"a": hash("abc")}, Values: map[string][]byte{"a": []byte("cba")}},
+				},
+			},
+		},
+		"HardFailure": {
+			reason: "We should return an error and produce the event again next time if the failure policy is hard.",
+			args: args{
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a")}, produce: errBoom, err: errors.Wrap(errBoom, errProduceConnectionEvent)},
+					{c: managed.ConnectionDetails{"a": []byte("a")}},
+				},
+			},
+			want: want{
+				events: []ConnectionEvent{
+					{Owner: "cool-uid", Changed: []string{"a"}, Hashes: map[string]string{"a": hash("a")}},
+				},
+			},
+		},
+		"SoftFailure": {
+			reason: "We should not return an error, or produce the lost event again, if the failure policy is soft.",
+			args: args{
+				opts: []StreamConnectionPublisherOption{WithStreamFailurePolicy(StreamFailurePolicySoft)},
+				publishes: []publish{
+					{c: managed.ConnectionDetails{"a": []byte("a")}, produce: errBoom},
+					{c: managed.ConnectionDetails{"a": []byte("a")}},
+				},
+			},
+			want: want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var events []ConnectionEvent
+			var produceErr error
+			p, err := NewStreamConnectionPublisher(managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+					return true, nil
+				},
+			}, StreamProducerFn(func(_ context.Context, key string, message []byte) error {
+				if produceErr != nil {
+					return produceErr
+				}
+				e := ConnectionEvent{}
+				if err := json.Unmarshal(message, &e); err != nil {
+					return err
+				}
+				if key != e.Owner {
+					t.Errorf("Produce(...): want key %q, got %q", e.Owner, key)
+				}
+				events = append(events, e)
+				return nil
+			}), tc.args.opts...)
+			if err != nil {
+				t.Fatalf("NewStreamConnectionPublisher(...): %s", err)
+			}
+
+			o := &fake.Composite{ObjectMeta: metav1.ObjectMeta{UID: "cool-uid"}}
+			for i, pub := range tc.args.publishes {
+				produceErr = pub.produce
+				_, err := p.PublishConnection(context.Background(), o, pub.c)
+				if diff := cmp.Diff(pub.err, err, test.EquateErrors()); diff != "" {
+					t.Errorf("\n%s\np.PublishConnection(...) %d: -want error, +got error:\n%s", tc.reason, i, diff)
+				}
+			}
+
+			if diff := cmp.Diff(tc.want.events, events, cmpopts.IgnoreFields(ConnectionEvent{}, "Time")); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewStreamConnectionPublisherInvalidPolicy(t *testing.T) {
+	_, err := NewStreamConnectionPublisher(nil, nil, WithStreamFailurePolicy("Sometimes"))
+	if diff := cmp.Diff(errors.Errorf(errFmtStreamFailurePolicy, "Sometimes"), err, test.EquateErrors()); diff != "" {
+		t.Errorf("NewStreamConnectionPublisher(...): -want error, +got error:\n%s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtConnDetailKeyMissing = "connection detail %q: composed resource %q has no connection secret key %q"
+)
+
+// A CheckedConnectionDetailsExtractorOption configures a
+// CheckedConnectionDetailsExtractor.
+type CheckedConnectionDetailsExtractorOption func(*CheckedConnectionDetailsExtractor)
+
+// WithStrictExtraction configures a CheckedConnectionDetailsExtractor to
+// return an error, rather than log, when a connection detail references a
+// connection secret key the composed resource doesn't have.
+func WithStrictExtraction() CheckedConnectionDetailsExtractorOption {
+	return func(e *CheckedConnectionDetailsExtractor) {
+		e.strict = true
+	}
+}
+
+// WithExtractionLogger configures the logger a
+// CheckedConnectionDetailsExtractor uses to record missing connection secret
+// keys.
+func WithExtractionLogger(l logging.Logger) CheckedConnectionDetailsExtractorOption {
+	return func(e *CheckedConnectionDetailsExtractor) {
+		e.log = l
+	}
+}
+
+// A CheckedConnectionDetailsExtractor extracts connection details like
+// ExtractConnectionDetails, but reports connection details that reference a
+// connection secret key the composed resource doesn't have. Such keys may
+// be written in future, so by default they're only logged. In strict mode
+// they're an error, which surfaces misconfigured compositions that would
+// otherwise produce incomplete connection details.
+//
+// Connection details with an empty name or a nil FromConnectionSecretKey are
+// invalid, and are an error in either mode.
+type CheckedConnectionDetailsExtractor struct {
+	log    logging.Logger
+	strict bool
+}
+
+// NewCheckedConnectionDetailsExtractor returns a
+// CheckedConnectionDetailsExtractor. It is lenient by default.
+func NewCheckedConnectionDetailsExtractor(o ...CheckedConnectionDetailsExtractorOption) *CheckedConnectionDetailsExtractor {
+	e := &CheckedConnectionDetailsExtractor{log: logging.NewNopLogger()}
+	for _, fn := range o {
+		fn(e)
+	}
+	return e
+}
+
+// ExtractConnection details from the supplied composed resource.
+func (e *CheckedConnectionDetailsExtractor) ExtractConnection(cd resource.Composed, conn managed.ConnectionDetails, cfg ...ConnectionDetailExtractConfig) (managed.ConnectionDetails, error) {
+	out, err := ExtractConnectionDetails(cd, conn, cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	// ExtractConnectionDetails has validated that every config has a name,
+	// and that FromConnectionSecretKey configs have a key.
+	for _, c := range cfg {
+		if c.Type != ConnectionDetailTypeFromConnectionSecretKey || conn[*c.FromConnectionSecretKey] != nil {
+			continue
+		}
+		if e.strict {
+			return nil, errors.Errorf(errFmtConnDetailKeyMissing, c.Name, cd.GetName(), *c.FromConnectionSecretKey)
+		}
+		e.log.Debug("Composed resource has no connection secret key", "connection-detail", c.Name, "composed", cd.GetName(), "key", *c.FromConnectionSecretKey)
+	}
+	return out, nil
+}
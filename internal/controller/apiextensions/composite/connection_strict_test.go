@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+// A debugRecorder is a logger that records debug messages.
+type debugRecorder struct {
+	messages *[]string
+}
+
+func (l debugRecorder) Info(_ string, _ ...any) {}
+
+func (l debugRecorder) Debug(msg string, _ ...any) { *l.messages = append(*l.messages, msg) }
+
+func (l debugRecorder) WithValues(_ ...any) logging.Logger { return l }
+
+func TestCheckedConnectionDetailsExtractor(t *testing.T) {
+	cd := &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}}
+	conn := managed.ConnectionDetails{"password": []byte("secret")}
+
+	type args struct {
+		strict bool
+		cfg    []ConnectionDetailExtractConfig
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		logs []string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"EmptyNameLenient": {
+			reason: "A connection detail with an empty name should be an error in lenient mode.",
+			args: args{
+				cfg: []ConnectionDetailExtractConfig{{Type: ConnectionDetailTypeFromConnectionSecretKey, FromConnectionSecretKey: pointer.String("password")}},
+			},
+			want: want{
+				err: errors.New(errConnDetailName),
+			},
+		},
+		"EmptyNameStrict": {
+			reason: "A connection detail with an empty name should be an error in strict mode.",
+			args: args{
+				strict: true,
+				cfg:    []ConnectionDetailExtractConfig{{Type: ConnectionDetailTypeFromConnectionSecretKey, FromConnectionSecretKey: pointer.String("password")}},
+			},
+			want: want{
+				err: errors.New(errConnDetailName),
+			},
+		},
+		"NilKeyLenient": {
+			reason: "A connection detail with a nil connection secret key should be an error in lenient mode.",
+			args: args{
+				cfg: []ConnectionDetailExtractConfig{{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "password"}},
+			},
+			want: want{
+				err: errors.Errorf(errFmtConnDetailKey, ConnectionDetailTypeFromConnectionSecretKey),
+			},
+		},
+		"NilKeyStrict": {
+			reason: "A connection detail with a nil connection secret key should be an error in strict mode.",
+			args: args{
+				strict: true,
+				cfg:    []ConnectionDetailExtractConfig{{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "password"}},
+			},
+			want: want{
+				err: errors.Errorf(errFmtConnDetailKey, ConnectionDetailTypeFromConnectionSecretKey),
+			},
+		},
+		"MissingKeyLenient": {
+			reason: "A connection secret key the composed resource doesn't have should be logged and skipped in lenient mode.",
+			args: args{
+				cfg: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "password", FromConnectionSecretKey: pointer.String("password")},
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "username", FromConnectionSecretKey: pointer.String("username")},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret")},
+				logs: []string{"Composed resource has no connection secret key"},
+			},
+		},
+		"MissingKeyStrict": {
+			reason: "A connection secret key the composed resource doesn't have should be an error in strict mode.",
+			args: args{
+				strict: true,
+				cfg: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "password", FromConnectionSecretKey: pointer.String("password")},
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "username", FromConnectionSecretKey: pointer.String("username")},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtConnDetailKeyMissing, "username", "cool-composed", "username"),
+			},
+		},
+		"AllKeysPresentStrict": {
+			reason: "Connection details should be extracted as usual in strict mode if all keys are present.",
+			args: args{
+				strict: true,
+				cfg: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "pw", FromConnectionSecretKey: pointer.String("password")},
+					{Type: ConnectionDetailTypeFromValue, Name: "username", Value: pointer.String("admin")},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"pw": []byte("secret"), "username": []byte("admin")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logs []string
+			o := []CheckedConnectionDetailsExtractorOption{WithExtractionLogger(debugRecorder{messages: &logs})}
+			if tc.args.strict {
+				o = append(o, WithStrictExtraction())
+			}
+			e := NewCheckedConnectionDetailsExtractor(o...)
+			got, err := e.ExtractConnection(cd, conn, tc.args.cfg...)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExtractConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, got); diff != "" {
+				t.Errorf("\n%s\nExtractConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.logs, logs); diff != "" {
+				t.Errorf("\n%s\nExtractConnection(...): -want logs, +got logs:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtSubstituteKey = "cannot resolve field path %q of connection secret key %q"
+)
+
+// connectionSecretKeyToken matches a $(field.path) substitution token. Neither
+// $, ( nor ) are valid in a Kubernetes Secret key, so a token can't be
+// mistaken for part of a literal key.
+var connectionSecretKeyToken = regexp.MustCompile(`\$\(([^)]+)\)`)
+
+// SubstituteConnectionSecretKeys resolves any $(field.path) tokens in the
+// FromConnectionSecretKey of the supplied extract configs using the supplied
+// composite resource. For example a key of 'user-$(spec.parameters.dbName)'
+// becomes 'user-example' if the composite's spec.parameters.dbName is
+// 'example'. A name that defaulted to the unresolved key is resolved too.
+// It returns an error if a token references a field the XR doesn't have.
+func SubstituteConnectionSecretKeys(xr resource.Composite, cfgs []ConnectionDetailExtractConfig) ([]ConnectionDetailExtractConfig, error) {
+	var p *fieldpath.Paved
+	out := make([]ConnectionDetailExtractConfig, len(cfgs))
+	for i := range cfgs {
+		out[i] = cfgs[i]
+		if cfgs[i].FromConnectionSecretKey == nil || !connectionSecretKeyToken.MatchString(*cfgs[i].FromConnectionSecretKey) {
+			continue
+		}
+
+		// Only pave the XR if we actually need to, which is rarely.
+		if p == nil {
+			m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(xr)
+			if err != nil {
+				return nil, errors.Wrap(err, errPaveObject)
+			}
+			p = fieldpath.Pave(m)
+		}
+
+		key := *cfgs[i].FromConnectionSecretKey
+		var rerr error
+		resolved := connectionSecretKeyToken.ReplaceAllStringFunc(key, func(token string) string {
+			path := connectionSecretKeyToken.FindStringSubmatch(token)[1]
+			v, err := p.GetValue(path)
+			if err != nil && rerr == nil {
+				rerr = errors.Wrapf(err, errFmtSubstituteKey, path, key)
+			}
+			return fmt.Sprint(v)
+		})
+		if rerr != nil {
+			return nil, rerr
+		}
+
+		out[i].FromConnectionSecretKey = &resolved
+		if cfgs[i].Name == key {
+			out[i].Name = resolved
+		}
+	}
+	return out, nil
+}
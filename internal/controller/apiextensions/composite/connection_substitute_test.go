@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestSubstituteConnectionSecretKeys(t *testing.T) {
+	xr := &composite.Unstructured{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"parameters": map[string]any{
+				"dbName": "example",
+				"shard":  int64(3),
+			},
+		},
+	}}}
+
+	type args struct {
+		xr   resource.Composite
+		cfgs []ConnectionDetailExtractConfig
+	}
+	type want struct {
+		cfgs []ConnectionDetailExtractConfig
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoTokens": {
+			reason: "Configs without substitution tokens should be returned unchanged.",
+			args: args{
+				xr: xr,
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user", FromConnectionSecretKey: pointer.String("user")},
+					{Type: ConnectionDetailTypeFromValue, Name: "port", Value: pointer.String("$(spec.port)")},
+				},
+			},
+			want: want{
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user", FromConnectionSecretKey: pointer.String("user")},
+					{Type: ConnectionDetailTypeFromValue, Name: "port", Value: pointer.String("$(spec.port)")},
+				},
+			},
+		},
+		"Substituted": {
+			reason: "Tokens in the connection secret key should be resolved from the XR.",
+			args: args{
+				xr: xr,
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user", FromConnectionSecretKey: pointer.String("user-$(spec.parameters.dbName)-$(spec.parameters.shard)")},
+				},
+			},
+			want: want{
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user", FromConnectionSecretKey: pointer.String("user-example-3")},
+				},
+			},
+		},
+		"DefaultedNameSubstituted": {
+			reason: "A name that defaulted to the unresolved key should also be resolved.",
+			args: args{
+				xr: xr,
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user-$(spec.parameters.dbName)", FromConnectionSecretKey: pointer.String("user-$(spec.parameters.dbName)")},
+				},
+			},
+			want: want{
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user-example", FromConnectionSecretKey: pointer.String("user-example")},
+				},
+			},
+		},
+		"MissingParameter": {
+			reason: "We should return an error if a token references a field the XR doesn't have.",
+			args: args{
+				xr: xr,
+				cfgs: []ConnectionDetailExtractConfig{
+					{Type: ConnectionDetailTypeFromConnectionSecretKey, Name: "user", FromConnectionSecretKey: pointer.String("user-$(spec.parameters.nope)")},
+				},
+			},
+			want: want{
+				err: errors.Wrapf(errors.New("spec.parameters.nope: no such field"), errFmtSubstituteKey, "spec.parameters.nope", "user-$(spec.parameters.nope)"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := SubstituteConnectionSecretKeys(tc.args.xr, tc.args.cfgs)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nSubstituteConnectionSecretKeys(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.cfgs, got); diff != "" {
+				t.Errorf("\n%s\nSubstituteConnectionSecretKeys(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
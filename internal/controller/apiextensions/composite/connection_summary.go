@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errSetConnectionSources = "cannot set connection sources status"
+)
+
+const (
+	// DefaultMaxConnectionSources is the default maximum number of
+	// connection sources summarized in a composite resource's status.
+	DefaultMaxConnectionSources = 50
+
+	// maxConnectionSourceErrorLength bounds the length of each summarized
+	// connection source error.
+	maxConnectionSourceErrorLength = 256
+)
+
+// A ConnectionSourceStatus summarizes the connection details a composite
+// resource fetched from one of its composed resources. It never includes
+// connection detail keys or values.
+type ConnectionSourceStatus struct {
+	// Name of the composed resource.
+	Name string `json:"name"`
+
+	// Keys is the number of connection detail keys the composed resource
+	// contributed.
+	Keys int `json:"keys"`
+
+	// Error encountered fetching connection details, if any.
+	Error string `json:"error,omitempty"`
+
+	// LastFetchTime is the time connection details were last fetched.
+	LastFetchTime *metav1.Time `json:"lastFetchTime,omitempty"`
+}
+
+// SetConnectionSources writes the supplied connection source summary to the
+// supplied composite resource's status.connectionSources field. At most max
+// sources are written; sources that errored are preferred, then sources are
+// ordered by name. Long errors are truncated. Composite resources that are
+// not unstructured are not modified.
+func SetConnectionSources(xr resource.Composite, s []ConnectionSourceStatus, max int) error {
+	u, ok := xr.(interface{ UnstructuredContent() map[string]any })
+	if !ok {
+		return nil
+	}
+
+	out := make([]ConnectionSourceStatus, len(s))
+	copy(out, s)
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].Error != "") != (out[j].Error != "") {
+			return out[i].Error != ""
+		}
+		return out[i].Name < out[j].Name
+	})
+	if max >= 0 && len(out) > max {
+		out = out[:max]
+	}
+	for i := range out {
+		if len(out[i].Error) > maxConnectionSourceErrorLength {
+			out[i].Error = out[i].Error[:maxConnectionSourceErrorLength]
+		}
+	}
+
+	return errors.Wrap(fieldpath.Pave(u.UnstructuredContent()).SetValue("status.connectionSources", out), errSetConnectionSources)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+)
+
+func TestSetConnectionSources(t *testing.T) {
+	type args struct {
+		s   []ConnectionSourceStatus
+		max int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []any
+	}{
+		"Ordered": {
+			reason: "Sources that errored should be written first, then sources ordered by name.",
+			args: args{
+				s: []ConnectionSourceStatus{
+					{Name: "b", Keys: 2},
+					{Name: "c", Error: "boom"},
+					{Name: "a", Keys: 1},
+				},
+				max: DefaultMaxConnectionSources,
+			},
+			want: []any{
+				map[string]any{"name": "c", "keys": int64(0), "error": "boom"},
+				map[string]any{"name": "a", "keys": int64(1)},
+				map[string]any{"name": "b", "keys": int64(2)},
+			},
+		},
+		"Bounded": {
+			reason: "No more than the maximum number of sources should be written, and long errors should be truncated.",
+			args: args{
+				s: []ConnectionSourceStatus{
+					{Name: "a", Keys: 1},
+					{Name: "b", Error: strings.Repeat("x", 1000)},
+				},
+				max: 1,
+			},
+			want: []any{
+				map[string]any{"name": "b", "keys": int64(0), "error": strings.Repeat("x", maxConnectionSourceErrorLength)},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xr := composite.New()
+			if err := SetConnectionSources(xr, tc.args.s, tc.args.max); err != nil {
+				t.Fatalf("SetConnectionSources(...): %s", err)
+			}
+			got, err := fieldpath.Pave(xr.UnstructuredContent()).GetValue("status.connectionSources")
+			if err != nil {
+				t.Fatalf("GetValue(...): %s", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nSetConnectionSources(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -18,36 +18,53 @@ package composite
 
 import (
 	"context"
+	"encoding/base64"
+	"path"
+	"regexp"
+	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 
 	iov1alpha1 "github.com/crossplane/crossplane/apis/apiextensions/fn/io/v1alpha1"
 	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/crossplane/crossplane/internal/xcrd"
 )
 
 var (
 	_ managed.ConnectionDetailsFetcher = &SecretConnectionDetailsFetcher{}
 	_ managed.ConnectionDetailsFetcher = ConnectionDetailsFetcherChain{}
+	_ managed.ConnectionDetailsFetcher = FirstWinsConnectionDetailsFetcherChain{}
+	_ managed.ConnectionDetailsFetcher = &CollectingConnectionDetailsFetcherChain{}
 )
 
 func TestSecretConnectionDetailsFetcher(t *testing.T) {
 	errBoom := errors.New("boom")
 	sref := &xpv1.SecretReference{Name: "foo", Namespace: "bar"}
+	_, errParse := fieldpath.Parse("status[")
 	s := &corev1.Secret{
 		Data: map[string][]byte{
 			"foo": []byte("a"),
@@ -57,6 +74,7 @@ func TestSecretConnectionDetailsFetcher(t *testing.T) {
 
 	type params struct {
 		kube client.Client
+		opts []SecretConnectionDetailsFetcherOption
 	}
 	type args struct {
 		ctx context.Context
@@ -106,6 +124,65 @@ func TestSecretConnectionDetailsFetcher(t *testing.T) {
 				err: errors.Wrap(errBoom, errGetSecret),
 			},
 		},
+		"RewriteSecretReferenceError": {
+			reason: "Should fail if the connection secret reference can't be rewritten.",
+			params: params{
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceRewrite(func(_ resource.ConnectionSecretOwner, _ xpv1.SecretReference) (xpv1.SecretReference, error) {
+					return xpv1.SecretReference{}, errBoom
+				})},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errRewriteSecretRef),
+			},
+		},
+		"RewriteSecretReferenceInvalid": {
+			reason: "Should fail if the connection secret reference is rewritten to an invalid reference.",
+			params: params{
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceRewrite(func(_ resource.ConnectionSecretOwner, ref xpv1.SecretReference) (xpv1.SecretReference, error) {
+					return xpv1.SecretReference{Name: "", Namespace: ref.Namespace}, nil
+				})},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtInvalidRewrittenRef, "bar", "foo", "bar", "", strings.Join(validation.IsDNS1123Subdomain(""), "; ")),
+			},
+		},
+		"RewriteSecretReference": {
+			reason: "Should fetch connection details from the rewritten connection secret reference.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					if key.Name != "restored-foo" || key.Namespace != "restored" {
+						t.Errorf("wrong secret is queried: %s", key)
+						return errBoom
+					}
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceRewrite(func(_ resource.ConnectionSecretOwner, ref xpv1.SecretReference) (xpv1.SecretReference, error) {
+					return xpv1.SecretReference{Name: "restored-" + ref.Name, Namespace: "restored"}, nil
+				})},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: &xpv1.SecretReference{Name: "foo", Namespace: "bar"}},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
 		"Success": {
 			reason: "Should fetch all connection details from the connection secret.",
 			params: params{
@@ -121,81 +198,1741 @@ func TestSecretConnectionDetailsFetcher(t *testing.T) {
 				}},
 			},
 			args: args{
-				o: &fake.Composed{
-					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
-				},
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
+		"ExposableKeysAnnotationMissing": {
+			reason: "Should fetch all connection details if the secret does not declare exposable keys.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithExposableKeysAnnotation(AnnotationKeyExposableKeys)},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
+		"ExposableKeysAnnotation": {
+			reason: "Should fetch only the connection details the secret declares exposable.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					obj.SetAnnotations(map[string]string{AnnotationKeyExposableKeys: "foo, baz"})
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithExposableKeysAnnotation(AnnotationKeyExposableKeys)},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+				},
+			},
+		},
+		"SecretNotReady": {
+			reason: "Should fetch no connection details if the secret is not yet annotated as ready.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					obj.SetAnnotations(map[string]string{AnnotationKeySecretReady: "false"})
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReadyAnnotation(AnnotationKeySecretReady)},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				conn: nil,
+			},
+		},
+		"SecretReady": {
+			reason: "Should fetch all connection details once the secret is annotated as ready.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					obj.SetAnnotations(map[string]string{AnnotationKeySecretReady: "true"})
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReadyAnnotation(AnnotationKeySecretReady)},
+			},
+			args: args{
+				o: &fake.Composed{
+					ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: sref},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
+		"SecretReferenceFieldPath": {
+			reason: "Should fetch connection details from the secret referenced at the configured field path.",
+			params: params{
+				kube: &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					if key.Name != sref.Name || key.Namespace != sref.Namespace {
+						t.Errorf("wrong secret is queried")
+						return errBoom
+					}
+					s.DeepCopyInto(obj.(*corev1.Secret))
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceFieldPath("status.atProvider.secretRef")},
+			},
+			args: args{
+				o: withContent(map[string]any{"status": map[string]any{"atProvider": map[string]any{
+					"secretRef": map[string]any{"name": sref.Name, "namespace": sref.Namespace},
+				}}}),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
+		"SecretReferenceFieldPathNotSet": {
+			reason: "Should not fail if the configured field path has yet to be written.",
+			params: params{
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceFieldPath("status.atProvider.secretRef")},
+			},
+			args: args{
+				o: withContent(map[string]any{}),
+			},
+		},
+		"SecretReferenceFieldPathInvalid": {
+			reason: "Should fail if the configured field path cannot be parsed.",
+			params: params{
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceFieldPath("status[")},
+			},
+			args: args{
+				o: withContent(map[string]any{}),
+			},
+			want: want{
+				err: errors.Wrapf(errParse, errFmtSecretRefPath, "status["),
+			},
+		},
+		"SecretDiscovered": {
+			reason: "Should fetch connection details from the one secret labelled with the composed resource's name.",
+			params: params{
+				kube: &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+					lo := &client.ListOptions{}
+					lo.ApplyOptions(opts)
+					if !lo.LabelSelector.Matches(labels.Set{"example.org/owner": "cool-composed"}) {
+						t.Errorf("wrong label selector: %s", lo.LabelSelector)
+					}
+					obj.(*corev1.SecretList).Items = []corev1.Secret{*s}
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretDiscoveryLabel("example.org/owner")},
+			},
+			args: args{
+				o: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"foo": s.Data["foo"],
+					"bar": s.Data["bar"],
+				},
+			},
+		},
+		"NoSecretDiscovered": {
+			reason: "Should fail if no secret is labelled with the composed resource's name.",
+			params: params{
+				kube: &test.MockClient{MockList: test.NewMockListFn(nil)},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretDiscoveryLabel("example.org/owner")},
+			},
+			args: args{
+				o: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}},
+			},
+			want: want{
+				err: errors.Errorf(errFmtNoDiscoveredSecret, "example.org/owner", "cool-composed"),
+			},
+		},
+		"MultipleSecretsDiscovered": {
+			reason: "Should fail if several secrets are labelled with the composed resource's name.",
+			params: params{
+				kube: &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+					obj.(*corev1.SecretList).Items = []corev1.Secret{*s, *s}
+					return nil
+				}},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretDiscoveryLabel("example.org/owner")},
+			},
+			args: args{
+				o: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}},
+			},
+			want: want{
+				err: errors.Errorf(errFmtDiscoveredSecrets, 2, "example.org/owner", "cool-composed"),
+			},
+		},
+		"ListSecretsError": {
+			reason: "Should fail if secrets cannot be listed.",
+			params: params{
+				kube: &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretDiscoveryLabel("example.org/owner")},
+			},
+			args: args{
+				o: &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errListSecrets),
+			},
+		},
+		"SecretReferenceFieldPathNoName": {
+			reason: "Should fail if the secret reference at the configured field path has no name.",
+			params: params{
+				opts: []SecretConnectionDetailsFetcherOption{WithSecretReferenceFieldPath("status.secretRef")},
+			},
+			args: args{
+				o: withContent(map[string]any{"status": map[string]any{"secretRef": map[string]any{"namespace": "bar"}}}),
+			},
+			want: want{
+				err: errors.Errorf(errFmtSecretRefNoName, "status.secretRef"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewSecretConnectionDetailsFetcher(tc.params.kube, tc.params.opts...)
+			conn, err := c.FetchConnection(tc.args.ctx, tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nFetchFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func withContent(c map[string]any) *composed.Unstructured {
+	cd := composed.New()
+	cd.SetUnstructuredContent(c)
+	return cd
+}
+
+func TestSecretStoreConnectionPublisherPublish(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type args struct {
+		p      managed.ConnectionPublisher
+		filter []string
+		c      managed.ConnectionDetails
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"Unchanged": {
+			reason: "We should report that we didn't publish connection details identical to those already published.",
+			args: args{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}, metadata: ownedBy("cool-uid")},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: false,
+		},
+		"ChangedValue": {
+			reason: "We should report that we published connection details with changed values.",
+			args: args{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				c: managed.ConnectionDetails{"a": []byte("A")},
+			},
+			want: true,
+		},
+		"AddedKey": {
+			reason: "We should report that we published connection details with new keys.",
+			args: args{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				c: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+			want: true,
+		},
+		"FilteredKeysIgnored": {
+			reason: "We should not consider keys the filter doesn't allow to be changes.",
+			args: args{
+				p:      &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a"), "stale": []byte("stale")}, metadata: ownedBy("cool-uid")},
+				filter: []string{"a"},
+				c:      managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+			want: false,
+		},
+		"OwnershipRecorded": {
+			reason: "We should consider recording ownership of an existing store entry to be a change.",
+			args: args{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: true,
+		},
+		"NotAFetcher": {
+			reason: "We should trust the underlying publisher if we can't read the published connection details.",
+			args: args{
+				p: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+						return true, nil
+					},
+				},
+				c: managed.ConnectionDetails{"a": []byte("a")},
+			},
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSecretStoreConnectionPublisher(tc.args.p, tc.args.filter)
+			published, err := p.PublishConnection(context.Background(), publishing, tc.args.c)
+			if err != nil {
+				t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherEmpty(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type args struct {
+		data     managed.ConnectionDetails
+		metadata *xpv1.ConnectionSecretMetadata
+		filter   []string
+		o        []SecretStoreConnectionPublisherOption
+		c        managed.ConnectionDetails
+	}
+	type want struct {
+		published bool
+		publishes int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"EmptySkipped": {
+			reason: "We should not call the store if there are no connection details to publish.",
+			args: args{
+				c: managed.ConnectionDetails{},
+			},
+			want: want{
+				published: false,
+				publishes: 0,
+			},
+		},
+		"FilteredToEmptySkipped": {
+			reason: "We should not call the store if the filter drops every connection detail.",
+			args: args{
+				filter: []string{"a"},
+				c:      managed.ConnectionDetails{"b": []byte("b")},
+			},
+			want: want{
+				published: false,
+				publishes: 0,
+			},
+		},
+		"EmptyPublished": {
+			reason: "We should create an empty store entry if configured to publish empty connection details.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithEmptyConnectionDetailsPublishing()},
+				c: managed.ConnectionDetails{},
+			},
+			want: want{
+				published: true,
+				publishes: 1,
+			},
+		},
+		"EmptyPublishedUnchanged": {
+			reason: "We should report that publishing empty connection details to an existing empty store entry changed nothing.",
+			args: args{
+				data:     managed.ConnectionDetails{},
+				metadata: ownedBy("cool-uid"),
+				o:        []SecretStoreConnectionPublisherOption{WithEmptyConnectionDetailsPublishing()},
+				c:        managed.ConnectionDetails{},
+			},
+			want: want{
+				published: false,
+				publishes: 1,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{data: tc.args.data, metadata: tc.args.metadata}
+			p := NewSecretStoreConnectionPublisher(s, tc.args.filter, tc.args.o...)
+			published, err := p.PublishConnection(context.Background(), publishing, tc.args.c)
+			if err != nil {
+				t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.publishes, s.publishes); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want publishes, +got publishes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherOwnership(t *testing.T) {
+	publishing := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type args struct {
+		data     managed.ConnectionDetails
+		metadata *xpv1.ConnectionSecretMetadata
+		opts     []SecretStoreConnectionPublisherOption
+	}
+	type want struct {
+		data     managed.ConnectionDetails
+		metadata *xpv1.ConnectionSecretMetadata
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FirstWriter": {
+			reason: "We should record ownership of a new store entry in its metadata, not its connection details.",
+			args:   args{},
+			want: want{
+				data:     managed.ConnectionDetails{"a": []byte("a")},
+				metadata: ownedBy("cool-uid"),
+			},
+		},
+		"SameOwner": {
+			reason: "We should publish to a store entry we own.",
+			args: args{
+				data:     managed.ConnectionDetails{"a": []byte("old")},
+				metadata: ownedBy("cool-uid"),
+			},
+			want: want{
+				data:     managed.ConnectionDetails{"a": []byte("a")},
+				metadata: ownedBy("cool-uid"),
+			},
+		},
+		"ConflictingOwner": {
+			reason: "We should refuse to publish to a store entry owned by another resource.",
+			args: args{
+				data:     managed.ConnectionDetails{"a": []byte("theirs")},
+				metadata: ownedBy("other-uid"),
+			},
+			want: want{
+				data:     managed.ConnectionDetails{"a": []byte("theirs")},
+				metadata: ownedBy("other-uid"),
+				err:      errors.Wrap(errors.Errorf(errFmtNotOwner, "other-uid"), errPublishStoreEntry),
+			},
+		},
+		"Shared": {
+			reason: "We should neither record nor enforce ownership of shared store entries.",
+			args: args{
+				data:     managed.ConnectionDetails{"b": []byte("b")},
+				metadata: ownedBy("other-uid"),
+				opts:     []SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries()},
+			},
+			want: want{
+				data:     managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+				metadata: ownedBy("other-uid"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{data: tc.args.data, metadata: tc.args.metadata}
+			p := NewSecretStoreConnectionPublisher(s, nil, tc.args.opts...)
+			_, err := p.PublishConnection(context.Background(), publishing, managed.ConnectionDetails{"a": []byte("a")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.metadata, s.metadata); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want metadata, +got metadata:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}, publishing.ConnectionDetailsPublisherTo); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want resource unchanged, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCopyOwner(t *testing.T) {
+	to := &xpv1.PublishConnectionDetailsTo{Name: "cool"}
+
+	cases := map[string]struct {
+		reason string
+		o      resource.ConnectionSecretOwner
+	}{
+		"Unstructured": {
+			reason: "We should copy an unstructured composite resource as a composite resource.",
+			o: func() resource.ConnectionSecretOwner {
+				xr := composite.New()
+				xr.SetPublishConnectionDetailsTo(to)
+				return xr
+			}(),
+		},
+		"Typed": {
+			reason: "We should copy a typed resource using DeepCopyObject.",
+			o:      &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: to.DeepCopy()}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, ok := copyOwner(tc.o)
+			if !ok {
+				t.Fatalf("\n%s\ncopyOwner(...): cannot copy %T", tc.reason, tc.o)
+			}
+
+			// Modifying the copy must not modify the original.
+			got.SetPublishConnectionDetailsTo(&xpv1.PublishConnectionDetailsTo{Name: "copy"})
+			if diff := cmp.Diff(to, tc.o.GetPublishConnectionDetailsTo()); diff != "" {
+				t.Errorf("\n%s\ncopyOwner(...): -want original unchanged, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherPublishConnectionWithReport(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	c := managed.ConnectionDetails{
+		"user":            []byte("admin"),
+		"password":        []byte("secret"),
+		"ca":              []byte("cert"),
+		"node-0-password": []byte("a"),
+	}
+
+	type args struct {
+		o    resource.ConnectionSecretOwner
+		p    func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher
+		conn managed.ConnectionDetails
+	}
+	type want struct {
+		r    PublishReport
+		logs []string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotPublishing": {
+			reason: "We should return an empty report if the resource doesn't publish connection details.",
+			args: args{
+				o: &fake.Composite{},
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					return NewSecretStoreConnectionPublisher(s, []string{"user"}, WithPublisherLogger(log))
+				},
+				conn: c,
+			},
+		},
+		"NoFilter": {
+			reason: "We should report that every key was published and none dropped if there is no filter.",
+			args: args{
+				o: publishing,
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					return NewSecretStoreConnectionPublisher(s, nil, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+				},
+				conn: c,
+			},
+			want: want{
+				r: PublishReport{
+					Published:     true,
+					PublishedKeys: []string{"ca", "node-0-password", "password", "user"},
+				},
+				logs: []string{"Published connection details"},
+			},
+		},
+		"ExactFilter": {
+			reason: "We should report the keys the filter dropped, and log them at debug level.",
+			args: args{
+				o: publishing,
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					return NewSecretStoreConnectionPublisher(s, []string{"user", "password"}, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+				},
+				conn: c,
+			},
+			want: want{
+				r: PublishReport{
+					Published:     true,
+					PublishedKeys: []string{"password", "user"},
+					DroppedKeys:   []string{"ca", "node-0-password"},
+				},
+				logs: []string{"Connection details were dropped by filter", "Published connection details"},
+			},
+		},
+		"GlobFilter": {
+			reason: "We should report the keys a glob filter dropped.",
+			args: args{
+				o: publishing,
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					p, _ := NewSecretStoreConnectionPublisherWithFilterMode(s, []string{"*password"}, FilterModeGlob, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+					return p
+				},
+				conn: c,
+			},
+			want: want{
+				r: PublishReport{
+					Published:     true,
+					PublishedKeys: []string{"node-0-password", "password"},
+					DroppedKeys:   []string{"ca", "user"},
+				},
+				logs: []string{"Connection details were dropped by filter", "Published connection details"},
+			},
+		},
+		"Remapped": {
+			reason: "We should report published keys as they were published, after renaming.",
+			args: args{
+				o: publishing,
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					return NewSecretStoreConnectionPublisherWithRemap(s, []string{"user"}, map[string]string{"user": "username"}, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+				},
+				conn: c,
+			},
+			want: want{
+				r: PublishReport{
+					Published:     true,
+					PublishedKeys: []string{"username"},
+					DroppedKeys:   []string{"ca", "node-0-password", "password"},
+				},
+				logs: []string{"Connection details were dropped by filter", "Published connection details"},
+			},
+		},
+		"DryRun": {
+			reason: "We should report which keys would be published and dropped during a dry run.",
+			args: args{
+				o: publishing,
+				p: func(s *memoryConnectionStore, log logging.Logger) *SecretStoreConnectionPublisher {
+					return NewSecretStoreConnectionPublisher(s, []string{"ca"}, WithDryRun(), WithPublisherLogger(log))
+				},
+				conn: c,
+			},
+			want: want{
+				r: PublishReport{
+					PublishedKeys: []string{"ca"},
+					DroppedKeys:   []string{"node-0-password", "password", "user"},
+				},
+				logs: []string{"Connection details were dropped by filter"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logs []string
+			p := tc.args.p(&memoryConnectionStore{}, debugRecorder{messages: &logs})
+			r, err := p.PublishConnectionWithReport(context.Background(), tc.args.o, tc.args.conn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.r, r, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.logs, logs); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want logs, +got logs:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherMaxSize(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+
+	// The filter allows user and password, which are 9 and 14 bytes
+	// respectively. The ca is filtered out, and must not count toward the size.
+	filter := []string{"user", "password"}
+	c := managed.ConnectionDetails{
+		"user":     []byte("admin"),
+		"password": []byte("secret"),
+		"ca":       []byte(strings.Repeat("x", 1024)),
+	}
+
+	type want struct {
+		data managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		max    int
+		want   want
+	}{
+		"Unlimited": {
+			reason: "We should not limit the size of connection details by default.",
+			want: want{
+				data: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+		},
+		"UnderLimit": {
+			reason: "We should publish connection details smaller than the limit.",
+			max:    24,
+			want: want{
+				data: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+		},
+		"AtLimit": {
+			reason: "We should publish connection details exactly as large as the limit.",
+			max:    23,
+			want: want{
+				data: managed.ConnectionDetails{"user": []byte("admin"), "password": []byte("secret")},
+			},
+		},
+		"OverLimit": {
+			reason: "We should return an error naming the largest keys if connection details are larger than the limit.",
+			max:    22,
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtConnectionDetailsTooLarge, 23, 22, `"password" (14 bytes), "user" (9 bytes)`), errPublishStoreEntry),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisher(s, filter, WithSharedSecretStoreEntries(), WithMaxConnectionDetailsSize(tc.max))
+			_, err := p.PublishConnection(context.Background(), publishing, c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// An infoRecorder is a logger that records info messages.
+type infoRecorder struct {
+	messages *[]string
+}
+
+func (l infoRecorder) Info(msg string, _ ...any) { *l.messages = append(*l.messages, msg) }
+
+func (l infoRecorder) Debug(_ string, _ ...any) {}
+
+func (l infoRecorder) WithValues(_ ...any) logging.Logger { return l }
+
+func TestSecretStoreConnectionPublisherMaxValueSize(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	kubeconfig := []byte(strings.Repeat("k", 64))
+	c := managed.ConnectionDetails{
+		"user":       []byte("admin"),
+		"kubeconfig": kubeconfig,
+		"ca":         []byte(strings.Repeat("x", 1024)),
+	}
+
+	type args struct {
+		o []SecretStoreConnectionPublisherOption
+	}
+	type want struct {
+		data      managed.ConnectionDetails
+		truncated []string
+		logs      []string
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"UnderLimit": {
+			reason: "We should publish values no larger than the limit. Filtered values don't count.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithMaxConnectionDetailValueSize(64)},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"user": []byte("admin"), "DB_KUBECONFIG": kubeconfig},
+			},
+		},
+		"ErrorOnExceed": {
+			reason: "We should return an error naming the renamed key if a value is larger than the limit.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithMaxConnectionDetailValueSize(16)},
+			},
+			want: want{
+				err:  errors.Wrap(errors.Errorf(errFmtConnectionDetailValueTooLarge, "DB_KUBECONFIG", 64, 16), errPublishStoreEntry),
+				logs: []string{"Cannot publish connection details"},
+			},
+		},
+		"TruncateOnExceed": {
+			reason: "We should truncate values larger than the limit, and warn that we did, if truncation is enabled.",
+			args: args{
+				o: []SecretStoreConnectionPublisherOption{WithMaxConnectionDetailValueSize(16), WithConnectionDetailValueTruncation()},
+			},
+			want: want{
+				data:      managed.ConnectionDetails{"user": []byte("admin"), "DB_KUBECONFIG": kubeconfig[:16]},
+				truncated: []string{"DB_KUBECONFIG"},
+				logs:      []string{"Connection detail values were truncated because they exceeded the maximum size"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logs []string
+			s := &memoryConnectionStore{}
+			o := append([]SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries(), WithPublisherLogger(infoRecorder{messages: &logs})}, tc.args.o...)
+			p := NewSecretStoreConnectionPublisherWithRemap(s, []string{"user", "kubeconfig"}, map[string]string{"kubeconfig": "DB_KUBECONFIG"}, o...)
+			r, err := p.PublishConnectionWithReport(context.Background(), publishing, c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.truncated, r.TruncatedKeys); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want truncated, +got truncated:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.logs, logs); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): -want logs, +got logs:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(string(kubeconfig), string(c["kubeconfig"])); diff != "" {
+				t.Errorf("\n%s\nPublishConnectionWithReport(...): must not modify the supplied connection details: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// A logEntry is a structured log entry.
+type logEntry struct {
+	Level string
+	Msg   string
+	KV    map[string]any
+}
+
+// A kvRecorder is a logger that records structured log entries.
+type kvRecorder struct {
+	entries *[]logEntry
+	values  []any
+}
+
+func (l kvRecorder) record(level, msg string, kv []any) {
+	e := logEntry{Level: level, Msg: msg, KV: map[string]any{}}
+	all := append(append([]any{}, l.values...), kv...)
+	for i := 0; i+1 < len(all); i += 2 {
+		e.KV[all[i].(string)] = all[i+1]
+	}
+	*l.entries = append(*l.entries, e)
+}
+
+func (l kvRecorder) Info(msg string, kv ...any) { l.record("info", msg, kv) }
+
+func (l kvRecorder) Debug(msg string, kv ...any) { l.record("debug", msg, kv) }
+
+func (l kvRecorder) WithValues(kv ...any) logging.Logger {
+	return kvRecorder{entries: l.entries, values: append(append([]any{}, l.values...), kv...)}
+}
+
+func TestConnectionDetailsLogging(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	xr := &fake.Composite{
+		ObjectMeta: metav1.ObjectMeta{Name: "cool-xr"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{
+			Name:                 "cool",
+			SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+		}},
+	}
+	cd := &fake.Composed{
+		ObjectMeta:               metav1.ObjectMeta{Name: "cool-composed", Labels: map[string]string{xcrd.LabelKeyNamePrefixForComposed: "cool-xr"}},
+		ConnectionSecretWriterTo: fake.ConnectionSecretWriterTo{Ref: &xpv1.SecretReference{Namespace: "default", Name: "cool-secret"}},
+	}
+	secret := test.NewMockGetFn(nil, func(obj client.Object) error {
+		obj.(*corev1.Secret).Data = map[string][]byte{"password": []byte("secret")}
+		return nil
+	})
+
+	cases := map[string]struct {
+		reason string
+		do     func(log logging.Logger) error
+		want   []logEntry
+	}{
+		"PublishSuccess": {
+			reason: "We should log successful publishes at debug level, with the composite and store.",
+			do: func(log logging.Logger) error {
+				p := NewSecretStoreConnectionPublisher(&memoryConnectionStore{}, nil, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+				_, err := p.PublishConnection(context.Background(), xr, managed.ConnectionDetails{"password": []byte("secret")})
+				return err
+			},
+			want: []logEntry{{
+				Level: "debug",
+				Msg:   "Published connection details",
+				KV:    map[string]any{"composite": "cool-xr", "store": "vault", "keys": 1, "changed": true},
+			}},
+		},
+		"PublishFailure": {
+			reason: "We should log failed publishes at info level, with the composite, store, and error.",
+			do: func(log logging.Logger) error {
+				p := NewSecretStoreConnectionPublisher(&memoryConnectionStore{errPublish: errBoom}, nil, WithSharedSecretStoreEntries(), WithPublisherLogger(log))
+				_, err := p.PublishConnection(context.Background(), xr, managed.ConnectionDetails{"password": []byte("secret")})
+				return err
+			},
+			want: []logEntry{{
+				Level: "info",
+				Msg:   "Cannot publish connection details",
+				KV:    map[string]any{"composite": "cool-xr", "store": "vault", "error": errBoom},
+			}},
+		},
+		"FetchSuccess": {
+			reason: "We should log successful fetches at debug level, with the composite and composed resource.",
+			do: func(log logging.Logger) error {
+				f := NewSecretConnectionDetailsFetcher(&test.MockClient{MockGet: secret}, WithFetcherLogger(log))
+				_, err := f.FetchConnection(context.Background(), cd)
+				return err
+			},
+			want: []logEntry{{
+				Level: "debug",
+				Msg:   "Fetched connection details",
+				KV:    map[string]any{"composite": "cool-xr", "composed": "cool-composed", "keys": 1},
+			}},
+		},
+		"FetchFailure": {
+			reason: "We should log failed fetches at info level, with the composite, composed resource, and error.",
+			do: func(log logging.Logger) error {
+				f := NewSecretConnectionDetailsFetcher(&test.MockClient{MockGet: test.NewMockGetFn(errBoom)}, WithFetcherLogger(log))
+				_, err := f.FetchConnection(context.Background(), cd)
+				return err
+			},
+			want: []logEntry{{
+				Level: "info",
+				Msg:   "Cannot fetch connection details",
+				KV:    map[string]any{"composite": "cool-xr", "composed": "cool-composed", "error": errors.Wrap(errBoom, errGetSecret)},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var entries []logEntry
+			_ = tc.do(kvRecorder{entries: &entries})
+			if diff := cmp.Diff(tc.want, entries, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\n-want logs, +got logs:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherRemap(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+
+	type args struct {
+		filter []string
+		remap  map[string]string
+		c      managed.ConnectionDetails
+	}
+	type want struct {
+		data managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Renamed": {
+			reason: "We should rename keys after filtering them by their original names.",
+			args: args{
+				filter: []string{"endpoint", "port"},
+				remap:  map[string]string{"endpoint": "DB_HOST", "password": "DB_PASSWORD"},
+				c:      managed.ConnectionDetails{"endpoint": []byte("db"), "port": []byte("5432"), "password": []byte("secret")},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"DB_HOST": []byte("db"), "port": []byte("5432")},
+			},
+		},
+		"RenamedKeysCollide": {
+			reason: "We should return an error if two keys are renamed to the same name.",
+			args: args{
+				remap: map[string]string{"b": "x", "a": "x"},
+				c:     managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+			want: want{
+				err: errors.Errorf(errFmtRemapCollision, "a", "b", "x"),
+			},
+		},
+		"RenamedKeyCollidesWithOriginal": {
+			reason: "We should return an error if a key is renamed to the name of a key that isn't renamed.",
+			args: args{
+				remap: map[string]string{"b": "a"},
+				c:     managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+			want: want{
+				err: errors.Errorf(errFmtRemapCollision, "a", "b", "a"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisherWithRemap(s, tc.args.filter, tc.args.remap, WithSharedSecretStoreEntries())
+			_, err := p.PublishConnection(context.Background(), publishing, tc.args.c)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherDenyList(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	c := managed.ConnectionDetails{
+		"endpoint":    []byte("db"),
+		"port":        []byte("5432"),
+		"private-key": []byte("key"),
+		"ca-cert":     []byte("cert"),
+	}
+
+	type args struct {
+		filter []string
+		deny   []string
+	}
+	type want struct {
+		data managed.ConnectionDetails
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Neither": {
+			reason: "We should publish every key if there is neither a filter nor a deny list.",
+			want: want{
+				data: c,
+			},
+		},
+		"AllowOnly": {
+			reason: "We should publish only the keys the filter allows if there is no deny list.",
+			args: args{
+				filter: []string{"endpoint", "port"},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"endpoint": []byte("db"), "port": []byte("5432")},
+			},
+		},
+		"DenyOnly": {
+			reason: "We should publish every key except those denied if there is no filter.",
+			args: args{
+				deny: []string{"private-key", "ca-cert"},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"endpoint": []byte("db"), "port": []byte("5432")},
+			},
+		},
+		"Combined": {
+			reason: "A denied key should not be published even if the filter allows it.",
+			args: args{
+				filter: []string{"endpoint", "private-key"},
+				deny:   []string{"private-key"},
+			},
+			want: want{
+				data: managed.ConnectionDetails{"endpoint": []byte("db")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p := NewSecretStoreConnectionPublisherWithDenyList(s, tc.args.filter, tc.args.deny, WithSharedSecretStoreEntries())
+			if _, err := p.PublishConnection(context.Background(), publishing, c); err != nil {
+				t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherFilterMode(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	c := managed.ConnectionDetails{
+		"node-0-password": []byte("a"),
+		"node-1-password": []byte("b"),
+		"node-0-username": []byte("c"),
+		"endpoint":        []byte("d"),
+	}
+	_, errRegex := regexp.Compile("^(?:node-(+)$")
+
+	type args struct {
+		filter []string
+		mode   FilterMode
+	}
+	type want struct {
+		data managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Exact": {
+			reason: "We should match keys exactly in exact mode.",
+			args: args{
+				filter: []string{"node-*-password", "endpoint"},
+				mode:   FilterModeExact,
+			},
+			want: want{
+				data: managed.ConnectionDetails{"endpoint": []byte("d")},
+			},
+		},
+		"Glob": {
+			reason: "We should match keys using glob patterns in glob mode.",
+			args: args{
+				filter: []string{"node-*-password", "endpoint"},
+				mode:   FilterModeGlob,
+			},
+			want: want{
+				data: managed.ConnectionDetails{"node-0-password": []byte("a"), "node-1-password": []byte("b"), "endpoint": []byte("d")},
+			},
+		},
+		"Regex": {
+			reason: "We should match entire keys using regular expressions in regex mode.",
+			args: args{
+				filter: []string{`node-\d+-username`, "end"},
+				mode:   FilterModeRegex,
+			},
+			want: want{
+				data: managed.ConnectionDetails{"node-0-username": []byte("c")},
+			},
+		},
+		"InvalidGlob": {
+			reason: "We should fail fast if a glob pattern is invalid.",
+			args: args{
+				filter: []string{"node-[-password"},
+				mode:   FilterModeGlob,
+			},
+			want: want{
+				err: errors.Wrapf(path.ErrBadPattern, errFmtInvalidFilterPattern, "node-[-password"),
+			},
+		},
+		"InvalidRegex": {
+			reason: "We should fail fast if a regular expression is invalid.",
+			args: args{
+				filter: []string{"node-(+"},
+				mode:   FilterModeRegex,
+			},
+			want: want{
+				err: errors.Wrapf(errRegex, errFmtInvalidFilterPattern, "node-(+"),
+			},
+		},
+		"UnknownMode": {
+			reason: "We should fail fast if the filter mode is unknown.",
+			args: args{
+				mode: "Fuzzy",
+			},
+			want: want{
+				err: errors.Errorf(errFmtUnknownFilterMode, "Fuzzy"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{}
+			p, err := NewSecretStoreConnectionPublisherWithFilterMode(s, tc.args.filter, tc.args.mode, WithSharedSecretStoreEntries())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewSecretStoreConnectionPublisherWithFilterMode(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := p.PublishConnection(context.Background(), publishing, c); err != nil {
+				t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherUnpublish(t *testing.T) {
+	errBoom := errors.New("boom")
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+
+	unpublisher := func(err error, called *bool) managed.ConnectionPublisher {
+		return managed.ConnectionPublisherFns{
+			UnpublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+				*called = true
+				return err
+			},
+		}
+	}
+
+	type args struct {
+		err error
+		o   resource.ConnectionSecretOwner
+	}
+	type want struct {
+		err    error
+		called bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotPublishing": {
+			reason: "We should do nothing if the resource doesn't publish connection details.",
+			args: args{
+				o: &fake.Composite{},
+			},
+			want: want{},
+		},
+		"AlreadyRemoved": {
+			reason: "We should not return an error if the connection details were already removed from the store.",
+			args: args{
+				err: kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "cool"),
+				o:   publishing,
+			},
+			want: want{
+				called: true,
+			},
+		},
+		"UnpublishError": {
+			reason: "We should return any other error encountered unpublishing connection details.",
+			args: args{
+				err: errBoom,
+				o:   publishing,
+			},
+			want: want{
+				err:    errBoom,
+				called: true,
+			},
+		},
+		"Unpublished": {
+			reason: "We should unpublish connection details from the store.",
+			args: args{
+				o: publishing,
+			},
+			want: want{
+				called: true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			p := NewSecretStoreConnectionPublisher(unpublisher(tc.args.err, &called), nil)
+			err := p.UnpublishConnection(context.Background(), tc.args.o, nil)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.called, called); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want called, +got called:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherUnpublishOwnership(t *testing.T) {
+	deleting := &fake.Composite{
+		ObjectMeta:                   metav1.ObjectMeta{UID: "cool-uid"},
+		ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}},
+	}
+
+	type args struct {
+		metadata *xpv1.ConnectionSecretMetadata
+		opts     []SecretStoreConnectionPublisherOption
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   managed.ConnectionDetails
+	}{
+		"Unowned": {
+			reason: "We should unpublish a store entry that doesn't record an owner.",
+			args:   args{},
+		},
+		"SameOwner": {
+			reason: "We should unpublish a store entry we own.",
+			args: args{
+				metadata: ownedBy("cool-uid"),
+			},
+		},
+		"ConflictingOwner": {
+			reason: "We should not unpublish a store entry owned by another resource, for example when deleting a resource whose entry collides with another's.",
+			args: args{
+				metadata: ownedBy("other-uid"),
+			},
+			want: managed.ConnectionDetails{"a": []byte("theirs")},
+		},
+		"Shared": {
+			reason: "We should not enforce ownership of shared store entries.",
+			args: args{
+				metadata: ownedBy("other-uid"),
+				opts:     []SecretStoreConnectionPublisherOption{WithSharedSecretStoreEntries()},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("theirs")}, metadata: tc.args.metadata}
+			p := NewSecretStoreConnectionPublisher(s, nil, tc.args.opts...)
+			if err := p.UnpublishConnection(context.Background(), deleting, nil); err != nil {
+				t.Fatalf("\n%s\nUnpublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, s.data); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherUnpublishFiltered(t *testing.T) {
+	errBoom := errors.New("boom")
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+
+	type params struct {
+		p      managed.ConnectionPublisher
+		filter []string
+	}
+	type want struct {
+		err  error
+		data managed.ConnectionDetails
+	}
+
+	cases := map[string]struct {
+		reason string
+		params params
+		o      resource.ConnectionSecretOwner
+		want   want
+	}{
+		"NotPublishing": {
+			reason: "We should do nothing if the resource doesn't publish connection details.",
+			params: params{
+				p:      &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				filter: []string{"b"},
+			},
+			o: &fake.Composite{},
+			want: want{
+				data: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"NoFilter": {
+			reason: "We should not unpublish anything if the filter allows all keys.",
+			params: params{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+			},
+			o: publishing,
+			want: want{
+				data: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+		"NotAFetcher": {
+			reason: "We should return an error if we can't read the published connection details.",
+			params: params{
+				p:      managed.ConnectionPublisherFns{},
+				filter: []string{"a"},
+			},
+			o: publishing,
+			want: want{
+				err: errors.New(errUnpublishFilteredNoFetcher),
+			},
+		},
+		"FetchError": {
+			reason: "We should return any error encountered reading the published connection details.",
+			params: params{
+				p:      &memoryConnectionStore{errFetch: errBoom},
+				filter: []string{"a"},
+			},
+			o: publishing,
+			want: want{
+				err: errors.Wrap(errBoom, errFetchPublished),
+			},
+		},
+		"UnpublishFiltered": {
+			reason: "We should unpublish only the keys the filter no longer allows.",
+			params: params{
+				p: &memoryConnectionStore{data: managed.ConnectionDetails{
+					"a": []byte("a"),
+					"b": []byte("b"),
+					"c": []byte("c"),
+				}},
+				filter: []string{"a", "c"},
+			},
+			o: publishing,
+			want: want{
+				data: managed.ConnectionDetails{
+					"a": []byte("a"),
+					"c": []byte("c"),
+				},
+			},
+		},
+		"ConflictingOwner": {
+			reason: "We should not unpublish keys from a store entry owned by another resource.",
+			params: params{
+				p: &memoryConnectionStore{
+					data:     managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+					metadata: ownedBy("other-uid"),
+				},
+				filter: []string{"a"},
+			},
+			o: publishing,
+			want: want{
+				data: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+		},
+		"NothingFiltered": {
+			reason: "We should not unpublish anything if the filter allows all published keys.",
+			params: params{
+				p:      &memoryConnectionStore{data: managed.ConnectionDetails{"a": []byte("a")}},
+				filter: []string{"a", "b"},
+			},
+			o: publishing,
+			want: want{
+				data: managed.ConnectionDetails{"a": []byte("a")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSecretStoreConnectionPublisher(tc.params.p, tc.params.filter)
+			err := p.UnpublishFiltered(context.Background(), tc.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishFiltered(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			s, ok := tc.params.p.(*memoryConnectionStore)
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(tc.want.data, s.data); diff != "" {
+				t.Errorf("\n%s\nUnpublishFiltered(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNopConnectionDetailsFetcher(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		f      managed.ConnectionDetailsFetcher
+	}{
+		"Nop": {
+			reason: "A NopConnectionDetailsFetcher should fetch no connection details.",
+			f:      NopConnectionDetailsFetcher{},
+		},
+		"EmptyChain": {
+			reason: "An empty ConnectionDetailsFetcherChain should behave like a NopConnectionDetailsFetcher.",
+			f:      ConnectionDetailsFetcherChain{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := tc.f.FetchConnection(context.Background(), &fake.Composite{})
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(managed.ConnectionDetails(nil), conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNopConnectionPublisher(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		p      managed.ConnectionPublisher
+	}{
+		"Nop": {
+			reason: "A NopConnectionPublisher should publish nothing.",
+			p:      NopConnectionPublisher{},
+		},
+		"EmptyChain": {
+			reason: "An empty PublisherChain should behave like a NopConnectionPublisher.",
+			p:      managed.PublisherChain{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn := managed.ConnectionDetails{"cool": []byte("secret")}
+			published, err := tc.p.PublishConnection(context.Background(), &fake.Composite{}, conn)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(false, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			err = tc.p.UnpublishConnection(context.Background(), &fake.Composite{}, conn)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionDetailsFetcherChain(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// cancelling returns connection details, then cancels the context of the
+	// test that uses it.
+	var cancelTest context.CancelFunc
+	cancelling := ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		cancelTest()
+		return managed.ConnectionDetails{"a": []byte("a")}, nil
+	})
+	notCalled := ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		t.Errorf("FetchConnection(...): unexpected call to fetcher after context was cancelled")
+		return nil, nil
+	})
+
+	type args struct {
+		ctx context.Context
+		o   resource.ConnectionSecretOwner
+	}
+	type want struct {
+		conn managed.ConnectionDetails
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		c      managed.ConnectionDetailsFetcher
+		args   args
+		want   want
+	}{
+		"EmptyChain": {
+			reason: "An empty chain should return empty connection details.",
+			c:      ConnectionDetailsFetcherChain{},
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"SingleFetcherChain": {
+			reason: "A chain of one fetcher should return only its connection details.",
+			c: ConnectionDetailsFetcherChain{
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("b")}, nil
+				}),
+			},
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"FetcherError": {
+			reason: "We should return errors from a chained fetcher.",
+			c: ConnectionDetailsFetcherChain{
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+			},
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"MultipleFetcherChain": {
+			reason: "A chain of multiple fetchers should return all of their connection details, with later fetchers winning if there are duplicates.",
+			c: ConnectionDetailsFetcherChain{
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{
+						"a": []byte("a"),
+						"b": []byte("b"),
+						"c": []byte("c"),
+					}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{
+						"a": []byte("A"),
+					}, nil
+				}),
+			},
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"a": []byte("A"),
+					"b": []byte("b"),
+					"c": []byte("c"),
+				},
+			},
+		},
+		"FirstWinsChain": {
+			reason: "A first wins chain of multiple fetchers should return all of their connection details, with earlier fetchers winning if there are duplicates.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeFirstWins,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{
+						"a": []byte("a"),
+					}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{
+						"a": []byte("A"),
+						"b": []byte("B"),
+					}, nil
+				}),
+			),
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"a": []byte("a"),
+					"b": []byte("B"),
+				},
+			},
+		},
+		"FirstWinsChainError": {
+			reason: "We should return errors from a fetcher in a first wins chain.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeFirstWins,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+			),
+			args: args{
+				o: &fake.Composed{},
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"CollectErrors": {
+			reason: "A collecting chain should return partial connection details alongside an aggregate of all errors.",
+			c: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeLastWins, ChainErrorCollect,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("A"), "b": []byte("b")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+			),
+			args: args{
+				o: &fake.Composed{},
 			},
 			want: want{
-				conn: managed.ConnectionDetails{
-					"foo": s.Data["foo"],
-					"bar": s.Data["bar"],
-				},
+				conn: managed.ConnectionDetails{"a": []byte("A"), "b": []byte("b")},
+				err: utilerrors.NewAggregate([]error{
+					errors.Wrapf(errBoom, errFmtChainFetcher, 1),
+					errors.Wrapf(errBoom, errFmtChainFetcher, 3),
+				}),
 			},
 		},
-	}
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			c := &SecretConnectionDetailsFetcher{client: tc.params.kube}
-			conn, err := c.FetchConnection(tc.args.ctx, tc.args.o)
-			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
-				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
-			}
-			if diff := cmp.Diff(tc.want.conn, conn, cmpopts.EquateEmpty()); diff != "" {
-				t.Errorf("\n%s\nFetchFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
-			}
-		})
-	}
-}
-
-func TestConnectionDetailsFetcherChain(t *testing.T) {
-	errBoom := errors.New("boom")
-
-	type args struct {
-		ctx context.Context
-		o   resource.ConnectionSecretOwner
-	}
-	type want struct {
-		conn managed.ConnectionDetails
-		err  error
-	}
-
-	cases := map[string]struct {
-		reason string
-		c      ConnectionDetailsFetcherChain
-		args   args
-		want   want
-	}{
-		"EmptyChain": {
-			reason: "An empty chain should return empty connection details.",
-			c:      ConnectionDetailsFetcherChain{},
+		"CollectErrorsFirstWins": {
+			reason: "A collecting first wins chain should let earlier fetchers that didn't fail take precedence.",
+			c: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeFirstWins, ChainErrorCollect,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("A")}, nil
+				}),
+			),
 			args: args{
 				o: &fake.Composed{},
 			},
 			want: want{
-				conn: managed.ConnectionDetails{},
+				conn: managed.ConnectionDetails{"a": []byte("a")},
+				err:  utilerrors.NewAggregate([]error{errors.Wrapf(errBoom, errFmtChainFetcher, 0)}),
 			},
 		},
-		"SingleFetcherChain": {
-			reason: "A chain of one fetcher should return only its connection details.",
-			c: ConnectionDetailsFetcherChain{
+		"CollectNoErrors": {
+			reason: "A collecting chain should return no error if no fetcher failed.",
+			c: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeLastWins, ChainErrorCollect,
 				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
-					return managed.ConnectionDetails{"a": []byte("b")}, nil
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
 				}),
-			},
+			),
 			args: args{
 				o: &fake.Composed{},
 			},
 			want: want{
-				conn: managed.ConnectionDetails{"a": []byte("b")},
+				conn: managed.ConnectionDetails{"a": []byte("a")},
 			},
 		},
-		"FetcherError": {
-			reason: "We should return errors from a chained fetcher.",
-			c: ConnectionDetailsFetcherChain{
+		"FailFast": {
+			reason: "A fail fast chain should return no connection details if any fetcher failed.",
+			c: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeLastWins, ChainErrorFailFast,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
 				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
 					return nil, errBoom
 				}),
-			},
+			),
 			args: args{
 				o: &fake.Composed{},
 			},
@@ -203,37 +1940,132 @@ func TestConnectionDetailsFetcherChain(t *testing.T) {
 				err: errBoom,
 			},
 		},
-		"MultipleFetcherChain": {
-			reason: "A chain of multiple fetchers should return all of their connection details, with later fetchers winning if there are duplicates.",
-			c: ConnectionDetailsFetcherChain{
+		"Cancelled": {
+			reason: "A chain should not call fetchers once its context is cancelled.",
+			c:      ConnectionDetailsFetcherChain{cancelling, notCalled},
+			want: want{
+				err: context.Canceled,
+			},
+		},
+		"CancelledFirstWins": {
+			reason: "A first wins chain should not call fetchers once its context is cancelled.",
+			c:      NewConnectionDetailsFetcherChain(ChainMergeFirstWins, notCalled),
+			args: args{
+				ctx: cancelled,
+			},
+			want: want{
+				err: context.Canceled,
+			},
+		},
+		"CancelledCollect": {
+			reason: "A collecting chain should return partial connection details and the context error once its context is cancelled.",
+			c:      NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeLastWins, ChainErrorCollect, cancelling, notCalled),
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("a")},
+				err:  utilerrors.NewAggregate([]error{context.Canceled}),
+			},
+		},
+		"StrictChain": {
+			reason: "A strict chain should return all connection details if fetchers that fetch the same key agree on its value.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeStrict,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")}, nil
+				}),
+			),
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+			},
+		},
+		"StrictChainConflict": {
+			reason: "A strict chain should return an error identifying the key and fetchers that disagree on its value.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeStrict,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"b": []byte("b")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"b": []byte("B")}, nil
+				}),
+			),
+			want: want{
+				err: errors.Errorf(errFmtChainConflict, "b", 1, 2),
+			},
+		},
+		"StrictChainError": {
+			reason: "We should return errors from a fetcher in a strict chain.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeStrict,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+			),
+			want: want{
+				err: errBoom,
+			},
+		},
+		"CollectConflictsStrict": {
+			reason: "A collecting strict chain should keep the earlier value of conflicting keys, and return the conflicts alongside other errors.",
+			c: NewConnectionDetailsFetcherChainWithErrorPolicy(ChainMergeStrict, ChainErrorCollect,
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("a")}, nil
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"a": []byte("A"), "b": []byte("b")}, nil
+				}),
+			),
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("a"), "b": []byte("b")},
+				err: utilerrors.NewAggregate([]error{
+					errors.Wrapf(errBoom, errFmtChainFetcher, 1),
+					errors.Errorf(errFmtChainConflict, "a", 0, 2),
+				}),
+			},
+		},
+		"LastWinsChain": {
+			reason: "A last wins chain should behave like a ConnectionDetailsFetcherChain.",
+			c: NewConnectionDetailsFetcherChain(ChainMergeLastWins,
 				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
 					return managed.ConnectionDetails{
 						"a": []byte("a"),
-						"b": []byte("b"),
-						"c": []byte("c"),
 					}, nil
 				}),
 				ConnectionDetailsFetcherFn(func(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
 					return managed.ConnectionDetails{
 						"a": []byte("A"),
+						"b": []byte("B"),
 					}, nil
 				}),
-			},
+			),
 			args: args{
 				o: &fake.Composed{},
 			},
 			want: want{
 				conn: managed.ConnectionDetails{
 					"a": []byte("A"),
-					"b": []byte("b"),
-					"c": []byte("c"),
+					"b": []byte("B"),
 				},
 			},
 		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			conn, err := tc.c.FetchConnection(tc.args.ctx, tc.args.o)
+			ctx := tc.args.ctx
+			if ctx == nil {
+				ctx, cancelTest = context.WithCancel(context.Background())
+				defer cancelTest()
+			}
+			o := tc.args.o
+			if o == nil {
+				o = &fake.Composed{}
+			}
+			conn, err := tc.c.FetchConnection(ctx, o)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
 			}
@@ -245,7 +2077,7 @@ func TestConnectionDetailsFetcherChain(t *testing.T) {
 }
 
 func TestExtractConnectionDetails(t *testing.T) {
-	// errBoom := errors.New("boom")
+	_, errMalformed := fieldpath.Parse("objectMeta.labels[")
 
 	type args struct {
 		cd   resource.Composed
@@ -289,6 +2121,53 @@ func TestExtractConnectionDetails(t *testing.T) {
 				err: errors.Errorf(errFmtConnDetailVal, ConnectionDetailTypeFromValue),
 			},
 		},
+		"MalformedFieldPathError": {
+			reason: "We should return an error if the field path is malformed.",
+			args: args{
+				cd: &fake.Composed{},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Name:          "cool-detail",
+						Type:          ConnectionDetailTypeFromFieldPath,
+						FromFieldPath: pointer.String("objectMeta.labels["),
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrapf(errMalformed, errFmtConnDetailPathInvalid, "objectMeta.labels["),
+			},
+		},
+		"MissingFieldPathSkipped": {
+			reason: "We should skip a well formed field path that doesn't exist yet.",
+			args: args{
+				cd: &fake.Composed{},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Name:          "cool-detail",
+						Type:          ConnectionDetailTypeFromFieldPath,
+						FromFieldPath: pointer.String("objectMeta.labels[cool]"),
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"FromValueWithoutFetchedDetails": {
+			reason: "We should extract fixed values even if no connection details were fetched, e.g. because no fetcher reads the composed resource's connection secret.",
+			args: args{
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Name:  "cool-detail",
+						Type:  ConnectionDetailTypeFromValue,
+						Value: pointer.String("cool-value"),
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"cool-detail": []byte("cool-value")},
+			},
+		},
 		"MissingConnectionSecretKeyError": {
 			reason: "We should return an error if the connection secret key is missing.",
 			args: args{
@@ -352,25 +2231,188 @@ func TestExtractConnectionDetails(t *testing.T) {
 						Value: pointer.String("value"),
 					},
 					{
-						Type:          ConnectionDetailTypeFromFieldPath,
-						Name:          "name",
-						FromFieldPath: pointer.String("objectMeta.name"),
+						Type:          ConnectionDetailTypeFromFieldPath,
+						Name:          "name",
+						FromFieldPath: pointer.String("objectMeta.name"),
+					},
+					{
+						Type:          ConnectionDetailTypeFromFieldPath,
+						Name:          "generation",
+						FromFieldPath: pointer.String("objectMeta.generation"),
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"convfoo":    []byte("a"),
+					"bar":        []byte("b"),
+					"fixed":      []byte("value"),
+					"name":       []byte("test"),
+					"generation": []byte("4"),
+				},
+			},
+		},
+		"NoConnectionSecret": {
+			reason: "We should skip connection secret keys, but still extract field path and value details, from composed resources that have no connection secret.",
+			args: args{
+				cd: &fake.Composed{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				data: nil,
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "password",
+						FromConnectionSecretKey: pointer.String("password"),
+					},
+					{
+						Type:  ConnectionDetailTypeFromValue,
+						Name:  "fixed",
+						Value: pointer.String("value"),
+					},
+					{
+						Type:          ConnectionDetailTypeFromFieldPath,
+						Name:          "name",
+						FromFieldPath: pointer.String("objectMeta.name"),
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"fixed": []byte("value"),
+					"name":  []byte("test"),
+				},
+			},
+		},
+		"TreatEmptyAsMissing": {
+			reason: "Empty values should be omitted if they're to be treated as missing.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"password": []byte(""),
+				},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "password",
+						FromConnectionSecretKey: pointer.String("password"),
+						TreatEmptyAs:            v1.ConnectionDetailEmptyPolicyMissing,
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"TreatEmptyAsValid": {
+			reason: "Empty values should be emitted by default.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"password": []byte(""),
+				},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "password",
+						FromConnectionSecretKey: pointer.String("password"),
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"password": []byte(""),
+				},
+			},
+		},
+		"TreatEmptyAsError": {
+			reason: "We should return an error if an empty value is to be treated as an error.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"password": []byte(""),
+				},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "password",
+						FromConnectionSecretKey: pointer.String("password"),
+						TreatEmptyAs:            v1.ConnectionDetailEmptyPolicyError,
+					},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtConnDetailEmpty, "password"),
+			},
+		},
+		"Transforms": {
+			reason: "We should apply a connection detail's transforms to its value, in order.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"token": []byte(" Y29vbC10b2tlbg== \n"),
+				},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "token",
+						FromConnectionSecretKey: pointer.String("token"),
+						Transforms: []v1.ConnectionDetailTransform{
+							v1.ConnectionDetailTransformTrim,
+							v1.ConnectionDetailTransformBase64Decode,
+							v1.ConnectionDetailTransformUppercase,
+						},
+					},
+					{
+						Type:       ConnectionDetailTypeFromValue,
+						Name:       "region",
+						Value:      pointer.String("US-EAST-1"),
+						Transforms: []v1.ConnectionDetailTransform{v1.ConnectionDetailTransformLowercase},
+					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{
+					"token":  []byte("COOL-TOKEN"),
+					"region": []byte("us-east-1"),
+				},
+			},
+		},
+		"TransformedToEmpty": {
+			reason: "We should apply a connection detail's empty value policy after its transforms.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"password": []byte("  "),
+				},
+				cfg: []ConnectionDetailExtractConfig{
+					{
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "password",
+						FromConnectionSecretKey: pointer.String("password"),
+						Transforms:              []v1.ConnectionDetailTransform{v1.ConnectionDetailTransformTrim},
+						TreatEmptyAs:            v1.ConnectionDetailEmptyPolicyMissing,
 					},
+				},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{},
+			},
+		},
+		"TransformError": {
+			reason: "We should return an error naming the connection detail if one of its transforms fails.",
+			args: args{
+				data: managed.ConnectionDetails{
+					"token": []byte("not base64!"),
+				},
+				cfg: []ConnectionDetailExtractConfig{
 					{
-						Type:          ConnectionDetailTypeFromFieldPath,
-						Name:          "generation",
-						FromFieldPath: pointer.String("objectMeta.generation"),
+						Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+						Name:                    "token",
+						FromConnectionSecretKey: pointer.String("token"),
+						Transforms:              []v1.ConnectionDetailTransform{v1.ConnectionDetailTransformBase64Decode},
 					},
 				},
 			},
 			want: want{
-				conn: managed.ConnectionDetails{
-					"convfoo":    []byte("a"),
-					"bar":        []byte("b"),
-					"fixed":      []byte("value"),
-					"name":       []byte("test"),
-					"generation": []byte("4"),
-				},
+				err: errors.Wrapf(base64.CorruptInputError(3), errFmtConnDetailTransform, 0, v1.ConnectionDetailTransformBase64Decode, "token"),
 			},
 		},
 	}
@@ -432,6 +2474,27 @@ func TestExtractConfigsFromTemplate(t *testing.T) {
 				}},
 			},
 		},
+		"Transforms": {
+			reason: "We should carry a template's connection detail transforms through to its extract configs.",
+			args: args{
+				t: &v1.ComposedTemplate{
+					ConnectionDetails: []v1.ConnectionDetail{{
+						Name:                    pointer.String("cool-detail"),
+						Type:                    &tfk,
+						FromConnectionSecretKey: pointer.String("cool-key"),
+						Transforms:              []v1.ConnectionDetailTransform{v1.ConnectionDetailTransformTrim},
+					}},
+				},
+			},
+			want: want{
+				cfgs: []ConnectionDetailExtractConfig{{
+					Name:                    "cool-detail",
+					Type:                    ConnectionDetailTypeFromConnectionSecretKey,
+					FromConnectionSecretKey: pointer.String("cool-key"),
+					Transforms:              []v1.ConnectionDetailTransform{v1.ConnectionDetailTransformTrim},
+				}},
+			},
+		},
 		"InferredName": {
 			reason: "When a template's connection details does not have an explicit name and is of TypeFromConnectionSecretKey, we should infer the name from the connection secret key.",
 			args: args{
@@ -598,3 +2661,519 @@ func TestConnectionDetailType(t *testing.T) {
 		})
 	}
 }
+
+func TestSecretStoreConnectionDetailsConfigure(t *testing.T) {
+	errBoom := errors.New("boom")
+	_, errParse := template.New("name").Parse("{{ .Name")
+
+	uid := types.UID("3f2a1b9c-5d6e-4f70-8a91-b2c3d4e5f607")
+	comp := &v1.Composition{
+		Spec: v1.CompositionSpec{
+			PublishConnectionDetailsWithStoreConfigRef: &v1.StoreConfigReference{Name: "vault"},
+		},
+	}
+	xr := func(to *xpv1.PublishConnectionDetailsTo) *fake.Composite {
+		return &fake.Composite{
+			ObjectMeta:                   metav1.ObjectMeta{Name: "cool-xr", UID: uid},
+			ClaimReferencer:              fake.ClaimReferencer{Ref: &corev1.ObjectReference{Namespace: "team-a", Name: "cool-claim"}},
+			ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: to},
+		}
+	}
+	to := func(name string) *xpv1.PublishConnectionDetailsTo {
+		return &xpv1.PublishConnectionDetailsTo{Name: name, SecretStoreConfigRef: &xpv1.Reference{Name: "vault"}}
+	}
+	nsComp := &v1.Composition{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyConnectionSecretNamespace: "team-b"}},
+		Spec:       comp.Spec,
+	}
+	nsTo := func(name, ns string) *xpv1.PublishConnectionDetailsTo {
+		t := to(name)
+		t.Metadata = &xpv1.ConnectionSecretMetadata{Annotations: map[string]string{AnnotationKeyConnectionSecretNamespace: ns}}
+		return t
+	}
+	opaque := corev1.SecretTypeOpaque
+	mdComp := &v1.Composition{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationKeyConnectionSecretMetadata: `{"labels":{"team":"payments","tier":"gold"},"type":"Opaque"}`,
+		}},
+		Spec: comp.Spec,
+	}
+	mdTo := func(name string, labels map[string]string) *xpv1.PublishConnectionDetailsTo {
+		t := to(name)
+		t.Metadata = &xpv1.ConnectionSecretMetadata{Labels: labels, Type: &opaque}
+		return t
+	}
+	typedComp := func(md string) *v1.Composition {
+		return &v1.Composition{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyConnectionSecretMetadata: md}},
+			Spec:       comp.Spec,
+		}
+	}
+	typedTo := func(name string, st *corev1.SecretType) *xpv1.PublishConnectionDetailsTo {
+		t := to(name)
+		t.Metadata = &xpv1.ConnectionSecretMetadata{Labels: map[string]string{"team": "payments"}, Type: st}
+		return t
+	}
+	tls := corev1.SecretTypeTLS
+	custom := corev1.SecretType("example.org/custom")
+
+	type args struct {
+		kube client.Client
+		o    []SecretStoreConnectionDetailsConfiguratorOption
+		cp   resource.Composite
+		comp *v1.Composition
+	}
+	type want struct {
+		cp  resource.Composite
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoStoreConfig": {
+			reason: "We should not configure where connection details are published to if the composition doesn't.",
+			args: args{
+				cp:   xr(nil),
+				comp: &v1.Composition{},
+			},
+			want: want{
+				cp: xr(nil),
+			},
+		},
+		"NoDefaultStoreConfig": {
+			reason: "We should not configure where connection details are published to if neither the composition nor the configurator specify a store config.",
+			args: args{
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithDefaultStoreConfig("")},
+				cp:   xr(nil),
+				comp: &v1.Composition{},
+			},
+			want: want{
+				cp: xr(nil),
+			},
+		},
+		"DefaultStoreConfig": {
+			reason: "We should publish connection details to the default store config if the composition doesn't specify one.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithDefaultStoreConfig("vault")},
+				cp:   xr(nil),
+				comp: &v1.Composition{},
+			},
+			want: want{
+				cp: xr(to(string(uid))),
+			},
+		},
+		"DefaultStoreConfigOverridden": {
+			reason: "We should publish connection details to the composition's store config in preference to the default.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithDefaultStoreConfig("kubernetes")},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to(string(uid))),
+			},
+		},
+		"DefaultStoreConfigUserSet": {
+			reason: "We should not replace a store config set on the composite resource with the default store config, or unpublish from it.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				o: []SecretStoreConnectionDetailsConfiguratorOption{
+					WithDefaultStoreConfig("vault"),
+					WithPreviousStoreCleanup(previousStoreUnpublisherFn(func(_ context.Context, _ resource.ConnectionSecretOwner, _, _ *xpv1.PublishConnectionDetailsTo) (bool, error) {
+						return false, errBoom
+					})),
+				},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				comp: &v1.Composition{},
+			},
+			want: want{
+				cp: xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+			},
+		},
+		"DefaultStoreConfigMissing": {
+			reason: "We should set the default store config if the composite resource doesn't have a store config, without trying to unpublish from a previous one.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o: []SecretStoreConnectionDetailsConfiguratorOption{
+					WithDefaultStoreConfig("vault"),
+					WithPreviousStoreCleanup(previousStoreUnpublisherFn(func(_ context.Context, _ resource.ConnectionSecretOwner, _, _ *xpv1.PublishConnectionDetailsTo) (bool, error) {
+						return false, errBoom
+					})),
+				},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing"}),
+				comp: &v1.Composition{},
+			},
+			want: want{
+				cp: xr(to("existing")),
+			},
+		},
+		"NoChange": {
+			reason: "We should not update the composite resource if where connection details are published to is already configured.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithStoreNameTemplate("{{ .Name }}")},
+				cp:   xr(to("existing")),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("existing")),
+			},
+		},
+		"StoreConfigChanged": {
+			reason: "We should update the store config, but not the store entry name, if the composition's store config changed.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp: xr(&xpv1.PublishConnectionDetailsTo{
+					Name:                 "existing",
+					Metadata:             &xpv1.ConnectionSecretMetadata{Labels: map[string]string{"cool": "label"}},
+					SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"},
+				}),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(&xpv1.PublishConnectionDetailsTo{
+					Name:                 "existing",
+					Metadata:             &xpv1.ConnectionSecretMetadata{Labels: map[string]string{"cool": "label"}},
+					SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+				}),
+			},
+		},
+		"StoreConfigChangedPreviousStoreCleanup": {
+			reason: "We should unpublish from the previous store config before we update the store config.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o: []SecretStoreConnectionDetailsConfiguratorOption{WithPreviousStoreCleanup(previousStoreUnpublisherFn(func(_ context.Context, _ resource.ConnectionSecretOwner, prev, cur *xpv1.PublishConnectionDetailsTo) (bool, error) {
+					if prev.SecretStoreConfigRef.Name != "kubernetes" || cur.SecretStoreConfigRef.Name != "vault" {
+						return false, errBoom
+					}
+					return true, nil
+				}))},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("existing")),
+			},
+		},
+		"StoreConfigChangedPreviousStoreCleanupUnsupported": {
+			reason: "We should update the store config if the previous store config doesn't support cleanup.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o: []SecretStoreConnectionDetailsConfiguratorOption{WithPreviousStoreCleanup(previousStoreUnpublisherFn(func(_ context.Context, _ resource.ConnectionSecretOwner, _, _ *xpv1.PublishConnectionDetailsTo) (bool, error) {
+					return false, errors.Wrap(ErrUnsupported, errListShared)
+				}))},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("existing")),
+			},
+		},
+		"StoreConfigChangedPreviousStoreCleanupError": {
+			reason: "We should not update the store config if we can't unpublish from the previous one, so that we try again.",
+			args: args{
+				o: []SecretStoreConnectionDetailsConfiguratorOption{WithPreviousStoreCleanup(previousStoreUnpublisherFn(func(_ context.Context, _ resource.ConnectionSecretOwner, _, _ *xpv1.PublishConnectionDetailsTo) (bool, error) {
+					return false, errBoom
+				}))},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				comp: comp,
+			},
+			want: want{
+				cp:  xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				err: errors.Wrap(errBoom, errUnpublishPrevious),
+			},
+		},
+		"StoreConfigMissing": {
+			reason: "We should set the store config if it is missing.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing"}),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("existing")),
+			},
+		},
+		"Namespace": {
+			reason: "We should record the namespace the composition wants connection details published to.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(nil),
+				comp: nsComp,
+			},
+			want: want{
+				cp: xr(nsTo(string(uid), "team-b")),
+			},
+		},
+		"NamespaceAllowed": {
+			reason: "We should record the namespace the composition wants connection details published to if it is allowed.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithAllowedNamespaces("team-a", "team-b")},
+				cp:   xr(nil),
+				comp: nsComp,
+			},
+			want: want{
+				cp: xr(nsTo(string(uid), "team-b")),
+			},
+		},
+		"NamespaceNotAllowed": {
+			reason: "We should return an error if the composition wants connection details published to a namespace that isn't allowed.",
+			args: args{
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithAllowedNamespaces("team-a")},
+				cp:   xr(nil),
+				comp: nsComp,
+			},
+			want: want{
+				cp:  xr(nil),
+				err: errors.Errorf(errFmtNamespaceNotAllowed, "team-b"),
+			},
+		},
+		"NamespaceUnchanged": {
+			reason: "We should not update the composite resource if its namespace is already in sync with the composition.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(nsTo("existing", "team-b")),
+				comp: nsComp,
+			},
+			want: want{
+				cp: xr(nsTo("existing", "team-b")),
+			},
+		},
+		"NamespaceAlreadySet": {
+			reason: "We should not change the namespace of a composite resource that is already configured, even if the composition's namespace differs.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(nsTo("existing", "team-a")),
+				comp: nsComp,
+			},
+			want: want{
+				cp: xr(nsTo("existing", "team-a")),
+			},
+		},
+		"NamespaceCompositionSilent": {
+			reason: "We should not remove the namespace of a composite resource that is already configured if the composition doesn't set one.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(nsTo("existing", "team-a")),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(nsTo("existing", "team-a")),
+			},
+		},
+		"Metadata": {
+			reason: "We should carry the composition's connection secret metadata through to the composite resource.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(nil),
+				comp: mdComp,
+			},
+			want: want{
+				cp: xr(mdTo(string(uid), map[string]string{"team": "payments", "tier": "gold"})),
+			},
+		},
+		"MetadataAlreadySet": {
+			reason: "We should leave the connection secret metadata of a composite resource that is already configured untouched.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp: xr(&xpv1.PublishConnectionDetailsTo{
+					Name:                 "existing",
+					Metadata:             &xpv1.ConnectionSecretMetadata{Labels: map[string]string{"tier": "silver"}},
+					SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+				}),
+				comp: mdComp,
+			},
+			want: want{
+				cp: xr(&xpv1.PublishConnectionDetailsTo{
+					Name:                 "existing",
+					Metadata:             &xpv1.ConnectionSecretMetadata{Labels: map[string]string{"tier": "silver"}},
+					SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+				}),
+			},
+		},
+		"MetadataUnchanged": {
+			reason: "We should not update the composite resource on subsequent reconciles once it has the composition's connection secret metadata.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(mdTo("existing", map[string]string{"team": "payments", "tier": "silver"})),
+				comp: mdComp,
+			},
+			want: want{
+				cp: xr(mdTo("existing", map[string]string{"team": "payments", "tier": "silver"})),
+			},
+		},
+		"SecretType": {
+			reason: "We should carry the secret type declared by the composition through to the composite resource.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(nil),
+				comp: typedComp(`{"labels":{"team":"payments"},"type":"kubernetes.io/tls"}`),
+			},
+			want: want{
+				cp: xr(typedTo(string(uid), &tls)),
+			},
+		},
+		"SecretTypeDefault": {
+			reason: "We should not set a secret type if the composition doesn't declare one, so that an Opaque secret is published.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithAllowedSecretTypes(KnownSecretTypes()...)},
+				cp:   xr(nil),
+				comp: typedComp(`{"labels":{"team":"payments"}}`),
+			},
+			want: want{
+				cp: xr(typedTo(string(uid), nil)),
+			},
+		},
+		"SecretTypeFreeForm": {
+			reason: "We should allow any secret type by default.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(nil),
+				comp: typedComp(`{"labels":{"team":"payments"},"type":"example.org/custom"}`),
+			},
+			want: want{
+				cp: xr(typedTo(string(uid), &custom)),
+			},
+		},
+		"SecretTypeAllowed": {
+			reason: "We should allow a secret type that is explicitly allowed.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithAllowedSecretTypes(KnownSecretTypes()...)},
+				cp:   xr(nil),
+				comp: typedComp(`{"labels":{"team":"payments"},"type":"kubernetes.io/tls"}`),
+			},
+			want: want{
+				cp: xr(typedTo(string(uid), &tls)),
+			},
+		},
+		"SecretTypeNotAllowed": {
+			reason: "We should return an error if the composition declares a secret type that isn't allowed.",
+			args: args{
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithAllowedSecretTypes(KnownSecretTypes()...)},
+				cp:   xr(nil),
+				comp: typedComp(`{"labels":{"team":"payments"},"type":"example.org/custom"}`),
+			},
+			want: want{
+				cp:  xr(nil),
+				err: errors.Errorf(errFmtSecretTypeNotAllowed, custom),
+			},
+		},
+		"InvalidMetadata": {
+			reason: "We should return an error if the composition's connection secret metadata cannot be parsed.",
+			args: args{
+				cp: xr(nil),
+				comp: &v1.Composition{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyConnectionSecretMetadata: "{"}},
+					Spec:       comp.Spec,
+				},
+			},
+			want: want{
+				cp:  xr(nil),
+				err: errors.Wrap(errors.New("unexpected end of JSON input"), errParseSecretMetadata),
+			},
+		},
+		"StoreConfigChangedUpdateError": {
+			reason: "We should return any error encountered updating the store config.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(&xpv1.PublishConnectionDetailsTo{Name: "existing", SecretStoreConfigRef: &xpv1.Reference{Name: "kubernetes"}}),
+				comp: comp,
+			},
+			want: want{
+				cp:  xr(to("existing")),
+				err: errors.Wrap(errBoom, errUpdateComposite),
+			},
+		},
+		"DefaultName": {
+			reason: "We should name the store entry after the composite resource's UID by default.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to(string(uid))),
+			},
+		},
+		"NameTemplate": {
+			reason: "We should name the store entry using the supplied template.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithStoreNameTemplate("{{ .Namespace }}-{{ .Name }}")},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("team-a-cool-xr")),
+			},
+		},
+		"NonUniqueNameTemplate": {
+			reason: "We should append a short UID suffix to names that do not include the composite resource's name.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithStoreNameTemplate("{{ .Namespace }}-db")},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to("team-a-db-3f2a1b9c")),
+			},
+		},
+		"EmptyNameTemplate": {
+			reason: "We should fall back to the composite resource's UID if the template renders an empty name.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithStoreNameTemplate(" ")},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp: xr(to(string(uid))),
+			},
+		},
+		"InvalidNameTemplate": {
+			reason: "We should return an error if the name template cannot be parsed.",
+			args: args{
+				o:    []SecretStoreConnectionDetailsConfiguratorOption{WithStoreNameTemplate("{{ .Name")},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp:  xr(nil),
+				err: errors.Wrap(errParse, errParseStoreNameTemplate),
+			},
+		},
+		"UpdateError": {
+			reason: "We should return any error encountered updating the composite resource.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				cp:   xr(nil),
+				comp: comp,
+			},
+			want: want{
+				cp:  xr(to(string(uid))),
+				err: errors.Wrap(errBoom, errUpdateComposite),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewSecretStoreConnectionDetailsConfigurator(tc.args.kube, tc.args.o...)
+			err := c.Configure(context.Background(), tc.args.cp, tc.args.comp)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConfigure(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.cp, tc.args.cp); diff != "" {
+				t.Errorf("\n%s\nConfigure(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
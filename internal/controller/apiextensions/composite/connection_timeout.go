@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtTimeout = "timed out after %s"
+)
+
+// A timeoutError indicates a call to a connection details backend didn't
+// complete before its timeout.
+type timeoutError struct {
+	error
+}
+
+func (e timeoutError) Unwrap() error { return e.error }
+
+// IsTimeout returns true if the supplied error indicates that a call to a
+// connection details backend didn't complete before the timeout configured
+// for it. Timeouts are retryable.
+func IsTimeout(err error) bool {
+	return errors.As(err, &timeoutError{})
+}
+
+// withTimeout calls the supplied function with a context that expires after
+// the supplied duration. If the function returns an error after the context
+// expired the error is marked as a timeout, unless the supplied context
+// expired first.
+//
+// The function is called synchronously, so no goroutine outlives the call. It
+// follows that the function can only be interrupted if it honors context
+// cancellation. A function that ignores cancellation runs to completion, and
+// its result is returned as usual if it succeeds.
+func withTimeout(ctx context.Context, d time.Duration, fn func(ctx context.Context) error) error {
+	if d <= 0 {
+		return fn(ctx)
+	}
+	tctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	err := fn(tctx)
+	if err == nil || ctx.Err() != nil || !errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return Retryable(timeoutError{errors.Wrapf(err, errFmtTimeout, d)})
+}
+
+// A TimeoutConnectionPublisher bounds how long each call to the publisher it
+// wraps may take, independently of the overall reconcile timeout. Calls that
+// exceed the timeout return an error satisfying IsTimeout. A publisher that
+// doesn't honor context cancellation can't be interrupted, and will still
+// block until it returns.
+type TimeoutConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	timeout   time.Duration
+}
+
+// NewTimeoutConnectionPublisher returns a ConnectionPublisher that cancels
+// calls to the supplied publisher that take longer than the supplied timeout.
+// Calls are not bounded if the timeout is not positive.
+func NewTimeoutConnectionPublisher(p managed.ConnectionPublisher, d time.Duration) *TimeoutConnectionPublisher {
+	return &TimeoutConnectionPublisher{publisher: p, timeout: d}
+}
+
+// PublishConnection details for the supplied resource, within the timeout.
+func (p *TimeoutConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published := false
+	err := withTimeout(ctx, p.timeout, func(ctx context.Context) error {
+		var err error
+		published, err = p.publisher.PublishConnection(ctx, o, c)
+		return err
+	})
+	return published, err
+}
+
+// UnpublishConnection details for the supplied resource, within the timeout.
+func (p *TimeoutConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	return withTimeout(ctx, p.timeout, func(ctx context.Context) error {
+		return p.publisher.UnpublishConnection(ctx, o, c)
+	})
+}
+
+// A TimeoutConnectionDetailsFetcher bounds how long each call to the fetcher
+// it wraps may take, independently of the overall reconcile timeout. Calls
+// that exceed the timeout return an error satisfying IsTimeout.
+type TimeoutConnectionDetailsFetcher struct {
+	fetcher managed.ConnectionDetailsFetcher
+	timeout time.Duration
+}
+
+// NewTimeoutConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// cancels calls to the supplied fetcher that take longer than the supplied
+// timeout. Calls are not bounded if the timeout is not positive.
+//
+// The timeout is enforced by cancelling the context passed to the fetcher,
+// which is called synchronously so that no goroutine is leaked. A fetcher
+// that ignores context cancellation therefore can't be interrupted; the call
+// returns when the fetcher does.
+func NewTimeoutConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, d time.Duration) *TimeoutConnectionDetailsFetcher {
+	return &TimeoutConnectionDetailsFetcher{fetcher: f, timeout: d}
+}
+
+// FetchConnection details of the supplied resource, within the timeout.
+func (f *TimeoutConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	var conn managed.ConnectionDetails
+	err := withTimeout(ctx, f.timeout, func(ctx context.Context) error {
+		var err error
+		conn, err = f.fetcher.FetchConnection(ctx, o)
+		return err
+	})
+	return conn, err
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var (
+	_ managed.ConnectionPublisher      = &TimeoutConnectionPublisher{}
+	_ managed.ConnectionDetailsFetcher = &TimeoutConnectionDetailsFetcher{}
+	_ managed.ConnectionPublisher      = &slowBackend{}
+	_ managed.ConnectionDetailsFetcher = &slowBackend{}
+)
+
+// A slowBackend takes the configured time to respond. It returns early if its
+// context is cancelled, unless it ignores cancellation.
+type slowBackend struct {
+	delay        time.Duration
+	ignoreCancel bool
+}
+
+func (b *slowBackend) wait(ctx context.Context) error {
+	if b.ignoreCancel {
+		time.Sleep(b.delay)
+		return nil
+	}
+	t := time.NewTimer(b.delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (b *slowBackend) FetchConnection(ctx context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	if err := b.wait(ctx); err != nil {
+		return nil, err
+	}
+	return managed.ConnectionDetails{"a": []byte("b")}, nil
+}
+
+func (b *slowBackend) PublishConnection(ctx context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+	if err := b.wait(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *slowBackend) UnpublishConnection(ctx context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+	return b.wait(ctx)
+}
+
+func TestTimeoutConnectionDetailsFetcher(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		b       *slowBackend
+		timeout time.Duration
+	}
+	type want struct {
+		conn    managed.ConnectionDetails
+		err     error
+		timeout bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"WithinTimeout": {
+			reason: "We should return the connection details of a fetch that completes within the timeout.",
+			args: args{
+				ctx:     context.Background(),
+				b:       &slowBackend{},
+				timeout: time.Minute,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"NoTimeout": {
+			reason: "We should not bound fetches if the timeout is not positive.",
+			args: args{
+				ctx: context.Background(),
+				b:   &slowBackend{delay: 10 * time.Millisecond},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"TimedOut": {
+			reason: "We should return a timeout error if the fetch doesn't complete within the timeout.",
+			args: args{
+				ctx:     context.Background(),
+				b:       &slowBackend{delay: time.Minute},
+				timeout: 10 * time.Millisecond,
+			},
+			want: want{
+				err:     Retryable(timeoutError{errors.Wrapf(context.DeadlineExceeded, errFmtTimeout, 10*time.Millisecond)}),
+				timeout: true,
+			},
+		},
+		"IgnoresCancellation": {
+			reason: "We should return the result of a fetch that ignores cancellation once it completes.",
+			args: args{
+				ctx:     context.Background(),
+				b:       &slowBackend{delay: 50 * time.Millisecond, ignoreCancel: true},
+				timeout: 10 * time.Millisecond,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"a": []byte("b")},
+			},
+		},
+		"ParentDeadline": {
+			reason: "We should not report a timeout if the caller's context expired first.",
+			args: args{
+				ctx: func() context.Context {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+					t.Cleanup(cancel)
+					return ctx
+				}(),
+				b:       &slowBackend{delay: time.Minute},
+				timeout: time.Minute,
+			},
+			want: want{
+				err: context.DeadlineExceeded,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewTimeoutConnectionDetailsFetcher(tc.args.b, tc.args.timeout)
+			conn, err := f.FetchConnection(tc.args.ctx, &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if got := IsTimeout(err); got != tc.want.timeout {
+				t.Errorf("\n%s\nIsTimeout(...): want %t, got %t", tc.reason, tc.want.timeout, got)
+			}
+			if got := IsRetryable(err); got != tc.want.timeout {
+				t.Errorf("\n%s\nIsRetryable(...): want %t, got %t", tc.reason, tc.want.timeout, got)
+			}
+		})
+	}
+}
+
+func TestTimeoutConnectionPublisher(t *testing.T) {
+	timeout := 10 * time.Millisecond
+	slow := &slowBackend{delay: time.Minute}
+	p := NewTimeoutConnectionPublisher(slow, timeout)
+
+	published, err := p.PublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{})
+	if diff := cmp.Diff(Retryable(timeoutError{errors.Wrapf(context.DeadlineExceeded, errFmtTimeout, timeout)}), err, test.EquateErrors()); diff != "" {
+		t.Errorf("PublishConnection(...): -want error, +got error:\n%s", diff)
+	}
+	if published {
+		t.Errorf("PublishConnection(...): want published false, got true")
+	}
+	if !IsTimeout(err) {
+		t.Errorf("PublishConnection(...): want IsTimeout(err)")
+	}
+
+	err = p.UnpublishConnection(context.Background(), &fake.Composite{}, managed.ConnectionDetails{})
+	if diff := cmp.Diff(Retryable(timeoutError{errors.Wrapf(context.DeadlineExceeded, errFmtTimeout, timeout)}), err, test.EquateErrors()); diff != "" {
+		t.Errorf("UnpublishConnection(...): -want error, +got error:\n%s", diff)
+	}
+	if !IsTimeout(err) {
+		t.Errorf("UnpublishConnection(...): want IsTimeout(err)")
+	}
+}
+
+func TestTimeoutDoesNotLeakGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	f := NewTimeoutConnectionDetailsFetcher(&slowBackend{delay: 20 * time.Millisecond, ignoreCancel: true}, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		_, _ = f.FetchConnection(context.Background(), &fake.Composed{})
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("FetchConnection(...): %d goroutines running before, %d after", before, after)
+	}
+}
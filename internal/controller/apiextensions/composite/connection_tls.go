@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtNoCertificate    = "connection detail %q does not contain a PEM encoded certificate"
+	errFmtParseCertificate = "cannot parse certificate in connection detail %q"
+	errFmtExpired          = "certificate in connection detail %q expired at %s"
+)
+
+// DefaultCertificateExpiryWindow is the default window before its expiry in
+// which a certificate is considered to be nearing expiry.
+const DefaultCertificateExpiryWindow = 30 * 24 * time.Hour
+
+// A CertificateExpiryWarnFn is called when a certificate connection detail is
+// nearing (or past) its expiry.
+type CertificateExpiryWarnFn func(o resource.ConnectionSecretOwner, key string, notAfter time.Time)
+
+// A CertificateExpiryConnectionDetailsFetcherOption configures a
+// CertificateExpiryConnectionDetailsFetcher.
+type CertificateExpiryConnectionDetailsFetcherOption func(*CertificateExpiryConnectionDetailsFetcher)
+
+// WithCertificateKeys configures which connection detail keys contain PEM
+// encoded certificates. Only tls.crt is checked by default.
+func WithCertificateKeys(k ...string) CertificateExpiryConnectionDetailsFetcherOption {
+	return func(f *CertificateExpiryConnectionDetailsFetcher) {
+		f.keys = k
+	}
+}
+
+// WithCertificateExpiryWindow configures how long before its expiry a
+// certificate is considered to be nearing expiry.
+func WithCertificateExpiryWindow(d time.Duration) CertificateExpiryConnectionDetailsFetcherOption {
+	return func(f *CertificateExpiryConnectionDetailsFetcher) {
+		f.window = d
+	}
+}
+
+// WithCertificateExpiryWarner configures a function to be called when a
+// certificate is nearing expiry, for example to set a condition or record a
+// metric.
+func WithCertificateExpiryWarner(fn CertificateExpiryWarnFn) CertificateExpiryConnectionDetailsFetcherOption {
+	return func(f *CertificateExpiryConnectionDetailsFetcher) {
+		f.warn = fn
+	}
+}
+
+// WithRejectExpiredCertificates configures a
+// CertificateExpiryConnectionDetailsFetcher to return an error rather than
+// an expired certificate.
+func WithRejectExpiredCertificates() CertificateExpiryConnectionDetailsFetcherOption {
+	return func(f *CertificateExpiryConnectionDetailsFetcher) {
+		f.rejectExpired = true
+	}
+}
+
+// A CertificateExpiryConnectionDetailsFetcher checks the expiry of any
+// certificates fetched by another ConnectionDetailsFetcher. Connection details
+// that aren't certificates are passed through untouched.
+type CertificateExpiryConnectionDetailsFetcher struct {
+	fetcher       managed.ConnectionDetailsFetcher
+	keys          []string
+	window        time.Duration
+	warn          CertificateExpiryWarnFn
+	rejectExpired bool
+	now           func() time.Time
+}
+
+// NewCertificateExpiryConnectionDetailsFetcher returns a
+// ConnectionDetailsFetcher that checks the expiry of certificates fetched by
+// the supplied fetcher.
+func NewCertificateExpiryConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, o ...CertificateExpiryConnectionDetailsFetcherOption) *CertificateExpiryConnectionDetailsFetcher {
+	cf := &CertificateExpiryConnectionDetailsFetcher{
+		fetcher: f,
+		keys:    []string{"tls.crt"},
+		window:  DefaultCertificateExpiryWindow,
+		warn:    func(_ resource.ConnectionSecretOwner, _ string, _ time.Time) {},
+		now:     time.Now,
+	}
+	for _, fn := range o {
+		fn(cf)
+	}
+	return cf
+}
+
+// FetchConnection details of the supplied resource, checking the expiry of any
+// certificates.
+func (f *CertificateExpiryConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range f.keys {
+		v, ok := conn[k]
+		if !ok {
+			continue
+		}
+		notAfter, err := earliestNotAfter(k, v)
+		if err != nil {
+			return nil, err
+		}
+		now := f.now()
+		if now.Add(f.window).Before(notAfter) {
+			continue
+		}
+		f.warn(o, k, notAfter)
+		if f.rejectExpired && now.After(notAfter) {
+			return nil, errors.Errorf(errFmtExpired, k, notAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	return conn, nil
+}
+
+// earliestNotAfter returns the earliest expiry of the PEM encoded certificates
+// in the supplied value. A certificate chain is only as valid as its first
+// certificate to expire.
+func earliestNotAfter(key string, v []byte) (time.Time, error) {
+	var earliest time.Time
+	for {
+		var b *pem.Block
+		b, v = pem.Decode(v)
+		if b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, errFmtParseCertificate, key)
+		}
+		if earliest.IsZero() || c.NotAfter.Before(earliest) {
+			earliest = c.NotAfter
+		}
+	}
+	if earliest.IsZero() {
+		return time.Time{}, errors.Errorf(errFmtNoCertificate, key)
+	}
+	return earliest, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &CertificateExpiryConnectionDetailsFetcher{}
+
+func certificate(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertificateExpiryConnectionDetailsFetcher(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := certificate(t, now.Add(365*24*time.Hour))
+	expiring := certificate(t, now.Add(24*time.Hour))
+	expired := certificate(t, now.Add(-24*time.Hour))
+
+	fetch := func(c managed.ConnectionDetails) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return c, nil
+		})
+	}
+
+	type args struct {
+		f    managed.ConnectionDetailsFetcher
+		opts []CertificateExpiryConnectionDetailsFetcherOption
+	}
+	type want struct {
+		conn   managed.ConnectionDetails
+		err    error
+		warned []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoCertificate": {
+			reason: "Connection details that aren't certificates should pass through untouched.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"password": []byte("secret")}),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret")},
+			},
+		},
+		"NotPEM": {
+			reason: "We should return an error if a certificate key does not contain a certificate.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"tls.crt": []byte("wat")}),
+			},
+			want: want{
+				err: errors.Errorf(errFmtNoCertificate, "tls.crt"),
+			},
+		},
+		"Valid": {
+			reason: "We should not warn about a certificate that is not nearing expiry.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"tls.crt": valid}),
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"tls.crt": valid},
+			},
+		},
+		"NearingExpiry": {
+			reason: "We should warn about a certificate that is nearing expiry.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"tls.crt": expiring}),
+			},
+			want: want{
+				conn:   managed.ConnectionDetails{"tls.crt": expiring},
+				warned: []string{"tls.crt"},
+			},
+		},
+		"ChainNearingExpiry": {
+			reason: "We should warn about a chain in which any certificate is nearing expiry.",
+			args: args{
+				f:    fetch(managed.ConnectionDetails{"ca.crt": append(append([]byte{}, valid...), expiring...)}),
+				opts: []CertificateExpiryConnectionDetailsFetcherOption{WithCertificateKeys("ca.crt")},
+			},
+			want: want{
+				conn:   managed.ConnectionDetails{"ca.crt": append(append([]byte{}, valid...), expiring...)},
+				warned: []string{"ca.crt"},
+			},
+		},
+		"ExpiredAllowed": {
+			reason: "We should warn about, but return, an expired certificate by default.",
+			args: args{
+				f: fetch(managed.ConnectionDetails{"tls.crt": expired}),
+			},
+			want: want{
+				conn:   managed.ConnectionDetails{"tls.crt": expired},
+				warned: []string{"tls.crt"},
+			},
+		},
+		"ExpiredRejected": {
+			reason: "We should return an error rather than an expired certificate if configured to.",
+			args: args{
+				f:    fetch(managed.ConnectionDetails{"tls.crt": expired}),
+				opts: []CertificateExpiryConnectionDetailsFetcherOption{WithRejectExpiredCertificates()},
+			},
+			want: want{
+				err:    errors.Errorf(errFmtExpired, "tls.crt", now.Add(-24*time.Hour).Format(time.RFC3339)),
+				warned: []string{"tls.crt"},
+			},
+		},
+		"OutsideWindow": {
+			reason: "We should not warn about a certificate that expires outside the configured window.",
+			args: args{
+				f:    fetch(managed.ConnectionDetails{"tls.crt": expiring}),
+				opts: []CertificateExpiryConnectionDetailsFetcherOption{WithCertificateExpiryWindow(time.Hour)},
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"tls.crt": expiring},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var warned []string
+			w := WithCertificateExpiryWarner(func(_ resource.ConnectionSecretOwner, key string, _ time.Time) {
+				warned = append(warned, key)
+			})
+			f := NewCertificateExpiryConnectionDetailsFetcher(tc.args.f, append([]CertificateExpiryConnectionDetailsFetcherOption{w}, tc.args.opts...)...)
+			f.now = func() time.Time { return now }
+
+			conn, err := f.FetchConnection(context.Background(), &fake.Composed{})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.warned, warned); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want warnings, +got warnings:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Span names.
+const (
+	SpanFetchConnectionDetails = "FetchConnectionDetails"
+	SpanFetchConnectionChain   = "ConnectionDetailsFetcherChain"
+)
+
+// Span attributes.
+const (
+	SpanAttributeComposedName = "crossplane.composed.name"
+	SpanAttributeKeys         = "crossplane.connection.keys"
+	SpanAttributeFetcherIndex = "crossplane.connection.fetcher.index"
+)
+
+// A Span is a traced operation.
+type Span interface {
+	// SetAttribute annotates the span.
+	SetAttribute(key string, value any)
+
+	// RecordError records that the operation failed.
+	RecordError(err error)
+
+	// End the span.
+	End()
+}
+
+// A Tracer starts spans. It is typically an adapter for an OpenTelemetry
+// tracer.
+type Tracer interface {
+	// Start a span that is a child of any span in the supplied context. It
+	// returns a context that contains the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A NopTracer starts spans that do nothing.
+type NopTracer struct{}
+
+// Start returns the supplied context, and a span that does nothing.
+func (NopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(_ string, _ any) {}
+func (nopSpan) RecordError(_ error)          {}
+func (nopSpan) End()                         {}
+
+// A TracingConnectionDetailsFetcher traces fetching connection details. Each
+// fetch is a span annotated with the name of the composed resource and the
+// number of keys fetched.
+type TracingConnectionDetailsFetcher struct {
+	fetcher managed.ConnectionDetailsFetcher
+	tracer  Tracer
+	name    string
+	attrs   map[string]any
+}
+
+// NewTracingConnectionDetailsFetcher returns a ConnectionDetailsFetcher that
+// traces the supplied fetcher using the supplied tracer. Tracing does nothing
+// if the tracer is nil.
+func NewTracingConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, t Tracer) *TracingConnectionDetailsFetcher {
+	if t == nil {
+		t = NopTracer{}
+	}
+	return &TracingConnectionDetailsFetcher{fetcher: f, tracer: t, name: SpanFetchConnectionDetails}
+}
+
+// NewTracingConnectionDetailsFetcherChain returns a ConnectionDetailsFetcher
+// that traces the supplied chain using the supplied tracer. Each fetch is a
+// span, whose children are a span for each fetcher in the chain. Tracing does
+// nothing if the tracer is nil.
+func NewTracingConnectionDetailsFetcherChain(fc ConnectionDetailsFetcherChain, t Tracer) *TracingConnectionDetailsFetcher {
+	if t == nil {
+		t = NopTracer{}
+	}
+	traced := make(ConnectionDetailsFetcherChain, len(fc))
+	for i, f := range fc {
+		traced[i] = &TracingConnectionDetailsFetcher{
+			fetcher: f,
+			tracer:  t,
+			name:    SpanFetchConnectionDetails,
+			attrs:   map[string]any{SpanAttributeFetcherIndex: i},
+		}
+	}
+	return &TracingConnectionDetailsFetcher{fetcher: traced, tracer: t, name: SpanFetchConnectionChain}
+}
+
+// FetchConnection details of the supplied composed resource in a span.
+func (f *TracingConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	ctx, span := f.tracer.Start(ctx, f.name)
+	defer span.End()
+
+	span.SetAttribute(SpanAttributeComposedName, o.GetName())
+	for k, v := range f.attrs {
+		span.SetAttribute(k, v)
+	}
+
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute(SpanAttributeKeys, len(conn))
+	return conn, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var (
+	_ managed.ConnectionDetailsFetcher = &TracingConnectionDetailsFetcher{}
+	_ Tracer                           = NopTracer{}
+)
+
+type spanKey struct{}
+
+// A recordedSpan is a span recorded by a recordingTracer.
+type recordedSpan struct {
+	Name   string
+	Parent string
+	Attrs  map[string]any
+	Err    error
+	Ended  bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.Attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)              { s.Err = err }
+func (s *recordedSpan) End()                               { s.Ended = true }
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordedSpan{Name: name, Attrs: map[string]any{}}
+	if p, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		s.Parent = p.Name
+	}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func TestTracingConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	cd := &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}}
+
+	fetcher := func(conn managed.ConnectionDetails, err error) managed.ConnectionDetailsFetcher {
+		return ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+			return conn, err
+		})
+	}
+
+	type want struct {
+		err   error
+		spans []*recordedSpan
+	}
+
+	cases := map[string]struct {
+		reason string
+		f      func(t Tracer) managed.ConnectionDetailsFetcher
+		want   want
+	}{
+		"Fetch": {
+			reason: "We should trace a fetch, annotated with the composed resource's name and the number of keys fetched.",
+			f: func(t Tracer) managed.ConnectionDetailsFetcher {
+				return NewTracingConnectionDetailsFetcher(fetcher(managed.ConnectionDetails{"a": nil, "b": nil}, nil), t)
+			},
+			want: want{
+				spans: []*recordedSpan{
+					{Name: SpanFetchConnectionDetails, Attrs: map[string]any{SpanAttributeComposedName: "cool-composed", SpanAttributeKeys: 2}, Ended: true},
+				},
+			},
+		},
+		"FetchError": {
+			reason: "We should record errors in the span.",
+			f: func(t Tracer) managed.ConnectionDetailsFetcher {
+				return NewTracingConnectionDetailsFetcher(fetcher(nil, errBoom), t)
+			},
+			want: want{
+				err: errBoom,
+				spans: []*recordedSpan{
+					{Name: SpanFetchConnectionDetails, Attrs: map[string]any{SpanAttributeComposedName: "cool-composed"}, Err: errBoom, Ended: true},
+				},
+			},
+		},
+		"Chain": {
+			reason: "We should trace a chain, with a child span for each fetcher in the chain.",
+			f: func(t Tracer) managed.ConnectionDetailsFetcher {
+				return NewTracingConnectionDetailsFetcherChain(ConnectionDetailsFetcherChain{
+					fetcher(managed.ConnectionDetails{"a": nil}, nil),
+					fetcher(managed.ConnectionDetails{"b": nil}, nil),
+				}, t)
+			},
+			want: want{
+				spans: []*recordedSpan{
+					{Name: SpanFetchConnectionChain, Attrs: map[string]any{SpanAttributeComposedName: "cool-composed", SpanAttributeKeys: 2}, Ended: true},
+					{Name: SpanFetchConnectionDetails, Parent: SpanFetchConnectionChain, Attrs: map[string]any{SpanAttributeComposedName: "cool-composed", SpanAttributeFetcherIndex: 0, SpanAttributeKeys: 1}, Ended: true},
+					{Name: SpanFetchConnectionDetails, Parent: SpanFetchConnectionChain, Attrs: map[string]any{SpanAttributeComposedName: "cool-composed", SpanAttributeFetcherIndex: 1, SpanAttributeKeys: 1}, Ended: true},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := &recordingTracer{}
+			_, err := tc.f(tr).FetchConnection(context.Background(), cd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.spans, tr.spans, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want spans, +got spans:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTracingConnectionDetailsFetcherNilTracer(t *testing.T) {
+	ctx := context.WithValue(context.Background(), spanKey{}, "parent")
+	f := NewTracingConnectionDetailsFetcher(ConnectionDetailsFetcherFn(func(got context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+		if got != ctx {
+			t.Errorf("FetchConnection(...): want the unmodified context when there is no tracer")
+		}
+		return nil, nil
+	}), nil)
+	if _, err := f.FetchConnection(ctx, &fake.Composed{}); err != nil {
+		t.Errorf("FetchConnection(...): %s", err)
+	}
+}
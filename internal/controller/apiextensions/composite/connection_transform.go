@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+// Error strings.
+const (
+	errFmtConnDetailTransform  = "cannot apply transform %d (%s) to connection detail %q"
+	errFmtUnknownTransformType = "unknown connection detail transform %q"
+)
+
+// transform applies the supplied config's transforms to the supplied value,
+// in order. The supplied value is never modified.
+func transform(cfg ConnectionDetailExtractConfig, v []byte) ([]byte, error) {
+	for i, t := range cfg.Transforms {
+		var err error
+		if v, err = TransformConnectionDetail(t, v); err != nil {
+			return nil, errors.Wrapf(err, errFmtConnDetailTransform, i, t, cfg.Name)
+		}
+	}
+	return v, nil
+}
+
+// TransformConnectionDetail applies the supplied transform to the supplied
+// connection detail value, returning a new value.
+func TransformConnectionDetail(t v1.ConnectionDetailTransform, v []byte) ([]byte, error) {
+	switch t {
+	case v1.ConnectionDetailTransformTrim:
+		return append([]byte(nil), bytes.TrimSpace(v)...), nil
+	case v1.ConnectionDetailTransformLowercase:
+		return bytes.ToLower(v), nil
+	case v1.ConnectionDetailTransformUppercase:
+		return bytes.ToUpper(v), nil
+	case v1.ConnectionDetailTransformBase64Encode:
+		out := make([]byte, base64.StdEncoding.EncodedLen(len(v)))
+		base64.StdEncoding.Encode(out, v)
+		return out, nil
+	case v1.ConnectionDetailTransformBase64Decode:
+		out := make([]byte, base64.StdEncoding.DecodedLen(len(v)))
+		n, err := base64.StdEncoding.Decode(out, v)
+		if err != nil {
+			return nil, err
+		}
+		return out[:n], nil
+	}
+	return nil, errors.Errorf(errFmtUnknownTransformType, t)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	v1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+)
+
+func TestTransformConnectionDetail(t *testing.T) {
+	type args struct {
+		t v1.ConnectionDetailTransform
+		v []byte
+	}
+	type want struct {
+		v   []byte
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Trim": {
+			reason: "Trim should remove leading and trailing whitespace.",
+			args:   args{t: v1.ConnectionDetailTransformTrim, v: []byte("\t https://example.org \n")},
+			want:   want{v: []byte("https://example.org")},
+		},
+		"Lowercase": {
+			reason: "Lowercase should convert the value to lower case.",
+			args:   args{t: v1.ConnectionDetailTransformLowercase, v: []byte("Cool-Value")},
+			want:   want{v: []byte("cool-value")},
+		},
+		"Uppercase": {
+			reason: "Uppercase should convert the value to upper case.",
+			args:   args{t: v1.ConnectionDetailTransformUppercase, v: []byte("Cool-Value")},
+			want:   want{v: []byte("COOL-VALUE")},
+		},
+		"Base64Encode": {
+			reason: "Base64Encode should encode the value using standard base64 encoding.",
+			args:   args{t: v1.ConnectionDetailTransformBase64Encode, v: []byte("cool-token")},
+			want:   want{v: []byte("Y29vbC10b2tlbg==")},
+		},
+		"Base64Decode": {
+			reason: "Base64Decode should decode a value encoded using standard base64 encoding.",
+			args:   args{t: v1.ConnectionDetailTransformBase64Decode, v: []byte("Y29vbC10b2tlbg==")},
+			want:   want{v: []byte("cool-token")},
+		},
+		"Base64DecodeError": {
+			reason: "Base64Decode should return an error if the value isn't valid base64.",
+			args:   args{t: v1.ConnectionDetailTransformBase64Decode, v: []byte("Y29vbC10b2tlbg")},
+			want:   want{err: base64.CorruptInputError(12)},
+		},
+		"UnknownTransform": {
+			reason: "We should return an error if the transform is unknown.",
+			args:   args{t: v1.ConnectionDetailTransform("Reverse"), v: []byte("cool")},
+			want:   want{err: errors.Errorf(errFmtUnknownTransformType, "Reverse")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v, err := TransformConnectionDetail(tc.args.t, tc.args.v)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nTransformConnectionDetail(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.v, v); diff != "" {
+				t.Errorf("\n%s\nTransformConnectionDetail(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTransformConnectionDetailDoesNotModifyInput(t *testing.T) {
+	in := []byte(" cool ")
+	for _, tr := range []v1.ConnectionDetailTransform{
+		v1.ConnectionDetailTransformTrim,
+		v1.ConnectionDetailTransformLowercase,
+		v1.ConnectionDetailTransformUppercase,
+		v1.ConnectionDetailTransformBase64Encode,
+	} {
+		out, _ := TransformConnectionDetail(tr, in)
+		if len(out) > 0 {
+			out[0] = 'X'
+		}
+		if string(in) != " cool " {
+			t.Errorf("TransformConnectionDetail(%s, ...): modified its input to %q", tr, in)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A ConnectionValueEncoding hints whether a connection detail value should be
+// stored as a string or as binary, by backends that distinguish the two.
+type ConnectionValueEncoding string
+
+// Connection value encodings.
+const (
+	// ConnectionValueEncodingAuto stores values that are valid UTF-8 as
+	// strings, and other values as binary.
+	ConnectionValueEncodingAuto ConnectionValueEncoding = "Auto"
+
+	// ConnectionValueEncodingString stores values as strings. Values that
+	// aren't valid UTF-8 are stored as binary regardless.
+	ConnectionValueEncodingString ConnectionValueEncoding = "String"
+
+	// ConnectionValueEncodingBinary stores values as binary.
+	ConnectionValueEncodingBinary ConnectionValueEncoding = "Binary"
+)
+
+// An EncodingAwareConnectionPublisher is a ConnectionPublisher for a backend
+// that distinguishes string and binary values.
+type EncodingAwareConnectionPublisher interface {
+	// PublishConnectionWithEncodings publishes the supplied connection
+	// details, storing each as either a string or binary value per the
+	// supplied encodings, which are either ConnectionValueEncodingString or
+	// ConnectionValueEncodingBinary.
+	PublishConnectionWithEncodings(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails, e map[string]ConnectionValueEncoding) (bool, error)
+}
+
+// WithValueEncodings configures a SecretStoreConnectionPublisher with hints
+// for how to encode the connection details it publishes, keyed by the name
+// they're published as. Connection details without a hint are encoded using
+// ConnectionValueEncodingAuto. Hints are only used by backend publishers that
+// are an EncodingAwareConnectionPublisher.
+func WithValueEncodings(hints map[string]ConnectionValueEncoding) SecretStoreConnectionPublisherOption {
+	return func(p *SecretStoreConnectionPublisher) {
+		p.encodings = hints
+	}
+}
+
+// ResolveValueEncodings returns whether each of the supplied connection
+// details should be stored as a string or as binary, per the supplied hints.
+func ResolveValueEncodings(c managed.ConnectionDetails, hints map[string]ConnectionValueEncoding) map[string]ConnectionValueEncoding {
+	out := make(map[string]ConnectionValueEncoding, len(c))
+	for k, v := range c {
+		out[k] = ConnectionValueEncodingBinary
+		if hints[k] != ConnectionValueEncodingBinary && utf8.Valid(v) {
+			out[k] = ConnectionValueEncodingString
+		}
+	}
+	return out
+}
+
+// ConnectionValues returns the supplied connection details as strings or
+// bytes per the supplied encodings, for example to marshal them for a backend
+// that stores JSON. Marshalled to JSON, strings are stored as is, while bytes
+// are stored base64 encoded.
+func ConnectionValues(c managed.ConnectionDetails, e map[string]ConnectionValueEncoding) map[string]any {
+	out := make(map[string]any, len(c))
+	for k, v := range c {
+		if e[k] == ConnectionValueEncodingString {
+			out[k] = string(v)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// publish the supplied connection details using the backend publisher,
+// including how to encode them if it can use that.
+func (p *SecretStoreConnectionPublisher) publish(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if ep, ok := p.publisher.(EncodingAwareConnectionPublisher); ok {
+		return ep.PublishConnectionWithEncodings(ctx, o, c, ResolveValueEncodings(c, p.encodings))
+	}
+	return p.publisher.PublishConnection(ctx, o, c)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+// A typedConnectionStore is a store that distinguishes string and binary
+// values, like Vault's KV engine.
+type typedConnectionStore struct {
+	*memoryConnectionStore
+	values map[string]any
+}
+
+func (s *typedConnectionStore) PublishConnectionWithEncodings(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails, e map[string]ConnectionValueEncoding) (bool, error) {
+	s.values = ConnectionValues(c, e)
+	return s.PublishConnection(ctx, o, c)
+}
+
+func TestSecretStoreConnectionPublisherValueEncodings(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+
+	// A DER encoded certificate isn't valid UTF-8.
+	der := []byte{0x30, 0x82, 0xff, 0xfe}
+	c := managed.ConnectionDetails{
+		"endpoint": []byte("db.example.org"),
+		"password": []byte("s3cret"),
+		"cert":     der,
+	}
+
+	type want struct {
+		values map[string]any
+	}
+
+	cases := map[string]struct {
+		reason string
+		hints  map[string]ConnectionValueEncoding
+		want   want
+	}{
+		"Auto": {
+			reason: "We should store valid UTF-8 values as strings, and other values as binary, by default.",
+			want: want{
+				values: map[string]any{
+					"endpoint": "db.example.org",
+					"password": "s3cret",
+					"cert":     der,
+				},
+			},
+		},
+		"HintBinary": {
+			reason: "We should store values hinted as binary as binary, even if they're valid UTF-8.",
+			hints:  map[string]ConnectionValueEncoding{"password": ConnectionValueEncodingBinary},
+			want: want{
+				values: map[string]any{
+					"endpoint": "db.example.org",
+					"password": []byte("s3cret"),
+					"cert":     der,
+				},
+			},
+		},
+		"HintStringInvalidUTF8": {
+			reason: "We should store values hinted as strings as binary if they aren't valid UTF-8.",
+			hints: map[string]ConnectionValueEncoding{
+				"endpoint": ConnectionValueEncodingString,
+				"cert":     ConnectionValueEncodingString,
+			},
+			want: want{
+				values: map[string]any{
+					"endpoint": "db.example.org",
+					"password": "s3cret",
+					"cert":     der,
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &typedConnectionStore{memoryConnectionStore: &memoryConnectionStore{}}
+			p := NewSecretStoreConnectionPublisher(s, nil, WithSharedSecretStoreEntries(), WithValueEncodings(tc.hints))
+			if _, err := p.PublishConnection(context.Background(), publishing, c); err != nil {
+				t.Fatalf("\n%s\nPublishConnection(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.values, s.values); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want stored values, +got stored values:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(c, s.data); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStoreConnectionPublisherValueEncodingsUnsupported(t *testing.T) {
+	publishing := &fake.Composite{ConnectionDetailsPublisherTo: fake.ConnectionDetailsPublisherTo{To: &xpv1.PublishConnectionDetailsTo{Name: "cool"}}}
+	c := managed.ConnectionDetails{"password": []byte("s3cret"), "cert": []byte{0xff}}
+
+	s := &memoryConnectionStore{}
+	p := NewSecretStoreConnectionPublisher(s, nil, WithSharedSecretStoreEntries(), WithValueEncodings(map[string]ConnectionValueEncoding{"password": ConnectionValueEncodingBinary}))
+	if _, err := p.PublishConnection(context.Background(), publishing, c); err != nil {
+		t.Fatalf("PublishConnection(...): %s", err)
+	}
+
+	// Backends that don't distinguish string and binary values should get the
+	// connection details as is.
+	if diff := cmp.Diff(c, s.data); diff != "" {
+		t.Errorf("PublishConnection(...): -want published, +got published:\n%s", diff)
+	}
+}
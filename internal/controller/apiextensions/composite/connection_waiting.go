@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Error strings.
+const (
+	errFmtKeyNotAvailable = "key %q on composed resource %q not yet available"
+)
+
+// TypeConnectionDetails indicates whether all of a composite resource's
+// required connection details are available.
+const TypeConnectionDetails xpv1.ConditionType = "ConnectionDetails"
+
+// Reasons a composite resource's connection details are or are not available.
+const (
+	ReasonWaitingForConnectionDetail xpv1.ConditionReason = "WaitingForConnectionDetail"
+	ReasonConnectionDetailsAvailable xpv1.ConditionReason = "ConnectionDetailsAvailable"
+)
+
+// WaitingForConnectionDetail returns a condition that indicates a composite
+// resource is waiting for the supplied key to be available on the supplied
+// composed resource. It never includes connection detail values.
+func WaitingForConnectionDetail(composed, key string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeConnectionDetails,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonWaitingForConnectionDetail,
+		Message:            "Waiting: " + fmt.Sprintf(errFmtKeyNotAvailable, key, composed),
+	}
+}
+
+// ConnectionDetailsAvailable returns a condition that indicates all of a
+// composite resource's required connection details are available.
+func ConnectionDetailsAvailable() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeConnectionDetails,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConnectionDetailsAvailable,
+	}
+}
+
+// A KeyNotAvailableError indicates a required connection detail key is not
+// yet available on a composed resource. It is retryable.
+type KeyNotAvailableError struct {
+	// Composed is the name of the composed resource, typically its resource
+	// template name.
+	Composed string
+
+	// Key that is not yet available.
+	Key string
+}
+
+func (e KeyNotAvailableError) Error() string {
+	return fmt.Sprintf(errFmtKeyNotAvailable, e.Key, e.Composed)
+}
+
+// KeyNotAvailable returns a retryable error that indicates the supplied key is
+// not yet available on the supplied composed resource.
+func KeyNotAvailable(composed, key string) error {
+	return Retryable(KeyNotAvailableError{Composed: composed, Key: key})
+}
+
+// IsKeyNotAvailable returns the KeyNotAvailableError the supplied error
+// wraps, if any.
+func IsKeyNotAvailable(err error) (KeyNotAvailableError, bool) {
+	e := KeyNotAvailableError{}
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// A RequiredKeysConnectionDetailsFetcher returns a KeyNotAvailableError if
+// the connection details fetched from a composed resource don't include all
+// of its required keys.
+type RequiredKeysConnectionDetailsFetcher struct {
+	fetcher  managed.ConnectionDetailsFetcher
+	required map[string][]string
+}
+
+// NewRequiredKeysConnectionDetailsFetcher returns a ConnectionDetailsFetcher
+// that requires the supplied keys. Required keys are keyed by composed
+// resource template name, or by composed resource name if it was not rendered
+// from a named template.
+func NewRequiredKeysConnectionDetailsFetcher(f managed.ConnectionDetailsFetcher, required map[string][]string) *RequiredKeysConnectionDetailsFetcher {
+	return &RequiredKeysConnectionDetailsFetcher{fetcher: f, required: required}
+}
+
+// FetchConnection details of the supplied composed resource, returning an
+// error if any required key is not yet available. The first missing key is
+// reported, in the order keys were required.
+func (f *RequiredKeysConnectionDetailsFetcher) FetchConnection(ctx context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	conn, err := f.fetcher.FetchConnection(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	name := GetCompositionResourceName(o)
+	if name == "" {
+		name = o.GetName()
+	}
+	for _, k := range f.required[name] {
+		if _, ok := conn[k]; !ok {
+			return nil, KeyNotAvailable(name, k)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+var _ managed.ConnectionDetailsFetcher = &RequiredKeysConnectionDetailsFetcher{}
+
+func TestRequiredKeysConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	named := &fake.Composed{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cool-xr-abcde",
+		Annotations: map[string]string{AnnotationKeyCompositionResourceName: "db"},
+	}}
+
+	type args struct {
+		f        managed.ConnectionDetailsFetcher
+		required map[string][]string
+		o        resource.ConnectionSecretOwner
+	}
+	type want struct {
+		conn    managed.ConnectionDetails
+		err     error
+		missing *KeyNotAvailableError
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FetchError": {
+			reason: "We should return errors encountered while fetching connection details.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, errBoom
+				}),
+				o: named,
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+		"KeyNotAvailable": {
+			reason: "We should report the first required key that is not yet available, and the template it's required of.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"username": []byte("admin")}, nil
+				}),
+				required: map[string][]string{"db": {"username", "password", "endpoint"}},
+				o:        named,
+			},
+			want: want{
+				err:     KeyNotAvailable("db", "password"),
+				missing: &KeyNotAvailableError{Composed: "db", Key: "password"},
+			},
+		},
+		"UnnamedTemplate": {
+			reason: "We should fall back to the composed resource's name if it was not rendered from a named template.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return nil, nil
+				}),
+				required: map[string][]string{"cool-composed": {"password"}},
+				o:        &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-composed"}},
+			},
+			want: want{
+				err:     KeyNotAvailable("cool-composed", "password"),
+				missing: &KeyNotAvailableError{Composed: "cool-composed", Key: "password"},
+			},
+		},
+		"Available": {
+			reason: "We should return connection details that include all required keys.",
+			args: args{
+				f: ConnectionDetailsFetcherFn(func(_ context.Context, _ resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+					return managed.ConnectionDetails{"password": []byte("secret")}, nil
+				}),
+				required: map[string][]string{"db": {"password"}},
+				o:        named,
+			},
+			want: want{
+				conn: managed.ConnectionDetails{"password": []byte("secret")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewRequiredKeysConnectionDetailsFetcher(tc.args.f, tc.args.required)
+			conn, err := f.FetchConnection(context.Background(), tc.args.o)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nf.FetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+
+			var missing *KeyNotAvailableError
+			if e, ok := IsKeyNotAvailable(errors.Wrap(err, "wrapped")); ok {
+				missing = &e
+				if !IsRetryable(err) {
+					t.Errorf("\n%s\nIsRetryable(...): missing keys should be retryable", tc.reason)
+				}
+			}
+			if diff := cmp.Diff(tc.want.missing, missing); diff != "" {
+				t.Errorf("\n%s\nIsKeyNotAvailable(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWaitingForConnectionDetail(t *testing.T) {
+	want := "Waiting: key \"password\" on composed resource \"db\" not yet available"
+	if diff := cmp.Diff(want, WaitingForConnectionDetail("db", "password").Message); diff != "" {
+		t.Errorf("WaitingForConnectionDetail(...): -want message, +got message:\n%s", diff)
+	}
+}
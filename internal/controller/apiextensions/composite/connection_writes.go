@@ -0,0 +1,211 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// Defaults for write amplification tracking.
+const (
+	DefaultWriteRateThreshold = 10
+	DefaultWriteRateWindow    = 10 * time.Minute
+)
+
+// A WriteRateExceeded event indicates an owner's connection details were
+// written more than the threshold number of times within the window, which
+// typically indicates they're flapping.
+type WriteRateExceeded struct {
+	// Owner is the UID of the owner of the connection details, or its
+	// namespace and name if it has no UID.
+	Owner string
+
+	// Writes within the window.
+	Writes int
+
+	// Window in which writes were counted.
+	Window time.Duration
+}
+
+// A WriteRateExceededFn is called when an owner's connection details are
+// written too frequently. It is called asynchronously, and must be safe for
+// concurrent use.
+type WriteRateExceededFn func(e WriteRateExceeded)
+
+// WriteMetrics are Prometheus metrics that describe how often connection
+// details are written. Owners are not used as labels, to bound cardinality.
+// Use a WriteRateExceededFn to identify flapping owners.
+type WriteMetrics struct {
+	writes   *prometheus.CounterVec
+	exceeded prometheus.Counter
+}
+
+// NewWriteMetrics returns new connection details write metrics. They must be
+// registered, for example with controller-runtime's metrics.Registry.
+func NewWriteMetrics() *WriteMetrics {
+	return &WriteMetrics{
+		writes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_writes_total",
+			Help:      "The number of times connection details were written to a secret store.",
+		}, []string{"kind"}),
+		exceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Subsystem: "composite",
+			Name:      "connection_details_write_rate_exceeded_total",
+			Help:      "The number of times an owner's connection details were written more frequently than the configured threshold.",
+		}),
+	}
+}
+
+// Describe sends the metric descriptors to the supplied channel.
+func (m *WriteMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.writes.Describe(ch)
+	m.exceeded.Describe(ch)
+}
+
+// Collect sends the metrics to the supplied channel.
+func (m *WriteMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.writes.Collect(ch)
+	m.exceeded.Collect(ch)
+}
+
+// A WriteTrackingConnectionPublisherOption configures a
+// WriteTrackingConnectionPublisher.
+type WriteTrackingConnectionPublisherOption func(*WriteTrackingConnectionPublisher)
+
+// WithWriteRateThreshold configures how many writes within the supplied window
+// a WriteTrackingConnectionPublisher considers excessive.
+func WithWriteRateThreshold(writes int, window time.Duration) WriteTrackingConnectionPublisherOption {
+	return func(p *WriteTrackingConnectionPublisher) {
+		p.threshold = writes
+		p.window = window
+	}
+}
+
+// WithWriteRateExceededFn configures the function a
+// WriteTrackingConnectionPublisher calls when an owner's connection details
+// are written too frequently.
+func WithWriteRateExceededFn(fn WriteRateExceededFn) WriteTrackingConnectionPublisherOption {
+	return func(p *WriteTrackingConnectionPublisher) {
+		p.exceeded = fn
+	}
+}
+
+// WithWriteMetrics configures the metrics a WriteTrackingConnectionPublisher
+// records writes to.
+func WithWriteMetrics(m *WriteMetrics) WriteTrackingConnectionPublisherOption {
+	return func(p *WriteTrackingConnectionPublisher) {
+		p.metrics = m
+	}
+}
+
+// A WriteTrackingConnectionPublisher tracks how frequently each owner's
+// connection details are written, in order to detect write amplification
+// caused by flapping composite resources. Publishes that don't change the
+// published connection details are not considered writes.
+type WriteTrackingConnectionPublisher struct {
+	publisher managed.ConnectionPublisher
+	threshold int
+	window    time.Duration
+	exceeded  WriteRateExceededFn
+	metrics   *WriteMetrics
+	now       func() time.Time
+
+	mu       sync.Mutex
+	writes   map[string][]time.Time
+	flapping map[string]bool
+}
+
+// NewWriteTrackingConnectionPublisher returns a ConnectionPublisher that
+// tracks writes made by the supplied publisher.
+func NewWriteTrackingConnectionPublisher(p managed.ConnectionPublisher, o ...WriteTrackingConnectionPublisherOption) *WriteTrackingConnectionPublisher {
+	wp := &WriteTrackingConnectionPublisher{
+		publisher: p,
+		threshold: DefaultWriteRateThreshold,
+		window:    DefaultWriteRateWindow,
+		exceeded:  func(_ WriteRateExceeded) {},
+		now:       time.Now,
+		writes:    make(map[string][]time.Time),
+		flapping:  make(map[string]bool),
+	}
+	for _, fn := range o {
+		fn(wp)
+	}
+	return wp
+}
+
+// PublishConnection details for the supplied resource, tracking whether doing
+// so wrote to the secret store.
+func (p *WriteTrackingConnectionPublisher) PublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	published, err := p.publisher.PublishConnection(ctx, o, c)
+	if err != nil || !published {
+		return published, err
+	}
+
+	if p.metrics != nil {
+		p.metrics.writes.WithLabelValues(o.GetObjectKind().GroupVersionKind().Kind).Inc()
+	}
+
+	id := ownerID(o)
+	now := p.now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	writes := append(p.writes[id], now)
+	for len(writes) > 0 && !writes[0].After(now.Add(-p.window)) {
+		writes = writes[1:]
+	}
+	p.writes[id] = writes
+
+	if len(writes) <= p.threshold {
+		delete(p.flapping, id)
+		return published, nil
+	}
+
+	// Only report an owner once each time it starts flapping.
+	if p.flapping[id] {
+		return published, nil
+	}
+	p.flapping[id] = true
+	if p.metrics != nil {
+		p.metrics.exceeded.Inc()
+	}
+	go p.exceeded(WriteRateExceeded{Owner: id, Writes: len(writes), Window: p.window})
+
+	return published, nil
+}
+
+// UnpublishConnection details for the supplied resource, and stop tracking
+// its writes.
+func (p *WriteTrackingConnectionPublisher) UnpublishConnection(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	if err := p.publisher.UnpublishConnection(ctx, o, c); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	delete(p.writes, ownerID(o))
+	delete(p.flapping, ownerID(o))
+	p.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+)
+
+var (
+	_ managed.ConnectionPublisher = &WriteTrackingConnectionPublisher{}
+	_ prometheus.Collector        = &WriteMetrics{}
+)
+
+func TestWriteTrackingConnectionPublisher(t *testing.T) {
+	type step struct {
+		after     time.Duration
+		published bool
+	}
+	type want struct {
+		exceeded []WriteRateExceeded
+		writes   float64
+	}
+
+	cases := map[string]struct {
+		reason string
+		steps  []step
+		want   want
+	}{
+		"BelowThreshold": {
+			reason: "We should not report owners that write no more than the threshold within the window.",
+			steps: []step{
+				{published: true},
+				{after: time.Second, published: true},
+			},
+			want: want{
+				writes: 2,
+			},
+		},
+		"UnchangedPublishes": {
+			reason: "We should not count publishes that didn't write to the store.",
+			steps: []step{
+				{published: true},
+				{after: time.Second},
+				{after: time.Second},
+				{after: time.Second},
+			},
+			want: want{
+				writes: 1,
+			},
+		},
+		"OutsideWindow": {
+			reason: "We should not count writes that fall outside the window.",
+			steps: []step{
+				{published: true},
+				{after: time.Second, published: true},
+				{after: 2 * time.Minute, published: true},
+			},
+			want: want{
+				writes: 3,
+			},
+		},
+		"Exceeded": {
+			reason: "We should report an owner once when it exceeds the threshold, and again if it starts flapping again.",
+			steps: []step{
+				{published: true},
+				{after: time.Second, published: true},
+				{after: time.Second, published: true},
+				{after: time.Second, published: true},
+				{after: 2 * time.Minute, published: true},
+				{after: time.Second, published: true},
+				{after: time.Second, published: true},
+			},
+			want: want{
+				exceeded: []WriteRateExceeded{
+					{Owner: "cool-uid", Writes: 3, Window: time.Minute},
+					{Owner: "cool-uid", Writes: 3, Window: time.Minute},
+				},
+				writes: 7,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			i := 0
+			now := time.Now()
+			exceeded := make(chan WriteRateExceeded, 10)
+			m := NewWriteMetrics()
+
+			p := NewWriteTrackingConnectionPublisher(managed.ConnectionPublisherFns{
+				PublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+					return tc.steps[i].published, nil
+				},
+			},
+				WithWriteRateThreshold(2, time.Minute),
+				WithWriteRateExceededFn(func(e WriteRateExceeded) { exceeded <- e }),
+				WithWriteMetrics(m),
+			)
+			p.now = func() time.Time { return now }
+
+			o := &fake.Composite{ObjectMeta: metav1.ObjectMeta{UID: "cool-uid"}}
+			for i = range tc.steps {
+				now = now.Add(tc.steps[i].after)
+				if _, err := p.PublishConnection(context.Background(), o, managed.ConnectionDetails{}); err != nil {
+					t.Fatalf("p.PublishConnection(...): %s", err)
+				}
+			}
+
+			var got []WriteRateExceeded
+			for range tc.want.exceeded {
+				select {
+				case e := <-exceeded:
+					got = append(got, e)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("\n%s\np.PublishConnection(...): timed out waiting for write rate exceeded callback", tc.reason)
+				}
+			}
+			select {
+			case e := <-exceeded:
+				t.Errorf("\n%s\np.PublishConnection(...): unexpected write rate exceeded callback: %+v", tc.reason, e)
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			if diff := cmp.Diff(tc.want.exceeded, got); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want exceeded, +got exceeded:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.writes, testutil.ToFloat64(m.writes)); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want writes, +got writes:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(float64(len(tc.want.exceeded)), testutil.ToFloat64(m.exceeded)); diff != "" {
+				t.Errorf("\n%s\np.PublishConnection(...): -want exceeded metric, +got exceeded metric:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
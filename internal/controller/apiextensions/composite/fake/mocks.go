@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake contains fake connection details publishers and fetchers, for
+// testing composite resource reconcilers.
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+var (
+	_ managed.ConnectionPublisher      = &ConnectionPublisher{}
+	_ managed.ConnectionDetailsFetcher = &ConnectionDetailsFetcher{}
+)
+
+// A Call records a call to a ConnectionPublisher or ConnectionDetailsFetcher.
+type Call struct {
+	// Owner the call was made for.
+	Owner resource.ConnectionSecretOwner
+
+	// ConnectionDetails that were published or unpublished. Always nil for
+	// fetches.
+	ConnectionDetails managed.ConnectionDetails
+}
+
+// A ConnectionPublisher is a fake managed.ConnectionPublisher that records
+// the connection details it's asked to publish and unpublish. It's safe for
+// concurrent use.
+type ConnectionPublisher struct {
+	// ErrPublish is returned by PublishConnection, if set. Failed publishes
+	// are not recorded.
+	ErrPublish error
+
+	// ErrUnpublish is returned by UnpublishConnection, if set. Failed
+	// unpublishes are not recorded.
+	ErrUnpublish error
+
+	// Unchanged causes PublishConnection to report that publishing didn't
+	// change the published connection details.
+	Unchanged bool
+
+	mu          sync.Mutex
+	published   []Call
+	unpublished []Call
+}
+
+// PublishConnection records the supplied connection details, unless the
+// publisher was told to return an error.
+func (p *ConnectionPublisher) PublishConnection(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+	if p.ErrPublish != nil {
+		return false, p.ErrPublish
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, Call{Owner: o, ConnectionDetails: copyDetails(c)})
+	return !p.Unchanged, nil
+}
+
+// UnpublishConnection records the supplied connection details, unless the
+// publisher was told to return an error.
+func (p *ConnectionPublisher) UnpublishConnection(_ context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) error {
+	if p.ErrUnpublish != nil {
+		return p.ErrUnpublish
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unpublished = append(p.unpublished, Call{Owner: o, ConnectionDetails: copyDetails(c)})
+	return nil
+}
+
+// Published returns every successful call to PublishConnection, in order.
+func (p *ConnectionPublisher) Published() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.published...)
+}
+
+// Unpublished returns every successful call to UnpublishConnection, in order.
+func (p *ConnectionPublisher) Unpublished() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.unpublished...)
+}
+
+// A ConnectionDetailsFetcher is a fake managed.ConnectionDetailsFetcher that
+// returns programmed connection details, and records the resources it fetches
+// them for. It's safe for concurrent use.
+type ConnectionDetailsFetcher struct {
+	// ConnectionDetails are returned for resources that don't have
+	// connection details in ConnectionDetailsByName.
+	ConnectionDetails managed.ConnectionDetails
+
+	// ConnectionDetailsByName are returned for the resource of the same
+	// name.
+	ConnectionDetailsByName map[string]managed.ConnectionDetails
+
+	// Err is returned by FetchConnection, if set.
+	Err error
+
+	mu      sync.Mutex
+	fetched []Call
+}
+
+// FetchConnection returns a copy of the programmed connection details for
+// the supplied resource.
+func (f *ConnectionDetailsFetcher) FetchConnection(_ context.Context, o resource.ConnectionSecretOwner) (managed.ConnectionDetails, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, Call{Owner: o})
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if c, ok := f.ConnectionDetailsByName[o.GetName()]; ok {
+		return copyDetails(c), nil
+	}
+	return copyDetails(f.ConnectionDetails), nil
+}
+
+// Fetched returns every call to FetchConnection, in order, including calls
+// that returned an error.
+func (f *ConnectionDetailsFetcher) Fetched() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.fetched...)
+}
+
+// copyDetails deep copies the supplied connection details, so that callers
+// can't modify what was recorded or programmed.
+func copyDetails(c managed.ConnectionDetails) managed.ConnectionDetails {
+	if c == nil {
+		return nil
+	}
+	out := make(managed.ConnectionDetails, len(c))
+	for k, v := range c {
+		out[k] = append([]byte(nil), v...)
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestConnectionPublisher(t *testing.T) {
+	errBoom := errors.New("boom")
+	xr := &fake.Composite{ObjectMeta: metav1.ObjectMeta{Name: "cool-xr"}}
+
+	type want struct {
+		published   bool
+		err         error
+		publishes   []Call
+		unpublishes []Call
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      *ConnectionPublisher
+		want   want
+	}{
+		"Records": {
+			reason: "We should record every publish and unpublish, in order.",
+			p:      &ConnectionPublisher{},
+			want: want{
+				published: true,
+				publishes: []Call{
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("1")}},
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("2")}},
+				},
+				unpublishes: []Call{
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("2")}},
+				},
+			},
+		},
+		"Unchanged": {
+			reason: "We should report that publishing didn't change anything if told to.",
+			p:      &ConnectionPublisher{Unchanged: true},
+			want: want{
+				publishes: []Call{
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("1")}},
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("2")}},
+				},
+				unpublishes: []Call{
+					{Owner: xr, ConnectionDetails: managed.ConnectionDetails{"a": []byte("2")}},
+				},
+			},
+		},
+		"Errors": {
+			reason: "We should return the programmed errors, and not record failed calls.",
+			p:      &ConnectionPublisher{ErrPublish: errBoom, ErrUnpublish: errBoom},
+			want: want{
+				err: errBoom,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var published bool
+			var err error
+			for _, v := range []string{"1", "2"} {
+				c := managed.ConnectionDetails{"a": []byte(v)}
+				published, err = tc.p.PublishConnection(context.Background(), xr, c)
+
+				// Modifying published connection details must not
+				// modify what was recorded.
+				c["a"][0] = 'X'
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+
+			err = tc.p.UnpublishConnection(context.Background(), xr, managed.ConnectionDetails{"a": []byte("2")})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUnpublishConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.publishes, tc.p.Published(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nPublished(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.unpublishes, tc.p.Unpublished(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nUnpublished(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionDetailsFetcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	cd := &fake.Composed{ObjectMeta: metav1.ObjectMeta{Name: "cool-cd"}}
+
+	type want struct {
+		conn    managed.ConnectionDetails
+		err     error
+		fetched []Call
+	}
+
+	cases := map[string]struct {
+		reason string
+		f      *ConnectionDetailsFetcher
+		want   want
+	}{
+		"Default": {
+			reason: "We should return the programmed connection details.",
+			f:      &ConnectionDetailsFetcher{ConnectionDetails: managed.ConnectionDetails{"a": []byte("b")}},
+			want: want{
+				conn:    managed.ConnectionDetails{"a": []byte("b")},
+				fetched: []Call{{Owner: cd}},
+			},
+		},
+		"ByName": {
+			reason: "We should return the connection details programmed for the resource's name in preference to the default.",
+			f: &ConnectionDetailsFetcher{
+				ConnectionDetails:       managed.ConnectionDetails{"a": []byte("b")},
+				ConnectionDetailsByName: map[string]managed.ConnectionDetails{"cool-cd": {"c": []byte("d")}},
+			},
+			want: want{
+				conn:    managed.ConnectionDetails{"c": []byte("d")},
+				fetched: []Call{{Owner: cd}},
+			},
+		},
+		"Error": {
+			reason: "We should return the programmed error, and record the failed fetch.",
+			f:      &ConnectionDetailsFetcher{Err: errBoom},
+			want: want{
+				err:     errBoom,
+				fetched: []Call{{Owner: cd}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := tc.f.FetchConnection(context.Background(), cd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conn, conn); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.fetched, tc.f.Fetched()); diff != "" {
+				t.Errorf("\n%s\nFetched(): -want, +got:\n%s", tc.reason, diff)
+			}
+
+			// Modifying fetched connection details must not modify what
+			// was programmed.
+			for k := range conn {
+				conn[k][0] = 'X'
+			}
+			again, _ := tc.f.FetchConnection(context.Background(), cd)
+			if diff := cmp.Diff(tc.want.conn, again); diff != "" {
+				t.Errorf("\n%s\nFetchConnection(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
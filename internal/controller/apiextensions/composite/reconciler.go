@@ -66,6 +66,7 @@ const (
 	errFetchEnvironment  = "cannot fetch environment"
 	errSelectEnvironment = "cannot select environment"
 	errCompose           = "cannot compose resources"
+	errEnrich            = "cannot enrich connection details"
 	errRenderCD          = "cannot render composed resource"
 
 	errFmtPatchEnvironment = "cannot apply environment patch at index %d"
@@ -187,6 +188,14 @@ type CompositionResult struct {
 	Composed          []ComposedResource
 	ConnectionDetails managed.ConnectionDetails
 	Events            []event.Event
+
+	// ConnectionDetailSources maps each connection detail key to the name of
+	// the composed resource it was extracted from, if any.
+	ConnectionDetailSources map[string]string
+
+	// ConnectionSources summarizes the connection details fetched from each
+	// composed resource. It may be returned along with an error.
+	ConnectionSources []ConnectionSourceStatus
 }
 
 // A Composer composes (i.e. creates, updates, or deletes) resources given the
@@ -305,6 +314,43 @@ func WithConnectionPublishers(p ...managed.ConnectionPublisher) ReconcilerOption
 	}
 }
 
+// WithConnectionDetailSourceAnnotations specifies that the Reconciler should
+// annotate published connection secrets with the composed resource that
+// produced each key. Only secret stores that support annotations will honor
+// them.
+func WithConnectionDetailSourceAnnotations() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.composite.annotateSources = true
+	}
+}
+
+// WithConnectionSourcesStatus specifies that the Reconciler should summarize
+// the connection details fetched from each composed resource in the XR's
+// status.connectionSources field. At most max sources are summarized.
+func WithConnectionSourcesStatus(max int) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.composite.maxConnectionSources = max
+	}
+}
+
+// WithConnectionDetailsCacheEviction specifies that the Reconciler should evict
+// a composite resource's entry from the supplied cache when the composite
+// resource is deleted. Pass it the same cache that is passed to the Composer
+// using WithCompositeConnectionDetailsCache.
+func WithConnectionDetailsCacheEviction(cc *CompositeConnectionDetailsCache) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.composite.cache = cc
+	}
+}
+
+// WithCatalogEnricher specifies how the Reconciler should enrich connection
+// details before they're published.
+func WithCatalogEnricher(e CatalogEnricher) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.composite.CatalogEnricher = e
+	}
+}
+
 // WithComposer specifies how the Reconciler should compose resources.
 func WithComposer(c Composer) ReconcilerOption {
 	return func(r *Reconciler) {
@@ -327,6 +373,17 @@ type compositeResource struct {
 	EnvironmentSelector
 	Configurator
 	managed.ConnectionPublisher
+	CatalogEnricher
+
+	annotateSources bool
+
+	// cache of assembled connection details, which we evict deleted composite
+	// resources from. Nothing is evicted if it is nil.
+	cache *CompositeConnectionDetailsCache
+
+	// maxConnectionSources to summarize in status. Sources are not
+	// summarized if it is zero.
+	maxConnectionSources int
 }
 
 // NewReconciler returns a new Reconciler of composite resources.
@@ -433,6 +490,9 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		log = log.WithValues("deletion-timestamp", xr.GetDeletionTimestamp())
 
 		xr.SetConditions(xpv1.Deleting())
+		if r.composite.cache != nil {
+			r.composite.cache.Forget(xr.GetUID())
+		}
 		if err := r.composite.UnpublishConnection(ctx, xr, nil); err != nil {
 			log.Debug(errUnpublish, "error", err)
 			err = errors.Wrap(err, errUnpublish)
@@ -519,7 +579,24 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 
 	// TODO(negz): Pass this method a copy of xr, to make very clear that
 	// anything it does won't be reflected in the state of xr?
-	res, err := r.resource.Compose(ctx, xr, CompositionRequest{Composition: comp, Environment: env})
+	//
+	// Several composed resources may reference the same connection secret, so
+	// we deduplicate reads of connection secrets (and of the ProviderConfigs
+	// they use) while composing.
+	res, err := r.resource.Compose(WithProviderConfigReadDeduplication(WithSecretReadDeduplication(ctx)), xr, CompositionRequest{Composition: comp, Environment: env})
+	if r.composite.maxConnectionSources > 0 && res.ConnectionSources != nil {
+		if err := SetConnectionSources(xr, res.ConnectionSources, r.composite.maxConnectionSources); err != nil {
+			log.Debug(errSetConnectionSources, "error", err)
+		}
+	}
+	if e, ok := IsKeyNotAvailable(err); ok {
+		// A required connection detail is not yet available. This is
+		// expected while composed resources are provisioned, so we report
+		// what we're waiting for and requeue with exponential backoff.
+		log.Debug("Waiting for connection detail", "composed", e.Composed, "key", e.Key)
+		xr.SetConditions(xpv1.ReconcileSuccess(), WaitingForConnectionDetail(e.Composed, e.Key))
+		return reconcile.Result{Requeue: true}, errors.Wrap(r.client.Status().Update(ctx, xr), errUpdateStatus)
+	}
 	if err != nil {
 		log.Debug(errCompose, "error", err)
 		err = errors.Wrap(err, errCompose)
@@ -527,8 +604,52 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		xr.SetConditions(xpv1.ReconcileError(err))
 		return reconcile.Result{Requeue: true}, errors.Wrap(r.client.Status().Update(ctx, xr), errUpdateStatus)
 	}
+	if xr.GetCondition(TypeConnectionDetails).Reason == ReasonWaitingForConnectionDetail {
+		xr.SetConditions(ConnectionDetailsAvailable())
+	}
+
+	if r.composite.CatalogEnricher != nil {
+		conn, err := r.composite.EnrichConnectionDetails(ctx, xr, res.ConnectionDetails)
+		if err != nil {
+			log.Debug(errEnrich, "error", err)
+			err = errors.Wrap(err, errEnrich)
+			r.record.Event(xr, event.Warning(reasonPublish, err))
+			xr.SetConditions(xpv1.ReconcileError(err))
+
+			// Transient catalog errors are retried with backoff. Others
+			// are unlikely to resolve quickly, so we wait to poll.
+			result := reconcile.Result{RequeueAfter: r.pollInterval}
+			if IsRetryable(err) {
+				result = reconcile.Result{Requeue: true}
+			}
+			return result, errors.Wrap(r.client.Status().Update(ctx, xr), errUpdateStatus)
+		}
+		res.ConnectionDetails = conn
+	}
+
+	// We publish using a copy of the XR when we annotate connection detail
+	// sources, because we don't want to persist the annotations to the XR.
+	pxr := xr
+	if xr.GetPublishConnectionDetailsTo() != nil && r.composite.annotateSources {
+		so, _ := copyOwner(xr)
+		if cp, ok := so.(resource.Composite); ok {
+			p := cp.GetPublishConnectionDetailsTo()
+			AnnotateConnectionDetailSources(p, res.ConnectionDetailSources)
+			cp.SetPublishConnectionDetailsTo(p)
+			pxr = cp
+		}
+	}
 
-	published, err := r.composite.PublishConnection(ctx, xr, res.ConnectionDetails)
+	published, err := r.composite.PublishConnection(ctx, pxr, res.ConnectionDetails)
+	if errors.Is(err, ErrStoreConfigNotFound) {
+		// A missing store config is a misconfiguration that won't resolve
+		// until someone creates it, so we don't retry with backoff.
+		log.Debug(errPublish, "error", err)
+		err = errors.Wrap(err, errPublish)
+		r.record.Event(xr, event.Warning(reasonPublish, err))
+		xr.SetConditions(StoreConfigNotFound(err))
+		return reconcile.Result{RequeueAfter: r.pollInterval}, errors.Wrap(r.client.Status().Update(ctx, xr), errUpdateStatus)
+	}
 	if err != nil {
 		log.Debug(errPublish, "error", err)
 		err = errors.Wrap(err, errPublish)
@@ -395,6 +395,39 @@ func TestReconcile(t *testing.T) {
 				r: reconcile.Result{Requeue: true},
 			},
 		},
+		"WaitingForConnectionDetail": {
+			reason: "We should report which connection detail we're waiting for, and requeue.",
+			args: args{
+				mgr: &fake.Manager{},
+				opts: []ReconcilerOption{
+					WithClient(&test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: WantComposite(t, NewComposite(func(cr resource.Composite) {
+							cr.SetCompositionReference(&corev1.ObjectReference{})
+							cr.SetConditions(xpv1.ReconcileSuccess(), WaitingForConnectionDetail("db", "password"))
+						})),
+					}),
+					WithCompositeFinalizer(resource.NewNopFinalizer()),
+					WithCompositionSelector(CompositionSelectorFn(func(_ context.Context, cr resource.Composite) error {
+						cr.SetCompositionReference(&corev1.ObjectReference{})
+						return nil
+					})),
+					WithCompositionFetcher(CompositionFetcherFn(func(_ context.Context, _ resource.Composite) (*v1.Composition, error) {
+						return &v1.Composition{}, nil
+					})),
+					WithCompositionValidator(CompositionValidatorFn(func(_ *v1.Composition) error { return nil })),
+					WithConfigurator(ConfiguratorFn(func(_ context.Context, _ resource.Composite, _ *v1.Composition) error {
+						return nil
+					})),
+					WithComposer(ComposerFn(func(ctx context.Context, xr resource.Composite, req CompositionRequest) (CompositionResult, error) {
+						return CompositionResult{}, errors.Wrap(KeyNotAvailable("db", "password"), "cannot fetch connection details")
+					})),
+				},
+			},
+			want: want{
+				r: reconcile.Result{Requeue: true},
+			},
+		},
 		"PublishConnectionDetailsError": {
 			reason: "We should return any error encountered while publishing connection details.",
 			args: args{
@@ -433,6 +466,44 @@ func TestReconcile(t *testing.T) {
 				r: reconcile.Result{Requeue: true},
 			},
 		},
+		"PublishConnectionDetailsStoreConfigNotFound": {
+			reason: "We should report a missing store config, and wait to poll rather than retrying with backoff.",
+			args: args{
+				mgr: &fake.Manager{},
+				opts: []ReconcilerOption{
+					WithClient(&test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: WantComposite(t, NewComposite(func(cr resource.Composite) {
+							cr.SetCompositionReference(&corev1.ObjectReference{})
+							cr.SetConditions(StoreConfigNotFound(errors.Wrap(errors.Wrapf(ErrStoreConfigNotFound, errFmtStoreConfigName, "vault"), errPublish)))
+						})),
+					}),
+					WithCompositeFinalizer(resource.NewNopFinalizer()),
+					WithCompositionSelector(CompositionSelectorFn(func(_ context.Context, cr resource.Composite) error {
+						cr.SetCompositionReference(&corev1.ObjectReference{})
+						return nil
+					})),
+					WithCompositionFetcher(CompositionFetcherFn(func(_ context.Context, _ resource.Composite) (*v1.Composition, error) {
+						return &v1.Composition{}, nil
+					})),
+					WithCompositionValidator(CompositionValidatorFn(func(_ *v1.Composition) error { return nil })),
+					WithConfigurator(ConfiguratorFn(func(_ context.Context, _ resource.Composite, _ *v1.Composition) error {
+						return nil
+					})),
+					WithComposer(ComposerFn(func(ctx context.Context, xr resource.Composite, req CompositionRequest) (CompositionResult, error) {
+						return CompositionResult{}, nil
+					})),
+					WithConnectionPublishers(managed.ConnectionPublisherFns{
+						PublishConnectionFn: func(ctx context.Context, o resource.ConnectionSecretOwner, c managed.ConnectionDetails) (published bool, err error) {
+							return false, errors.Wrapf(ErrStoreConfigNotFound, errFmtStoreConfigName, "vault")
+						},
+					}),
+				},
+			},
+			want: want{
+				r: reconcile.Result{RequeueAfter: defaultPollInterval},
+			},
+		},
 		"CompositionWarnings": {
 			reason: "We should not requeue if our Composer returned warning events.",
 			args: args{
@@ -713,6 +784,174 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcileConnectionDetailsLastPublishedTime(t *testing.T) {
+	cd := managed.ConnectionDetails{"a": []byte("b")}
+
+	// The XR is stored between reconciles, like the API server would.
+	stored := NewComposite(func(cr resource.Composite) {
+		cr.SetUID("cool-uid")
+		cr.SetPublishConnectionDetailsTo(&xpv1.PublishConnectionDetailsTo{Name: "cool"})
+	})
+	writes := 0
+
+	opts := []ReconcilerOption{
+		WithClient(&test.MockClient{
+			MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				if o, ok := obj.(*composite.Unstructured); ok {
+					*o = composite.Unstructured{Unstructured: *stored.Unstructured.DeepCopy()}
+				}
+				return nil
+			},
+			MockUpdate: test.NewMockUpdateFn(nil),
+			MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+				got := obj.(*composite.Unstructured)
+				if !cmp.Equal(stored.GetConnectionDetailsLastPublishedTime(), got.GetConnectionDetailsLastPublishedTime()) {
+					writes++
+				}
+				stored = &composite.Unstructured{Unstructured: *got.Unstructured.DeepCopy()}
+
+				// Timestamps are stored with second precision, so we backdate
+				// the stored timestamp to tell whether a reconcile that happens
+				// within the same second rewrites it.
+				if stored.GetConnectionDetailsLastPublishedTime() != nil {
+					stored.SetConnectionDetailsLastPublishedTime(&metav1.Time{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)})
+				}
+				return nil
+			},
+		}),
+		WithCompositeFinalizer(resource.NewNopFinalizer()),
+		WithCompositionSelector(CompositionSelectorFn(func(_ context.Context, cr resource.Composite) error {
+			cr.SetCompositionReference(&corev1.ObjectReference{})
+			return nil
+		})),
+		WithCompositionFetcher(CompositionFetcherFn(func(_ context.Context, _ resource.Composite) (*v1.Composition, error) {
+			return &v1.Composition{}, nil
+		})),
+		WithCompositionValidator(CompositionValidatorFn(func(_ *v1.Composition) error { return nil })),
+		WithConfigurator(ConfiguratorFn(func(_ context.Context, _ resource.Composite, _ *v1.Composition) error {
+			return nil
+		})),
+		WithComposer(ComposerFn(func(_ context.Context, _ resource.Composite, _ CompositionRequest) (CompositionResult, error) {
+			return CompositionResult{ConnectionDetails: cd}, nil
+		})),
+		WithConnectionPublishers(NewSecretStoreConnectionPublisher(&memoryConnectionStore{}, nil)),
+	}
+
+	r := NewReconciler(&fake.Manager{}, resource.CompositeKind{}, opts...)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+			t.Fatalf("r.Reconcile(...): %s", err)
+		}
+	}
+
+	// The second reconcile publishes identical connection details, which the
+	// store already has. It mustn't touch the timestamp.
+	if stored.GetConnectionDetailsLastPublishedTime() == nil {
+		t.Errorf("r.Reconcile(...): connection details last published time was never written")
+	}
+	if diff := cmp.Diff(1, writes); diff != "" {
+		t.Errorf("r.Reconcile(...): -want connection details last published time writes, +got:\n%s", diff)
+	}
+}
+
+func TestReconcileConnectionDetailsCacheEviction(t *testing.T) {
+	now := metav1.Now()
+	xr := NewComposite(func(cr resource.Composite) {
+		cr.SetUID("cool-uid")
+		cr.SetDeletionTimestamp(&now)
+	})
+
+	cc := NewCompositeConnectionDetailsCache()
+	cc.Set("cool-uid", "cool-key", CompositeConnectionDetails{})
+	cc.Set("other-uid", "other-key", CompositeConnectionDetails{})
+
+	r := NewReconciler(&fake.Manager{}, resource.CompositeKind{},
+		WithClient(&test.MockClient{
+			MockGet:          WithComposite(t, xr),
+			MockStatusUpdate: test.NewMockSubResourceUpdateFn(nil),
+		}),
+		WithCompositeFinalizer(resource.NewNopFinalizer()),
+		WithConnectionPublishers(managed.ConnectionPublisherFns{
+			UnpublishConnectionFn: func(_ context.Context, _ resource.ConnectionSecretOwner, _ managed.ConnectionDetails) error {
+				return nil
+			},
+		}),
+		WithConnectionDetailsCacheEviction(cc),
+	)
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+		t.Fatalf("r.Reconcile(...): %s", err)
+	}
+
+	// Only the deleted XR should be evicted.
+	if _, hit := cc.Get("cool-uid", "cool-key"); hit {
+		t.Errorf("r.Reconcile(...): want deleted composite evicted from cache")
+	}
+	if _, hit := cc.Get("other-uid", "other-key"); !hit {
+		t.Errorf("r.Reconcile(...): want other composite to remain cached")
+	}
+}
+
+func TestReconcileConnectionDetailSourceAnnotations(t *testing.T) {
+	xr := NewComposite(func(cr resource.Composite) {
+		cr.SetUID("cool-uid")
+		cr.SetPublishConnectionDetailsTo(&xpv1.PublishConnectionDetailsTo{Name: "cool"})
+	})
+
+	var published *xpv1.PublishConnectionDetailsTo
+	opts := []ReconcilerOption{
+		WithClient(&test.MockClient{
+			MockGet:          WithComposite(t, xr),
+			MockUpdate:       test.NewMockUpdateFn(nil),
+			MockStatusUpdate: test.NewMockSubResourceUpdateFn(nil),
+		}),
+		WithCompositeFinalizer(resource.NewNopFinalizer()),
+		WithCompositionSelector(CompositionSelectorFn(func(_ context.Context, cr resource.Composite) error {
+			cr.SetCompositionReference(&corev1.ObjectReference{})
+			return nil
+		})),
+		WithCompositionFetcher(CompositionFetcherFn(func(_ context.Context, _ resource.Composite) (*v1.Composition, error) {
+			return &v1.Composition{}, nil
+		})),
+		WithCompositionValidator(CompositionValidatorFn(func(_ *v1.Composition) error { return nil })),
+		WithConfigurator(ConfiguratorFn(func(_ context.Context, _ resource.Composite, _ *v1.Composition) error {
+			return nil
+		})),
+		WithComposer(ComposerFn(func(_ context.Context, _ resource.Composite, _ CompositionRequest) (CompositionResult, error) {
+			return CompositionResult{
+				ConnectionDetails:       managed.ConnectionDetails{"password": []byte("secret")},
+				ConnectionDetailSources: map[string]string{"password": "cool-db"},
+			}, nil
+		})),
+		WithConnectionPublishers(managed.ConnectionPublisherFns{
+			PublishConnectionFn: func(_ context.Context, o resource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+				published = o.GetPublishConnectionDetailsTo()
+				return true, nil
+			},
+		}),
+		WithConnectionDetailSourceAnnotations(),
+	}
+
+	r := NewReconciler(&fake.Manager{}, resource.CompositeKind{}, opts...)
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+		t.Fatalf("r.Reconcile(...): %s", err)
+	}
+
+	want := &xpv1.PublishConnectionDetailsTo{
+		Name: "cool",
+		Metadata: &xpv1.ConnectionSecretMetadata{
+			Annotations: map[string]string{AnnotationKeyPrefixConnectionDetailSource + "password": "cool-db"},
+		},
+	}
+	if diff := cmp.Diff(want, published); diff != "" {
+		t.Errorf("r.Reconcile(...): -want published to, +got:\n%s", diff)
+	}
+
+	// The annotations are only published, never persisted to the XR.
+	if diff := cmp.Diff(&xpv1.PublishConnectionDetailsTo{Name: "cool"}, xr.GetPublishConnectionDetailsTo()); diff != "" {
+		t.Errorf("r.Reconcile(...): -want XR publish to, +got:\n%s", diff)
+	}
+}
+
 type CompositeModifier func(cr resource.Composite)
 
 func NewComposite(m ...CompositeModifier) *composite.Unstructured {
@@ -467,9 +467,10 @@ func CompositeReconcilerOptions(co controller.Options, d *v1.CompositeResourceDe
 	// reflects PublishConnectionDetailsWithStoreConfigRef in Composition to
 	// the composite resource.
 	if co.Features.Enabled(features.EnableAlphaExternalSecretStores) {
+		sp := composite.NewSecretStoreConnectionPublisher(connection.NewDetailsManager(c, v1alpha1.StoreConfigGroupVersionKind), d.GetConnectionSecretKeys())
 		pc := []managed.ConnectionPublisher{
 			composite.NewAPIFilteredSecretPublisher(c, d.GetConnectionSecretKeys()),
-			composite.NewSecretStoreConnectionPublisher(connection.NewDetailsManager(c, v1alpha1.StoreConfigGroupVersionKind), d.GetConnectionSecretKeys()),
+			sp,
 		}
 
 		// If external secret stores are enabled we need to support fetching
@@ -482,7 +483,7 @@ func CompositeReconcilerOptions(co controller.Options, d *v1.CompositeResourceDe
 		cc := composite.NewConfiguratorChain(
 			composite.NewAPINamingConfigurator(c),
 			composite.NewAPIConfigurator(c),
-			composite.NewSecretStoreConnectionDetailsConfigurator(c),
+			composite.NewSecretStoreConnectionDetailsConfigurator(c, composite.WithPreviousStoreCleanup(sp)),
 		)
 
 		o = append(o,
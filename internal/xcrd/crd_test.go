@@ -384,6 +384,20 @@ func TestForCompositeResource(t *testing.T) {
 											"lastPublishedTime": {Type: "string", Format: "date-time"},
 										},
 									},
+									"connectionSources": {
+										Type: "array",
+										Items: &extv1.JSONSchemaPropsOrArray{
+											Schema: &extv1.JSONSchemaProps{
+												Type: "object",
+												Properties: map[string]extv1.JSONSchemaProps{
+													"name":          {Type: "string"},
+													"keys":          {Type: "integer"},
+													"error":         {Type: "string"},
+													"lastFetchTime": {Type: "string", Format: "date-time"},
+												},
+											},
+										},
+									},
 								},
 							},
 						},
@@ -802,6 +816,20 @@ func TestForCompositeResourceClaim(t *testing.T) {
 												"lastPublishedTime": {Type: "string", Format: "date-time"},
 											},
 										},
+										"connectionSources": {
+											Type: "array",
+											Items: &extv1.JSONSchemaPropsOrArray{
+												Schema: &extv1.JSONSchemaProps{
+													Type: "object",
+													Properties: map[string]extv1.JSONSchemaProps{
+														"name":          {Type: "string"},
+														"keys":          {Type: "integer"},
+														"error":         {Type: "string"},
+														"lastFetchTime": {Type: "string", Format: "date-time"},
+													},
+												},
+											},
+										},
 									},
 								},
 							},
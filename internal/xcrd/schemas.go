@@ -346,6 +346,20 @@ func CompositeResourceStatusProps() map[string]extv1.JSONSchemaProps {
 				"lastPublishedTime": {Type: "string", Format: "date-time"},
 			},
 		},
+		"connectionSources": {
+			Type: "array",
+			Items: &extv1.JSONSchemaPropsOrArray{
+				Schema: &extv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"name":          {Type: "string"},
+						"keys":          {Type: "integer"},
+						"error":         {Type: "string"},
+						"lastFetchTime": {Type: "string", Format: "date-time"},
+					},
+				},
+			},
+		},
 	}
 }
 